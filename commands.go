@@ -0,0 +1,793 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/loxtest"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/resolve"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"github.com/urfave/cli/v2"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// exit codes, following the BSD sysexits convention used by the book
+const (
+	exitUsage   = 64
+	exitData    = 65
+	exitRuntime = 70
+)
+
+// parseError and runtimeError wrap the error of a failed script,
+// so commands can tell which stage failed
+type parseError struct{ error }
+
+type runtimeError struct{ error }
+
+// scriptExit converts the error of a failed script to an exit code
+func scriptExit(err error) error {
+	switch err.(type) {
+	case nil:
+		return nil
+	case parseError:
+		return cli.Exit("", exitData)
+	case runtimeError:
+		return cli.Exit("", exitRuntime)
+	}
+
+	return cli.Exit(err.Error(), exitData)
+}
+
+// usageError prints the error described by format followed by
+// the usage of the current command and exits with a usage error
+func usageError(cCtx *cli.Context, format string, args ...any) error {
+	w := cCtx.App.ErrWriter
+	fmt.Fprintf(w, "error: "+format+"\n", args...)
+
+	// the root command is named after the app
+	if cCtx.Command == nil || cCtx.Command.Name == cCtx.App.Name {
+		fmt.Fprintf(w, "usage: %s\n", strings.ReplaceAll(cCtx.App.UsageText, "\n", "\n       "))
+		fmt.Fprintln(w, "run 'lox --help' for more information")
+	} else {
+		fmt.Fprintf(w, "usage: lox %s [options] %s\n", cCtx.Command.Name, cCtx.Command.ArgsUsage)
+		fmt.Fprintf(w, "run 'lox %s --help' for more information\n", cCtx.Command.Name)
+	}
+	return cli.Exit("", exitUsage)
+}
+
+// onUsageError reports invalid flags the same way as invalid arguments
+func onUsageError(cCtx *cli.Context, err error, _ bool) error {
+	return usageError(cCtx, "%s", err.Error())
+}
+
+// expectScripts validates that the command got between min
+// and max (negative for no limit) script arguments
+func expectScripts(cCtx *cli.Context, min int, max int) error {
+	n := cCtx.Args().Len()
+	switch {
+	case n < min && min == 1 && max == 1:
+		return usageError(cCtx, "expected a script")
+	case max == 1 && n > 1:
+		return usageError(cCtx, "expected one script but got %d arguments", n)
+	case n < min:
+		return usageError(cCtx, "expected at least %d script(s) but got %d", min, n)
+	case max >= 0 && n > max:
+		return usageError(cCtx, "expected at most %d script(s) but got %d", max, n)
+	}
+
+	return nil
+}
+
+// scriptArguments returns the arguments following the script, which
+// are separated from it by "--", e.g. lox run script.lox -- a b
+func scriptArguments(cCtx *cli.Context) ([]string, error) {
+	args := cCtx.Args().Tail()
+	if len(args) == 0 {
+		return nil, nil
+	}
+	if args[0] != "--" {
+		return nil, usageError(cCtx, "expected one script but got %d arguments, pass arguments to the script after --", cCtx.Args().Len())
+	}
+	return args[1:], nil
+}
+
+func readScript(path string) (string, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return "", cli.Exit(err.Error(), exitUsage)
+	}
+	return string(text), nil
+}
+
+var dialects = map[string]token.Dialect{
+	"book":         token.BOOK,
+	"native-print": token.NATIVE_PRINT,
+}
+
+var dialectFlag = &cli.StringFlag{
+	Name:  "dialect",
+	Usage: "language `DIALECT`, either book (print is a statement) or native-print (print is a function)",
+	Value: "book",
+	Action: func(cCtx *cli.Context, name string) error {
+		d, ok := dialects[name]
+		if !ok {
+			return usageError(cCtx, "unknown dialect '%s', expected book or native-print", name)
+		}
+		dialect = d
+		ast.SetDialect(dialect)
+		return nil
+	},
+}
+
+var typecheckFlag = &cli.BoolFlag{
+	Name:  "typecheck",
+	Usage: "check the type annotations of scripts before running them",
+	Action: func(cCtx *cli.Context, enabled bool) error {
+		checkTypes = enabled
+		return nil
+	},
+}
+
+var emitFlag = &cli.StringFlag{
+	Name:  "emit",
+	Usage: "print the parsed script as `FORMAT` (ast-json or dot) instead of running it",
+	Action: func(cCtx *cli.Context, format string) error {
+		if format != "ast-json" && format != "dot" {
+			return usageError(cCtx, "unknown emit format '%s', expected ast-json or dot", format)
+		}
+		return nil
+	},
+}
+
+var debugFlag = &cli.BoolFlag{
+	Name:  "debug",
+	Usage: "pause before the first statement and at breakpoints to step through the script",
+}
+
+// interpreterFlags returns the flags configuring the interpreter, they
+// are applied by configureInterpreter. Every flag except leakcheck,
+// record and replay can also be set by a script using a pragma, see
+// scriptPragmas.
+func interpreterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:   "leakcheck",
+			Usage:  "report environments and functions still alive after the script finishes",
+			Hidden: true,
+		},
+		&cli.IntFlag{
+			Name:  "max-depth",
+			Usage: "maximum number of nested function calls before reporting a stack overflow",
+			Value: ast.DefaultMaxCallDepth,
+			Action: func(cCtx *cli.Context, depth int) error {
+				if depth < 1 {
+					return usageError(cCtx, "max-depth must be at least 1 but is %d", depth)
+				}
+				return nil
+			},
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "stop the script once it has run for `DURATION`",
+		},
+		&cli.IntFlag{
+			Name:  "fuel",
+			Usage: "stop the script once it has evaluated `N` statements, 0 allows any number",
+			Action: func(cCtx *cli.Context, n int) error {
+				if n < 0 {
+					return usageError(cCtx, "fuel must be at least 0 but is %d", n)
+				}
+				return nil
+			},
+		},
+		&cli.DurationFlag{
+			Name:  "slow-statement",
+			Usage: "report statements taking longer than `DURATION` once the script finishes",
+		},
+		&cli.IntFlag{
+			Name:  "max-iterations",
+			Usage: "fail loops running more than `N` iterations at once, 0 allows any number",
+			Action: func(cCtx *cli.Context, n int) error {
+				if n < 0 {
+					return usageError(cCtx, "max-iterations must be at least 0 but is %d", n)
+				}
+				return nil
+			},
+		},
+		&cli.BoolFlag{
+			Name:  "count-loops",
+			Usage: "report how often every loop ran once the script finishes",
+		},
+		&cli.IntFlag{
+			Name:  "memoize",
+			Usage: "remember up to `N` results of every pure function, 0 disables memoization",
+			Action: func(cCtx *cli.Context, size int) error {
+				if size < 0 {
+					return usageError(cCtx, "memoize must be at least 0 but is %d", size)
+				}
+				return nil
+			},
+		},
+		&cli.BoolFlag{
+			Name:  "normalize",
+			Usage: "print the same line endings and error messages on every platform, e.g. for golden files",
+		},
+		&cli.BoolFlag{
+			Name:  "strict-bool",
+			Usage: "fail if a condition is not a boolean instead of treating nil and false as false",
+		},
+		&cli.PathFlag{
+			Name:  "record",
+			Usage: "record the calls to natives interacting with the outside world, such as clock, to `FILE`",
+		},
+		&cli.PathFlag{
+			Name:  "replay",
+			Usage: "replay the calls recorded in `FILE` instead of interacting with the outside world",
+		},
+		dialectFlag,
+		typecheckFlag,
+	}
+}
+
+// configureInterpreter applies the interpreter flags, overridden by
+// pragmas returned by scriptPragmas. The returned function must be
+// called once the interpreter has finished.
+func configureInterpreter(cCtx *cli.Context, pragmas map[string]string) (func(), error) {
+	if cCtx.IsSet("record") && cCtx.IsSet("replay") {
+		return nil, usageError(cCtx, "--record and --replay cannot be combined")
+	}
+
+	cleanup := func() {}
+	if cCtx.Bool("leakcheck") {
+		ast.TrackAllocations(true)
+		cleanup = func() { ast.ReportLeaks(os.Stderr) }
+	}
+
+	// pragmas have been validated by scriptPragmas
+	strictBool := cCtx.Bool("strict-bool")
+	if value, ok := pragmas["strict-bool"]; ok {
+		strictBool = value != "false"
+	}
+	ast.SetStrictBool(strictBool)
+
+	normalize := cCtx.Bool("normalize")
+	if value, ok := pragmas["normalize"]; ok {
+		normalize = value != "false"
+	}
+	ast.SetNormalizedOutput(normalize)
+	if normalize {
+		previousOut, previousErr, previous := stdout, stderr, cleanup
+		stdout, stderr = ast.NormalizeLineEndings(stdout), ast.NormalizeLineEndings(stderr)
+		ast.SetOutput(stdout)
+		ast.SetErrorOutput(stderr)
+		cleanup = func() {
+			stdout, stderr = previousOut, previousErr
+			ast.SetOutput(stdout)
+			ast.SetErrorOutput(stderr)
+			ast.SetNormalizedOutput(false)
+			previous()
+		}
+	}
+
+	depth := cCtx.Int("max-depth")
+	if value, ok := pragmas["max-depth"]; ok {
+		depth, _ = strconv.Atoi(value)
+	}
+	ast.SetMaxCallDepth(depth)
+
+	iterations := cCtx.Int("max-iterations")
+	if value, ok := pragmas["max-iterations"]; ok {
+		iterations, _ = strconv.Atoi(value)
+	}
+	ast.SetMaxIterations(iterations)
+
+	fuel := cCtx.Int("fuel")
+	if value, ok := pragmas["fuel"]; ok {
+		fuel, _ = strconv.Atoi(value)
+	}
+	ast.SetFuel(fuel)
+
+	countLoops := cCtx.Bool("count-loops")
+	if value, ok := pragmas["count-loops"]; ok {
+		countLoops = value != "false"
+	}
+	ast.CountLoops(countLoops)
+
+	memoize := cCtx.Int("memoize")
+	if value, ok := pragmas["memoize"]; ok {
+		memoize = ast.DefaultMemoSize
+		if value != "" {
+			memoize, _ = strconv.Atoi(value)
+		}
+	}
+	ast.SetMemoize(memoize)
+
+	timeout := cCtx.Duration("timeout")
+	if value, ok := pragmas["timeout"]; ok {
+		timeout, _ = time.ParseDuration(value)
+	}
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		ast.SetContext(ctx)
+		previous := cleanup
+		cleanup = func() {
+			cancel()
+			previous()
+		}
+	}
+	threshold := cCtx.Duration("slow-statement")
+	if value, ok := pragmas["slow-statement"]; ok {
+		threshold, _ = time.ParseDuration(value)
+	}
+	ast.WatchStatements(threshold)
+
+	if path := cCtx.Path("record"); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			cleanup()
+			return nil, cli.Exit(err.Error(), exitUsage)
+		}
+
+		ast.RecordNatives(file)
+		previous := cleanup
+		cleanup = func() {
+			ast.RecordNatives(nil)
+			file.Close()
+			previous()
+		}
+	}
+
+	if path := cCtx.Path("replay"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			cleanup()
+			return nil, cli.Exit(err.Error(), exitUsage)
+		}
+		defer file.Close()
+
+		if err := ast.ReplayNatives(file); err != nil {
+			cleanup()
+			return nil, cli.Exit(fmt.Sprintf("%s: %s", path, err), exitData)
+		}
+		previous := cleanup
+		cleanup = func() {
+			ast.StopReplay()
+			previous()
+		}
+	}
+
+	return cleanup, nil
+}
+
+var runCommand = &cli.Command{
+	Name:         "run",
+	Usage:        "run a script",
+	ArgsUsage:    "<script> [-- arguments...]",
+	Flags:        append(interpreterFlags(), emitFlag, debugFlag),
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if err := expectScripts(cCtx, 1, -1); err != nil {
+			return err
+		}
+		args, err := scriptArguments(cCtx)
+		if err != nil {
+			return err
+		}
+
+		source, err := readScript(cCtx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		pragmas, err := scriptPragmas(cCtx, source)
+		if err != nil {
+			return err
+		}
+
+		switch cCtx.String("emit") {
+		case "ast-json":
+			return emitAST(source, "json")
+		case "dot":
+			return emitAST(source, "dot")
+		}
+
+		cleanup, err := configureInterpreter(cCtx, pragmas)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		if cCtx.Bool("debug") {
+			ast.SetDebugger(newDebugger(source, os.Stdin, stderr).pause)
+			defer ast.SetDebugger(nil)
+		}
+		ast.SetModuleDir(filepath.Dir(cCtx.Args().First()))
+		ast.SetArguments(args)
+		return scriptExit(exec(source))
+	},
+}
+
+var replCommand = &cli.Command{
+	Name:         "repl",
+	Usage:        "start an interactive session",
+	Flags:        interpreterFlags(),
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.Args().Len() != 0 {
+			return usageError(cCtx, "the REPL takes no arguments but got %d", cCtx.Args().Len())
+		}
+
+		cleanup, err := configureInterpreter(cCtx, nil)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		runRepl()
+		print("Leaving Lox REPL")
+		return nil
+	},
+}
+
+var checkCommand = &cli.Command{
+	Name:         "check",
+	Usage:        "report scan and parse errors without running the scripts",
+	ArgsUsage:    "<script>...",
+	Flags:        []cli.Flag{dialectFlag, typecheckFlag},
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if err := expectScripts(cCtx, 1, -1); err != nil {
+			return err
+		}
+
+		failed := false
+		for _, path := range cCtx.Args().Slice() {
+			source, err := readScript(path)
+			if err != nil {
+				return err
+			}
+
+			if _, err := scriptPragmas(cCtx, source); err != nil {
+				failed = true
+				continue
+			}
+
+			if _, err := parseSource(source); err != nil {
+				failed = true
+			}
+		}
+
+		if failed {
+			return cli.Exit("", exitData)
+		}
+		return nil
+	},
+}
+
+var lintCommand = &cli.Command{
+	Name:         "lint",
+	Usage:        "report errors and warnings, such as unused variables, without running the scripts, failing on either",
+	ArgsUsage:    "<script>...",
+	Flags:        []cli.Flag{dialectFlag, typecheckFlag},
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if err := expectScripts(cCtx, 1, -1); err != nil {
+			return err
+		}
+
+		failed := false
+		for _, path := range cCtx.Args().Slice() {
+			source, err := readScript(path)
+			if err != nil {
+				return err
+			}
+
+			if _, err := scriptPragmas(cCtx, source); err != nil {
+				failed = true
+				continue
+			}
+
+			if !lintScript(path, source) {
+				failed = true
+			}
+		}
+
+		if failed {
+			return cli.Exit("", exitData)
+		}
+		return nil
+	},
+}
+
+// lintScript reports the diagnostics of the script at path to stderr,
+// prefixed by the path, and reports whether there were none
+func lintScript(path string, source string) bool {
+	render := diag.NewRenderer(source, stderr).Report
+	clean := true
+	report := func(err error) {
+		clean = false
+		fmt.Fprintf(stderr, "%s: ", path)
+		render(err)
+	}
+
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{Dialect: dialect})
+	if stmts, err := parse.Parse(tokens, report); err == nil && scanErr == nil {
+		analyze(stmts, report)
+	}
+	return clean
+}
+
+var astCommand = &cli.Command{
+	Name:      "ast",
+	Usage:     "print the syntax tree of a script",
+	ArgsUsage: "<script>",
+	Flags: []cli.Flag{
+		dialectFlag,
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output `FORMAT`, either json, sexpr or dot",
+			Value: "json",
+			Action: func(cCtx *cli.Context, format string) error {
+				if format != "json" && format != "sexpr" && format != "dot" {
+					return usageError(cCtx, "unknown format '%s', expected json, sexpr or dot", format)
+				}
+				return nil
+			},
+		},
+	},
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if err := expectScripts(cCtx, 1, 1); err != nil {
+			return err
+		}
+
+		source, err := readScript(cCtx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		if _, err := scriptPragmas(cCtx, source); err != nil {
+			return err
+		}
+
+		return emitAST(source, cCtx.String("format"))
+	},
+}
+
+// emitAST prints the syntax tree of source in format
+func emitAST(source string, format string) error {
+	stmts, err := parseSource(source)
+	if err != nil {
+		return cli.Exit("", exitData)
+	}
+
+	switch format {
+	case "sexpr":
+		for _, stmt := range stmts {
+			fmt.Fprintln(stdout, stmt.DebugPrint())
+		}
+		return nil
+	case "dot":
+		return ast.WriteDOT(stdout, stmts)
+	}
+
+	out, err := ast.MarshalJSON(stmts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, string(out))
+	return nil
+}
+
+var benchCommand = &cli.Command{
+	Name:      "bench",
+	Usage:     "run a script repeatedly and report how long scanning, parsing, resolving and evaluating it took",
+	ArgsUsage: "<script>",
+	Flags: append(interpreterFlags(), &cli.IntFlag{
+		Name:    "iterations",
+		Aliases: []string{"runs"},
+		Usage:   "run the script `N` times",
+		Value:   10,
+		Action: func(cCtx *cli.Context, iterations int) error {
+			if iterations < 1 {
+				return usageError(cCtx, "iterations must be at least 1 but is %d", iterations)
+			}
+			return nil
+		},
+	}),
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if err := expectScripts(cCtx, 1, 1); err != nil {
+			return err
+		}
+
+		source, err := readScript(cCtx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		pragmas, err := scriptPragmas(cCtx, source)
+		if err != nil {
+			return err
+		}
+
+		cleanup, err := configureInterpreter(cCtx, pragmas)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		ast.SetModuleDir(filepath.Dir(cCtx.Args().First()))
+		// only the timings are printed, not what the script prints
+		previous := ast.Output()
+		ast.SetOutput(io.Discard)
+		defer ast.SetOutput(previous)
+
+		iterations := cCtx.Int("iterations")
+		times := make(map[string][]time.Duration, len(benchPhases))
+		for i := 0; i < iterations; i++ {
+			// modules are run again by every iteration
+			ast.ClearModules()
+			phases, err := benchOnce(source)
+			if err != nil {
+				return scriptExit(err)
+			}
+			for phase, elapsed := range phases {
+				times[phase] = append(times[phase], elapsed)
+			}
+		}
+
+		fmt.Fprintf(stdout, "%d iteration(s)\n", iterations)
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "phase\tmin\tmedian\tmean")
+		for _, phase := range benchPhases {
+			fastest, median, mean := durationStats(times[phase])
+			fmt.Fprintf(w, "%s\t%v\t%v\t%v\n", phase, fastest, median, mean)
+		}
+		return w.Flush()
+	},
+}
+
+// the phases timed by the bench command, in the order they run
+var benchPhases = []string{"scan", "parse", "resolve", "evaluate", "total"}
+
+// benchOnce runs source once, returning how long every phase took. The
+// returned error is a parseError or runtimeError as for exec.
+func benchOnce(source string) (map[string]time.Duration, error) {
+	phases := make(map[string]time.Duration, len(benchPhases))
+	report := diag.NewRenderer(source, stderr).Report
+	start := time.Now()
+	// lap records the time since the previous phase ended as phase
+	lap := func(phase string) {
+		now := time.Now()
+		phases[phase] = now.Sub(start) - phases["total"]
+		phases["total"] = now.Sub(start)
+	}
+
+	tokens, err := scan.Scan(source, report, scan.ScanContext{Dialect: dialect})
+	if err != nil {
+		return nil, parseError{err}
+	}
+	lap("scan")
+	stmts, err := parse.Parse(tokens, report)
+	if err != nil {
+		return nil, parseError{err}
+	}
+	lap("parse")
+	resolution, err := resolve.Resolve(stmts, report)
+	if err != nil {
+		return nil, parseError{err}
+	}
+	lap("resolve")
+	if err := ast.Interpret(stmts, resolution, report); err != nil {
+		return nil, runtimeError{err}
+	}
+	lap("evaluate")
+	return phases, nil
+}
+
+// durationStats returns the shortest, median and mean of durations,
+// which must not be empty
+func durationStats(durations []time.Duration) (fastest, median, mean time.Duration) {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+
+	n := len(sorted)
+	median = sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[0], median, total / time.Duration(n)
+}
+
+var testCommand = &cli.Command{
+	Name:         "test",
+	Usage:        "run every *_test.lox script in the given directories, a script passes if it meets the expectations in its comments, such as // expect: 3, or runs without errors if it has none",
+	ArgsUsage:    "[directory]...",
+	Flags:        interpreterFlags(),
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		dirs := cCtx.Args().Slice()
+		if len(dirs) == 0 {
+			dirs = []string{"."}
+		}
+
+		var scripts []string
+		for _, dir := range dirs {
+			err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && strings.HasSuffix(path, "_test.lox") {
+					scripts = append(scripts, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return usageError(cCtx, "%s", err.Error())
+			}
+		}
+
+		failed := 0
+		for _, path := range scripts {
+			source, err := readScript(path)
+			if err != nil {
+				return err
+			}
+
+			pragmas, err := scriptPragmas(cCtx, source)
+			if err != nil {
+				failed++
+				fmt.Printf("FAIL\t%s\n", path)
+				continue
+			}
+
+			start := time.Now()
+			cleanup, err := configureInterpreter(cCtx, pragmas)
+			if err != nil {
+				return err
+			}
+			// every test script imports its modules anew
+			ast.SetModuleDir(filepath.Dir(path))
+			ast.ClearModules()
+			// scripts without expectations pass if they run without errors
+			var failures []loxtest.Failure
+			var passed bool
+			if len(loxtest.Expectations(source, dialect)) > 0 {
+				failures = loxtest.Run(source, dialect)
+				passed = len(failures) == 0
+			} else {
+				passed = exec(source) == nil
+			}
+			cleanup()
+			if !passed {
+				failed++
+				fmt.Printf("FAIL\t%s\n", path)
+				for _, failure := range failures {
+					fmt.Printf("\t%s\n", failure)
+				}
+				continue
+			}
+			fmt.Printf("ok\t%s\t%v\n", path, time.Since(start).Round(time.Millisecond))
+		}
+
+		if failed > 0 {
+			return cli.Exit(fmt.Sprintf("%d of %d test script(s) failed", failed, len(scripts)), 1)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/urfave/cli/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the commands")
+
+// expectGolden runs command with args and compares what it printed with
+// testdata/golden. Run `go test . -update` to accept changed output.
+func expectGolden(t *testing.T, golden string, command *cli.Command, args ...string) {
+	t.Helper()
+	compareGolden(t, golden, capture(t, command, args...))
+}
+
+// capture runs command with args, returning what it printed
+func capture(t *testing.T, command *cli.Command, args ...string) string {
+	t.Helper()
+	var out, errs bytes.Buffer
+	stdout, stderr = &out, &errs
+	ast.SetOutput(&out)
+	defer func() {
+		stdout, stderr = os.Stdout, os.Stderr
+		ast.SetOutput(os.Stdout)
+	}()
+
+	app := &cli.App{
+		Commands: []*cli.Command{command},
+		// exit codes are returned instead of ending the test
+		ExitErrHandler: func(*cli.Context, error) {},
+	}
+	if err := app.Run(append([]string{"lox", command.Name}, args...)); err != nil {
+		t.Fatalf("%s: expected the command to succeed but got %v\n%s", command.Name, err, errs.String())
+	}
+	return out.String()
+}
+
+// compareGolden compares got with testdata/golden, or
+// rewrites the golden file if the tests are run with -update
+func compareGolden(t *testing.T, golden string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", golden)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestLint(t *testing.T) {
+	var errs bytes.Buffer
+	stderr = &errs
+	defer func() { stderr = os.Stderr }()
+
+	tests := []struct {
+		source string
+		clean  bool
+		want   string
+	}{
+		{`var x = 1; print x;`, true, ""},
+		{`{ var unused = 2; }`, false, "script.lox: [1] warning at \"unused\" - variable 'unused' is never used"},
+		{`fun f() { return 1; print 2; }`, false, "unreachable code"},
+		{`var = 1;`, false, "script.lox: [1] error"},
+	}
+
+	for _, test := range tests {
+		errs.Reset()
+		if clean := lintScript("script.lox", test.source); clean != test.clean {
+			t.Errorf("%s: expected clean to be %v but got %v", test.source, test.clean, clean)
+		}
+		if !strings.Contains(errs.String(), test.want) {
+			t.Errorf("%s: expected the diagnostics to contain %q but got %q", test.source, test.want, errs.String())
+		}
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	var out, errs bytes.Buffer
+	stdout, stderr = &out, &errs
+	ast.SetOutput(&out)
+	defer func() {
+		stdout, stderr = os.Stdout, os.Stderr
+		ast.SetOutput(os.Stdout)
+	}()
+
+	for _, source := range []string{"print 1;\n/* unterminated", "print 1;\n\"unterminated", "print 1; @"} {
+		errs.Reset()
+		if _, ok := exec(source).(parseError); !ok {
+			t.Errorf("%q: expected the script to fail to scan", source)
+		}
+		if _, err := parseSource(source); err == nil {
+			t.Errorf("%q: expected check to fail", source)
+		}
+		if errs.Len() == 0 {
+			t.Errorf("%q: expected the scan error to be reported", source)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%q: expected nothing to run but got %q", source, out.String())
+		}
+	}
+}
+
+func TestAstJSON(t *testing.T) {
+	expectGolden(t, "ast.json.golden", astCommand, "testdata/script.lox")
+}
+
+func TestTokens(t *testing.T) {
+	expectGolden(t, "tokens.golden", tokensCommand, "testdata/script.lox")
+	expectGolden(t, "tokens.json.golden", tokensCommand, "--json", "testdata/script.lox")
+}
+
+func TestHighlight(t *testing.T) {
+	expectGolden(t, "highlight.ansi.golden", highlightCommand, "testdata/script.lox")
+	expectGolden(t, "highlight.html.golden", highlightCommand, "--format", "html", "--theme", "light", "testdata/script.lox")
+}
+
+func TestBench(t *testing.T) {
+	out := capture(t, benchCommand, "--iterations", "3", "testdata/script.lox")
+
+	// the timings change from run to run
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if _, err := time.ParseDuration(field); err == nil {
+				fields[i] = "<duration>"
+			}
+		}
+		lines = append(lines, strings.Join(fields, " "))
+	}
+	compareGolden(t, "bench.golden", strings.Join(lines, "\n"))
+}
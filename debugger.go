@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const debuggerHelp = `commands:
+  c, continue     run until the next breakpoint
+  s, step         run until the next statement, entering calls
+  n, next         run until the next statement, stepping over calls
+  o, out          run until the current function has returned
+  b, break LINE   pause at the statements on LINE
+  d, delete LINE  remove the breakpoint on LINE
+  l, locals       print the local variables
+  g, globals      print the global variables
+  p, print EXPR   print the value of EXPR
+  w, where        print the calls leading to the statement
+  q, quit         stop the script
+an empty line repeats the last command`
+
+// debugger is the front end of the interpreter's debugger, it reads
+// commands from in whenever the script is paused
+type debugger struct {
+	source string
+	index  *token.LineIndex
+	in     *bufio.Scanner
+	out    io.Writer
+	// the command an empty line repeats
+	last string
+}
+
+func newDebugger(source string, in io.Reader, out io.Writer) *debugger {
+	return &debugger{
+		source: source,
+		index:  token.NewLineIndex(source),
+		in:     bufio.NewScanner(in),
+		out:    out,
+	}
+}
+
+// pause prompts for commands until one resumes the script,
+// the script is stopped once the input ends
+func (d *debugger) pause(p ast.Pause) ast.StepMode {
+	fmt.Fprintf(d.out, "paused at line %d\n%s\n", p.Token.Line, d.line(p.Token.Line))
+
+	for {
+		fmt.Fprint(d.out, "(debug) ")
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			return ast.Abort
+		}
+
+		input := strings.TrimSpace(d.in.Text())
+		if input == "" {
+			input = d.last
+		}
+		d.last = input
+
+		command, arg, _ := strings.Cut(input, " ")
+		arg = strings.TrimSpace(arg)
+		switch command {
+		case "":
+		case "c", "continue":
+			return ast.Continue
+		case "s", "step":
+			return ast.StepInto
+		case "n", "next":
+			return ast.StepOver
+		case "o", "out":
+			return ast.StepOut
+		case "q", "quit":
+			return ast.Abort
+		case "b", "break", "d", "delete":
+			line, err := strconv.Atoi(arg)
+			if err != nil || line < 1 || line > d.index.LineCount() {
+				fmt.Fprintf(d.out, "expected a line between 1 and %d\n", d.index.LineCount())
+				continue
+			}
+			ast.SetBreakpoint(line, command == "b" || command == "break")
+			breakpoints := ast.Breakpoints()
+			slices.Sort(breakpoints)
+			fmt.Fprintf(d.out, "breakpoints: %v\n", breakpoints)
+		case "l", "locals":
+			d.locals(p.Env)
+		case "g", "globals":
+			d.globals()
+		case "p", "print":
+			d.print(arg, p.Env)
+		case "w", "where":
+			for i := len(p.Calls) - 1; i >= 0; i-- {
+				fmt.Fprintf(d.out, "  at %s\n", p.Calls[i])
+			}
+			fmt.Fprintln(d.out, "  at <script>")
+		case "h", "help":
+			fmt.Fprintln(d.out, debuggerHelp)
+		default:
+			fmt.Fprintf(d.out, "unknown command '%s', see help\n", command)
+		}
+	}
+}
+
+// line formats the source of line the way diagnostics do
+func (d *debugger) line(line int) string {
+	text := d.source[d.index.LineStart(line):d.index.LineEnd(line)]
+	return fmt.Sprintf("%4d | %s", line, strings.TrimRight(text, "\r"))
+}
+
+// locals prints the variables of env and the environments enclosing
+// it, except for the global environment, nearest scope first
+func (d *debugger) locals(env *ast.Environment) {
+	for ; env != nil && env != ast.GlobalEnvironment(); env = env.Enclosing() {
+		for _, name := range env.Names() {
+			value, _ := env.Lookup(name)
+			fmt.Fprintln(d.out, binding(name, value))
+		}
+	}
+}
+
+// globals prints the global variables defined by the script
+func (d *debugger) globals() {
+	env := ast.GlobalEnvironment()
+	for _, name := range env.Names() {
+		if !ast.IsBuiltin(name) {
+			value, _ := env.Lookup(name)
+			fmt.Fprintln(d.out, binding(name, value))
+		}
+	}
+}
+
+// print evaluates the expression source in env and prints its value
+func (d *debugger) print(source string, env *ast.Environment) {
+	report := diag.NewRenderer(source, d.out).Report
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{Dialect: dialect})
+	expr, err := parse.ParseExpression(tokens, report)
+	if err != nil || scanErr != nil {
+		return
+	}
+
+	value, err := expr.Evaluate(env)
+	if err != nil {
+		report(err)
+		return
+	}
+	fmt.Fprintln(d.out, value.DebugPrint())
+}
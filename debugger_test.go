@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDebuggerCommands(t *testing.T) {
+	var errs bytes.Buffer
+	stderr = &errs
+	defer func() { stderr = os.Stderr }()
+
+	global := ast.GlobalEnvironment()
+	env := ast.NewEnvironment(global)
+	env.Define("a", ast.LoxNumber(1))
+	env.Define("s", ast.LoxString("x"))
+	pause := ast.Pause{
+		Token: token.Token{Type: token.VAR, Lexme: "var", Line: 2},
+		Env:   env,
+		Calls: []string{"f (line 3)"},
+	}
+
+	var out bytes.Buffer
+	source := "fun f() {\n  var b = 2;\n}\nf();"
+	d := newDebugger(source, strings.NewReader("l\np a + 1\nb 4\nw\n\nb 9\nx\nn\n"), &out)
+	if mode := d.pause(pause); mode != ast.StepOver {
+		t.Errorf("expected to step over but got %v", mode)
+	}
+	ast.SetBreakpoint(4, false)
+
+	want := []string{
+		"paused at line 2",
+		"   2 |   var b = 2;",
+		`(debug) a = 1 (NUMBER)`,
+		`s = "x" (STRING)`,
+		"(debug) 2",
+		"(debug) breakpoints: [4]",
+		"(debug)   at f (line 3)",
+		"  at <script>",
+		"(debug)   at f (line 3)",
+		"  at <script>",
+		"(debug) expected a line between 1 and 4",
+		"(debug) unknown command 'x', see help",
+		"(debug) ",
+	}
+	if got := out.String(); got != strings.Join(want, "\n") {
+		t.Errorf("expected output\n%s\nbut got\n%s", strings.Join(want, "\n"), got)
+	}
+
+	// the script is stopped once the input ends
+	d = newDebugger(source, strings.NewReader(""), &out)
+	if mode := d.pause(pause); mode != ast.Abort {
+		t.Errorf("expected to abort but got %v", mode)
+	}
+}
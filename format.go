@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/urfave/cli/v2"
+	"os"
+)
+
+var fmtCommand = &cli.Command{
+	Name:      "fmt",
+	Usage:     "format scripts in the canonical style, printing the result",
+	ArgsUsage: "<script>...",
+	Flags: []cli.Flag{
+		dialectFlag,
+		&cli.BoolFlag{
+			Name:  "w",
+			Usage: "write the result to the scripts instead of printing it",
+		},
+		&cli.BoolFlag{
+			Name:  "check",
+			Usage: "list the scripts that are not formatted and fail if there are any",
+		},
+	},
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if err := expectScripts(cCtx, 1, -1); err != nil {
+			return err
+		}
+
+		write, check := cCtx.Bool("w"), cCtx.Bool("check")
+		if write && check {
+			return usageError(cCtx, "-w and --check cannot be combined")
+		}
+
+		unformatted := 0
+		for _, path := range cCtx.Args().Slice() {
+			source, err := readScript(path)
+			if err != nil {
+				return err
+			}
+
+			if _, err := scriptPragmas(cCtx, source); err != nil {
+				return err
+			}
+
+			formatted, err := formatSource(source)
+			if err != nil {
+				return cli.Exit("", exitData)
+			}
+
+			switch {
+			case check:
+				if formatted != source {
+					unformatted++
+					fmt.Println(path)
+				}
+			case write:
+				if formatted == source {
+					continue
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					return cli.Exit(err.Error(), exitUsage)
+				}
+				if err := os.WriteFile(path, []byte(formatted), info.Mode()); err != nil {
+					return cli.Exit(err.Error(), exitUsage)
+				}
+			default:
+				fmt.Print(formatted)
+			}
+		}
+
+		if unformatted > 0 {
+			return cli.Exit("", 1)
+		}
+		return nil
+	},
+}
+
+// formatSource formats source, scripts which do not
+// parse are reported to stderr and left as they are
+func formatSource(source string) (string, error) {
+	report := diag.NewRenderer(source, os.Stderr).Report
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{Dialect: dialect, IncludeComments: true})
+
+	stmts, comments, err := parse.ParseWithComments(tokens, report, parse.DefaultParseOptions())
+	if err != nil {
+		return "", err
+	}
+	if scanErr != nil {
+		return "", scanErr
+	}
+
+	return ast.Format(source, stmts, comments.All), nil
+}
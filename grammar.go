@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/urfave/cli/v2"
+)
+
+var grammarCommand = &cli.Command{
+	Name:  "grammar",
+	Usage: "print the grammar of the parser as W3C EBNF or as a JSON rule graph for railroad diagrams",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "the output format, ebnf or json",
+			Value: "ebnf",
+			Action: func(cCtx *cli.Context, format string) error {
+				if format != "ebnf" && format != "json" {
+					return usageError(cCtx, "unknown format '%s', expected ebnf or json", format)
+				}
+				return nil
+			},
+		},
+	},
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.Args().Len() > 0 {
+			return usageError(cCtx, "expected no arguments but got %d", cCtx.Args().Len())
+		}
+
+		rules, err := parse.Grammar()
+		if err != nil {
+			return cli.Exit(err.Error(), exitData)
+		}
+
+		if cCtx.String("format") == "ebnf" {
+			fmt.Fprint(stdout, parse.WriteEBNF(rules))
+			return nil
+		}
+
+		out, err := json.MarshalIndent(rules, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+		return nil
+	},
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"github.com/urfave/cli/v2"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// style is how a class of tokens is drawn, zero values are left plain
+type style struct {
+	// the SGR parameters used for ANSI output, e.g. "1;34"
+	ansi string
+	// the CSS color used for HTML output
+	color string
+	bold  bool
+}
+
+type theme struct {
+	// the CSS colors of the HTML page
+	background string
+	foreground string
+	styles     map[token.Class]style
+}
+
+var themes = map[string]theme{
+	"dark": {
+		background: "#1e1e1e",
+		foreground: "#d4d4d4",
+		styles: map[token.Class]style{
+			token.ClassKeyword:  {ansi: "1;35", color: "#c586c0", bold: true},
+			token.ClassString:   {ansi: "32", color: "#ce9178"},
+			token.ClassNumber:   {ansi: "33", color: "#b5cea8"},
+			token.ClassConstant: {ansi: "36", color: "#569cd6"},
+			token.ClassOperator: {ansi: "37", color: "#d4d4d4"},
+			token.ClassComment:  {ansi: "2;3", color: "#6a9955"},
+		},
+	},
+	"light": {
+		background: "#ffffff",
+		foreground: "#1f1f1f",
+		styles: map[token.Class]style{
+			token.ClassKeyword:  {ansi: "1;34", color: "#0000ff", bold: true},
+			token.ClassString:   {ansi: "31", color: "#a31515"},
+			token.ClassNumber:   {ansi: "32", color: "#098658"},
+			token.ClassConstant: {ansi: "34", color: "#0451a5"},
+			token.ClassComment:  {ansi: "3;32", color: "#008000"},
+		},
+	},
+	"mono": {
+		background: "#ffffff",
+		foreground: "#000000",
+		styles: map[token.Class]style{
+			token.ClassKeyword: {ansi: "1", bold: true},
+			token.ClassComment: {ansi: "3"},
+		},
+	},
+}
+
+func themeNames() string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+var highlightCommand = &cli.Command{
+	Name:      "highlight",
+	Usage:     "print a script with syntax highlighting as ANSI colored text or HTML",
+	ArgsUsage: "<script>",
+	Flags: []cli.Flag{
+		dialectFlag,
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "the output format, ansi or html",
+			Value: "ansi",
+			Action: func(cCtx *cli.Context, format string) error {
+				if format != "ansi" && format != "html" {
+					return usageError(cCtx, "unknown format '%s', expected ansi or html", format)
+				}
+				return nil
+			},
+		},
+		&cli.StringFlag{
+			Name:  "theme",
+			Usage: "the colors to use, one of " + themeNames(),
+			Value: "dark",
+			Action: func(cCtx *cli.Context, name string) error {
+				if _, ok := themes[name]; !ok {
+					return usageError(cCtx, "unknown theme '%s', expected one of %s", name, themeNames())
+				}
+				return nil
+			},
+		},
+	},
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if err := expectScripts(cCtx, 1, 1); err != nil {
+			return err
+		}
+
+		source, err := readScript(cCtx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		if _, err := scriptPragmas(cCtx, source); err != nil {
+			return err
+		}
+
+		theme := themes[cCtx.String("theme")]
+		if cCtx.String("format") == "html" {
+			return highlightHTML(stdout, source, theme)
+		}
+		return highlightANSI(stdout, source, theme)
+	},
+}
+
+// span is a part of the source and the class of the token it belongs to,
+// ClassOther for the text between tokens
+type span struct {
+	text  string
+	class token.Class
+}
+
+// highlightSpans splits source into spans covering all of it. Malformed
+// source is still highlighted, the parts the scanner rejects are plain,
+// and the error of the scanner is returned with the spans.
+func highlightSpans(source string) ([]span, error) {
+	report := diag.NewRenderer(source, stderr).Report
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{Dialect: dialect, IncludeComments: true})
+
+	var spans []span
+	end := 0
+	for _, tok := range tokens {
+		start, stop := tok.Offset, tok.Offset+len(tok.Lexme)
+		// the lexme of a string leaves out its quotes
+		if tok.Type == token.STRING {
+			start, stop = start-1, min(stop+1, len(source))
+		}
+		if start < end || stop == start {
+			continue
+		}
+
+		if start > end {
+			spans = append(spans, span{source[end:start], token.ClassOther})
+		}
+		spans = append(spans, span{source[start:stop], tok.Type.Class()})
+		end = stop
+	}
+
+	if end < len(source) {
+		spans = append(spans, span{source[end:], token.ClassOther})
+	}
+	return spans, scanErr
+}
+
+func highlightANSI(w io.Writer, source string, theme theme) error {
+	spans, scanErr := highlightSpans(source)
+	var builder strings.Builder
+	for _, span := range spans {
+		style := theme.styles[span.class]
+		if style.ansi == "" {
+			builder.WriteString(span.text)
+			continue
+		}
+
+		// reset before every line break so pagers showing
+		// a single line do not bleed the style into others
+		lines := strings.Split(span.text, "\n")
+		for i, line := range lines {
+			if i > 0 {
+				builder.WriteString("\n")
+			}
+			if line != "" {
+				fmt.Fprintf(&builder, "\x1b[%sm%s\x1b[0m", style.ansi, line)
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return err
+	}
+	return highlightError(scanErr)
+}
+
+func highlightHTML(w io.Writer, source string, theme theme) error {
+	spans, scanErr := highlightSpans(source)
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "<pre class=\"lox\" style=\"background:%s;color:%s\"><code>",
+		theme.background, theme.foreground)
+	for _, span := range spans {
+		text := html.EscapeString(span.text)
+		style := theme.styles[span.class]
+		if style.color == "" && !style.bold {
+			builder.WriteString(text)
+			continue
+		}
+
+		var css []string
+		if style.color != "" {
+			css = append(css, "color:"+style.color)
+		}
+		if style.bold {
+			css = append(css, "font-weight:bold")
+		}
+		fmt.Fprintf(&builder, "<span class=\"%s\" style=\"%s\">%s</span>",
+			span.class, strings.Join(css, ";"), text)
+	}
+	builder.WriteString("</code></pre>\n")
+
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return err
+	}
+	return highlightError(scanErr)
+}
+
+// highlightError is the error of highlighting a script the scanner
+// failed on, the script is highlighted anyway
+func highlightError(scanErr error) error {
+	if scanErr != nil {
+		return cli.Exit("", exitData)
+	}
+	return nil
+}
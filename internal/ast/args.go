@@ -0,0 +1,50 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"os"
+)
+
+// Scripts used for automation read their arguments from the global list
+// ARGS, defined every time a script is interpreted like the natives, and
+// the environment of the process with env.
+
+// SetArguments sets the arguments of the default interpreter, see
+// Interpreter.SetArguments.
+func SetArguments(args []string) {
+	defaultInterpreter.SetArguments(args)
+}
+
+// SetArguments sets the arguments the scripts interpreted afterwards
+// find in ARGS, which is empty by default.
+func (in *Interpreter) SetArguments(args []string) {
+	in.arguments = args
+}
+
+// defineArguments defines ARGS as a new list, so a
+// script modifying it does not affect the next one
+func (in *Interpreter) defineArguments() {
+	elements := make([]LoxValue, len(in.arguments))
+	for i, arg := range in.arguments {
+		elements[i] = LoxString(arg)
+	}
+	in.definedArguments = NewLoxList(elements)
+	in.globals.Define("ARGS", in.definedArguments)
+}
+
+// env(name) returns the value of the environment variable name, nil if it is not set
+var envFunc = NativeFunction{
+	paramLen: 1,
+	external: true,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isString(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "env expects a string name")
+		}
+
+		value, ok := os.LookupEnv(AsString(args[0]))
+		if !ok {
+			return LoxNil{}, nil
+		}
+		return LoxString(value), nil
+	},
+}
@@ -0,0 +1,40 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"strings"
+	"testing"
+)
+
+func TestAssert(t *testing.T) {
+	tests := []struct {
+		source string
+		// the message of the runtime error, empty if the assertion holds
+		want string
+	}{
+		{`assert 1 + 1 == 2;`, ""},
+		{`assert 1+1==3;`, "assertion failed: 1 + 1 == 3"},
+		{`var l = [1]; assert len(l)>1, "l has " + str(len(l));`, "assertion failed: len(l) > 1 (l has 1)"},
+		{`assert nil;`, "assertion failed: nil"},
+	}
+
+	for _, test := range tests {
+		var errs []error
+		report := func(err error) { errs = append(errs, err) }
+		tokens, _ := scan.Scan(test.source, report, scan.ScanContext{})
+		stmts, err := parse.Parse(tokens, report)
+		if err != nil {
+			t.Fatal(errs)
+		}
+		ast.Interpret(stmts, nil, report)
+
+		switch {
+		case test.want == "" && len(errs) != 0:
+			t.Errorf("%s: unexpected errors %v", test.source, errs)
+		case test.want != "" && (len(errs) != 1 || !strings.Contains(errs[0].Error(), test.want)):
+			t.Errorf("%s: expected %q but got %v", test.source, test.want, errs)
+		}
+	}
+}
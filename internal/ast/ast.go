@@ -0,0 +1,8 @@
+// Package ast declares the syntax tree of Lox programs, which the parser
+// produces, and the tree-walking interpreter evaluating it. The nodes in
+// expr.go and stmt.go and their visitors in visitor.go are generated by
+// tools/astgen from its declarations of the nodes, so nodes are added and
+// changed there.
+package ast
+
+//go:generate go run ../../tools/astgen
@@ -0,0 +1,33 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"testing"
+)
+
+func TestArityErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"fun f(a, b) {}\nf(1,\n2,\n3\n);", "[5] runtime error at \")\" - expected 2 arguments but got 3\n"},
+		{"len();", "[1] runtime error at \")\" - expected 1 arguments but got 0\n"},
+		{"println;\nformat();", "[2] runtime error at \")\" - expected at least 1 arguments but got 0\n"},
+	}
+
+	for _, test := range tests {
+		tokens, _ := scan.Scan(test.source, func(err error) { t.Error(err) }, scan.ScanContext{})
+		stmts, err := parse.Parse(tokens, func(err error) { t.Error(err) })
+		if err != nil {
+			continue
+		}
+
+		var errs []error
+		ast.Interpret(stmts, nil, func(err error) { errs = append(errs, err) })
+		if len(errs) != 1 || errs[0].Error() != test.want {
+			t.Errorf("%q: expected error %q but got %v", test.source, test.want, errs)
+		}
+	}
+}
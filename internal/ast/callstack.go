@@ -0,0 +1,83 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+// DefaultMaxCallDepth is the number of nested calls to Lox functions
+// allowed before the interpreter reports a stack overflow.
+const DefaultMaxCallDepth = 1024
+
+// SetMaxCallDepth sets the number of nested calls to Lox functions
+// the default interpreter allows before a stack overflow is reported.
+func SetMaxCallDepth(depth int) {
+	defaultInterpreter.maxCallDepth = depth
+}
+
+// callFrame is a call to a Lox function which has not yet returned
+type callFrame struct {
+	function string
+	// the call expression the function was called from
+	site token.Token
+}
+
+// number of frames printed from either end of
+// the stack before the middle is omitted
+const traceEnds = 10
+
+// StackOverflowError is returned once the call depth exceeds the limit
+// set with SetMaxCallDepth, it carries a trace of the calls leading to it.
+type StackOverflowError struct {
+	RuntimeError
+	Trace []callFrame
+}
+
+func (e StackOverflowError) Error() string {
+	var builder strings.Builder
+	builder.WriteString(e.RuntimeError.Error())
+	builder.WriteString("stack trace (most recent call first):\n")
+	for i := len(e.Trace) - 1; i >= 0; i-- {
+		depth := len(e.Trace) - 1 - i
+		if depth == traceEnds && len(e.Trace) > 2*traceEnds {
+			fmt.Fprintf(&builder, "  ... %d calls omitted\n", len(e.Trace)-2*traceEnds)
+			i = traceEnds - 1
+		}
+
+		frame := e.Trace[i]
+		if frame.site.Line == 0 {
+			// called back by a native function
+			fmt.Fprintf(&builder, "  at %s\n", frame.function)
+			continue
+		}
+		fmt.Fprintf(&builder, "  at %s (line %d)\n", frame.function, frame.site.Line)
+	}
+	return builder.String()
+}
+
+// pushFrame records a call to function from the call expression at site,
+// the returned function removes the frame once the call has returned. An
+// error is returned instead if the call exceeds the maximum call depth.
+func (in *Interpreter) pushFrame(function LoxFunction, site token.Token) (pop func(), err error) {
+	name := "<anonymous>"
+	if !function.IsAnonymous {
+		name = function.Name.Lexme
+	}
+
+	depth := len(in.callStack)
+	in.callStack = append(in.callStack, callFrame{function: name, site: site})
+	pop = func() { in.callStack = in.callStack[:depth] }
+
+	if len(in.callStack) > in.maxCallDepth {
+		trace := append([]callFrame{}, in.callStack...)
+		pop()
+		return nil, StackOverflowError{
+			RuntimeError: NewRuntimeError(site,
+				fmt.Sprintf("stack overflow: max call depth %d exceeded", in.maxCallDepth)),
+			Trace: trace,
+		}
+	}
+
+	return pop, nil
+}
@@ -0,0 +1,82 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+)
+
+// Topics let a host exchange values with a running script. The host binds
+// a Go channel to a topic name with BindTopic, scripts then use the send and
+// receive natives to pass values over it. Blocking sends and receives are
+// abandoned once the context set with SetContext is done.
+
+// BindTopic binds ch to topic for the default interpreter, see
+// Interpreter.BindTopic.
+func BindTopic(topic string, ch chan LoxValue) {
+	defaultInterpreter.BindTopic(topic, ch)
+}
+
+// BindTopic binds ch to topic, replacing any channel previously bound to
+// it. The host must not close a channel scripts send values to.
+func (in *Interpreter) BindTopic(topic string, ch chan LoxValue) {
+	in.topicsMu.Lock()
+	defer in.topicsMu.Unlock()
+	in.topics[topic] = ch
+}
+
+func (in *Interpreter) topicChannel(v LoxValue, native string) (chan LoxValue, error) {
+	if !isString(v) {
+		return nil, NewRuntimeError(token.Token{}, native+" expects a string topic")
+	}
+
+	in.topicsMu.Lock()
+	defer in.topicsMu.Unlock()
+	ch, ok := in.topics[AsString(v)]
+	if !ok {
+		return nil, NewRuntimeError(token.Token{}, "unknown topic '"+AsString(v)+"'")
+	}
+
+	return ch, nil
+}
+
+// send(topic, value) sends value to the host over topic, blocking
+// until the host is ready to receive it
+var sendFunc = NativeFunction{
+	paramLen: 2,
+	external: true,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		ch, err := in.topicChannel(args[0], "send")
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case ch <- args[1]:
+			return LoxNil{}, nil
+		case <-in.interrupt.Done():
+			return nil, in.checkInterrupt()
+		}
+	},
+}
+
+// receive(topic) blocks until the host sends a value over topic and
+// returns it, nil is returned once the host has closed the topic
+var receiveFunc = NativeFunction{
+	paramLen: 1,
+	external: true,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		ch, err := in.topicChannel(args[0], "receive")
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case value, ok := <-ch:
+			if !ok {
+				return LoxNil{}, nil
+			}
+			return value, nil
+		case <-in.interrupt.Done():
+			return nil, in.checkInterrupt()
+		}
+	},
+}
@@ -0,0 +1,96 @@
+package ast_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func interpret(t *testing.T, source string) error {
+	var errs []error
+	report := func(err error) { errs = append(errs, err) }
+	tokens, _ := scan.Scan(source, report, scan.ScanContext{})
+	stmts, err := parse.Parse(tokens, report)
+	if err != nil {
+		t.Fatal(errs)
+	}
+
+	return ast.Interpret(stmts, nil, report)
+}
+
+// interpretOutput interprets source like interpret, returning
+// what it printed and the errors it reported
+func interpretOutput(t *testing.T, source string) (string, []error) {
+	var out bytes.Buffer
+	var errs []error
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	ast.Interpret(parseScript(t, source), nil, func(err error) { errs = append(errs, err) })
+	return out.String(), errs
+}
+
+// expectOutput fails the test case name unless source prints want, or
+// reports a single error containing err if err is not empty
+func expectOutput(t *testing.T, name string, source string, want string, err string) {
+	t.Helper()
+	out, errs := interpretOutput(t, source)
+	switch {
+	case err == "" && len(errs) > 0:
+		t.Errorf("%s: unexpected error %v", name, errs)
+	case err != "" && (len(errs) != 1 || !strings.Contains(errs[0].Error(), err)):
+		t.Errorf("%s: expected the error %q but got %v", name, err, errs)
+	case out != want:
+		t.Errorf("%s: expected %q but got %q", name, want, out)
+	}
+}
+
+func TestTopics(t *testing.T) {
+	in := make(chan ast.LoxValue)
+	out := make(chan ast.LoxValue)
+	ast.BindTopic("in", in)
+	ast.BindTopic("out", out)
+
+	go func() {
+		in <- ast.LoxNumber(1)
+		in <- ast.LoxNumber(2)
+		close(in)
+	}()
+
+	done := make(chan error)
+	go func() {
+		done <- interpret(t, `
+var sum = 0;
+for (var v = receive("in"); v != nil; v = receive("in")) {
+	sum = sum + v;
+}
+send("out", sum);
+`)
+	}()
+
+	if sum := <-out; sum != ast.LoxNumber(3) {
+		t.Errorf("expected 3 but got %v", sum)
+	}
+
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTopicInterrupted(t *testing.T) {
+	ast.BindTopic("never", make(chan ast.LoxValue))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ast.SetContext(ctx)
+	defer ast.SetContext(context.Background())
+
+	if err := interpret(t, `receive("never");`); err == nil {
+		t.Error("expected receive to be interrupted")
+	}
+}
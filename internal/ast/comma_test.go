@@ -0,0 +1,43 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"os"
+	"testing"
+)
+
+func TestCommaOperator(t *testing.T) {
+	source := `
+	var n = 0;
+	fun next() { n = n + 1; return n; }
+	print (next(), next(), "last");
+	print n;
+	var i; var j;
+	for (i = 0, j = 10; i < 3; i = i + 1, j = j - 1) {}
+	print str(i) + " " + str(j);
+	`
+
+	var errs []error
+	report := func(err error) { errs = append(errs, err) }
+	tokens, _ := scan.Scan(source, report, scan.ScanContext{})
+	options := parse.DefaultParseOptions()
+	options.EnableComma = true
+	stmts, err := parse.ParseWith(tokens, report, options)
+	if err != nil {
+		t.Fatal(errs)
+	}
+
+	var buf bytes.Buffer
+	ast.SetOutput(&buf)
+	defer ast.SetOutput(os.Stdout)
+	if err := ast.Interpret(stmts, nil, report); err != nil {
+		t.Fatal(errs)
+	}
+
+	if want := "last\n2\n3 7\n"; buf.String() != want {
+		t.Errorf("expected %q but got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,132 @@
+package ast
+
+import (
+	"cmp"
+	"github.com/LucazFFz/lox/internal/token"
+	"slices"
+)
+
+// Comparer is implemented by values with a user defined ordering, which
+// is consulted by the comparison operators and the sort natives. Values
+// such as class instances implement it by dispatching to their `compare`
+// method. Compare returns a negative number if the value is less than
+// other, zero if they are equal and a positive number otherwise.
+type Comparer interface {
+	Compare(other LoxValue) (int, error)
+}
+
+// compareProtocol compares left and right using the Comparer protocol,
+// ok is false if left does not implement it.
+func compareProtocol(left LoxValue, right LoxValue) (c int, ok bool, err error) {
+	comparer, ok := left.(Comparer)
+	if !ok {
+		return 0, false, nil
+	}
+
+	c, err = comparer.Compare(right)
+	return c, true, err
+}
+
+// compareValues orders numbers, strings and values implementing Comparer.
+// Unlike the comparison operators NaN is ordered before all other numbers
+// so that sorting is well defined.
+func compareValues(left LoxValue, right LoxValue) (int, error) {
+	switch {
+	case isNumber(left) && isNumber(right):
+		return cmp.Compare(AsNumber(left), AsNumber(right)), nil
+	case isString(left) && isString(right):
+		return cmp.Compare(AsString(left), AsString(right)), nil
+	}
+
+	if c, ok, err := compareProtocol(left, right); ok {
+		return c, err
+	}
+
+	return 0, NewRuntimeError(token.Token{}, "values are not comparable")
+}
+
+// sortList stably sorts the elements of list in place using compare,
+// the first error returned by compare is returned and the order of the
+// elements is unspecified.
+func sortList(list LoxList, compare func(LoxValue, LoxValue) (int, error)) error {
+	if err := list.l.checkMutable("list"); err != nil {
+		return err
+	}
+
+	var sortErr error
+	slices.SortStableFunc(list.Elements(), func(a LoxValue, b LoxValue) int {
+		if sortErr != nil {
+			return 0
+		}
+
+		c, err := compare(a, b)
+		if err != nil {
+			sortErr = err
+		}
+		return c
+	})
+
+	return sortErr
+}
+
+// sort(iterable) sorts a list in place and returns it, other iterables
+// are sorted into a new list. The sort is stable, all elements must be
+// numbers, strings or implement the compare protocol.
+var sortFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		list := args[0]
+		if !isList(list) {
+			elements := []LoxValue{}
+			err := iterate(list, func(v LoxValue) error {
+				elements = append(elements, v)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			list = NewLoxList(elements)
+		}
+
+		if err := sortList(AsList(list), compareValues); err != nil {
+			return nil, err
+		}
+
+		return list, nil
+	},
+}
+
+// sortBy(list, compare) stably sorts list in place using the function
+// compare(a, b), which returns a negative number if a is ordered before
+// b, zero if they are equal and a positive number otherwise.
+var sortByFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isList(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "sortBy expects a list")
+		}
+
+		compare, ok := args[1].(Callable)
+		if !ok || compare.Arity() != 2 {
+			return nil, NewRuntimeError(token.Token{}, "sortBy expects a function taking two arguments")
+		}
+
+		err := sortList(AsList(args[0]), func(a LoxValue, b LoxValue) (int, error) {
+			result, err := compare.Call([]LoxValue{a, b})
+			if err != nil {
+				return 0, err
+			}
+
+			if !isNumber(result) {
+				return 0, NewRuntimeError(token.Token{}, "sortBy compare function must return a number")
+			}
+
+			return cmp.Compare(AsNumber(result), 0), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return args[0], nil
+	},
+}
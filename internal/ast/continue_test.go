@@ -0,0 +1,57 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"strings"
+	"testing"
+)
+
+func TestContinue(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "for runs the increment",
+			source: `for (var i = 0; i < 4; i = i + 1) { if (i == 1) continue; print i; }`,
+			want:   "0\n2\n3\n",
+		},
+		{
+			name:   "while checks the condition",
+			source: `var i = 0; while (i < 3) { i = i + 1; if (i == 2) continue; print i; }`,
+			want:   "1\n3\n",
+		},
+		{
+			name:   "for-in takes the next element",
+			source: `for (x in [1, 2, 3]) { if (x == 2) continue; print x; }`,
+			want:   "1\n3\n",
+		},
+		{
+			name:   "inner loops only",
+			source: `for (var i = 0; i < 2; i = i + 1) { for (var j = 0; j < 2; j = j + 1) { if (j == 0) continue; print str(i) + str(j); } }`,
+			want:   "01\n11\n",
+		},
+		{
+			name:   "each iteration has its own scope",
+			source: `var fs = []; for (var i = 0; i < 3; i = i + 1) { var j = i; if (i == 1) continue; push(fs, fun() { return j; }); } for (f in fs) print f();`,
+			want:   "0\n2\n",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, "")
+	}
+}
+
+func TestContinueOutsideLoop(t *testing.T) {
+	for _, source := range []string{`fun f() { continue; }`, `while (true) { fun f() { continue; } }`} {
+		var errs []error
+		tokens, _ := scan.Scan(source, func(err error) { t.Error(err) }, scan.ScanContext{})
+		parse.Parse(tokens, func(err error) { errs = append(errs, err) })
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "cannot use 'continue' outside of a loop") {
+			t.Errorf("%s: expected continue to be rejected but got %v", source, errs)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"strconv"
+	"strings"
+)
+
+// The global types str, num and bool can be called to convert values to
+// them, e.g. str(1.5) returns "1.5", num(" 42 ") returns 42 and bool(0)
+// returns true like a condition would, while still comparing equal to the
+// result of type.
+
+func (t LoxType) Arity() int {
+	return 1
+}
+
+func (t LoxType) Call(arguments []LoxValue) (LoxValue, error) {
+	if len(arguments) != t.Arity() {
+		return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("expected %d arguments but got %d", t.Arity(), len(arguments)))
+	}
+
+	switch t.Typ {
+	case STRING:
+		str, err := valueToString(arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		return LoxString(str), nil
+	case NUMBER:
+		return toNumber(arguments[0])
+	case BOOLEAN:
+		return LoxBoolean(isTruthy(arguments[0])), nil
+	}
+
+	return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("cannot convert values to %s", t.Typ))
+}
+
+// toNumber converts numbers and strings holding a number, surrounding
+// whitespace is ignored
+func toNumber(v LoxValue) (LoxValue, error) {
+	switch {
+	case isNumber(v):
+		return v, nil
+	case isString(v):
+		number, err := strconv.ParseFloat(strings.TrimSpace(AsString(v)), 64)
+		if err != nil {
+			return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("cannot convert '%s' to a number", AsString(v)))
+		}
+		return LoxNumber(number), nil
+	}
+
+	return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("cannot convert %s to a number", v.Type()))
+}
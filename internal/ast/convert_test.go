@@ -0,0 +1,40 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"os"
+	"testing"
+)
+
+func TestConversions(t *testing.T) {
+	var out bytes.Buffer
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	err := interpret(t, `
+fun add(a, b) { return a + b; }
+print str(1.5) + str(2) + str(nil) + str([true]);
+print num(" 42 ") + num("1e3") + num(0.5);
+print type("a") == str and type(1) == num;
+print str(bool(0)) + str(bool(nil)) + str(bool(""));
+print str(1 is num) + str("a" is num) + str(nil is type(nil)) + str(num is type(str)) + str([] is func);
+print add;
+print fun () {};
+print len;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "1.52nil[true]\n1042.5\ntrue\ntruefalsetrue\ntruefalsetruetruefalse\n<fn add>\n<fn>\n<native fn len>\n"
+	if out.String() != want {
+		t.Errorf("expected %q but got %q", want, out.String())
+	}
+
+	for _, source := range []string{`num("abc");`, `num(nil);`, `func(1);`, `1 is 1;`, `str(1, 2);`} {
+		if err := interpret(t, source); err == nil {
+			t.Errorf("expected %s to fail", source)
+		}
+	}
+}
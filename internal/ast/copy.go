@@ -0,0 +1,102 @@
+package ast
+
+// copy(value) returns a shallow copy of a list, map or set, the copy
+// contains the same elements as the original. Other values are immutable
+// and returned as is. Copies are never frozen.
+var copyFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		return copyValue(args[0], nil)
+	},
+}
+
+// deepCopy(value) returns a copy of a list, map or set where every
+// element is deep copied as well. Values referenced more than once,
+// including values referencing themselves, are copied once, so the
+// copy has the same shape as the original.
+var deepCopyFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		return copyValue(args[0], make(map[any]LoxValue))
+	},
+}
+
+// copyValue copies v, if copies is nil the copy is shallow. Otherwise
+// elements are copied recursively and copies maps the identity (see
+// hashKey) of every value copied so far to its copy.
+func copyValue(v LoxValue, copies map[any]LoxValue) (LoxValue, error) {
+	if !isList(v) && !isMap(v) && !isSet(v) {
+		return v, nil
+	}
+
+	identity, _ := hashKey(v)
+	if copied, ok := copies[identity]; ok {
+		return copied, nil
+	}
+
+	element := func(v LoxValue) (LoxValue, error) {
+		if copies == nil {
+			return v, nil
+		}
+		return copyValue(v, copies)
+	}
+
+	switch {
+	case isList(v):
+		list := NewLoxList(make([]LoxValue, 0, AsList(v).Len()))
+		if copies != nil {
+			copies[identity] = list
+		}
+
+		for _, e := range AsList(v).Elements() {
+			e, err := element(e)
+			if err != nil {
+				return nil, err
+			}
+			list.l.elements = append(list.l.elements, e)
+		}
+
+		return list, nil
+	case isMap(v):
+		m := NewLoxMap()
+		if copies != nil {
+			copies[identity] = m
+		}
+
+		for _, entry := range AsMap(v).m.entries {
+			key, err := element(entry.key)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := element(entry.value)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := m.Set(key, value); err != nil {
+				return nil, err
+			}
+		}
+
+		return m, nil
+	default:
+		set := NewLoxSet()
+		if copies != nil {
+			copies[identity] = set
+		}
+
+		for _, member := range AsSet(v).Members() {
+			member, err := element(member)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := set.Add(member); err != nil {
+				return nil, err
+			}
+		}
+
+		return set, nil
+	}
+}
@@ -0,0 +1,74 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"os"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "shallow copy of a list",
+			source: `var a = [1, 2]; var b = copy(a); push(b, 3); print a; print b; print a == b;`,
+			want:   "[1, 2]\n[1, 2, 3]\nfalse\n",
+		},
+		{
+			name:   "shallow copy shares nested values",
+			source: `var a = [[1], {"k": 1}]; var b = copy(a); b[0][0] = 2; b[1]["k"] = 2; print a; print a[0] == b[0];`,
+			want:   "[[2], {\"k\": 2}]\ntrue\n",
+		},
+		{
+			name:   "shallow copy of a map",
+			source: `var a = {"x": [1]}; var b = copy(a); b["y"] = 2; push(b["x"], 2); print a; print b;`,
+			want:   "{\"x\": [1, 2]}\n{\"x\": [1, 2], \"y\": 2}\n",
+		},
+		{
+			name:   "deep copy of nested lists and maps",
+			source: `var a = [[1], {"k": [1]}]; var b = deepCopy(a); b[0][0] = 2; push(b[1]["k"], 2); print a; print b;`,
+			want:   "[[1], {\"k\": [1]}]\n[[2], {\"k\": [1, 2]}]\n",
+		},
+		{
+			name:   "deep copy keeps shared values shared",
+			source: `var shared = [1]; var a = [shared, shared]; var b = deepCopy(a); print b[0] == b[1]; print b[0] == shared;`,
+			want:   "true\nfalse\n",
+		},
+		{
+			name:   "deep copy of a cyclic list",
+			source: `var a = [1]; push(a, a); var b = deepCopy(a); print b[1] == b; print b[1] == a; print len(b);`,
+			want:   "true\nfalse\n2\n",
+		},
+		{
+			name:   "deep copy of a cyclic map",
+			source: `var a = {}; a["self"] = a; var b = deepCopy(a); print b["self"] == b; print b["self"] == a;`,
+			want:   "true\nfalse\n",
+		},
+		{
+			name:   "copies are not frozen",
+			source: `var a = freeze([[1]]); var b = copy(a); push(b, 2); print isFrozen(b); print b;`,
+			want:   "false\n[[1], 2]\n",
+		},
+		{
+			name:   "other values are returned as is",
+			source: `print copy(1); print deepCopy("s"); print copy(nil);`,
+			want:   "1\ns\nnil\n",
+		},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		ast.SetOutput(&out)
+		if err := interpret(t, test.source); err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s: expected %q but got %q", test.name, test.want, out.String())
+		}
+	}
+	ast.SetOutput(os.Stdout)
+}
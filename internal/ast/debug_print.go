@@ -2,6 +2,8 @@ package ast
 
 import (
 	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -26,6 +28,14 @@ func (t UnaryExpr) DebugPrint() string {
 	return parenthesize(t.Op.Lexme, t.Right)
 }
 
+func (t PrefixExpr) DebugPrint() string {
+	return parenthesize(t.Op.Lexme, t.Target)
+}
+
+func (t PostfixExpr) DebugPrint() string {
+	return parenthesize("postfix"+t.Op.Lexme, t.Target)
+}
+
 func (t TernaryExpr) DebugPrint() string {
 	return parenthesize("ternary", t.Condition, t.Left, t.Right)
 }
@@ -39,6 +49,63 @@ func (t AssignExpr) DebugPrint() string {
 }
 
 
+func (t ListExpr) DebugPrint() string {
+	args := make([]DebugPrint, len(t.Elements))
+	for i := range t.Elements {
+		args[i] = t.Elements[i]
+	}
+	return parenthesize("list", args...)
+}
+
+func (t MapExpr) DebugPrint() string {
+	args := make([]DebugPrint, 0, 2*len(t.Keys))
+	for i := range t.Keys {
+		args = append(args, t.Keys[i], t.Values[i])
+	}
+	return parenthesize("map", args...)
+}
+
+func (t ObjectExpr) DebugPrint() string {
+	args := make([]DebugPrint, 0, 2*len(t.Names))
+	for i := range t.Names {
+		args = append(args, LoxString(t.Names[i].Lexme), t.Values[i])
+	}
+	return parenthesize("object", args...)
+}
+
+func (t IndexExpr) DebugPrint() string {
+	return parenthesize("index", t.Object, t.Index)
+}
+
+func (t SliceExpr) DebugPrint() string {
+	return parenthesize("slice", t.Object, orNothing(t.Start), orNothing(t.End))
+}
+
+func (t IndexAssignExpr) DebugPrint() string {
+	return parenthesize("assign", IndexExpr{Object: t.Object, Index: t.Index}, t.Value)
+}
+
+func (t GetExpr) DebugPrint() string {
+	return parenthesize("get "+t.Name.Lexme, t.Object)
+}
+
+func (t SetExpr) DebugPrint() string {
+	return parenthesize("assign", GetExpr{Object: t.Object, Name: t.Name}, t.Value)
+}
+
+func (t SliceAssignExpr) DebugPrint() string {
+	slice := SliceExpr{Object: t.Object, Start: t.Start, End: t.End}
+	return parenthesize("assign", slice, t.Value)
+}
+
+// orNothing replaces omitted (nil) expressions with NothingExpr
+func orNothing(expr Expr) Expr {
+	if expr == nil {
+		return NothingExpr{}
+	}
+	return expr
+}
+
 func (t NothingExpr) DebugPrint() string {
 	return parenthesize("Nothing")
 }
@@ -70,8 +137,13 @@ func (v LoxBoolean) DebugPrint() string {
 	}
 }
 
+// Numbers are printed with as few digits as needed to read them back
+// unchanged, very large and very small numbers in scientific notation.
 func (v LoxNumber) DebugPrint() string {
-	return strconv.FormatFloat(AsNumber(v), 'f', -1, 64)
+	if abs := math.Abs(float64(v)); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	}
+	return strconv.FormatFloat(float64(v), 'f', -1, 64)
 }
 
 func (v LoxNil) DebugPrint() string {
@@ -79,7 +151,8 @@ func (v LoxNil) DebugPrint() string {
 }
 
 func (v LoxObject) DebugPrint() string {
-	return "object"
+	str, _ := valueToString(v)
+	return str
 }
 
 func (v LoxString) DebugPrint() string {
@@ -111,7 +184,27 @@ func (s IfStmt) DebugPrint() string {
 }
 
 func (s WhileStmt) DebugPrint() string {
-	return parenthesize("while", s.Condition, s.Body)
+	name := "while"
+	if s.Keyword.Type == token.DO {
+		name = "do-while"
+	}
+	name = labeledName(name, s.Label)
+	if s.Increment != nil {
+		return parenthesize(name, s.Condition, s.Body, s.Increment)
+	}
+	return parenthesize(name, s.Condition, s.Body)
+}
+
+func (s ForInStmt) DebugPrint() string {
+	return parenthesize(labeledName("for", s.Label)+" "+s.Name.Lexme, s.Iterable, s.Body)
+}
+
+// labeledName prefixes the name of a loop with its label
+func labeledName(name string, label token.Token) string {
+	if label.Lexme == "" {
+		return name
+	}
+	return label.Lexme + ": " + name
 }
 
 func (s BlockStmt) DebugPrint() string {
@@ -127,9 +220,37 @@ func (s BlockStmt) DebugPrint() string {
 }
 
 func (s BreakStmt) DebugPrint() string {
+	if s.Label.Lexme != "" {
+		return parenthesize("break " + s.Label.Lexme)
+	}
 	return parenthesize("break")
 }
 
+func (s ContinueStmt) DebugPrint() string {
+	if s.Label.Lexme != "" {
+		return parenthesize("continue " + s.Label.Lexme)
+	}
+	return parenthesize("continue")
+}
+
+func (s AssertStmt) DebugPrint() string {
+	if s.Message != nil {
+		return parenthesize("assert", s.Condition, s.Message)
+	}
+	return parenthesize("assert", s.Condition)
+}
+
+func (s ImportStmt) DebugPrint() string {
+	if s.Path.Type == token.STRING {
+		return parenthesize(fmt.Sprintf("import %q", s.Path.Lexme))
+	}
+	return parenthesize("import " + s.Path.Lexme)
+}
+
+func (s YieldStmt) DebugPrint() string {
+	return parenthesize("yield", orNothing(s.Expr))
+}
+
 func (s ReturnStmt) DebugPrint() string {
     return parenthesize("return", s.Expr)
 }
@@ -139,7 +260,7 @@ func (t FunctionStmt) DebugPrint() string {
 }
 
 
-func (t CallStmt) DebugPrint() string {
+func (t CallExpr) DebugPrint() string {
 	// args := make([]PrettyPrint, len(t.Arguments)+1)
 	// args[0] = t.Callee
 	// for i := range args {
@@ -0,0 +1,165 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+)
+
+// The debugger pauses the interpreter before statements and hands the
+// paused statement to a front end, such as the debugger of the lox
+// command, which inspects it and decides how the script continues. The
+// interpreter pauses at statements on lines with a breakpoint, at calls
+// to the breakpoint native and while stepping through the script.
+
+// StepMode is how the script continues once the debugger resumes it.
+type StepMode int
+
+const (
+	// run until the next breakpoint
+	Continue StepMode = iota
+	// pause at the next statement, including the statements of called functions
+	StepInto
+	// pause at the next statement which is not part of a function called
+	// by the paused statement
+	StepOver
+	// pause at the next statement once the function of the paused
+	// statement has returned
+	StepOut
+	// stop the script with a runtime error
+	Abort
+)
+
+// Pause is a statement the interpreter is paused at.
+type Pause struct {
+	// the first token of the statement, or of the call to breakpoint
+	Token token.Token
+	// the environment the statement is evaluated in
+	Env *Environment
+	// the calls to Lox functions leading to the statement, the
+	// innermost call last
+	Calls []string
+}
+
+// debugState is the state of the debugger of an interpreter
+type debugState struct {
+	// the front end of the debugger, nil unless debugging
+	frontEnd func(Pause) StepMode
+	// the lines the debugger pauses at
+	breakpoints map[int]bool
+	step        StepMode
+	// the call depth of the statement paused at last
+	stepDepth int
+	// the line of the statement evaluated last, a breakpoint only
+	// pauses the first statement on its line
+	lastLine int
+	// the statement being evaluated and its environment
+	current debugged
+	// the statement paused at last
+	paused token.Token
+}
+
+type debugged struct {
+	stmt token.Token
+	env  *Environment
+}
+
+// SetDebugger sets the front end the default interpreter pauses at, nil disables
+// the debugger. The interpreter pauses at the first statement it evaluates
+// after the debugger is set.
+func SetDebugger(frontEnd func(Pause) StepMode) {
+	d := &defaultInterpreter.debug
+	d.frontEnd = frontEnd
+	d.step = StepInto
+	d.lastLine = 0
+}
+
+// SetBreakpoint sets or clears the breakpoint at line.
+func SetBreakpoint(line int, set bool) {
+	if set {
+		defaultInterpreter.debug.breakpoints[line] = true
+	} else {
+		delete(defaultInterpreter.debug.breakpoints, line)
+	}
+}
+
+// Breakpoints returns the lines with a breakpoint.
+func Breakpoints() []int {
+	breakpoints := defaultInterpreter.debug.breakpoints
+	lines := make([]int, 0, len(breakpoints))
+	for line := range breakpoints {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// debugStmt pauses before stmt if a breakpoint or the step mode says so
+func (in *Interpreter) debugStmt(stmt Stmt, env *Environment) error {
+	// the statements of a block are paused at instead
+	if _, ok := stmt.(BlockStmt); ok {
+		return nil
+	}
+
+	tok := StmtToken(stmt)
+	if tok.Line == 0 {
+		return nil
+	}
+
+	d := &in.debug
+	pause := false
+	switch d.step {
+	case StepInto:
+		pause = true
+	case StepOver:
+		pause = len(in.callStack) <= d.stepDepth
+	case StepOut:
+		pause = len(in.callStack) < d.stepDepth
+	}
+
+	if d.breakpoints[tok.Line] && tok.Line != d.lastLine {
+		pause = true
+	}
+	d.lastLine = tok.Line
+
+	if !pause {
+		return nil
+	}
+	d.paused = tok
+	return in.debugPause(tok, env)
+}
+
+func (in *Interpreter) debugPause(tok token.Token, env *Environment) error {
+	calls := make([]string, len(in.callStack))
+	for i, frame := range in.callStack {
+		calls[i] = fmt.Sprintf("%s (line %d)", frame.function, frame.site.Line)
+	}
+
+	// expressions the front end evaluates do not pause
+	d := &in.debug
+	frontEnd := d.frontEnd
+	d.frontEnd = nil
+	d.step = frontEnd(Pause{Token: tok, Env: env, Calls: calls})
+	d.stepDepth = len(in.callStack)
+	if d.step == Abort {
+		// the debugger stays disabled while the script unwinds
+		return NewRuntimeError(tok, "stopped by the debugger")
+	}
+	d.frontEnd = frontEnd
+	return nil
+}
+
+// breakpoint() pauses the debugger at the call, it does nothing unless debugging
+var breakpointFunc = NativeFunction{
+	paramLen: 0,
+	functionIn: func(in *Interpreter, site token.Token, _ []LoxValue) (LoxValue, error) {
+		// the statement of the call has just been paused at
+		d := &in.debug
+		if d.frontEnd == nil || d.current.stmt == d.paused {
+			return LoxNil{}, nil
+		}
+
+		if err := in.debugPause(site, d.current.env); err != nil {
+			return nil, err
+		}
+		return LoxNil{}, nil
+	},
+}
@@ -0,0 +1,75 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestDebugger(t *testing.T) {
+	source := `fun f(a) {
+  var b = a + 1;
+  return b;
+}
+var x = f(1);
+breakpoint();
+var y = f(x);
+print y;`
+	ast.SetOutput(&bytes.Buffer{})
+	defer ast.SetOutput(os.Stdout)
+
+	tests := []struct {
+		name  string
+		modes []ast.StepMode
+		// the lines paused at
+		want []int
+	}{
+		{"step into", []ast.StepMode{ast.StepInto, ast.StepInto, ast.StepInto, ast.StepInto, ast.Continue}, []int{1, 5, 2, 3, 6}},
+		{"step over", []ast.StepMode{ast.StepOver, ast.StepOver, ast.StepOver, ast.StepOver, ast.Continue}, []int{1, 5, 6, 7, 8}},
+		{"step out", []ast.StepMode{ast.StepInto, ast.StepInto, ast.StepOut, ast.Continue}, []int{1, 5, 2, 6}},
+		{"continue", []ast.StepMode{ast.Continue, ast.Continue}, []int{1, 6}},
+	}
+
+	for _, test := range tests {
+		var lines []int
+		ast.SetDebugger(func(p ast.Pause) ast.StepMode {
+			lines = append(lines, p.Token.Line)
+			if len(lines) > len(test.modes) {
+				return ast.Abort
+			}
+			return test.modes[len(lines)-1]
+		})
+
+		interpret(t, source)
+		if !slices.Equal(lines, test.want) {
+			t.Errorf("%s: expected to pause at lines %v but paused at %v", test.name, test.want, lines)
+		}
+	}
+
+	var locals []string
+	ast.SetBreakpoint(3, true)
+	defer ast.SetBreakpoint(3, false)
+	ast.SetDebugger(func(p ast.Pause) ast.StepMode {
+		if p.Token.Line == 3 {
+			value, _ := p.Env.Lookup("b")
+			locals = append(locals, value.DebugPrint())
+			if len(p.Calls) != 1 || p.Calls[0] != "f (line 5)" && p.Calls[0] != "f (line 7)" {
+				t.Errorf("expected a call to f but got %v", p.Calls)
+			}
+		}
+		return ast.Continue
+	})
+	defer ast.SetDebugger(nil)
+
+	interpret(t, source)
+	if !slices.Equal(locals, []string{"2", "3"}) {
+		t.Errorf("expected to pause at the breakpoint with b = 2 and b = 3 but got %v", locals)
+	}
+
+	ast.SetDebugger(func(ast.Pause) ast.StepMode { return ast.Abort })
+	if err := interpret(t, source); err == nil {
+		t.Error("expected aborting to stop the script")
+	}
+}
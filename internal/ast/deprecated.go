@@ -0,0 +1,6 @@
+package ast
+
+// CallStmt is the former name of CallExpr, calls are expressions.
+//
+// Deprecated: use CallExpr, CallStmt will be removed.
+type CallStmt = CallExpr
@@ -0,0 +1,43 @@
+package ast_test
+
+import "testing"
+
+func TestDoWhile(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+		// a substring of the runtime error, empty if the source runs
+		err string
+	}{
+		{
+			name:   "runs until the condition is false",
+			source: `var i = 0; do { print i; i = i + 1; } while (i < 3);`,
+			want:   "0\n1\n2\n",
+		},
+		{
+			name:   "the body runs at least once",
+			source: `var ran = false; do ran = true; while (ran and false); print ran;`,
+			want:   "true\n",
+		},
+		{
+			name:   "break leaves the loop",
+			source: `var i = 0; do { i = i + 1; if (i == 2) break; } while (true); print i;`,
+			want:   "2\n",
+		},
+		{
+			name:   "continue checks the condition",
+			source: `var i = 0; do { i = i + 1; if (i == 2) continue; print i; } while (i < 3);`,
+			want:   "1\n3\n",
+		},
+		{
+			name:   "the body scope ends before the condition",
+			source: `do { var bodyLocal = 1; } while (bodyLocal);`,
+			err:    "undefined variable 'bodyLocal'",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, test.err)
+	}
+}
@@ -0,0 +1,103 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteDOT renders a parsed program as a Graphviz DOT graph, one node per
+// statement and expression with edges to their children labelled by the
+// field holding them. The output can be piped into `dot -Tsvg`.
+func WriteDOT(w io.Writer, statements []Stmt) error {
+	g := &dotGraph{}
+	g.line("digraph ast {")
+	g.line("  node [shape=box, fontname=\"monospace\"];")
+	g.line("  n0 [label=\"program\", shape=ellipse];")
+	for i, stmt := range statements {
+		child := g.node(stmtNode(stmt))
+		g.line(fmt.Sprintf("  n0 -> n%d [label=\"%d\"];", child, i))
+	}
+	g.line("}")
+
+	_, err := io.WriteString(w, g.builder.String())
+	return err
+}
+
+type dotGraph struct {
+	builder strings.Builder
+	// id of the last node written, the program is node zero
+	last int
+}
+
+func (g *dotGraph) line(text string) {
+	g.builder.WriteString(text)
+	g.builder.WriteString("\n")
+}
+
+// node writes the node for n, the object serialized by MarshalJSON,
+// and its children, returning the id of the node
+func (g *dotGraph) node(n map[string]any) int {
+	g.last++
+	id := g.last
+
+	// members holding nodes are children, everything else except
+	// the location and unset flags such as isGenerator is part of the label
+	var fields, children []string
+	for key, value := range n {
+		switch value.(type) {
+		case map[string]any, []map[string]any:
+			children = append(children, key)
+		default:
+			if key != "type" && key != "line" && key != "offset" && value != nil && value != false {
+				fields = append(fields, key)
+			}
+		}
+	}
+	sort.Strings(fields)
+	sort.Strings(children)
+
+	label := []string{n["type"].(string)}
+	for _, key := range fields {
+		label = append(label, fmt.Sprintf("%s: %v", key, dotValue(n[key])))
+	}
+	if line, ok := n["line"]; ok {
+		label = append(label, fmt.Sprintf("line %d", line))
+	}
+	g.line(fmt.Sprintf("  n%d [label=%s];", id, strconv.Quote(strings.Join(label, "\n"))))
+
+	for _, key := range children {
+		switch child := n[key].(type) {
+		case map[string]any:
+			if child == nil {
+				continue
+			}
+			g.edge(id, g.node(child), key)
+		case []map[string]any:
+			for i, element := range child {
+				g.edge(id, g.node(element), fmt.Sprintf("%s[%d]", key, i))
+			}
+		}
+	}
+
+	return id
+}
+
+func (g *dotGraph) edge(from int, to int, label string) {
+	g.line(fmt.Sprintf("  n%d -> n%d [label=%s];", from, to, strconv.Quote(label)))
+}
+
+// dotValue formats a field for a label, strings are quoted
+// so literals such as "1" and 1 can be told apart
+func dotValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []string:
+		return "(" + strings.Join(v, ", ") + ")"
+	}
+
+	return fmt.Sprint(v)
+}
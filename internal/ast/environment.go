@@ -1,52 +1,356 @@
 package ast
 
 import (
-	"errors"
+	"fmt"
 	"github.com/LucazFFz/lox/internal/token"
+	"sort"
+	"strings"
 )
 
+// Variables are stored in the order they are defined, the index of a
+// variable in its environment is its slot. The resolver binds every read
+// and assignment of a local variable to the slot of the variable and
+// the number of environments enclosing the one it is evaluated in, so
+// locals are accessed without comparing names. The bindings of a program
+// are held by its Resolution. Globals are looked up by
+// name in the top-level environment of the program and those enclosing
+// it, so a function reading an undefined global fails even if the scope
+// it is defined in later declares a local of the same name. The variables
+// of programs which are not resolved, and those which may be defined by
+// a module imported into a local scope, are looked up by name in every
+// enclosing environment.
+
 type Environment struct {
-	enclosing   *Environment
-	enviornment map[string]LoxValue
+	enclosing *Environment
+	names     []string
+	values    []LoxValue
+	// the slots of the names, only built once an environment
+	// has many variables, such as the global one
+	index map[string]int
+	// only set when leak detection is enabled
+	allocation *allocation
+	// set for the environments of modules, which like the global
+	// environment hold the top-level declarations of a program
+	toplevel bool
+	// the absolute path of the module, empty for the main script
+	path string
+	// the declarations of the modules imported by path into a local
+	// scope. They are kept apart from the variables of the scope so
+	// the slots the resolver bound later locals to stay the same.
+	imports []*Environment
+	// the bindings of the program the code evaluated in
+	// the environment is part of, inherited by the
+	// environments it encloses
+	resolution *Resolution
+	// the interpreter the environment belongs to, inherited
+	// like the resolution
+	interp *Interpreter
+}
+
+// Binding locates the local variable a VariableExpr or AssignExpr refers
+// to, Depth environments out from the one the expression is evaluated in.
+type Binding struct {
+	Resolved bool
+	Depth    int
+	Slot     int
+	// set by the resolver for variables which are not local, they
+	// are looked up in the top-level environment of the program
+	Global bool
+}
+
+// environments with more variables than this look names up in a map
+const indexThreshold = 8
+
+// UndefinedVariableError is returned when getting or assigning a variable
+// which is not defined in the environment or any environment enclosing it.
+type UndefinedVariableError struct {
+	RuntimeError
+	Name string
+	// the defined names most similar to Name, names in
+	// nearer scopes first
+	Suggestions []string
 }
 
 func NewEnvironment(enclosing *Environment) *Environment {
-	return &Environment{
-		enviornment: make(map[string]LoxValue),
-		enclosing:   enclosing,
+	env := &Environment{
+		enclosing:  enclosing,
+		allocation: trackEnvironment(),
 	}
+	if enclosing != nil {
+		env.resolution = enclosing.resolution
+		env.interp = enclosing.interp
+	}
+	return env
 }
 
+// interpreter returns the interpreter e belongs to, the default
+// interpreter for environments created by the host
+func (e *Environment) interpreter() *Interpreter {
+	if e.interp == nil {
+		return defaultInterpreter
+	}
+	return e.interp
+}
+
+// Define defines name in e, a variable defined again keeps its slot.
 func (e *Environment) Define(name string, value LoxValue) {
-	e.enviornment[name] = value
+	if slot, ok := e.slot(name); ok {
+		e.values[slot] = value
+		return
+	}
+
+	e.names = append(e.names, name)
+	e.values = append(e.values, value)
+	switch {
+	case e.index != nil:
+		e.index[name] = len(e.names) - 1
+	case len(e.names) > indexThreshold:
+		e.index = make(map[string]int, len(e.names))
+		for slot, name := range e.names {
+			e.index[name] = slot
+		}
+	}
+}
+
+// slot returns the slot of name in e
+func (e *Environment) slot(name string) (int, bool) {
+	if e.index != nil {
+		slot, ok := e.index[name]
+		return slot, ok
+	}
+
+	for slot, defined := range e.names {
+		if defined == name {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+// remove removes name from e, changing the slots of the variables defined
+// after it. Only globals, which are not accessed by slot, are removed.
+func (e *Environment) remove(name string) {
+	slot, ok := e.slot(name)
+	if !ok {
+		return
+	}
+
+	e.names = append(e.names[:slot], e.names[slot+1:]...)
+	e.values = append(e.values[:slot], e.values[slot+1:]...)
+	if e.index != nil {
+		delete(e.index, name)
+		for i := slot; i < len(e.names); i++ {
+			e.index[e.names[i]] = i
+		}
+	}
+}
+
+// Names returns the names defined in e, leaving out the
+// environments enclosing it, sorted.
+func (e *Environment) Names() []string {
+	names := append([]string{}, e.names...)
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the value of name if it is defined in e,
+// leaving out the environments enclosing it.
+func (e *Environment) Lookup(name string) (LoxValue, bool) {
+	slot, ok := e.slot(name)
+	if !ok {
+		return nil, false
+	}
+	return e.values[slot], true
+}
+
+// resolved returns the environment and slot of the variable name
+// bound to binding, a nil environment if it is not bound and must be
+// looked up by name. A slot holding another variable is a bug of the
+// resolver, it fails instead of reading the wrong variable.
+func (e *Environment) resolved(name token.Token, binding Binding) (*Environment, int, error) {
+	if !binding.Resolved {
+		return nil, 0, nil
+	}
+
+	env := e
+	for i := 0; i < binding.Depth && env != nil; i++ {
+		env = env.enclosing
+	}
+	if env == nil || binding.Slot >= len(env.names) || env.names[binding.Slot] != name.Lexme {
+		return nil, 0, NewRuntimeError(name, fmt.Sprintf("variable '%s' is bound to a wrong slot", name.Lexme))
+	}
+	return env, binding.Slot, nil
+}
+
+// unbound returns the environment the variable bound to binding is looked
+// up in by name, the top-level environment enclosing e for a global
+func (e *Environment) unbound(binding Binding) *Environment {
+	if !binding.Global {
+		return e
+	}
+
+	env := e
+	for env.enclosing != nil && !env.toplevel {
+		env = env.enclosing
+	}
+	return env
+}
+
+// sourcePath returns the absolute path of the module the code evaluated
+// in e is written in, empty for the main script
+func (e *Environment) sourcePath() string {
+	env := e
+	for env.enclosing != nil && !env.toplevel {
+		env = env.enclosing
+	}
+	return env.path
 }
 
-func (e *Environment) Assign(name string, value LoxValue) error {
-	_, ok := e.enviornment[name]
-	if ok {
-		e.enviornment[name] = value
+// GetBound returns the value of the variable name, by its slot if
+// binding is resolved and by name otherwise.
+func (e *Environment) GetBound(name token.Token, binding Binding) (LoxValue, error) {
+	env, slot, err := e.resolved(name, binding)
+	if err != nil {
+		return nil, err
+	}
+	if env != nil {
+		return env.values[slot], nil
+	}
+	return e.unbound(binding).Get(name)
+}
+
+// AssignBound assigns the variable name like GetBound looks it up.
+func (e *Environment) AssignBound(name token.Token, binding Binding, value LoxValue) error {
+	env, slot, err := e.resolved(name, binding)
+	if err != nil {
+		return err
+	}
+	if env != nil {
+		env.values[slot] = value
 		return nil
 	}
+	return e.unbound(binding).Assign(name, value)
+}
 
-	if e.enclosing != nil {
-		return e.enclosing.Assign(name, value)
+// Enclosing returns the environment enclosing e,
+// nil for the global environment.
+func (e *Environment) Enclosing() *Environment {
+	return e.enclosing
+}
+
+func (e *Environment) Assign(name token.Token, value LoxValue) error {
+	for env := e; env != nil; env = env.enclosing {
+		if env, slot, ok := env.lookup(name.Lexme); ok {
+			env.values[slot] = value
+			return nil
+		}
 	}
 
-	return errors.New("")
+	return e.undefined(name)
 }
 
 func (e *Environment) Get(name token.Token) (LoxValue, error) {
-	// try to get variable for this scope
-	if value, ok := e.enviornment[name.Lexme]; ok {
-		return value, nil
+	for env := e; env != nil; env = env.enclosing {
+		if env, slot, ok := env.lookup(name.Lexme); ok {
+			return env.values[slot], nil
+		}
+	}
+
+	return nil, e.undefined(name)
+}
+
+// lookup returns the environment and slot of name among the variables
+// of e and then the declarations of the modules imported into e, the
+// latest import first
+func (e *Environment) lookup(name string) (*Environment, int, bool) {
+	if slot, ok := e.slot(name); ok {
+		return e, slot, true
+	}
+
+	for i := len(e.imports) - 1; i >= 0; i-- {
+		if slot, ok := e.imports[i].slot(name); ok {
+			return e.imports[i], slot, true
+		}
+	}
+	return nil, 0, false
+}
+
+// importInto makes the declarations of the module env m visible to the
+// code evaluated in e. The global environment and those of modules are
+// not resolved, the declarations are defined in them like any global.
+func (e *Environment) importInto(m *Environment) {
+	if e.enclosing == nil || e.toplevel {
+		for i, name := range m.names {
+			e.Define(name, m.values[i])
+		}
+		return
+	}
+
+	imported := NewEnvironment(nil)
+	for i, name := range m.names {
+		imported.Define(name, m.values[i])
+	}
+	e.imports = append(e.imports, imported)
+}
+
+// the maximum number of suggestions of an UndefinedVariableError
+const maxSuggestions = 3
+
+// undefined returns the error for the undefined variable name,
+// suggesting the similar names defined in the searched scopes
+func (e *Environment) undefined(name token.Token) UndefinedVariableError {
+	// typos change a few characters, short names are
+	// only similar to names differing by one character
+	maxDistance := min(2, max(1, len(name.Lexme)/3))
+
+	var suggestions []string
+	seen := map[string]bool{}
+	for env := e; env != nil && len(suggestions) < maxSuggestions; env = env.enclosing {
+		var similar []string
+		for _, defined := range env.names {
+			if !seen[defined] && editDistance(name.Lexme, defined) <= maxDistance {
+				similar = append(similar, defined)
+			}
+			seen[defined] = true
+		}
+
+		sort.Strings(similar)
+		suggestions = append(suggestions, similar...)
+	}
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+
+	message := fmt.Sprintf("undefined variable '%s'", name.Lexme)
+	if len(suggestions) > 0 {
+		message += fmt.Sprintf(", did you mean '%s'?", strings.Join(suggestions, "', '"))
+	}
+
+	return UndefinedVariableError{
+		RuntimeError: NewRuntimeError(name, message),
+		Name:         name.Lexme,
+		Suggestions:  suggestions}
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
 	}
 
-	if e.enclosing != nil {
-		// try to get variable for enclosing scope recursively
-		if value, err := e.enclosing.Get(name); err == nil {
-			return value, nil
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
 		}
+		previous, current = current, previous
 	}
 
-	return nil, errors.New("")
+	return previous[len(b)]
 }
@@ -0,0 +1,271 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/resolve"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUndefinedVariable(t *testing.T) {
+	global := ast.NewEnvironment(nil)
+	global.Define("count", ast.LoxNumber(1))
+	global.Define("total", ast.LoxNumber(2))
+	local := ast.NewEnvironment(global)
+	local.Define("counter", ast.LoxNumber(3))
+
+	name := token.Token{Type: token.IDENTIFIER, Lexme: "countr", Line: 1}
+	_, err := local.Get(name)
+	undefined, ok := err.(ast.UndefinedVariableError)
+	if !ok {
+		t.Fatalf("expected an UndefinedVariableError but got %v", err)
+	}
+
+	if undefined.Name != "countr" || undefined.Token.Lexme != name.Lexme {
+		t.Errorf("expected the error to locate countr but got %q at %v", undefined.Name, undefined.Token)
+	}
+
+	// the nearer scope is suggested first
+	if want := []string{"counter", "count"}; !slices.Equal(undefined.Suggestions, want) {
+		t.Errorf("expected suggestions %v but got %v", want, undefined.Suggestions)
+	}
+
+	err = local.Assign(token.Token{Type: token.IDENTIFIER, Lexme: "x", Line: 1}, ast.LoxNil{})
+	if undefined, ok := err.(ast.UndefinedVariableError); !ok || len(undefined.Suggestions) != 0 {
+		t.Errorf("expected an UndefinedVariableError without suggestions but got %#v", err)
+	}
+}
+
+func TestEnvironmentBindings(t *testing.T) {
+	global := ast.NewEnvironment(nil)
+	global.Define("b", ast.LoxNumber(2))
+	global.Define("a", ast.LoxNumber(1))
+	local := ast.NewEnvironment(global)
+	local.Define("c", ast.LoxNumber(3))
+
+	if names := global.Names(); !slices.Equal(names, []string{"a", "b"}) {
+		t.Errorf("expected the sorted names [a b] but got %v", names)
+	}
+	if _, ok := local.Lookup("a"); ok {
+		t.Error("expected Lookup to leave out the enclosing environment")
+	}
+	if value, ok := local.Lookup("c"); !ok || value != ast.LoxNumber(3) {
+		t.Errorf("expected c to be 3 but got %v", value)
+	}
+	if local.Enclosing() != global || global.Enclosing() != nil {
+		t.Error("expected local to be enclosed by global")
+	}
+}
+
+func TestIsBuiltin(t *testing.T) {
+	if err := interpret(t, `var x = 1; var num2 = num;`); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]bool{"len": true, "num": true, "x": false, "num2": false, "undefined": false} {
+		if got := ast.IsBuiltin(name); got != want {
+			t.Errorf("IsBuiltin(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// TestSlotBindings runs a program accessing locals by slot once it is
+// resolved, it must print the same as when every variable is looked up
+// by name
+func TestSlotBindings(t *testing.T) {
+	source := `
+fun counter() {
+    var count = 0;
+    fun next() {
+        count = count + 1;
+        return count;
+    }
+    return next;
+}
+var next = counter();
+next();
+print next();
+
+var fns = [];
+for (i in [1, 2, 3]) {
+    var twice = i * 2;
+    push(fns, fun () { return twice; });
+}
+for (f in fns) print f();
+
+{
+    var a = "outer";
+    {
+        var b = 1;
+        b++;
+        var a = "inner";
+        print a + str(b);
+    }
+    print a;
+}
+
+fun fib(n) {
+    if (n < 2) return n;
+    return fib(n - 1) + fib(n - 2);
+}
+for (var i = 0; i < 3; i = i + 1) {
+    var n = i + 5;
+    print fib(n);
+}
+`
+	run := func(resolved bool) string {
+		var out bytes.Buffer
+		ast.SetOutput(&out)
+		defer ast.SetOutput(os.Stdout)
+
+		report := func(err error) { t.Error(err) }
+		tokens, _ := scan.Scan(source, report, scan.ScanContext{})
+		stmts, err := parse.Parse(tokens, report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resolution *ast.Resolution
+		if resolved {
+			resolution, _ = resolve.Resolve(stmts, func(error) {})
+		}
+		ast.Interpret(stmts, resolution, report)
+		return out.String()
+	}
+
+	want := "2\n2\n4\n6\ninner2\nouter\n5\n8\n13\n"
+	if got := run(true); got != want {
+		t.Errorf("expected the resolved program to print\n%s\nbut got\n%s", want, got)
+	}
+	if got := run(false); got != want {
+		t.Errorf("expected the unresolved program to print\n%s\nbut got\n%s", want, got)
+	}
+}
+
+// TestFreeVariableIsGlobal calls a function reading an undefined global
+// from a block which declares a local of the same name after the
+// function, the local must not be found
+func TestFreeVariableIsGlobal(t *testing.T) {
+	source := `
+{
+    fun f() { return undefinedGlobal; }
+    var undefinedGlobal = "local";
+    print f();
+}
+`
+	var out bytes.Buffer
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	tokens, _ := scan.Scan(source, func(err error) { t.Error(err) }, scan.ScanContext{})
+	stmts, err := parse.Parse(tokens, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolution, _ := resolve.Resolve(stmts, func(error) {})
+
+	var errs []error
+	ast.Interpret(stmts, resolution, func(err error) { errs = append(errs, err) })
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "undefined variable 'undefinedGlobal'") {
+		t.Errorf("expected reading the global to fail but got %v and printed %q", errs, out.String())
+	}
+}
+
+// TestImportIntoLocalScope imports a module by path into a block and a
+// function, the locals declared after the import must keep their slots
+func TestImportIntoLocalScope(t *testing.T) {
+	ast.SetModuleLoader(func(path string) ([]ast.Stmt, *ast.Resolution, func(error), error) {
+		tokens, _ := scan.Scan(`var one = 1; var two = 2;`, func(err error) { t.Error(err) }, scan.ScanContext{})
+		stmts, err := parse.Parse(tokens, func(err error) { t.Error(err) })
+		return stmts, nil, func(err error) { t.Error(err) }, err
+	})
+	defer ast.SetModuleLoader(nil)
+	defer ast.ClearModules()
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`{ import "mod.lox"; var after = 3; print after; print one + two; }`, "3\n3\n"},
+		{`fun f() { var a = 1; import "mod.lox"; var b = 2; print a + b; print two; } f();`, "3\n2\n"},
+		{`{ var one = "local"; { import "mod.lox"; print one; } print one; }`, "1\nlocal\n"},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		ast.SetOutput(&out)
+
+		tokens, _ := scan.Scan(test.source, func(err error) { t.Error(err) }, scan.ScanContext{})
+		stmts, err := parse.Parse(tokens, func(err error) { t.Error(err) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolution, _ := resolve.Resolve(stmts, func(error) {})
+
+		var errs []error
+		ast.Interpret(stmts, resolution, func(err error) { errs = append(errs, err) })
+		if len(errs) > 0 {
+			t.Errorf("%s: unexpected errors %v", test.source, errs)
+		} else if out.String() != test.want {
+			t.Errorf("%s: expected %q but got %q", test.source, test.want, out.String())
+		}
+	}
+	ast.SetOutput(os.Stdout)
+}
+
+func TestWrongSlot(t *testing.T) {
+	env := ast.NewEnvironment(nil)
+	env.Define("a", ast.LoxNumber(1))
+	name := token.NewToken(token.IDENTIFIER, "b", nil, 1, 0)
+
+	if _, err := env.GetBound(name, ast.Binding{Resolved: true, Slot: 0}); err == nil || !strings.Contains(err.Error(), "bound to a wrong slot") {
+		t.Errorf("expected reading a variable bound to a wrong slot to fail but got %v", err)
+	}
+	if err := env.AssignBound(name, ast.Binding{Resolved: true, Depth: 1}, ast.LoxNil{}); err == nil {
+		t.Error("expected assigning a variable bound to a missing environment to fail")
+	}
+}
+
+// TestResolution interprets inputs of a session resolved independently,
+// a function keeps the bindings of the input it is declared in
+func TestResolution(t *testing.T) {
+	parseSource := func(source string) []ast.Stmt {
+		tokens, _ := scan.Scan(source, func(err error) { t.Error(err) }, scan.ScanContext{})
+		stmts, err := parse.Parse(tokens, func(err error) { t.Error(err) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stmts
+	}
+
+	declare := parseSource(`fun f(x) { var y = x * 2; { var z = y + 1; return z; } }`)
+	call := parseSource(`{ var a = 1; var b = 2; { print f(a + b); } }`)
+
+	// the same statements resolved twice
+	first, err := resolve.Resolve(declare, func(error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := resolve.Resolve(declare, func(error) {})
+	resolution, _ := resolve.Resolve(call, func(error) {})
+
+	var out bytes.Buffer
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	for _, declared := range []*ast.Resolution{first, second} {
+		out.Reset()
+		session := ast.NewSession()
+		report := func(err error) { t.Error(err) }
+		session.Interpret(declare, declared, report)
+		session.Interpret(call, resolution, report)
+		if out.String() != "7\n" {
+			t.Errorf("expected 7 but got %q", out.String())
+		}
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"github.com/LucazFFz/lox/internal/token"
 )
 
+// Expressions and statements are evaluated in the environment passed to
+// them, blocks and function calls pass a new environment enclosing it to
+// the statements they run.
+
 type EvaluateExpr interface {
-	Evaluate() (LoxValue, error)
+	Evaluate(env *Environment) (LoxValue, error)
 }
 
 type EvaluateStmt interface {
-	Evaluate() error
+	Evaluate(env *Environment) error
 }
 
 // evaluating a break statement will return a BreakError
@@ -19,6 +23,22 @@ type EvaluateStmt interface {
 // unsure if this is the best way to handle this
 type BreakError struct {
 	RuntimeError
+	// the label of the loop to break out of, empty for the innermost loop
+	Label string
+}
+
+// evaluating a continue statement returns a ContinueError which
+// loops catch to skip the remainder of the current iteration
+type ContinueError struct {
+	RuntimeError
+	// the label of the loop to continue, empty for the innermost loop
+	Label string
+}
+
+// targets reports whether a break or continue statement with
+// the label target applies to the loop labeled label
+func targets(target string, label token.Token) bool {
+	return target == "" || target == label.Lexme
 }
 
 type ReturnError struct {
@@ -27,25 +47,58 @@ type ReturnError struct {
 }
 
 type RuntimeError struct {
+	// the token (operator, identifier etc.) the error originates
+	// from, zero if the error cannot be tied to a location
+	Token   token.Token
 	message string
 }
 
-func NewRuntimeError(message string) RuntimeError {
-	return RuntimeError{message: message}
+func NewRuntimeError(tok token.Token, message string) RuntimeError {
+	return RuntimeError{Token: tok, message: message}
 }
 
 func (r RuntimeError) Error() string {
-	return "runtime error - " + r.message + "\n"
+	if r.Token.Line == 0 {
+		return "runtime error - " + r.message + "\n"
+	}
+
+	return fmt.Sprintf("[%d] runtime error at \"%s\" - %s\n", r.Token.Line, r.Token.Lexme, r.message)
+}
+
+// Span returns the byte offset and length of the token the error
+// originates from, the offset is negative if there is no such token.
+func (r RuntimeError) Span() (int, int) {
+	if r.Token.Line == 0 {
+		return -1, 0
+	}
+
+	return r.Token.Offset, len(r.Token.Lexme)
+}
+
+// withToken attaches tok to err if err is a runtime error
+// which is not yet tied to a location
+func withToken(err error, tok token.Token) error {
+	if r, ok := err.(RuntimeError); ok && r.Token.Line == 0 {
+		r.Token = tok
+		return r
+	}
+
+	if l, ok := err.(LimitError); ok && l.Token.Line == 0 {
+		l.Token = tok
+		return l
+	}
+
+	return err
 }
 
 // statements
-func (s ExpressionStmt) Evaluate() error {
-	_, err := s.Expr.Evaluate()
+func (s ExpressionStmt) Evaluate(env *Environment) error {
+	_, err := s.Expr.Evaluate(env)
 	return err
 }
 
-func (s PrintStmt) Evaluate() error {
-	value, err := s.Expr.Evaluate()
+func (s PrintStmt) Evaluate(env *Environment) error {
+	value, err := s.Expr.Evaluate(env)
 	if err != nil {
 		return err
 	}
@@ -55,41 +108,46 @@ func (s PrintStmt) Evaluate() error {
 		return err
 	}
 
-	println(str)
+	fmt.Fprintln(env.interpreter().output, str)
 	return nil
 }
 
-func (s BlockStmt) Evaluate() error {
-	return executeBlock(s.Statements, NewEnvironment(current_env))
+func (s BlockStmt) Evaluate(env *Environment) error {
+	return executeBlock(s.Statements, NewEnvironment(env))
 }
 
-func (s VarStmt) Evaluate() error {
+func (s VarStmt) Evaluate(env *Environment) error {
 	if (s.Initializer == NothingExpr{}) {
-		current_env.Define(s.Name.Lexme, LoxNil{})
+		env.Define(s.Name.Lexme, LoxNil{})
 	}
 
-	value, err := s.Initializer.Evaluate()
+	value, err := s.Initializer.Evaluate(env)
 	if err != nil {
 		return err
 	}
 
-	current_env.Define(s.Name.Lexme, value)
+	env.Define(s.Name.Lexme, value)
 	return nil
 }
 
-func (s IfStmt) Evaluate() error {
-	value, err := s.Condition.Evaluate()
+func (s IfStmt) Evaluate(env *Environment) error {
+	value, err := s.Condition.Evaluate(env)
 	if err != nil {
 		return err
 	}
 
-	if isTruthy(value) {
-		err := s.ThenBranch.Evaluate()
+	ok, err := env.interpreter().truthy(value, ExprToken(s.Condition))
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		err := evaluateStmt(s.ThenBranch, env)
 		if err != nil {
 			return err
 		}
 	} else if s.ElseBranch != nil {
-		err := s.ElseBranch.Evaluate()
+		err := evaluateStmt(s.ElseBranch, env)
 		if err != nil {
 			return err
 		}
@@ -98,42 +156,98 @@ func (s IfStmt) Evaluate() error {
 	return nil
 }
 
-func (s WhileStmt) Evaluate() error {
-	value, err := s.Condition.Evaluate()
-	if err != nil {
-		return err
+func (s WhileStmt) Evaluate(env *Environment) error {
+	// the body of a do-while loop runs once before
+	// the condition is evaluated
+	var value LoxValue = LoxBoolean(true)
+	if s.Keyword.Type != token.DO {
+		var err error
+		if value, err = s.Condition.Evaluate(env); err != nil {
+			return err
+		}
 	}
 
-	for isTruthy(value) {
-		err := s.Body.Evaluate()
+	loop := beginLoop(s.Keyword, env)
+	for {
+		if ok, err := env.interpreter().truthy(value, ExprToken(s.Condition)); err != nil || !ok {
+			return err
+		}
+
+		if err := loop.iterate(); err != nil {
+			return err
+		}
+
+		err := evaluateStmt(s.Body, env)
 		if err != nil {
 			// if we encounter a breakError,
 			// we want to break out of the loop
-			if _, ok := err.(BreakError); ok {
+			if breakErr, ok := err.(BreakError); ok && targets(breakErr.Label, s.Label) {
 				return nil
 			}
 
-			return err
+			// break and continue statements for enclosing loops
+			// end this loop as well
+			if continueErr, ok := err.(ContinueError); !ok || !targets(continueErr.Label, s.Label) {
+				return err
+			}
+		}
+
+		if s.Increment != nil {
+			if _, err := s.Increment.Evaluate(env); err != nil {
+				return err
+			}
 		}
 
-		value, err = s.Condition.Evaluate()
+		value, err = s.Condition.Evaluate(env)
 		if err != nil {
 			return err
 		}
 	}
+}
 
-	return nil
+func (s ForInStmt) Evaluate(env *Environment) error {
+	iterable, err := s.Iterable.Evaluate(env)
+	if err != nil {
+		return err
+	}
+
+	loop := beginLoop(s.Name, env)
+	err = iterate(iterable, func(v LoxValue) error {
+		if err := loop.iterate(); err != nil {
+			return err
+		}
+
+		// every iteration gets a fresh environment so closures
+		// created in the body capture the current element
+		env := NewEnvironment(env)
+		env.Define(s.Name.Lexme, v)
+		err := executeBlock([]Stmt{s.Body}, env)
+		if continueErr, ok := err.(ContinueError); ok && targets(continueErr.Label, s.Label) {
+			return nil
+		}
+		return err
+	})
+
+	if breakErr, ok := err.(BreakError); ok && targets(breakErr.Label, s.Label) {
+		return nil
+	}
+
+	return withToken(err, s.Name)
 }
 
-func (s BreakStmt) Evaluate() error {
-	return BreakError{NewRuntimeError("unexpected break statement")}
+func (s BreakStmt) Evaluate(env *Environment) error {
+	return BreakError{NewRuntimeError(token.Token{}, "unexpected break statement"), s.Label.Lexme}
 }
 
-func (s ReturnStmt) Evaluate() error {
+func (s ContinueStmt) Evaluate(env *Environment) error {
+	return ContinueError{NewRuntimeError(token.Token{}, "unexpected continue statement"), s.Label.Lexme}
+}
+
+func (s ReturnStmt) Evaluate(env *Environment) error {
 	var value LoxValue = LoxNil{}
 	var err error
 	if s.Expr != nil {
-		value, err = s.Expr.Evaluate()
+		value, err = s.Expr.Evaluate(env)
 	}
 
 	if err != nil {
@@ -141,20 +255,64 @@ func (s ReturnStmt) Evaluate() error {
 	}
 
 	return ReturnError{
-		RuntimeError: NewRuntimeError("unexpected return statement"),
+		RuntimeError: NewRuntimeError(token.Token{}, "unexpected return statement"),
 		Value:        value,
 	}
 }
 
-func (t CallStmt) Evaluate() (LoxValue, error) {
-	callee, err := t.Callee.Evaluate()
+func (s AssertStmt) Evaluate(env *Environment) error {
+	value, err := s.Condition.Evaluate(env)
+	if err != nil {
+		return err
+	}
+
+	if ok, err := env.interpreter().truthy(value, ExprToken(s.Condition)); err != nil || ok {
+		return err
+	}
+
+	msg := "assertion failed: " + FormatExpr(s.Condition)
+	if s.Message != nil {
+		message, err := s.Message.Evaluate(env)
+		if err != nil {
+			return err
+		}
+
+		str, err := valueToString(message)
+		if err != nil {
+			return err
+		}
+		msg += " (" + str + ")"
+	}
+
+	return NewRuntimeError(s.Keyword, msg)
+}
+
+func (s YieldStmt) Evaluate(env *Environment) error {
+	var value LoxValue = LoxNil{}
+	if s.Expr != nil {
+		var err error
+		if value, err = s.Expr.Evaluate(env); err != nil {
+			return err
+		}
+	}
+
+	generator := env.interpreter().currentGenerator
+	if generator == nil {
+		return NewRuntimeError(s.Keyword, "can only yield inside a generator")
+	}
+
+	return generator.suspend(value)
+}
+
+func (t CallExpr) Evaluate(env *Environment) (LoxValue, error) {
+	callee, err := t.Callee.Evaluate(env)
 	if err != nil {
 		return nil, err
 	}
 
 	arguments := []LoxValue{}
 	for _, arg := range t.Arguments {
-		arg, err := arg.Evaluate()
+		arg, err := arg.Evaluate(env)
 		if err != nil {
 			return nil, err
 		}
@@ -163,54 +321,63 @@ func (t CallStmt) Evaluate() (LoxValue, error) {
 	}
 
 	if function, ok := callee.(Callable); ok {
-		if len(arguments) != function.Arity() {
-			return nil, NewRuntimeError(
-				fmt.Sprintf("expected {%d} arguments but got {%d} arguments",
-					len(arguments),
-					function.Arity()))
+		if !acceptsArguments(function, len(arguments)) {
+			return nil, NewRuntimeError(t.Paren, arityMessage(function, len(arguments)))
+		}
+
+		var value LoxValue
+		switch function := function.(type) {
+		case NativeFunction:
+			value, err = function.CallAt(t.Paren, arguments)
+		case LoxFunction:
+			value, err = function.CallAt(t.Paren, arguments)
+		default:
+			value, err = function.Call(arguments)
 		}
 
-		value, err := function.Call(arguments)
 		if err != nil {
-			return nil, err
+			// errors raised by native functions do not know where
+			// they were called from
+			return nil, withToken(err, t.Paren)
 		}
 
 		return value, nil
 	}
 
-	return nil, NewRuntimeError("can only invoke functions and methods")
+	return nil, NewRuntimeError(t.Paren, "can only invoke functions and methods")
 }
 
-func (t FunctionStmt) Evaluate() error {
-	function := LoxFunction{
-		Name:       t.Name,
-		Parameters: t.Parameters,
-		Body:       t.Body,
-		Closure:    current_env}
-	current_env.Define(t.Name.Lexme, function)
+func (t FunctionStmt) Evaluate(env *Environment) error {
+	function := t.function(env)
+	function.Name = t.Name
+	env.Define(t.Name.Lexme, function)
 	return nil
 }
 
 // expressions
-func (t LiteralExpr) Evaluate() (LoxValue, error) {
+func (t LiteralExpr) Evaluate(env *Environment) (LoxValue, error) {
 	return t.Value, nil
 }
 
-func (t GroupingExpr) Evaluate() (LoxValue, error) {
-	return t.Expr.Evaluate()
+func (t GroupingExpr) Evaluate(env *Environment) (LoxValue, error) {
+	return t.Expr.Evaluate(env)
 }
 
-func (t UnaryExpr) Evaluate() (LoxValue, error) {
-	right, err := t.Right.Evaluate()
+func (t UnaryExpr) Evaluate(env *Environment) (LoxValue, error) {
+	right, err := t.Right.Evaluate(env)
 	if err != nil {
 		return nil, err
 	}
 	switch t.Op.Type {
 	case token.BANG:
-		return LoxBoolean(!isTruthy(right)), nil
+		ok, err := env.interpreter().truthy(right, t.Op)
+		if err != nil {
+			return nil, err
+		}
+		return LoxBoolean(!ok), nil
 	case token.MINUS:
 		if !isNumber(right) {
-			return nil, NewRuntimeError("operand must be a number")
+			return nil, NewRuntimeError(t.Op, "operand must be a number")
 		}
 		return LoxNumber(-AsNumber(right)), nil
 
@@ -219,10 +386,10 @@ func (t UnaryExpr) Evaluate() (LoxValue, error) {
 	panic("should never reach here")
 }
 
-func (t BinaryExpr) Evaluate() (LoxValue, error) {
+func (t BinaryExpr) Evaluate(env *Environment) (LoxValue, error) {
 	checkNumberOperands := func(left, right LoxValue) error {
 		if !isNumber(left) || !isNumber(right) {
-			return NewRuntimeError("both operands must be numbers")
+			return NewRuntimeError(t.Op, "both operands must be numbers")
 		}
 
 		return nil
@@ -230,18 +397,18 @@ func (t BinaryExpr) Evaluate() (LoxValue, error) {
 
 	checkStringOperands := func(left, right LoxValue) error {
 		if !isString(left) || !isString(right) {
-			return NewRuntimeError("both operands must be strings")
+			return NewRuntimeError(t.Op, "both operands must be strings")
 		}
 
 		return nil
 	}
 
 	evaluateOperands := func() (LoxValue, LoxValue, error) {
-		left, err := t.Left.Evaluate()
+		left, err := t.Left.Evaluate(env)
 		if err != nil {
 			return nil, nil, err
 		}
-		right, err := t.Right.Evaluate()
+		right, err := t.Right.Evaluate(env)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -252,24 +419,32 @@ func (t BinaryExpr) Evaluate() (LoxValue, error) {
 	case token.AND:
 		fallthrough
 	case token.OR:
-		left, err := t.Left.Evaluate()
+		left, err := t.Left.Evaluate(env)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := env.interpreter().truthy(left, t.Op)
 		if err != nil {
 			return nil, err
 		}
 
 		if token.OR == t.Op.Type {
-			if isTruthy(left) {
+			if ok {
 				return left, nil
 			}
 		} else {
-			if !isTruthy(left) {
+			if !ok {
 				return left, nil
 			}
 		}
 
 		// if AND we know that left is true here, if OR we know
 		// that left is false
-		return t.Right.Evaluate()
+		return t.Right.Evaluate(env)
+	case token.COMMA:
+		_, right, err := evaluateOperands()
+		return right, err
 	case token.PLUS:
 		left, right, err := evaluateOperands()
 		if err != nil {
@@ -283,7 +458,7 @@ func (t BinaryExpr) Evaluate() (LoxValue, error) {
 			return LoxString(AsString(left) + AsString(right)), nil
 		}
 
-		return nil, NewRuntimeError("operands must be of same type")
+		return nil, NewRuntimeError(t.Op, "operands must be of same type")
 	case token.MINUS:
 		left, right, err := evaluateOperands()
 		if err != nil {
@@ -312,7 +487,7 @@ func (t BinaryExpr) Evaluate() (LoxValue, error) {
 		}
 
 		if AsNumber(right) == 0 {
-			return nil, NewRuntimeError("division by zero")
+			return nil, NewRuntimeError(t.Op, "division by zero")
 		}
 
 		return LoxNumber(AsNumber(left) / AsNumber(right)), nil
@@ -329,7 +504,14 @@ func (t BinaryExpr) Evaluate() (LoxValue, error) {
 			return LoxBoolean(AsString(left) > AsString(right)), nil
 		}
 
-		return nil, NewRuntimeError("operands must be of same type")
+		if c, ok, err := compareProtocol(left, right); ok {
+			if err != nil {
+				return nil, withToken(err, t.Op)
+			}
+			return LoxBoolean(c > 0), nil
+		}
+
+		return nil, NewRuntimeError(t.Op, "operands must be of same type")
 	case token.GREATER_EQUAL:
 		left, right, err := evaluateOperands()
 		if err != nil {
@@ -343,7 +525,14 @@ func (t BinaryExpr) Evaluate() (LoxValue, error) {
 			return LoxBoolean(AsString(left) >= AsString(right)), nil
 		}
 
-		return nil, NewRuntimeError("operands must be of same type")
+		if c, ok, err := compareProtocol(left, right); ok {
+			if err != nil {
+				return nil, withToken(err, t.Op)
+			}
+			return LoxBoolean(c >= 0), nil
+		}
+
+		return nil, NewRuntimeError(t.Op, "operands must be of same type")
 	case token.LESS:
 		left, right, err := evaluateOperands()
 		if err != nil {
@@ -357,7 +546,14 @@ func (t BinaryExpr) Evaluate() (LoxValue, error) {
 			return LoxBoolean(AsString(left) < AsString(right)), nil
 		}
 
-		return nil, NewRuntimeError("operands must be of same type")
+		if c, ok, err := compareProtocol(left, right); ok {
+			if err != nil {
+				return nil, withToken(err, t.Op)
+			}
+			return LoxBoolean(c < 0), nil
+		}
+
+		return nil, NewRuntimeError(t.Op, "operands must be of same type")
 	case token.LESS_EQUAL:
 		left, right, err := evaluateOperands()
 		if err != nil {
@@ -371,7 +567,14 @@ func (t BinaryExpr) Evaluate() (LoxValue, error) {
 			return LoxBoolean(AsString(left) <= AsString(right)), nil
 		}
 
-		return nil, NewRuntimeError("operands must be of same type")
+		if c, ok, err := compareProtocol(left, right); ok {
+			if err != nil {
+				return nil, withToken(err, t.Op)
+			}
+			return LoxBoolean(c <= 0), nil
+		}
+
+		return nil, NewRuntimeError(t.Op, "operands must be of same type")
 	case token.EQUAL_EQUAL:
 		left, right, err := evaluateOperands()
 		if err != nil {
@@ -384,55 +587,476 @@ func (t BinaryExpr) Evaluate() (LoxValue, error) {
 			return nil, err
 		}
 		return LoxBoolean(!equals(left, right)), nil
+	case token.IS:
+		left, right, err := evaluateOperands()
+		if err != nil {
+			return nil, err
+		}
+
+		typ, ok := right.(LoxType)
+		if !ok {
+			return nil, NewRuntimeError(t.Op, "right-hand-side of 'is' must be a type")
+		}
+		return LoxBoolean(left.Type() == typ.Typ), nil
 	}
 
 	panic("should never reach here (binary)")
 }
 
-func (t TernaryExpr) Evaluate() (LoxValue, error) {
-	condition, err := t.Condition.Evaluate()
+func (t PrefixExpr) Evaluate(env *Environment) (LoxValue, error) {
+	_, updated, err := increment(t.Target, t.Op, env)
+	return updated, err
+}
+
+func (t PostfixExpr) Evaluate(env *Environment) (LoxValue, error) {
+	old, _, err := increment(t.Target, t.Op, env)
+	return old, err
+}
+
+// increment adds or subtracts one from the variable or element target
+// depending on op, returning the value before and after the update
+func increment(target Expr, op token.Token, env *Environment) (LoxValue, LoxValue, error) {
+	update := func(old LoxValue) (LoxValue, error) {
+		if !isNumber(old) {
+			return nil, NewRuntimeError(op, "operand must be a number")
+		}
+
+		if op.Type == token.PLUS_PLUS {
+			return LoxNumber(AsNumber(old) + 1), nil
+		}
+		return LoxNumber(AsNumber(old) - 1), nil
+	}
+
+	switch target := target.(type) {
+	case VariableExpr:
+		old, err := target.Evaluate(env)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		updated, err := update(old)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := env.AssignBound(target.Name, env.resolution.Binding(target.Name), updated); err != nil {
+			return nil, nil, err
+		}
+
+		return old, updated, nil
+	case IndexExpr:
+		// evaluate the object and index only once, so
+		// list[f()]++ calls f a single time
+		object, err := target.Object.Evaluate(env)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		index, err := target.Index.Evaluate(env)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		old, err := IndexExpr{
+			Object:  LiteralExpr{Value: object},
+			Bracket: target.Bracket,
+			Index:   LiteralExpr{Value: index}}.Evaluate(env)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		updated, err := update(old)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		_, err = IndexAssignExpr{
+			Object:  LiteralExpr{Value: object},
+			Bracket: target.Bracket,
+			Index:   LiteralExpr{Value: index},
+			Value:   LiteralExpr{Value: updated}}.Evaluate(env)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return old, updated, nil
+	case GetExpr:
+		object, err := target.Object.Evaluate(env)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		old, err := getProperty(object, target.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		updated, err := update(old)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := setProperty(object, target.Name, updated); err != nil {
+			return nil, nil, err
+		}
+
+		return old, updated, nil
+	}
+
+	return nil, nil, NewRuntimeError(op, "invalid increment target")
+}
+
+func (t TernaryExpr) Evaluate(env *Environment) (LoxValue, error) {
+	condition, err := t.Condition.Evaluate(env)
 	if err != nil {
 		return nil, err
 	}
 
-	if isTruthy(condition) {
-		return t.Left.Evaluate()
+	ok, err := env.interpreter().truthy(condition, ExprToken(t.Condition))
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		return t.Left.Evaluate(env)
 	}
 
-	return t.Right.Evaluate()
+	return t.Right.Evaluate(env)
 }
 
-func (t VariableExpr) Evaluate() (LoxValue, error) {
-	value, err := current_env.Get(t.Name)
+func (t VariableExpr) Evaluate(env *Environment) (LoxValue, error) {
+	value, err := env.GetBound(t.Name, env.resolution.Binding(t.Name))
 	if err != nil {
-		return nil, NewRuntimeError("undefined variable '" + t.Name.Lexme + "'")
+		return nil, err
 	}
 
 	return value, nil
 }
 
-func (t AssignExpr) Evaluate() (LoxValue, error) {
-	value, err := t.Value.Evaluate()
+func (t AssignExpr) Evaluate(env *Environment) (LoxValue, error) {
+	value, err := t.Value.Evaluate(env)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := current_env.Assign(t.Name.Lexme, value); err != nil {
-		return nil, NewRuntimeError("undefined variable '" + t.Name.Lexme + "'")
+	if err := env.AssignBound(t.Name, env.resolution.Binding(t.Name), value); err != nil {
+		return nil, err
 	}
 
 	return value, nil
 }
 
-func (t FunctionExpr) Evaluate() (LoxValue, error) {
+func (t FunctionExpr) Evaluate(env *Environment) (LoxValue, error) {
+	function := t.function(env)
+	function.IsAnonymous = true
+	return function, nil
+}
+
+// function returns the function closing over env, without a name
+func (t FunctionExpr) function(env *Environment) LoxFunction {
 	return LoxFunction{
-		Name:       token.Token{},
-        IsAnonymous: true,
-		Parameters: t.Parameters,
-		Body:       t.Body,
-		Closure:    current_env}, nil
+		IsGenerator: t.IsGenerator,
+		Parameters:  t.Parameters,
+		Body:        t.Body,
+		Closure:     env,
+		allocation:  trackFunction(),
+		memo:        &memo{},
+		resolution:  env.resolution}
+}
+
+func (t ListExpr) Evaluate(env *Environment) (LoxValue, error) {
+	elements := make([]LoxValue, 0, len(t.Elements))
+	for _, element := range t.Elements {
+		value, err := element.Evaluate(env)
+		if err != nil {
+			return nil, err
+		}
+
+		elements = append(elements, value)
+	}
+
+	return NewLoxList(elements), nil
+}
+
+func (t MapExpr) Evaluate(env *Environment) (LoxValue, error) {
+	m := NewLoxMap()
+	for i := range t.Keys {
+		key, err := t.Keys[i].Evaluate(env)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := t.Values[i].Evaluate(env)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := m.Set(key, value); err != nil {
+			return nil, withToken(err, t.Brace)
+		}
+	}
+
+	return m, nil
+}
+
+func (t ObjectExpr) Evaluate(env *Environment) (LoxValue, error) {
+	object := NewLoxObject()
+	for i, name := range t.Names {
+		value, err := t.Values[i].Evaluate(env)
+		if err != nil {
+			return nil, err
+		}
+
+		object.Set(name.Lexme, value)
+	}
+
+	return object, nil
+}
+
+func (t IndexExpr) Evaluate(env *Environment) (LoxValue, error) {
+	object, err := t.Object.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := t.Index.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isString(object):
+		runes := []rune(AsString(object))
+		i, err := asIndex(index, len(runes))
+		if err != nil {
+			return nil, withToken(err, t.Bracket)
+		}
+		return LoxString(runes[i]), nil
+	case isList(object):
+		elements := AsList(object).Elements()
+		i, err := asIndex(index, len(elements))
+		if err != nil {
+			return nil, withToken(err, t.Bracket)
+		}
+		return elements[i], nil
+	case isMap(object):
+		if value, ok := AsMap(object).Get(index); ok {
+			return value, nil
+		}
+		return nil, NewRuntimeError(t.Bracket, "undefined map key")
+	}
+
+	return nil, NewRuntimeError(t.Bracket, "can only index strings, lists and maps")
+}
+
+func (t SliceExpr) Evaluate(env *Environment) (LoxValue, error) {
+	object, err := t.Object.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := evaluateBounds(t.Start, t.End, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isString(object):
+		runes := []rune(AsString(object))
+		low, high, err := sliceBounds(start, end, len(runes))
+		if err != nil {
+			return nil, withToken(err, t.Bracket)
+		}
+		return LoxString(runes[low:high]), nil
+	case isList(object):
+		elements := AsList(object).Elements()
+		low, high, err := sliceBounds(start, end, len(elements))
+		if err != nil {
+			return nil, withToken(err, t.Bracket)
+		}
+		// slicing copies, the new list does not alias the old one
+		return NewLoxList(append([]LoxValue{}, elements[low:high]...)), nil
+	}
+
+	return nil, NewRuntimeError(t.Bracket, "can only slice strings and lists")
+}
+
+func (t IndexAssignExpr) Evaluate(env *Environment) (LoxValue, error) {
+	object, err := t.Object.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := t.Index.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := t.Value.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if isString(object) {
+		return nil, NewRuntimeError(t.Bracket, "strings are immutable")
+	}
+
+	if isMap(object) {
+		if err := AsMap(object).Set(index, value); err != nil {
+			return nil, withToken(err, t.Bracket)
+		}
+		return value, nil
+	}
+
+	if !isList(object) {
+		return nil, NewRuntimeError(t.Bracket, "can only assign to list elements and map entries")
+	}
+
+	list := AsList(object)
+	i, err := asIndex(index, list.Len())
+	if err != nil {
+		return nil, withToken(err, t.Bracket)
+	}
+
+	if err := list.SetElement(i, value); err != nil {
+		return nil, withToken(err, t.Bracket)
+	}
+
+	return value, nil
+}
+
+func (t GetExpr) Evaluate(env *Environment) (LoxValue, error) {
+	object, err := t.Object.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	return getProperty(object, t.Name)
+}
+
+func (t SetExpr) Evaluate(env *Environment) (LoxValue, error) {
+	object, err := t.Object.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := t.Value.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setProperty(object, t.Name, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// getProperty returns the property name of object. The properties of an
+// object are its fields, the properties of a map are its entries with
+// string keys, the properties of a foreign value are its methods, bound
+// to the value.
+func getProperty(object LoxValue, name token.Token) (LoxValue, error) {
+	switch {
+	case isObject(object):
+		if value, ok := AsObject(object).Get(name.Lexme); ok {
+			return value, nil
+		}
+		return nil, NewRuntimeError(name, fmt.Sprintf("undefined field '%s'", name.Lexme))
+	case isMap(object):
+		if value, ok := AsMap(object).Get(LoxString(name.Lexme)); ok {
+			return value, nil
+		}
+		return nil, NewRuntimeError(name, fmt.Sprintf("undefined property '%s'", name.Lexme))
+	case isForeign(object):
+		foreign := AsForeign(object)
+		if method, ok := foreign.Method(name.Lexme); ok {
+			return method, nil
+		}
+		return nil, NewRuntimeError(name, fmt.Sprintf("%s has no method '%s'", foreign.typ.Name, name.Lexme))
+	}
+
+	return nil, NewRuntimeError(name, "only objects, maps and foreign values have properties")
+}
+
+// setProperty sets the property name of object, only objects
+// and maps have properties which can be set
+func setProperty(object LoxValue, name token.Token, value LoxValue) error {
+	if isObject(object) {
+		AsObject(object).Set(name.Lexme, value)
+		return nil
+	}
+
+	if !isMap(object) {
+		return NewRuntimeError(name, "can only set properties of objects and maps")
+	}
+
+	if err := AsMap(object).Set(LoxString(name.Lexme), value); err != nil {
+		return withToken(err, name)
+	}
+	return nil
+}
+
+func (t SliceAssignExpr) Evaluate(env *Environment) (LoxValue, error) {
+	object, err := t.Object.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := evaluateBounds(t.Start, t.End, env)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := t.Value.Evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if isString(object) {
+		return nil, NewRuntimeError(t.Bracket, "strings are immutable")
+	}
+
+	if !isList(object) {
+		return nil, NewRuntimeError(t.Bracket, "can only assign to list slices")
+	}
+
+	if !isList(value) {
+		return nil, NewRuntimeError(t.Bracket, "can only assign a list to a slice")
+	}
+
+	list := AsList(object)
+	low, high, err := sliceBounds(start, end, list.Len())
+	if err != nil {
+		return nil, withToken(err, t.Bracket)
+	}
+
+	if err := list.Replace(low, high, AsList(value).Elements()); err != nil {
+		return nil, withToken(err, t.Bracket)
+	}
+
+	return value, nil
+}
+
+// evaluateBounds evaluates the bounds of a slice,
+// omitted (nil) bounds evaluate to nil
+func evaluateBounds(start Expr, end Expr, env *Environment) (LoxValue, LoxValue, error) {
+	var low, high LoxValue
+	var err error
+	if start != nil {
+		if low, err = start.Evaluate(env); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if end != nil {
+		if high, err = end.Evaluate(env); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return low, high, nil
 }
 
-func (t NothingExpr) Evaluate() (LoxValue, error) {
+func (t NothingExpr) Evaluate(env *Environment) (LoxValue, error) {
 	return LoxNil{}, nil
 }
@@ -0,0 +1,140 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"reflect"
+	"sort"
+)
+
+// ForeignValue is a handle to an object of the program embedding the
+// interpreter, such as a game entity. Scripts cannot look inside foreign
+// values, they can only pass them around and call the methods registered
+// for their ForeignType:
+//
+//	entity.move(1, 2);
+//	method(entity, "move")(1, 2);
+//
+// Two foreign values are equal if they hold the same object, foreign
+// values holding comparable objects can be used as map keys.
+type ForeignValue struct {
+	typ    *ForeignType
+	object any
+}
+
+// ForeignType is the method table shared by the foreign values of a
+// kind, methods are registered by the host before scripts run.
+type ForeignType struct {
+	Name    string
+	methods map[string]ForeignMethod
+}
+
+// ForeignMethod is a method of a ForeignType, Function is called with the
+// object of the foreign value the method is called on as the receiver.
+type ForeignMethod struct {
+	Arity    int
+	Function func(receiver any, args []LoxValue) (LoxValue, error)
+}
+
+func NewForeignType(name string) *ForeignType {
+	return &ForeignType{Name: name, methods: map[string]ForeignMethod{}}
+}
+
+// AddMethod registers a method taking arity arguments, replacing
+// any method of the same name.
+func (t *ForeignType) AddMethod(name string, arity int, fn func(receiver any, args []LoxValue) (LoxValue, error)) {
+	t.methods[name] = ForeignMethod{Arity: arity, Function: fn}
+}
+
+// Methods returns the names of the methods of t in sorted order.
+func (t *ForeignType) Methods() []string {
+	names := make([]string, 0, len(t.methods))
+	for name := range t.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewForeignValue wraps object in a foreign value of type typ.
+func NewForeignValue(typ *ForeignType, object any) ForeignValue {
+	return ForeignValue{typ: typ, object: object}
+}
+
+func (v ForeignValue) Type() LoxValueType {
+	return FOREIGN
+}
+
+func (v ForeignValue) DebugPrint() string {
+	return "<" + v.typ.Name + ">"
+}
+
+// Object returns the object held by v.
+func (v ForeignValue) Object() any {
+	return v.object
+}
+
+func (v ForeignValue) ForeignType() *ForeignType {
+	return v.typ
+}
+
+// Method returns the method called name bound to v,
+// false if the type of v has no such method.
+func (v ForeignValue) Method(name string) (NativeFunction, bool) {
+	m, ok := v.typ.methods[name]
+	if !ok {
+		return NativeFunction{}, false
+	}
+
+	return NativeFunction{
+		paramLen: m.Arity,
+		name:     v.typ.Name + "." + name,
+		Function: func(args []LoxValue) (LoxValue, error) {
+			return m.Function(v.object, args)
+		},
+	}, true
+}
+
+// comparable reports whether the object of v can be compared with ==
+func (v ForeignValue) comparable() bool {
+	return v.object == nil || reflect.TypeOf(v.object).Comparable()
+}
+
+func isForeign(v LoxValue) bool {
+	return v.Type() == FOREIGN
+}
+
+func AsForeign(v LoxValue) ForeignValue {
+	if v, ok := v.(ForeignValue); ok {
+		return v
+	}
+	panic("Cannot convert non-foreign value to foreign value")
+}
+
+func foreignEquals(v1 ForeignValue, v2 ForeignValue) bool {
+	return v1.typ == v2.typ && v1.comparable() && v2.comparable() && v1.object == v2.object
+}
+
+// method(value, name) returns the method called name of the foreign
+// value, bound to the value
+var methodFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isForeign(args[0]) {
+			return nil, NewRuntimeError(token.Token{},
+				fmt.Sprintf("expected a foreign value but got %s", args[0].Type()))
+		}
+
+		if !isString(args[1]) {
+			return nil, NewRuntimeError(token.Token{}, "method name must be a string")
+		}
+
+		v := AsForeign(args[0])
+		method, ok := v.Method(AsString(args[1]))
+		if !ok {
+			return nil, NewRuntimeError(token.Token{},
+				fmt.Sprintf("%s has no method '%s'", v.typ.Name, AsString(args[1])))
+		}
+		return method, nil
+	},
+}
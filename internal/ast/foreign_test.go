@@ -0,0 +1,45 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/ast"
+	"testing"
+)
+
+type entity struct {
+	x, y float64
+}
+
+func TestForeignValue(t *testing.T) {
+	entityType := ast.NewForeignType("entity")
+	entityType.AddMethod("move", 2, func(receiver any, args []ast.LoxValue) (ast.LoxValue, error) {
+		e := receiver.(*entity)
+		e.x += float64(args[0].(ast.LoxNumber))
+		e.y += float64(args[1].(ast.LoxNumber))
+		return ast.LoxNil{}, nil
+	})
+
+	player := &entity{}
+	ast.DefineGlobal("player", ast.NewForeignValue(entityType, player))
+	ast.DefineGlobal("other", ast.NewForeignValue(entityType, player))
+
+	err := interpret(t, `
+var move = method(player, "move");
+move(1, 2);
+method(player, "move")(1, 0);
+player.move(0, -1);
+player.move(0, 1);
+var seen = {player: true};
+if (!seen[other]) move(100, 100);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if player.x != 2 || player.y != 2 {
+		t.Errorf("expected the player at (2, 2) but it is at (%v, %v)", player.x, player.y)
+	}
+
+	if err := interpret(t, `method(player, "jump");`); err == nil {
+		t.Error("expected calling an unknown method to fail")
+	}
+}
@@ -0,0 +1,515 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+// Format pretty-prints a parsed program in the canonical style: one
+// statement per line, four spaces of indentation, braces on the line of
+// the statement they belong to and single spaces around operators.
+//
+// comments are the COMMENT tokens of source, scanned with
+// ScanContext.IncludeComments. A comment written after code stays at the
+// end of that line, other comments are placed on their own line before
+// the statement or closing brace following them. Blank lines between
+// statements are kept, but runs of blank lines are collapsed into one.
+func Format(source string, statements []Stmt, comments []token.Token) string {
+	f := &formatter{source: source, index: token.NewLineIndex(source), comments: comments}
+	for _, stmt := range statements {
+		f.stmt(stmt)
+	}
+	f.flushComments(len(source) + 1)
+
+	return string(f.out)
+}
+
+// FormatExpr pretty-prints e in the canonical style of Format.
+func FormatExpr(e Expr) string {
+	f := &formatter{}
+	f.expr(e)
+	return string(f.out)
+}
+
+type formatter struct {
+	out    []byte
+	indent int
+	source string
+	index  *token.LineIndex
+	// comments not yet written, in source order
+	comments []token.Token
+}
+
+func (f *formatter) write(strs ...string) {
+	for _, str := range strs {
+		f.out = append(f.out, str...)
+	}
+}
+
+// beginLine writes the indentation of a new line, preceded by a
+// blank line if the element at tok was preceded by one in the source
+func (f *formatter) beginLine(tok token.Token) {
+	if tok.Line != 0 {
+		f.flushComments(tok.Offset)
+		f.blankLine(f.index.Position(tok.Offset).Line)
+	}
+	f.write(strings.Repeat("    ", f.indent))
+}
+
+// blankLine writes a blank line if the line before line is blank in
+// the source, unless it would start a block or the program
+func (f *formatter) blankLine(line int) {
+	if line <= 1 || len(f.out) == 0 || strings.HasSuffix(string(f.out), "{\n") {
+		return
+	}
+
+	previous := f.source[f.index.LineStart(line-1):f.index.LineEnd(line-1)]
+	if strings.TrimSpace(previous) == "" {
+		f.write("\n")
+	}
+}
+
+// flushComments writes the comments starting before offset
+func (f *formatter) flushComments(offset int) {
+	for len(f.comments) > 0 && f.comments[0].Offset < offset {
+		comment := f.comments[0]
+		f.comments = f.comments[1:]
+
+		start := comment.Offset
+		text := comment.Lexme
+		if strings.HasPrefix(text, "//") {
+			text = strings.TrimRight(text, " \t\r")
+		}
+
+		pos := f.index.Position(start)
+		before := f.source[f.index.LineStart(pos.Line):start]
+		if strings.TrimSpace(before) != "" && strings.HasSuffix(string(f.out), "\n") {
+			// written after code, keep it on the line of that code
+			f.out = append(f.out[:len(f.out)-1], " "+text+"\n"...)
+			continue
+		}
+
+		f.blankLine(pos.Line)
+		f.write(strings.Repeat("    ", f.indent), text, "\n")
+	}
+}
+
+func (f *formatter) stmt(s Stmt) {
+	switch s := s.(type) {
+	case ExpressionStmt:
+		f.beginLine(StmtToken(s))
+		f.expr(s.Expr)
+		f.write(";\n")
+	case PrintStmt:
+		f.beginLine(StmtToken(s))
+		f.write("print ")
+		f.expr(s.Expr)
+		f.write(";\n")
+	case VarStmt:
+		f.beginLine(s.Name)
+		f.varDeclaration(s)
+		f.write("\n")
+	case BlockStmt:
+		// for loops with an initializer are desugared into a block
+		if loop, ok := desugaredFor(s); ok {
+			f.beginLoop(loop.Label, loop.Keyword)
+			f.forLoop(s.Statements[0], loop)
+			return
+		}
+
+		f.beginLine(StmtToken(s))
+		f.block(s.Statements, s.Brace)
+		f.write("\n")
+	case IfStmt:
+		f.beginLine(StmtToken(s))
+		f.ifStmt(s)
+	case WhileStmt:
+		f.beginLoop(s.Label, s.Keyword)
+		if s.Keyword.Type == token.FOR {
+			f.forLoop(nil, s)
+			return
+		}
+
+		if s.Keyword.Type == token.DO {
+			f.write("do")
+			f.body(s.Body)
+			if _, ok := isBlock(s.Body); ok {
+				f.write(" ")
+			} else {
+				f.write("\n", strings.Repeat("    ", f.indent))
+			}
+			f.write("while (")
+			f.expr(s.Condition)
+			f.write(");\n")
+			return
+		}
+
+		f.write("while (")
+		f.expr(s.Condition)
+		f.write(")")
+		f.body(s.Body)
+		f.write("\n")
+	case ForInStmt:
+		f.beginLoop(s.Label, s.Name)
+		f.write("for (var ", s.Name.Lexme, " in ")
+		f.expr(s.Iterable)
+		f.write(")")
+		f.body(s.Body)
+		f.write("\n")
+	case BreakStmt:
+		f.beginLine(s.Keyword)
+		f.write("break", labelSuffix(s.Label), ";\n")
+	case ContinueStmt:
+		f.beginLine(s.Keyword)
+		f.write("continue", labelSuffix(s.Label), ";\n")
+	case ReturnStmt:
+		f.beginLine(StmtToken(s))
+		f.write("return")
+		if s.Expr != nil {
+			f.write(" ")
+			f.expr(s.Expr)
+		}
+		f.write(";\n")
+	case AssertStmt:
+		f.beginLine(s.Keyword)
+		f.write("assert ")
+		f.expr(s.Condition)
+		if s.Message != nil {
+			f.write(", ")
+			f.expr(s.Message)
+		}
+		f.write(";\n")
+	case ImportStmt:
+		f.beginLine(s.Keyword)
+		if s.Path.Type == token.STRING {
+			f.write("import \"" + s.Path.Lexme + "\";\n")
+		} else {
+			f.write("import " + s.Path.Lexme + ";\n")
+		}
+	case YieldStmt:
+		f.beginLine(s.Keyword)
+		f.write("yield")
+		if s.Expr != nil {
+			f.write(" ")
+			f.expr(s.Expr)
+		}
+		f.write(";\n")
+	case FunctionStmt:
+		f.beginLine(s.Name)
+		f.write("fun ", s.Name.Lexme, signature(s.Parameters, s.ParameterTypes, s.ReturnType), " ")
+		f.block(s.Body, s.Brace)
+		f.write("\n")
+	default:
+		panic("should never reach here (unknown statement)")
+	}
+}
+
+// beginLoop begins the line of a loop located by tok,
+// followed by the label of the loop if it has one
+func (f *formatter) beginLoop(label token.Token, tok token.Token) {
+	if label.Lexme == "" {
+		f.beginLine(tok)
+		return
+	}
+
+	f.beginLine(label)
+	f.write(label.Lexme, ": ")
+}
+
+func labelSuffix(label token.Token) string {
+	if label.Lexme == "" {
+		return ""
+	}
+	return " " + label.Lexme
+}
+
+// signature formats the parameters of a function and the types
+// they and the function are annotated with
+func signature(parameters []token.Token, types []token.Token, returns token.Token) string {
+	params := names(parameters)
+	for i, typ := range types {
+		if typ.Lexme != "" {
+			params[i] += ": " + typ.Lexme
+		}
+	}
+
+	sig := "(" + strings.Join(params, ", ") + ")"
+	if returns.Lexme != "" {
+		sig += " -> " + returns.Lexme
+	}
+	return sig
+}
+
+func (f *formatter) varDeclaration(s VarStmt) {
+	f.write("var ", s.Name.Lexme)
+	if s.Type.Lexme != "" {
+		f.write(": ", s.Type.Lexme)
+	}
+	if _, ok := s.Initializer.(NothingExpr); !ok && s.Initializer != nil {
+		f.write(" = ")
+		f.expr(s.Initializer)
+	}
+	f.write(";")
+}
+
+// block writes statements enclosed in braces, the closing brace
+// is not followed by a newline
+func (f *formatter) block(statements []Stmt, brace token.Token) {
+	f.write("{")
+	if len(statements) == 0 && (len(f.comments) == 0 || f.comments[0].Offset > brace.Offset) {
+		f.write("}")
+		return
+	}
+
+	f.write("\n")
+	f.indent++
+	for _, stmt := range statements {
+		f.stmt(stmt)
+	}
+	if brace.Line != 0 {
+		f.flushComments(brace.Offset)
+	}
+	f.indent--
+	f.write(strings.Repeat("    ", f.indent), "}")
+}
+
+// body writes the body of an if statement or loop following its
+// header. Blocks start on the line of the header, other statements
+// on the next line. The body is not followed by a newline.
+func (f *formatter) body(s Stmt) {
+	if block, ok := isBlock(s); ok {
+		f.write(" ")
+		f.block(block.Statements, block.Brace)
+		return
+	}
+
+	f.write("\n")
+	f.indent++
+	f.stmt(s)
+	f.indent--
+	// statements end with a newline, bodies do not
+	f.out = f.out[:len(f.out)-1]
+}
+
+func (f *formatter) ifStmt(s IfStmt) {
+	f.write("if (")
+	f.expr(s.Condition)
+	f.write(")")
+	f.body(s.ThenBranch)
+	if s.ElseBranch == nil {
+		f.write("\n")
+		return
+	}
+
+	if _, ok := isBlock(s.ThenBranch); ok {
+		f.write(" else")
+	} else {
+		f.write("\n", strings.Repeat("    ", f.indent), "else")
+	}
+
+	if elseIf, ok := s.ElseBranch.(IfStmt); ok {
+		f.write(" ")
+		f.ifStmt(elseIf)
+		return
+	}
+
+	f.body(s.ElseBranch)
+	f.write("\n")
+}
+
+// forLoop writes a for loop, which has been desugared into a
+// while loop preceded by initializer (nil if omitted)
+func (f *formatter) forLoop(initializer Stmt, loop WhileStmt) {
+	f.write("for (")
+	switch initializer := initializer.(type) {
+	case VarStmt:
+		f.varDeclaration(initializer)
+	case ExpressionStmt:
+		f.expr(initializer.Expr)
+		f.write(";")
+	default:
+		f.write(";")
+	}
+
+	// an omitted condition is replaced by a literal true without a token
+	if literal, ok := loop.Condition.(LiteralExpr); !ok || literal.Token.Line != 0 {
+		f.write(" ")
+		f.expr(loop.Condition)
+	}
+	f.write(";")
+
+	if loop.Increment != nil {
+		f.write(" ")
+		f.expr(loop.Increment)
+	}
+	f.write(")")
+	f.body(loop.Body)
+	f.write("\n")
+}
+
+// isBlock reports whether s was written as a block
+func isBlock(s Stmt) (BlockStmt, bool) {
+	block, ok := s.(BlockStmt)
+	if !ok {
+		return block, false
+	}
+
+	_, desugared := desugaredFor(block)
+	return block, !desugared
+}
+
+// desugaredFor returns the loop of a block created by desugaring
+// a for loop with an initializer
+func desugaredFor(block BlockStmt) (WhileStmt, bool) {
+	if block.Brace.Line != 0 || len(block.Statements) != 2 {
+		return WhileStmt{}, false
+	}
+
+	loop, ok := block.Statements[1].(WhileStmt)
+	return loop, ok && loop.Keyword.Type == token.FOR
+}
+
+func (f *formatter) exprs(exprs []Expr) {
+	for i, e := range exprs {
+		if i > 0 {
+			f.write(", ")
+		}
+		f.expr(e)
+	}
+}
+
+func (f *formatter) expr(e Expr) {
+	switch e := e.(type) {
+	case BinaryExpr:
+		f.expr(e.Left)
+		if e.Op.Type == token.COMMA {
+			f.write(", ")
+		} else {
+			f.write(" ", e.Op.Lexme, " ")
+		}
+		f.expr(e.Right)
+	case GroupingExpr:
+		f.write("(")
+		f.expr(e.Expr)
+		f.write(")")
+	case LiteralExpr:
+		f.literal(e)
+	case VariableExpr:
+		f.write(e.Name.Lexme)
+	case UnaryExpr:
+		f.write(e.Op.Lexme)
+		// - -x must not become the decrement --x
+		if tok := ExprToken(e.Right); e.Op.Type == token.MINUS &&
+			(tok.Type == token.MINUS || tok.Type == token.MINUS_MINUS) {
+			f.write(" ")
+		}
+		f.expr(e.Right)
+	case PrefixExpr:
+		f.write(e.Op.Lexme)
+		f.expr(e.Target)
+	case PostfixExpr:
+		f.expr(e.Target)
+		f.write(e.Op.Lexme)
+	case TernaryExpr:
+		f.expr(e.Condition)
+		f.write(" ? ")
+		f.expr(e.Left)
+		f.write(" : ")
+		f.expr(e.Right)
+	case AssignExpr:
+		f.write(e.Name.Lexme, " = ")
+		f.expr(e.Value)
+	case ListExpr:
+		f.write("[")
+		f.exprs(e.Elements)
+		f.write("]")
+	case MapExpr:
+		f.write("{")
+		for i := range e.Keys {
+			if i > 0 {
+				f.write(", ")
+			}
+			f.expr(e.Keys[i])
+			f.write(": ")
+			f.expr(e.Values[i])
+		}
+		f.write("}")
+	case ObjectExpr:
+		f.write("object {")
+		for i := range e.Names {
+			if i > 0 {
+				f.write(",")
+			}
+			f.write(" ", e.Names[i].Lexme, ": ")
+			f.expr(e.Values[i])
+		}
+		if len(e.Names) > 0 {
+			f.write(" ")
+		}
+		f.write("}")
+	case IndexExpr:
+		f.expr(e.Object)
+		f.write("[")
+		f.expr(e.Index)
+		f.write("]")
+	case SliceExpr:
+		f.slice(e.Object, e.Start, e.End)
+	case IndexAssignExpr:
+		f.expr(e.Object)
+		f.write("[")
+		f.expr(e.Index)
+		f.write("] = ")
+		f.expr(e.Value)
+	case SliceAssignExpr:
+		f.slice(e.Object, e.Start, e.End)
+		f.write(" = ")
+		f.expr(e.Value)
+	case GetExpr:
+		f.expr(e.Object)
+		f.write(".", e.Name.Lexme)
+	case SetExpr:
+		f.expr(e.Object)
+		f.write(".", e.Name.Lexme, " = ")
+		f.expr(e.Value)
+	case FunctionExpr:
+		f.write("fun ", signature(e.Parameters, e.ParameterTypes, e.ReturnType), " ")
+		f.block(e.Body, e.Brace)
+	case CallExpr:
+		f.expr(e.Callee)
+		f.write("(")
+		f.exprs(e.Arguments)
+		f.write(")")
+	case NothingExpr:
+	default:
+		panic("should never reach here (unknown expression)")
+	}
+}
+
+func (f *formatter) slice(object Expr, start Expr, end Expr) {
+	f.expr(object)
+	f.write("[")
+	if start != nil {
+		f.expr(start)
+	}
+	f.write(":")
+	if end != nil {
+		f.expr(end)
+	}
+	f.write("]")
+}
+
+// literal writes a literal as it was written in the source,
+// e.g. numbers keep their trailing zeros
+func (f *formatter) literal(e LiteralExpr) {
+	switch {
+	case e.Token.Type == token.STRING:
+		f.write("\"", e.Token.Lexme, "\"")
+	case e.Token.Line != 0:
+		f.write(e.Token.Lexme)
+	case e.Value.Type() == STRING:
+		f.write("\"", string(e.Value.(LoxString)), "\"")
+	default:
+		text, _ := valueToString(e.Value)
+		f.write(text)
+	}
+}
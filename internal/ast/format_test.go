@@ -0,0 +1,49 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"testing"
+)
+
+func format(t *testing.T, source string) string {
+	report := func(err error) { t.Fatal(err) }
+	tokens, _ := scan.Scan(source, report, scan.ScanContext{IncludeComments: true})
+
+	stmts, comments, err := parse.ParseWithComments(tokens, report, parse.DefaultParseOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ast.Format(source, stmts, comments.All)
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{"var a=1+2 ;", "var a = 1 + 2;\n"},
+		{"fun f(a,b){return a;}", "fun f(a, b) {\n    return a;\n}\n"},
+		{"for(var i=0;i<2;i++)print i;", "for (var i = 0; i < 2; i++)\n    print i;\n"},
+		{"for(;;){}", "for (;;) {}\n"},
+		{"if (a) { b; } else c;", "if (a) {\n    b;\n} else\n    c;\n"},
+		{"print - -1;", "print - -1;\n"},
+		{"a.b.c=a . d(1);", "a.b.c = a.d(1);\n"},
+		{"do{a;}while(b);", "do {\n    a;\n} while (b);\n"},
+		{"do a; while(b);", "do\n    a;\nwhile (b);\n"},
+		{"import  \"lib/a.lox\" ;import b;", "import \"lib/a.lox\";\nimport b;\n"},
+		{"assert a==1 ;assert b,\"m\";", "assert a == 1;\nassert b, \"m\";\n"},
+		{"a:for(var i=0;;)while(b)break a;", "a: for (var i = 0;;)\n    while (b)\n        break a;\n"},
+		{"a;\n\n\n// own line\nb; // trailing\n", "a;\n\n// own line\nb; // trailing\n"},
+		{"while (a) {\n  a; /* end */\n  // last\n}", "while (a) {\n    a; /* end */\n    // last\n}\n"},
+		{"var a:num=1;fun f(b:str,c)->nil{}var g=fun(d:list)->fun{};", "var a: num = 1;\nfun f(b: str, c) -> nil {}\nvar g = fun (d: list) -> fun {};\n"},
+	}
+
+	for _, test := range tests {
+		got := format(t, test.source)
+		if got != test.want {
+			t.Errorf("format(%q)\ngot:  %q\nwant: %q", test.source, got, test.want)
+		}
+		if again := format(t, got); again != got {
+			t.Errorf("formatting %q again changed it to %q", got, again)
+		}
+	}
+}
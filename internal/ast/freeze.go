@@ -0,0 +1,67 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+)
+
+// frozen is embedded in the backing storage of mutable values. Once a
+// value is frozen every attempt to mutate it is a runtime error.
+type frozen struct {
+	isFrozen bool
+	// the call to freeze() which froze the value
+	site token.Token
+}
+
+func (f *frozen) freeze(site token.Token) {
+	if !f.isFrozen {
+		f.isFrozen = true
+		f.site = site
+	}
+}
+
+func (f *frozen) checkMutable(kind string) error {
+	if !f.isFrozen {
+		return nil
+	}
+
+	return NewRuntimeError(token.Token{},
+		fmt.Sprintf("cannot modify frozen %s (frozen at line %d)", kind, f.site.Line))
+}
+
+// freeze(value) makes a list, map or set immutable and returns it. Freezing
+// is shallow, values stored in a frozen list can still be modified unless
+// they are frozen themselves. Other values are already immutable and are
+// returned as is.
+var freezeFunc = NativeFunction{
+	paramLen: 1,
+	FunctionAt: func(site token.Token, args []LoxValue) (LoxValue, error) {
+		switch {
+		case isList(args[0]):
+			AsList(args[0]).l.freeze(site)
+		case isMap(args[0]):
+			AsMap(args[0]).m.freeze(site)
+		case isSet(args[0]):
+			AsSet(args[0]).members.m.freeze(site)
+		}
+
+		return args[0], nil
+	},
+}
+
+// isFrozen(value) reports whether value is a frozen list, map or set
+var isFrozenFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		switch {
+		case isList(args[0]):
+			return LoxBoolean(AsList(args[0]).l.isFrozen), nil
+		case isMap(args[0]):
+			return LoxBoolean(AsMap(args[0]).m.isFrozen), nil
+		case isSet(args[0]):
+			return LoxBoolean(AsSet(args[0]).members.m.isFrozen), nil
+		}
+
+		return LoxBoolean(false), nil
+	},
+}
@@ -0,0 +1,95 @@
+package ast_test
+
+import "testing"
+
+func TestFreeze(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+		// a substring of the runtime error, empty if the source runs
+		err string
+	}{
+		{
+			name:   "freeze returns the frozen value",
+			source: `var a = [1]; var b = freeze(a); print isFrozen(a); print b == a; print isFrozen([1]);`,
+			want:   "true\ntrue\nfalse\n",
+		},
+		{
+			name:   "frozen lists can be read",
+			source: `var a = freeze([3, 1, 2]); print a[0]; print a[1:]; print len(a); for (x in a) print x;`,
+			want:   "3\n[1, 2]\n3\n3\n1\n2\n",
+		},
+		{
+			name:   "index assignment to a frozen list",
+			source: `var a = freeze([1, 2]); a[0] = 3;`,
+			err:    "cannot modify frozen list (frozen at line 1)",
+		},
+		{
+			name:   "slice assignment to a frozen list",
+			source: `var a = freeze([1, 2]); a[0:1] = [3];`,
+			err:    "cannot modify frozen list",
+		},
+		{
+			name:   "push to a frozen list",
+			source: `var a = freeze([1, 2]); push(a, 3);`,
+			err:    "cannot modify frozen list",
+		},
+		{
+			name:   "pop from a frozen list",
+			source: `var a = freeze([1, 2]); pop(a);`,
+			err:    "cannot modify frozen list",
+		},
+		{
+			name:   "sort a frozen list",
+			source: `var a = freeze([2, 1]); sort(a);`,
+			err:    "cannot modify frozen list",
+		},
+		{
+			name:   "frozen maps can be read",
+			source: `var m = freeze({"a": 1}); print m["a"]; print has(m, "a");`,
+			want:   "1\ntrue\n",
+		},
+		{
+			name:   "assignment to a frozen map",
+			source: `var m = freeze({"a": 1}); m["b"] = 2;`,
+			err:    "cannot modify frozen map (frozen at line 1)",
+		},
+		{
+			name:   "remove from a frozen map",
+			source: `var m = freeze({"a": 1}); remove(m, "a");`,
+			err:    "cannot modify frozen map",
+		},
+		{
+			name:   "add to a frozen set",
+			source: `var s = freeze(set([1])); setAdd(s, 2);`,
+			err:    "cannot modify frozen set",
+		},
+		{
+			name: "the error names the line of the freeze",
+			source: `var a = [1];
+freeze(a);
+a[0] = 2;`,
+			err: "frozen at line 2",
+		},
+		{
+			name:   "freezing is shallow",
+			source: `var inner = [1]; var a = freeze([inner, {"k": 1}]); push(a[0], 2); a[1]["k"] = 2; print a; print isFrozen(a[0]);`,
+			want:   "[[1, 2], {\"k\": 2}]\nfalse\n",
+		},
+		{
+			name:   "nested values frozen themselves",
+			source: `var a = freeze([freeze([1])]); push(a[0], 2);`,
+			err:    "cannot modify frozen list",
+		},
+		{
+			name:   "freezing other values",
+			source: `print freeze(1); print isFrozen(freeze("s"));`,
+			want:   "1\nfalse\n",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, test.err)
+	}
+}
@@ -0,0 +1,36 @@
+package ast_test
+
+import "testing"
+
+func TestAnonymousFunctionStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "immediately invoked",
+			source: `fun () { print "called"; }(); fun (x) { print x; }(3);`,
+			want:   "called\n3\n",
+		},
+		{
+			name:   "declarations are still declarations",
+			source: `fun () { print "anonymous"; }(); fun f() { return "named"; } print f();`,
+			want:   "anonymous\nnamed\n",
+		},
+		{
+			name:   "expression statement without a call",
+			source: `fun () { print "never"; }; print "done";`,
+			want:   "done\n",
+		},
+		{
+			name:   "closures of anonymous functions",
+			source: `var n = 1; fun () { n = n + 1; }(); print n;`,
+			want:   "2\n",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, "")
+	}
+}
@@ -0,0 +1,333 @@
+package ast_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/resolve"
+	"github.com/LucazFFz/lox/internal/scan"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The fuzz tests interpret random programs, which must never panic, must
+// stop at the iteration limit and must print the same output and errors
+// every time they are run. The programs are valid to parse but not to run,
+// runtime errors such as adding a bool to a number are expected.
+
+// program generates a random program from seed
+func program(seed int64) string {
+	g := &generator{rand: rand.New(rand.NewSource(seed))}
+
+	// globals outlive the program, so every global is
+	// redeclared to run the program from the same state
+	for i := 0; i < 3; i++ {
+		g.line("var g%d = %s;", i, g.number())
+		g.scopes = append(g.scopes, fmt.Sprintf("g%d", i))
+	}
+
+	for i := g.rand.Intn(3); i > 0; i-- {
+		g.function()
+	}
+	for i := 1 + g.rand.Intn(8); i > 0; i-- {
+		g.stmt(0)
+	}
+
+	// a global whose initializer fails is left undefined, declaring every
+	// name first keeps earlier programs from defining it instead
+	var declarations strings.Builder
+	for i := 1; i <= g.nextVariable; i++ {
+		fmt.Fprintf(&declarations, "var v%d;\n", i)
+	}
+	return declarations.String() + g.source.String()
+}
+
+type generator struct {
+	rand   *rand.Rand
+	source strings.Builder
+	indent int
+	// the variables in scope, innermost last
+	scopes []string
+	// the functions declared so far and their number of parameters,
+	// a function only calls functions declared before it
+	functions []int
+	// the number of enclosing loops and whether a function encloses the current statement
+	loops        int
+	inFunction   bool
+	nextVariable int
+}
+
+func (g *generator) line(format string, args ...any) {
+	g.source.WriteString(strings.Repeat("    ", g.indent))
+	fmt.Fprintf(&g.source, format, args...)
+	g.source.WriteString("\n")
+}
+
+func (g *generator) variable() string {
+	g.nextVariable++
+	return fmt.Sprintf("v%d", g.nextVariable)
+}
+
+func (g *generator) number() string {
+	if g.rand.Intn(4) == 0 {
+		return fmt.Sprintf("%d.5", g.rand.Intn(10))
+	}
+	return fmt.Sprint(g.rand.Intn(10))
+}
+
+func (g *generator) function() {
+	params := g.rand.Intn(3)
+	names := make([]string, params)
+	for i := range names {
+		names[i] = g.variable()
+	}
+
+	g.line("fun f%d(%s) {", len(g.functions), strings.Join(names, ", "))
+	scopes, loops := g.scopes, g.loops
+	g.scopes, g.loops, g.inFunction = append(g.scopes, names...), 0, true
+	g.block(1)
+	g.scopes, g.loops, g.inFunction = scopes, loops, false
+	g.line("}")
+
+	g.functions = append(g.functions, params)
+}
+
+// block generates the statements of a block, its braces are left to the caller
+func (g *generator) block(depth int) {
+	scopes := g.scopes
+	g.indent++
+	for i := 1 + g.rand.Intn(3); i > 0; i-- {
+		g.stmt(depth)
+	}
+	g.indent--
+	g.scopes = scopes
+}
+
+func (g *generator) stmt(depth int) {
+	// nested statements are only generated up to a depth of 3
+	kind := g.rand.Intn(12)
+	if depth >= 3 {
+		kind = g.rand.Intn(4)
+	}
+
+	switch kind {
+	case 0:
+		name := g.variable()
+		g.line("var %s = %s;", name, g.expr(0))
+		g.scopes = append(g.scopes, name)
+	case 1:
+		g.line("%s = %s;", g.scopes[g.rand.Intn(len(g.scopes))], g.expr(0))
+	case 2:
+		g.line("print %s;", g.expr(0))
+	case 3:
+		g.jump()
+	case 4:
+		g.line("if (%s) {", g.expr(0))
+		g.block(depth + 1)
+		if g.rand.Intn(2) == 0 {
+			g.line("} else {")
+			g.block(depth + 1)
+		}
+		g.line("}")
+	case 5:
+		g.line("while (%s) {", g.expr(0))
+		g.loop(depth)
+	case 6:
+		i := g.variable()
+		g.line("for (var %s = 0; %s < %d; %s = %s + 1) {", i, i, g.rand.Intn(5), i, i)
+		g.loop(depth)
+	case 7:
+		g.line("do {")
+		g.loops++
+		g.block(depth + 1)
+		g.loops--
+		g.line("} while (%s);", g.expr(0))
+	case 8:
+		g.line("for (%s in [%s, %s]) {", g.variable(), g.expr(0), g.expr(0))
+		g.loop(depth)
+	case 9:
+		g.line("assert %s, %q;", g.expr(0), "generated")
+	case 10:
+		g.line("{")
+		g.block(depth + 1)
+		g.line("}")
+	default:
+		g.line("%s;", g.call())
+	}
+}
+
+func (g *generator) loop(depth int) {
+	g.loops++
+	g.block(depth + 1)
+	g.loops--
+	g.line("}")
+}
+
+// jump generates a break, continue or return statement
+// if the current statement allows one, otherwise a print
+func (g *generator) jump() {
+	switch {
+	case g.loops > 0 && g.rand.Intn(3) == 0:
+		g.line("break;")
+	case g.loops > 0 && g.rand.Intn(2) == 0:
+		g.line("continue;")
+	case g.inFunction:
+		g.line("return %s;", g.expr(0))
+	default:
+		g.line("print %s;", g.expr(0))
+	}
+}
+
+func (g *generator) call() string {
+	if len(g.functions) == 0 {
+		return fmt.Sprintf("str(%s)", g.expr(1))
+	}
+
+	f := g.rand.Intn(len(g.functions))
+	// a wrong number of arguments now and then
+	arity := g.functions[f]
+	if g.rand.Intn(8) == 0 {
+		arity = g.rand.Intn(3)
+	}
+
+	args := make([]string, arity)
+	for i := range args {
+		args[i] = g.expr(1)
+	}
+	return fmt.Sprintf("f%d(%s)", f, strings.Join(args, ", "))
+}
+
+func (g *generator) expr(depth int) string {
+	kind := g.rand.Intn(9)
+	if depth >= 3 {
+		kind = g.rand.Intn(3)
+	}
+
+	switch kind {
+	case 0:
+		return g.number()
+	case 1:
+		return g.scopes[g.rand.Intn(len(g.scopes))]
+	case 2:
+		return []string{"true", "false", "nil", `"s"`}[g.rand.Intn(4)]
+	case 3, 4:
+		operators := []string{"+", "-", "*", "/", "<", "<=", "==", "!=", "and", "or"}
+		return fmt.Sprintf("%s %s %s", g.expr(depth+1), operators[g.rand.Intn(len(operators))], g.expr(depth+1))
+	case 5:
+		return fmt.Sprintf("%s(%s)", []string{"-", "!"}[g.rand.Intn(2)], g.expr(depth+1))
+	case 6:
+		return fmt.Sprintf("(%s ? %s : %s)", g.expr(depth+1), g.expr(depth+1), g.expr(depth+1))
+	case 7:
+		return fmt.Sprintf("(%s)", g.expr(depth+1))
+	}
+	return g.call()
+}
+
+// run interprets source with limits, returning everything it printed and
+// reported. The program is reported as the cause of a panic.
+func run(t *testing.T, seed int64, source string) string {
+	t.Helper()
+	var out bytes.Buffer
+	ast.SetOutput(&out)
+	ast.SetMaxIterations(20)
+	ast.SetMaxCallDepth(32)
+	defer func() {
+		ast.SetOutput(os.Stdout)
+		ast.SetMaxIterations(0)
+		ast.SetMaxCallDepth(ast.DefaultMaxCallDepth)
+		if r := recover(); r != nil {
+			t.Fatalf("program %d panicked: %v\n%s", seed, r, source)
+		}
+	}()
+
+	report := func(err error) { fmt.Fprint(&out, err) }
+	tokens, _ := scan.Scan(source, report, scan.ScanContext{})
+	stmts, err := parse.Parse(tokens, report)
+	if err != nil {
+		t.Fatalf("program %d does not parse:\n%s\n%s", seed, out.String(), source)
+	}
+
+	ast.Interpret(stmts, nil, report)
+	return out.String()
+}
+
+func fuzzProgram(t *testing.T, seed int64) {
+	source := program(seed)
+	first := run(t, seed, source)
+	if second := run(t, seed, source); first != second {
+		t.Fatalf("program %d printed\n%s\nthe first time but\n%s\nthe second time:\n%s", seed, first, second, source)
+	}
+}
+
+func TestRandomPrograms(t *testing.T) {
+	n := int64(500)
+	if testing.Short() {
+		n = 50
+	}
+
+	for seed := int64(0); seed < n; seed++ {
+		fuzzProgram(t, seed)
+	}
+}
+
+// FuzzInterpret fuzzes the seed of the generated programs,
+// run with go test -fuzz FuzzInterpret ./internal/ast
+func FuzzInterpret(f *testing.F) {
+	for seed := int64(0); seed < 10; seed++ {
+		f.Add(seed)
+	}
+
+	f.Fuzz(fuzzProgram)
+}
+
+// FuzzInterpretSource interprets arbitrary sources, which must never panic
+// and must report the errors they return. The file natives are disabled and
+// the statements and time a source may take are limited,
+// run with go test -fuzz FuzzInterpretSource ./internal/ast
+func FuzzInterpretSource(f *testing.F) {
+	for seed := int64(0); seed < 5; seed++ {
+		f.Add(program(seed))
+	}
+	f.Add(`var l = [1, "a", nil]; l[3] = 1; print l[0:9];`)
+	f.Add(`fun f(n) { return n < 1 ? 0 : f(n - 1); } print f(1e9);`)
+	f.Add(`var m = {1: object { a: 2 }}; print m[1].a.b;`)
+
+	f.Fuzz(func(t *testing.T, source string) {
+		var out bytes.Buffer
+		ast.SetOutput(&out)
+		ast.SetInput(strings.NewReader(""))
+		ast.SetFileNatives(false)
+		ast.SetFuel(1000)
+		ast.SetMaxCallDepth(32)
+		defer func() {
+			ast.SetOutput(os.Stdout)
+			ast.SetInput(os.Stdin)
+			ast.SetFileNatives(true)
+			ast.SetFuel(0)
+			ast.SetMaxCallDepth(ast.DefaultMaxCallDepth)
+		}()
+
+		var reported []error
+		report := func(err error) { reported = append(reported, err) }
+		tokens, _ := scan.Scan(source, report, scan.ScanContext{})
+		stmts, err := parse.Parse(tokens, report)
+		if err != nil {
+			return
+		}
+		resolution, err := resolve.Resolve(stmts, report)
+		if err != nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := ast.InterpretContext(ctx, stmts, resolution, report); err != nil && len(reported) == 0 {
+			t.Fatalf("returned %v but reported nothing", err)
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"runtime"
+	"sync"
+)
+
+// LoxGenerator is returned by calling a function containing a yield
+// statement. The body of the function does not run until the first value
+// is requested, it then runs until the next yield statement and is
+// suspended until the following value is requested. Generators are
+// iterators and can only be iterated over once, a loop or native
+// iterating over a generator which stops before it is exhausted, e.g.
+// after a break statement, closes it.
+//
+// The body runs on its own goroutine which hands control back and forth
+// with the caller, so only one of them ever runs at a time. Closing a
+// suspended generator makes its yield statement unwind the body, which
+// ends the goroutine. A generator which is dropped before it is exhausted
+// is closed once the garbage collector has found it unreachable, the next
+// time a generator is created or a script finishes.
+type LoxGenerator struct {
+	g *generator
+}
+
+// generator is the handle scripts hold, the goroutine running the body
+// only holds the coroutine so the handle can be collected while it waits
+type generator struct {
+	function  LoxFunction
+	arguments []LoxValue
+	co        *coroutine
+}
+
+type coroutine struct {
+	started bool
+	running bool
+	done    bool
+	// receives false once the generator is closed
+	resume chan bool
+	yield  chan generatorResult
+}
+
+type generatorResult struct {
+	value LoxValue
+	ok    bool
+	err   error
+}
+
+// generatorClosed unwinds the body of a generator closed while suspended
+type generatorClosed struct{}
+
+func (generatorClosed) Error() string {
+	return "generator closed"
+}
+
+// the coroutines of the generators collected before they were exhausted,
+// appended to by finalizers and closed by closeDroppedGenerators
+var (
+	droppedMu sync.Mutex
+	dropped   []*coroutine
+)
+
+func newLoxGenerator(function LoxFunction, arguments []LoxValue) LoxGenerator {
+	closeDroppedGenerators()
+
+	g := &generator{
+		function:  function,
+		arguments: arguments,
+		co: &coroutine{
+			resume: make(chan bool),
+			yield:  make(chan generatorResult),
+		},
+	}
+	runtime.SetFinalizer(g, func(g *generator) {
+		droppedMu.Lock()
+		defer droppedMu.Unlock()
+		dropped = append(dropped, g.co)
+	})
+	return LoxGenerator{g: g}
+}
+
+// closeDroppedGenerators ends the goroutines of the generators
+// which were dropped, it must be called by the interpreter
+func closeDroppedGenerators() {
+	droppedMu.Lock()
+	closing := dropped
+	dropped = nil
+	droppedMu.Unlock()
+
+	for _, co := range closing {
+		co.close()
+	}
+}
+
+func (v LoxGenerator) Type() LoxValueType {
+	return GENERATOR
+}
+
+func (v LoxGenerator) DebugPrint() string {
+	return "generator"
+}
+
+// Iterator returns the generator itself, iterating
+// over a generator consumes its values.
+func (v LoxGenerator) Iterator() Iterator {
+	return v.g
+}
+
+func isGenerator(v LoxValue) bool {
+	return v.Type() == GENERATOR
+}
+
+func AsGenerator(v LoxValue) LoxGenerator {
+	if v, ok := v.(LoxGenerator); ok {
+		return v
+	}
+	panic("Cannot convert non-generator to generator")
+}
+
+// Next runs the body of the generator until it yields the next value,
+// ok is false once the body has returned.
+func (g *generator) Next() (LoxValue, bool, error) {
+	co := g.co
+	if co.done {
+		return nil, false, nil
+	}
+
+	if co.running {
+		return nil, false, NewRuntimeError(token.Token{}, "generator is already running")
+	}
+
+	// the running generator belongs to the interpreter, swap
+	// in the body and restore the caller once it yields
+	in := g.function.Closure.interpreter()
+	callerGenerator := in.currentGenerator
+	in.currentGenerator = co
+	co.running = true
+	if !co.started {
+		co.started = true
+		go co.run(g.function, g.arguments)
+	} else {
+		co.resume <- true
+	}
+
+	result := <-co.yield
+	co.running = false
+	in.currentGenerator = callerGenerator
+
+	if !result.ok {
+		co.done = true
+	}
+
+	return result.value, result.ok, result.err
+}
+
+// Close ends the generator, later calls of Next report it as exhausted.
+// A generator cannot be closed by its own body.
+func (g *generator) Close() {
+	g.co.close()
+}
+
+func (co *coroutine) close() {
+	if co.running {
+		return
+	}
+	if co.started && !co.done {
+		co.running = true
+		co.resume <- false
+		<-co.yield
+		co.running = false
+	}
+	co.done = true
+}
+
+func (co *coroutine) run(function LoxFunction, arguments []LoxValue) {
+	_, err := function.execute(arguments)
+	if _, ok := err.(generatorClosed); ok {
+		err = nil
+	}
+	co.yield <- generatorResult{ok: false, err: err}
+}
+
+// suspend hands value to the caller of Next and blocks until the next
+// value is requested, it returns generatorClosed if the generator is
+// closed instead
+func (co *coroutine) suspend(value LoxValue) error {
+	co.yield <- generatorResult{value: value, ok: true}
+	if !<-co.resume {
+		return generatorClosed{}
+	}
+	return nil
+}
+
+// next(generator) returns the next value of generator, or nil
+// once the generator is exhausted
+var nextFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isGenerator(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "next expects a generator")
+		}
+
+		value, ok, err := AsGenerator(args[0]).g.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return LoxNil{}, nil
+		}
+
+		return value, nil
+	},
+}
@@ -0,0 +1,83 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/ast"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"exhaustion", `
+fun count(n) { for (var i = 0; i < n; i = i + 1) yield i; }
+var g = count(2);
+print next(g); print next(g); print next(g); print next(g);
+for (x in count(3)) print x;
+`, "0\n1\nnil\nnil\n0\n1\n2\n"},
+		{"early exit", `
+fun naturals() { var i = 0; while (true) { yield i; i = i + 1; } }
+var g = naturals();
+for (x in g) { if (x == 2) break; print x; }
+print next(g);
+`, "0\n1\nnil\n"},
+		{"nested", `
+fun inner(n) { yield n; yield n * 10; }
+fun outer() {
+    for (i in [1, 2]) {
+        {
+            for (x in inner(i)) if (x > 1) yield x;
+        }
+    }
+}
+print map(outer(), fun (x) { return x + 1; });
+`, "[11, 3, 21]\n"},
+	}
+
+	for _, test := range tests {
+		var out strings.Builder
+		ast.SetOutput(&out)
+		if err := interpret(t, test.source); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if out.String() != test.want {
+			t.Errorf("%s: expected %q but got %q", test.name, test.want, out.String())
+		}
+	}
+	ast.SetOutput(os.Stdout)
+}
+
+// TestGeneratorGoroutines checks that generators stopped early by a
+// loop or dropped before they are exhausted do not leak their goroutines
+func TestGeneratorGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	err := interpret(t, `
+fun naturals() { var i = 0; while (true) { yield i; i = i + 1; } }
+for (var i = 0; i < 20; i = i + 1) {
+    for (x in naturals()) if (x == 3) break;
+    next(naturals());
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the dropped generators are closed once they have been
+	// collected, when a generator is created or a script finishes
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+		if err := interpret(t, `var reaped = true;`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected the generators to end their goroutines but %d are left", after-before)
+	}
+}
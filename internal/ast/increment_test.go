@@ -0,0 +1,48 @@
+package ast_test
+
+import "testing"
+
+func TestIncrementAndDecrement(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+		// a substring of the runtime error, empty if the source runs
+		err string
+	}{
+		{
+			name:   "prefix yields the new value, postfix the old one",
+			source: `var i = 1; print i++; print i; print ++i; print i--; print --i;`,
+			want:   "1\n2\n3\n3\n1\n",
+		},
+		{
+			name:   "elements and fields",
+			source: `var l = [1]; l[0]++; print l; print l[0]--; print l; var m = {"a": 1}; ++m["a"]; print m; var o = object { a: 1 }; o.a++; print o.a;`,
+			want:   "[2]\n2\n[1]\n{\"a\": 2}\n2\n",
+		},
+		{
+			name:   "locals and captured variables",
+			source: `fun counter() { var n = 0; fun next() { return ++n; } return next; } var c = counter(); c(); print c();`,
+			want:   "2\n",
+		},
+		{
+			name:   "string operand",
+			source: `var s = "a"; s++;`,
+			err:    "operand must be a number",
+		},
+		{
+			name:   "nil operand",
+			source: `var x = nil; --x;`,
+			err:    "operand must be a number",
+		},
+		{
+			name:   "missing key",
+			source: `var m = {}; m["a"]++;`,
+			err:    "undefined map key",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, test.err)
+	}
+}
@@ -1,26 +1,199 @@
 package ast
 
 import (
+	"bufio"
+	"context"
 	"errors"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"maps"
+	"math/rand"
+	"os"
+	"sync"
 	"time"
 )
 
-// the global environment
-var global_env = NewEnvironment(nil)
+// Interpreter holds the state of the scripts it interprets: the global
+// environment, the call stack and the settings and counters of the
+// interpreter. Every environment knows the interpreter it belongs to, so
+// the code evaluated in it and the natives defined in it use the state of
+// that interpreter. Interpreters are independent of each other and may
+// be used by different goroutines, but a single interpreter must only be
+// used by one goroutine at a time.
+//
+// The package level functions, such as Interpret and SetFuel, use the
+// default interpreter, which is the one of the lox command.
+type Interpreter struct {
+	globals *Environment
+	// checked between statements, once it is done the running
+	// script stops with a runtime error
+	interrupt context.Context
+	// the writer print statements and natives write to
+	output io.Writer
+	// the writer the errors of the scripts are reported to
+	errOutput io.Writer
+	dialect token.Dialect
+	// the source of the random natives
+	random *rand.Rand
+	// when set, conditions must be booleans instead of treating
+	// nil and false as falsy and everything else as truthy
+	strictBool bool
+	// the number of results remembered per function, zero
+	// disables memoization
+	memoSize int
 
-// the current environment (used for block scopes) we 
-// operate in, starts as the global environment but may be
-// reassigned by block scopes
-var current_env = global_env
+	// the calls to Lox functions currently being evaluated, the most
+	// recent call last. Native functions do not get a frame.
+	callStack    []callFrame
+	maxCallDepth int
+	// the coroutine of the generator whose body is currently
+	// running, nil outside of generators
+	currentGenerator *coroutine
 
+	// the number of statements a script may evaluate, zero if unlimited
+	fuel int
+	// the number of statements the running script may still evaluate
+	fuelLeft int
 
+	// zero if the number of iterations is unlimited
+	maxIterations int
+	countLoops    bool
+	// loop counts keyed by the position of the token of their loop
+	loopCounts map[sourcePos]*LoopCount
+
+	// zero if the watchdog is disabled
+	slowThreshold time.Duration
+	// slow statements keyed by the position of their token
+	slowStatements map[sourcePos]*SlowStatement
+
+	debug debugState
+
+	// the reader readLine reads from
+	input        *bufio.Reader
+	filesEnabled bool
+	mathEnabled  bool
+	normalized   bool
+
+	// the registered natives and modules
+	natives       map[string]registeredNative
+	nativeModules map[string]*nativeModule
+
+	// the arguments passed to the script
+	arguments []string
+	// the list defined as ARGS by the last defineArguments
+	definedArguments LoxList
+	// the objects defined for the modules by the last defineModules
+	definedModules map[string]LoxObject
+
+	// nil if imports are not supported
+	moduleLoader ModuleLoader
+	// the directory imports of the main script are relative to
+	moduleDir string
+	// the imported modules keyed by their absolute path
+	modules map[string]*module
+	// the paths of the modules being run, the innermost last
+	importStack []string
+
+	record recordState
+
+	topicsMu sync.Mutex
+	topics   map[string]chan LoxValue
+}
+
+// NewInterpreter returns an interpreter with an empty global environment,
+// the default settings and the standard natives registered, the natives
+// are defined once it interprets a script.
+func NewInterpreter() *Interpreter {
+	in := &Interpreter{
+		interrupt:      context.Background(),
+		output:         os.Stdout,
+		errOutput:      os.Stderr,
+		random:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		dialect:        token.BOOK,
+		maxCallDepth:   DefaultMaxCallDepth,
+		loopCounts:     make(map[sourcePos]*LoopCount),
+		slowStatements: make(map[sourcePos]*SlowStatement),
+		debug:          debugState{breakpoints: make(map[int]bool)},
+		input:          bufio.NewReader(os.Stdin),
+		filesEnabled:   true,
+		mathEnabled:    true,
+		natives:        maps.Clone(standardNatives),
+		nativeModules:  maps.Clone(standardModules),
+		definedModules: map[string]LoxObject{},
+		moduleDir:      ".",
+		modules:        map[string]*module{},
+		topics:         make(map[string]chan LoxValue),
+	}
+	in.globals = NewEnvironment(nil)
+	in.globals.interp = in
+	return in
+}
+
+// the interpreter used by the package level functions, created
+// once the standard natives are registered
+var defaultInterpreter *Interpreter
+
+// clock() returns the seconds since the Unix epoch
 var clockFunc = NativeFunction{
 	paramLen: 0,
+	external: true,
 	Function: func(_ []LoxValue) (LoxValue, error) {
 		return LoxNumber(float64(time.Now().UnixNano()) / 1e9), nil
 	},
 }
 
+// SetOutput sets the output of the default interpreter, see
+// Interpreter.SetOutput.
+func SetOutput(w io.Writer) {
+	defaultInterpreter.SetOutput(w)
+}
+
+// Output returns the output of the default interpreter.
+func Output() io.Writer {
+	return defaultInterpreter.Output()
+}
+
+// SetOutput sets the writer print statements and natives
+// write to, stdout by default.
+func (in *Interpreter) SetOutput(w io.Writer) {
+	in.output = w
+}
+
+// Output returns the writer print statements and natives write to.
+func (in *Interpreter) Output() io.Writer {
+	return in.output
+}
+
+// SetErrorOutput sets the error output of the default interpreter, see
+// Interpreter.SetErrorOutput.
+func SetErrorOutput(w io.Writer) {
+	defaultInterpreter.SetErrorOutput(w)
+}
+
+// ErrorOutput returns the error output of the default interpreter.
+func ErrorOutput() io.Writer {
+	return defaultInterpreter.ErrorOutput()
+}
+
+// SetErrorOutput sets the writer the host reports the errors of the
+// scripts to, stderr by default. The interpreter does not write to it
+// itself, runtime errors are handed to the report function of Interpret.
+func (in *Interpreter) SetErrorOutput(w io.Writer) {
+	in.errOutput = w
+}
+
+// ErrorOutput returns the writer set with SetErrorOutput.
+func (in *Interpreter) ErrorOutput() io.Writer {
+	return in.errOutput
+}
+
+// SetDialect sets the dialect of the scripts being interpreted,
+// which decides the natives available to them.
+func SetDialect(d token.Dialect) {
+	defaultInterpreter.dialect = d
+}
+
+// type(value) returns the type of value
 var typeFunc = NativeFunction{
 	paramLen: 1,
 	Function: func(args []LoxValue) (LoxValue, error) {
@@ -28,17 +201,25 @@ var typeFunc = NativeFunction{
 	},
 }
 
-func addNativeFunction(name string, f NativeFunction) {
-	global_env.Define(name, f)
+// DefineGlobal defines a global variable visible to the scripts
+// interpreted afterwards by the default interpreter.
+func DefineGlobal(name string, value LoxValue) {
+	defaultInterpreter.DefineGlobal(name, value)
 }
 
-func executeBlock(statements []Stmt, env *Environment) error {
-    previous := current_env
-    current_env = env
-    defer func() { current_env = previous }()
+// DefineGlobal defines a global variable visible to the scripts
+// interpreted afterwards, e.g. to hand them foreign values.
+func (in *Interpreter) DefineGlobal(name string, value LoxValue) {
+	in.globals.Define(name, value)
+}
 
+func executeBlock(statements []Stmt, env *Environment) error {
     for _, stmt := range statements {
-        if err := stmt.Evaluate(); err != nil {
+        if err := env.interpreter().checkInterrupt(); err != nil {
+            return withToken(err, StmtToken(stmt))
+        }
+
+        if err := evaluateStmt(stmt, env); err != nil {
             return err
         }
     }
@@ -46,25 +227,156 @@ func executeBlock(statements []Stmt, env *Environment) error {
     return nil
 }
 
-func Interpret(statements []Stmt, report func(error)) error {
-	addNativeFunction("type", typeFunc)
-	addNativeFunction("clock", clockFunc)
-	global_env.Define("str", LoxType{Typ: STRING})
-	global_env.Define("num", LoxType{Typ: NUMBER})
-	global_env.Define("func", LoxType{Typ: FUNCTION})
-	global_env.Define("bool", LoxType{Typ: BOOLEAN})
+// Interpret interprets statements with the default interpreter, see
+// Interpreter.Interpret.
+func Interpret(statements []Stmt, resolution *Resolution, report func(error)) error {
+	return defaultInterpreter.Interpret(statements, resolution, report)
+}
+
+// Interpret interprets statements in the global environment, reporting
+// every runtime error to report. The locals of the statements are bound
+// by resolution, which is nil if they were not resolved.
+func (in *Interpreter) Interpret(statements []Stmt, resolution *Resolution, report func(error)) error {
+	in.defineGlobals()
+	return in.interpret(statements, resolution, report)
+}
 
+func (in *Interpreter) interpret(statements []Stmt, resolution *Resolution, report func(error)) error {
+	defer in.bindGlobals(resolution)()
+	in.refuel()
 	var errorHasOccured = false
 	for _, stmt := range statements {
-		if err := stmt.Evaluate(); err != nil {
+		if err := in.checkInterrupt(); err != nil {
+			report(err)
+			return err
+		}
+
+		if err := evaluateStmt(stmt, in.globals); err != nil {
 			report(err)
+			// the following statements would exceed the limit as well
+			if _, ok := err.(LimitError); ok {
+				return err
+			}
 			errorHasOccured = true
 		}
 	}
 
+	closeDroppedGenerators()
 	if errorHasOccured {
 		return errors.New("")
 	}
 
 	return nil
 }
+
+// InterpretExpr evaluates expr with the default interpreter, see
+// Interpreter.InterpretExpr.
+func InterpretExpr(expr Expr, resolution *Resolution) (LoxValue, error) {
+	return defaultInterpreter.InterpretExpr(expr, resolution)
+}
+
+// InterpretExpr evaluates expr in the global environment, its locals
+// are bound by resolution like those of the statements of Interpret.
+func (in *Interpreter) InterpretExpr(expr Expr, resolution *Resolution) (LoxValue, error) {
+	in.defineGlobals()
+	defer in.bindGlobals(resolution)()
+	in.refuel()
+	return expr.Evaluate(in.globals)
+}
+
+// bindGlobals makes resolution bind the locals of the code evaluated in
+// the global environment until the returned function restores the
+// previous one. Functions keep the resolution they are declared with.
+func (in *Interpreter) bindGlobals(resolution *Resolution) func() {
+	previous := in.globals.resolution
+	in.globals.resolution = resolution
+	return func() { in.globals.resolution = previous }
+}
+
+// the types defined in the global environment
+var globalTypes = map[string]LoxType{
+	"str":  {Typ: STRING},
+	"num":  {Typ: NUMBER},
+	"func": {Typ: FUNCTION},
+	"bool": {Typ: BOOLEAN},
+}
+
+// Session interprets a sequence of inputs sharing the globals they define,
+// such as the inputs of a REPL. Unlike Interpret, which defines the natives
+// anew for every script, the natives are only defined for the first input,
+// so a native redefined by one input stays redefined for the next.
+type Session struct {
+	in      *Interpreter
+	started bool
+}
+
+// NewSession returns a session of the default interpreter.
+func NewSession() *Session {
+	return defaultInterpreter.NewSession()
+}
+
+// NewSession returns a session interpreting its inputs with in.
+func (in *Interpreter) NewSession() *Session {
+	return &Session{in: in}
+}
+
+func (s *Session) start() {
+	if !s.started {
+		s.in.defineGlobals()
+		s.started = true
+	}
+}
+
+// Interpret interprets the statements of the next input, the globals
+// defined before a runtime error stay defined.
+func (s *Session) Interpret(statements []Stmt, resolution *Resolution, report func(error)) error {
+	s.start()
+	return s.in.interpret(statements, resolution, report)
+}
+
+// InterpretExpr evaluates the expression of the next input.
+func (s *Session) InterpretExpr(expr Expr, resolution *Resolution) (LoxValue, error) {
+	s.start()
+	defer s.in.bindGlobals(resolution)()
+	s.in.refuel()
+	return expr.Evaluate(s.in.globals)
+}
+
+// defineGlobals defines the natives and types in the global environment
+func (in *Interpreter) defineGlobals() {
+	in.defineNatives()
+	for name, typ := range globalTypes {
+		in.globals.Define(name, typ)
+	}
+}
+
+// GlobalEnvironment returns the environment the globals of the
+// scripts interpreted by the default interpreter are defined in.
+func GlobalEnvironment() *Environment {
+	return defaultInterpreter.globals
+}
+
+// IsBuiltin reports whether the global name of the default interpreter
+// is bound to the native or type the interpreter defines, rather than a
+// value a script defined.
+func IsBuiltin(name string) bool {
+	return defaultInterpreter.isBuiltin(name)
+}
+
+func (in *Interpreter) isBuiltin(name string) bool {
+	value, _ := in.globals.Lookup(name)
+	switch value := value.(type) {
+	case NativeFunction:
+		_, registered := in.natives[name]
+		return registered && value.name == name
+	case LoxType:
+		typ, ok := globalTypes[name]
+		return ok && typ == value
+	case LoxObject:
+		module, ok := in.definedModules[name]
+		return ok && module == value
+	case LoxList:
+		return name == "ARGS" && value == in.definedArguments
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package ast
+
+import (
+	"bufio"
+	"errors"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"os"
+	"strings"
+)
+
+// SetInput sets the input of the default interpreter, see
+// Interpreter.SetInput.
+func SetInput(r io.Reader) {
+	defaultInterpreter.SetInput(r)
+}
+
+// SetInput sets the reader readLine reads from, stdin by default.
+// Readers which are already a *bufio.Reader are used as they are.
+func (in *Interpreter) SetInput(r io.Reader) {
+	in.input = bufio.NewReader(r)
+}
+
+// SetFileNatives decides whether the default interpreter defines the
+// natives touching the file system, see Interpreter.SetFileNatives.
+func SetFileNatives(enabled bool) {
+	defaultInterpreter.SetFileNatives(enabled)
+}
+
+// SetFileNatives decides whether the natives touching the file system are
+// defined for the scripts interpreted afterwards, they are by default.
+func (in *Interpreter) SetFileNatives(enabled bool) {
+	in.filesEnabled = enabled
+}
+
+// ioError turns the error of an I/O operation into a runtime error
+func (in *Interpreter) ioError(err error) error {
+	return NewRuntimeError(token.Token{}, in.osError(err))
+}
+
+// readLine() returns the next line of input without the line
+// terminator, nil once the input is exhausted
+var readLineFunc = NativeFunction{
+	paramLen: 0,
+	external: true,
+	functionIn: func(in *Interpreter, _ token.Token, _ []LoxValue) (LoxValue, error) {
+		line, err := in.input.ReadString('\n')
+		if errors.Is(err, io.EOF) && line == "" {
+			return LoxNil{}, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, in.ioError(err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		return LoxString(strings.TrimSuffix(line, "\r")), nil
+	},
+}
+
+// readFile(path) returns the contents of the file at path
+var readFileFunc = NativeFunction{
+	paramLen: 1,
+	external: true,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		if !isString(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "readFile expects a string path")
+		}
+
+		contents, err := os.ReadFile(AsString(args[0]))
+		if err != nil {
+			return nil, in.ioError(err)
+		}
+		return LoxString(contents), nil
+	},
+}
+
+// writeFile(path, contents) replaces the contents of the
+// file at path, creating it if it does not exist
+var writeFileFunc = NativeFunction{
+	paramLen: 2,
+	external: true,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		return in.writeFile("writeFile", args, os.O_TRUNC)
+	},
+}
+
+// appendFile(path, contents) appends contents to the file
+// at path, creating it if it does not exist
+var appendFileFunc = NativeFunction{
+	paramLen: 2,
+	external: true,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		return in.writeFile("appendFile", args, os.O_APPEND)
+	},
+}
+
+func (in *Interpreter) writeFile(name string, args []LoxValue, flag int) (LoxValue, error) {
+	if !isString(args[0]) || !isString(args[1]) {
+		return nil, NewRuntimeError(token.Token{}, name+" expects a string path and contents")
+	}
+
+	file, err := os.OpenFile(AsString(args[0]), os.O_WRONLY|os.O_CREATE|flag, 0o644)
+	if err != nil {
+		return nil, in.ioError(err)
+	}
+
+	_, err = file.WriteString(AsString(args[1]))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, in.ioError(err)
+	}
+	return LoxNil{}, nil
+}
@@ -0,0 +1,218 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+// Iterable is implemented by values which can be iterated over, both
+// by the for-in statement and natives such as map and filter. New
+// iterable types only have to implement Iterable to work with all of them.
+type Iterable interface {
+	Iterator() Iterator
+}
+
+// Iterator yields the elements of an iterable value one at a time.
+type Iterator interface {
+	// Next returns the next element, ok is false once
+	// the iterator is exhausted
+	Next() (value LoxValue, ok bool, err error)
+}
+
+// sliceIterator iterates over the elements of a slice
+type sliceIterator struct {
+	elements []LoxValue
+	next     int
+}
+
+func (it *sliceIterator) Next() (LoxValue, bool, error) {
+	if it.next >= len(it.elements) {
+		return nil, false, nil
+	}
+
+	it.next++
+	return it.elements[it.next-1], true, nil
+}
+
+// listIterator iterates over a list by index, so elements
+// appended during iteration are visited as well
+type listIterator struct {
+	list LoxList
+	next int
+}
+
+func (it *listIterator) Next() (LoxValue, bool, error) {
+	if it.next >= it.list.Len() {
+		return nil, false, nil
+	}
+
+	it.next++
+	return it.list.Elements()[it.next-1], true, nil
+}
+
+// Iterator iterates over the elements of the list.
+func (v LoxList) Iterator() Iterator {
+	return &listIterator{list: v}
+}
+
+// Iterator iterates over the characters of the string.
+func (v LoxString) Iterator() Iterator {
+	runes := []rune(string(v))
+	characters := make([]LoxValue, len(runes))
+	for i, r := range runes {
+		characters[i] = LoxString(r)
+	}
+	return &sliceIterator{elements: characters}
+}
+
+// Iterator iterates over a snapshot of the keys of the map.
+func (v LoxMap) Iterator() Iterator {
+	return &sliceIterator{elements: v.Keys()}
+}
+
+// Iterator iterates over a snapshot of the members of the set.
+func (v LoxSet) Iterator() Iterator {
+	return &sliceIterator{elements: v.Members()}
+}
+
+// iterate calls f with every element of v in order until either the
+// elements are exhausted or f returns an error
+func iterate(v LoxValue, f func(LoxValue) error) error {
+	iterable, ok := v.(Iterable)
+	if !ok {
+		return NewRuntimeError(token.Token{}, "value is not iterable")
+	}
+
+	it := iterable.Iterator()
+	for {
+		value, ok, err := it.Next()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+
+		if err := f(value); err != nil {
+			// stopping early closes iterators which hold
+			// resources, such as the goroutine of a generator
+			if closer, ok := it.(interface{ Close() }); ok {
+				closer.Close()
+			}
+			return err
+		}
+	}
+}
+
+// asCallback returns v as a function taking arity arguments
+func asCallback(v LoxValue, arity int, native string) (Callable, error) {
+	function, ok := v.(Callable)
+	if !ok || function.Arity() != arity {
+		return nil, NewRuntimeError(token.Token{},
+			fmt.Sprintf("%s expects a function taking %d argument(s)", native, arity))
+	}
+
+	return function, nil
+}
+
+// forEach(iterable, fn) calls fn with every element of iterable
+var forEachFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		fn, err := asCallback(args[1], 1, "forEach")
+		if err != nil {
+			return nil, err
+		}
+
+		err = iterate(args[0], func(v LoxValue) error {
+			_, err := fn.Call([]LoxValue{v})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return LoxNil{}, nil
+	},
+}
+
+// map(iterable, fn) returns a list of the results of
+// calling fn with every element of iterable
+var mapFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		fn, err := asCallback(args[1], 1, "map")
+		if err != nil {
+			return nil, err
+		}
+
+		results := []LoxValue{}
+		err = iterate(args[0], func(v LoxValue) error {
+			result, err := fn.Call([]LoxValue{v})
+			results = append(results, result)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return NewLoxList(results), nil
+	},
+}
+
+// filter(iterable, fn) returns a list of the elements
+// of iterable for which fn returns a truthy value
+var filterFunc = NativeFunction{
+	paramLen: 2,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		fn, err := asCallback(args[1], 1, "filter")
+		if err != nil {
+			return nil, err
+		}
+
+		results := []LoxValue{}
+		err = iterate(args[0], func(v LoxValue) error {
+			keep, err := fn.Call([]LoxValue{v})
+			if err != nil {
+				return err
+			}
+
+			if ok, err := in.truthy(keep, token.Token{}); err != nil {
+				return err
+			} else if ok {
+				results = append(results, v)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return NewLoxList(results), nil
+	},
+}
+
+// join(iterable, sep) returns the string representations
+// of the elements of iterable separated by sep
+var joinFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isString(args[1]) {
+			return nil, NewRuntimeError(token.Token{}, "join expects a string separator")
+		}
+
+		parts := []string{}
+		err := iterate(args[0], func(v LoxValue) error {
+			str, err := valueToString(v)
+			parts = append(parts, str)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return LoxString(strings.Join(parts, AsString(args[1]))), nil
+	},
+}
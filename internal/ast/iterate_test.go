@@ -0,0 +1,53 @@
+package ast_test
+
+import "testing"
+
+func TestIteration(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+		// a substring of the runtime error, empty if the source runs
+		err string
+	}{
+		{
+			name:   "for-in over every iterable",
+			source: `for (x in [1, 2]) print x; for (c in "hé") print c; for (k in {"a": 1, "b": 2}) print k; for (x in set([3, 3])) print x;`,
+			want:   "1\n2\nh\né\na\nb\n3\n",
+		},
+		{
+			name:   "for-in over a generator",
+			source: `fun g() { yield 1; yield 2; } for (x in g()) print x;`,
+			want:   "1\n2\n",
+		},
+		{
+			name:   "natives consume every iterable",
+			source: `fun g() { yield 1; yield 2; } print map(g(), fun(x) { return x * 2; }); print filter(set([1, 2, 3]), fun(x) { return x > 1; }); print join("abc", "-"); print sort(set([3, 1, 2])); forEach({"k": 1}, fun(k) { print k; });`,
+			want:   "[2, 4]\n[2, 3]\na-b-c\n[1, 2, 3]\nk\n",
+		},
+		{
+			name:   "elements pushed while iterating are visited",
+			source: `var l = [1, 2]; for (x in l) { if (x == 1) push(l, 3); print x; }`,
+			want:   "1\n2\n3\n",
+		},
+		{
+			name:   "empty iterables",
+			source: `for (x in []) print x; for (x in "") print x; print map({}, fun(x) { return x; });`,
+			want:   "[]\n",
+		},
+		{
+			name:   "for-in over a number",
+			source: `for (x in 1) print x;`,
+			err:    "value is not iterable",
+		},
+		{
+			name:   "native over nil",
+			source: `map(nil, fun(x) { return x; });`,
+			err:    "value is not iterable",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, test.err)
+	}
+}
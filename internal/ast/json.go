@@ -0,0 +1,250 @@
+package ast
+
+import (
+	"encoding/json"
+	"github.com/LucazFFz/lox/internal/token"
+)
+
+// MarshalJSON serializes a parsed program as an indented JSON array of
+// statements, so tools can consume the AST without parsing Lox themselves.
+// Every node is an object with a "type" (the name of the node, e.g.
+// "BinaryExpr"), the "line" and "offset" of the node when known, and one
+// member per field of the node. Operators and names are serialized as
+// their lexmes, omitted children as null.
+func MarshalJSON(statements []Stmt) ([]byte, error) {
+	nodes := make([]map[string]any, len(statements))
+	for i, stmt := range statements {
+		nodes[i] = stmtNode(stmt)
+	}
+
+	return json.MarshalIndent(nodes, "", "  ")
+}
+
+// node creates the object for a node of type typ located at tok
+func node(typ string, tok token.Token, fields map[string]any) map[string]any {
+	fields["type"] = typ
+	if tok.Line != 0 {
+		fields["line"] = tok.Line
+		fields["offset"] = tok.Offset
+	}
+	return fields
+}
+
+// labeled adds the label of a loop, break or continue
+// statement to fields, if it has one
+func labeled(label token.Token, fields map[string]any) map[string]any {
+	if label.Lexme != "" {
+		fields["label"] = label.Lexme
+	}
+	return fields
+}
+
+// annotated adds the annotated type of a variable to fields, if it has one
+func annotated(typ token.Token, fields map[string]any) map[string]any {
+	if typ.Lexme != "" {
+		fields["type"] = typ.Lexme
+	}
+	return fields
+}
+
+// typed adds the annotated types of the parameters and return
+// value of a function to fields, if it has any
+func typed(types []token.Token, returns token.Token, fields map[string]any) map[string]any {
+	if types != nil {
+		fields["parameterTypes"] = names(types)
+	}
+	if returns.Lexme != "" {
+		fields["returnType"] = returns.Lexme
+	}
+	return fields
+}
+
+func names(tokens []token.Token) []string {
+	lexmes := make([]string, len(tokens))
+	for i, tok := range tokens {
+		lexmes[i] = tok.Lexme
+	}
+	return lexmes
+}
+
+func stmtNodes(statements []Stmt) []map[string]any {
+	nodes := make([]map[string]any, len(statements))
+	for i, stmt := range statements {
+		nodes[i] = stmtNode(stmt)
+	}
+	return nodes
+}
+
+func exprNodes(exprs []Expr) []map[string]any {
+	nodes := make([]map[string]any, len(exprs))
+	for i, expr := range exprs {
+		nodes[i] = exprNode(expr)
+	}
+	return nodes
+}
+
+func stmtNode(s Stmt) map[string]any {
+	if s == nil {
+		return nil
+	}
+
+	tok := StmtToken(s)
+	switch s := s.(type) {
+	case ExpressionStmt:
+		return node("ExpressionStmt", tok, map[string]any{"expr": exprNode(s.Expr)})
+	case PrintStmt:
+		return node("PrintStmt", tok, map[string]any{"expr": exprNode(s.Expr)})
+	case VarStmt:
+		return node("VarStmt", tok, annotated(s.Type, map[string]any{
+			"name":        s.Name.Lexme,
+			"initializer": exprNode(s.Initializer)}))
+	case BlockStmt:
+		return node("BlockStmt", tok, map[string]any{"statements": stmtNodes(s.Statements)})
+	case IfStmt:
+		return node("IfStmt", tok, map[string]any{
+			"condition":  exprNode(s.Condition),
+			"thenBranch": stmtNode(s.ThenBranch),
+			"elseBranch": stmtNode(s.ElseBranch)})
+	case WhileStmt:
+		return node("WhileStmt", tok, labeled(s.Label, map[string]any{
+			"condition": exprNode(s.Condition),
+			"body":      stmtNode(s.Body),
+			"increment": exprNode(s.Increment),
+			"doWhile":   s.Keyword.Type == token.DO}))
+	case ForInStmt:
+		return node("ForInStmt", tok, labeled(s.Label, map[string]any{
+			"name":     s.Name.Lexme,
+			"iterable": exprNode(s.Iterable),
+			"body":     stmtNode(s.Body)}))
+	case BreakStmt:
+		return node("BreakStmt", tok, labeled(s.Label, map[string]any{}))
+	case ContinueStmt:
+		return node("ContinueStmt", tok, labeled(s.Label, map[string]any{}))
+	case ReturnStmt:
+		return node("ReturnStmt", tok, map[string]any{"expr": exprNode(s.Expr)})
+	case AssertStmt:
+		return node("AssertStmt", tok, map[string]any{
+			"condition": exprNode(s.Condition),
+			"message":   exprNode(s.Message)})
+	case ImportStmt:
+		return node("ImportStmt", tok, map[string]any{"path": s.Path.Lexme, "named": s.Path.Type == token.IDENTIFIER})
+	case YieldStmt:
+		return node("YieldStmt", tok, map[string]any{"expr": exprNode(s.Expr)})
+	case FunctionStmt:
+		return node("FunctionStmt", tok, typed(s.ParameterTypes, s.ReturnType, map[string]any{
+			"name":        s.Name.Lexme,
+			"parameters":  names(s.Parameters),
+			"body":        stmtNodes(s.Body),
+			"isGenerator": s.IsGenerator}))
+	}
+
+	panic("should never reach here (unknown statement)")
+}
+
+func exprNode(e Expr) map[string]any {
+	if e == nil {
+		return nil
+	}
+
+	tok := ExprToken(e)
+	switch e := e.(type) {
+	case BinaryExpr:
+		return node("BinaryExpr", tok, map[string]any{
+			"op":    e.Op.Lexme,
+			"left":  exprNode(e.Left),
+			"right": exprNode(e.Right)})
+	case GroupingExpr:
+		return node("GroupingExpr", tok, map[string]any{"expr": exprNode(e.Expr)})
+	case LiteralExpr:
+		return node("LiteralExpr", tok, map[string]any{"value": literalValue(e.Value)})
+	case VariableExpr:
+		return node("VariableExpr", tok, map[string]any{"name": e.Name.Lexme})
+	case UnaryExpr:
+		return node("UnaryExpr", tok, map[string]any{
+			"op":    e.Op.Lexme,
+			"right": exprNode(e.Right)})
+	case PrefixExpr:
+		return node("PrefixExpr", tok, map[string]any{
+			"op":     e.Op.Lexme,
+			"target": exprNode(e.Target)})
+	case PostfixExpr:
+		return node("PostfixExpr", tok, map[string]any{
+			"op":     e.Op.Lexme,
+			"target": exprNode(e.Target)})
+	case TernaryExpr:
+		return node("TernaryExpr", tok, map[string]any{
+			"condition": exprNode(e.Condition),
+			"left":      exprNode(e.Left),
+			"right":     exprNode(e.Right)})
+	case AssignExpr:
+		return node("AssignExpr", tok, map[string]any{
+			"name":  e.Name.Lexme,
+			"value": exprNode(e.Value)})
+	case ListExpr:
+		return node("ListExpr", tok, map[string]any{"elements": exprNodes(e.Elements)})
+	case MapExpr:
+		return node("MapExpr", tok, map[string]any{
+			"keys":   exprNodes(e.Keys),
+			"values": exprNodes(e.Values)})
+	case ObjectExpr:
+		return node("ObjectExpr", tok, map[string]any{
+			"names":  names(e.Names),
+			"values": exprNodes(e.Values)})
+	case IndexExpr:
+		return node("IndexExpr", tok, map[string]any{
+			"object": exprNode(e.Object),
+			"index":  exprNode(e.Index)})
+	case SliceExpr:
+		return node("SliceExpr", tok, map[string]any{
+			"object": exprNode(e.Object),
+			"start":  exprNode(e.Start),
+			"end":    exprNode(e.End)})
+	case IndexAssignExpr:
+		return node("IndexAssignExpr", tok, map[string]any{
+			"object": exprNode(e.Object),
+			"index":  exprNode(e.Index),
+			"value":  exprNode(e.Value)})
+	case SliceAssignExpr:
+		return node("SliceAssignExpr", tok, map[string]any{
+			"object": exprNode(e.Object),
+			"start":  exprNode(e.Start),
+			"end":    exprNode(e.End),
+			"value":  exprNode(e.Value)})
+	case GetExpr:
+		return node("GetExpr", tok, map[string]any{
+			"object": exprNode(e.Object),
+			"name":   e.Name.Lexme})
+	case SetExpr:
+		return node("SetExpr", tok, map[string]any{
+			"object": exprNode(e.Object),
+			"name":   e.Name.Lexme,
+			"value":  exprNode(e.Value)})
+	case FunctionExpr:
+		return node("FunctionExpr", tok, typed(e.ParameterTypes, e.ReturnType, map[string]any{
+			"parameters":  names(e.Parameters),
+			"body":        stmtNodes(e.Body),
+			"isGenerator": e.IsGenerator}))
+	case CallExpr:
+		return node("CallExpr", tok, map[string]any{
+			"callee":    exprNode(e.Callee),
+			"arguments": exprNodes(e.Arguments)})
+	case NothingExpr:
+		return node("NothingExpr", tok, map[string]any{})
+	}
+
+	panic("should never reach here (unknown expression)")
+}
+
+// literalValue converts the value of a literal to its JSON equivalent
+func literalValue(v LoxValue) any {
+	switch v := v.(type) {
+	case LoxNumber:
+		return float64(v)
+	case LoxString:
+		return string(v)
+	case LoxBoolean:
+		return bool(v)
+	}
+
+	return nil
+}
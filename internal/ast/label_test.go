@@ -0,0 +1,58 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/resolve"
+	"strings"
+	"testing"
+)
+
+func TestLabeledLoops(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "continue the outer for",
+			source: `outer: for (var i = 0; i < 3; i = i + 1) { for (var j = 0; j < 3; j = j + 1) { if (j == 1) continue outer; print str(i) + str(j); } }`,
+			want:   "00\n10\n20\n",
+		},
+		{
+			name:   "break the outer while",
+			source: `a: while (true) { while (true) { break a; } print "unreachable"; } print "out";`,
+			want:   "out\n",
+		},
+		{
+			name:   "continue the outer for-in",
+			source: `a: for (x in [1, 2]) { for (y in [1, 2]) { if (y == 2) continue a; print str(x) + str(y); } }`,
+			want:   "11\n21\n",
+		},
+		{
+			name:   "continue a labeled do-while checks the condition",
+			source: `var i = 0; a: do { i = i + 1; if (i < 3) continue a; print i; } while (i < 3);`,
+			want:   "3\n",
+		},
+		{
+			name:   "an unlabeled break leaves the innermost loop",
+			source: `a: for (var i = 0; i < 2; i = i + 1) { while (true) break; print i; }`,
+			want:   "0\n1\n",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, "")
+	}
+}
+
+func TestUnknownLabel(t *testing.T) {
+	for _, source := range []string{
+		`for (;;) { continue nope; }`,
+		`while (true) { break nope; }`,
+	} {
+		var errs []error
+		resolve.Resolve(parseScript(t, source), func(err error) { errs = append(errs, err) })
+		if len(errs) == 0 || !strings.Contains(errs[0].Error(), "no enclosing loop labeled") {
+			t.Errorf("%s: expected the label to be rejected but got %v", source, errs)
+		}
+	}
+}
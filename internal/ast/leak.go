@@ -0,0 +1,95 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Leak detection is a diagnostic mode used to validate that environments
+// and functions are released once they are no longer reachable (e.g. when
+// reworking closure capture). When enabled every Environment and LoxFunction
+// is counted on creation and a finalizer decrements the live count once the
+// garbage collector has reclaimed it.
+//
+// Tracking adds a finalizer to every allocation and should never be enabled
+// outside of debugging.
+
+var trackAllocations = false
+
+type allocCounter struct {
+	allocated atomic.Int64
+	live      atomic.Int64
+}
+
+var (
+	environmentAllocs allocCounter
+	functionAllocs    allocCounter
+)
+
+// allocation is a marker attached to every tracked value. The finalizer is
+// set on the marker rather than the value itself since environments and
+// closures reference each other, and cycles containing a finalizer are
+// never collected.
+type allocation struct {
+	_ byte
+}
+
+// TrackAllocations enables or disables leak detection. Only
+// allocations made while tracking is enabled are counted.
+func TrackAllocations(enable bool) {
+	trackAllocations = enable
+}
+
+func (c *allocCounter) track() *allocation {
+	if !trackAllocations {
+		return nil
+	}
+
+	c.allocated.Add(1)
+	c.live.Add(1)
+	a := &allocation{}
+	runtime.SetFinalizer(a, func(*allocation) { c.live.Add(-1) })
+	return a
+}
+
+func trackEnvironment() *allocation {
+	return environmentAllocs.track()
+}
+
+func trackFunction() *allocation {
+	return functionAllocs.track()
+}
+
+// ReportLeaks forces garbage collection and writes the number of
+// environments and functions still alive to w. The global environment
+// and everything reachable from it is expected to be alive, anything
+// beyond that after a script finishes is most likely a leak.
+func ReportLeaks(w io.Writer) {
+	collectGarbage()
+
+	fmt.Fprintln(w, "allocation report:")
+	fmt.Fprintf(w, "  environments: %d allocated, %d live\n",
+		environmentAllocs.allocated.Load(), environmentAllocs.live.Load())
+	fmt.Fprintf(w, "  functions:    %d allocated, %d live\n",
+		functionAllocs.allocated.Load(), functionAllocs.live.Load())
+}
+
+// collectGarbage runs the garbage collector and waits for the finalizers
+// queued by it to run. Finalizers run sequentially on a single goroutine,
+// so once the finalizer of a sentinel allocated before the collection has
+// run, the finalizers queued before it have most likely run as well.
+func collectGarbage() {
+	for i := 0; i < 2; i++ {
+		done := make(chan struct{})
+		runtime.SetFinalizer(&allocation{}, func(*allocation) { close(done) })
+
+		runtime.GC()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+		}
+	}
+}
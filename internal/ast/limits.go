@@ -0,0 +1,104 @@
+package ast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+)
+
+// Hosts running untrusted scripts, e.g. on a server, can limit the work a
+// script does: SetFuel caps the number of statements it evaluates and the
+// context set with SetContext, typically with a deadline, caps its running
+// time. A script exceeding either stops with a LimitError.
+
+// ErrFuelExhausted is the cause of the LimitError stopping a script
+// which evaluated more statements than the fuel set with SetFuel.
+var ErrFuelExhausted = errors.New("fuel exhausted")
+
+// LimitError stops a script which has exceeded its fuel or whose context
+// is done, the cause is ErrFuelExhausted or the error of the context.
+type LimitError struct {
+	RuntimeError
+	Cause error
+}
+
+func (e LimitError) Unwrap() error {
+	return e.Cause
+}
+
+// SetFuel sets the fuel of the default interpreter, see Interpreter.SetFuel.
+func SetFuel(statements int) {
+	defaultInterpreter.SetFuel(statements)
+}
+
+// SetFuel limits the number of statements every script, expression or
+// input of a Session may evaluate, a limit of zero removes the limit.
+func (in *Interpreter) SetFuel(statements int) {
+	in.fuel = statements
+	in.fuelLeft = statements
+}
+
+// refuel resets the fuel before the next script is interpreted
+func (in *Interpreter) refuel() {
+	in.fuelLeft = in.fuel
+}
+
+// burnFuel uses up the fuel of a statement, failing once there is none left
+func (in *Interpreter) burnFuel() error {
+	if in.fuelLeft == 0 {
+		return LimitError{
+			RuntimeError: NewRuntimeError(token.Token{}, fmt.Sprintf("exceeded the limit of %d statements", in.fuel)),
+			Cause:        ErrFuelExhausted,
+		}
+	}
+
+	in.fuelLeft--
+	return nil
+}
+
+// SetContext sets the context of the default interpreter, see
+// Interpreter.SetContext.
+func SetContext(ctx context.Context) {
+	defaultInterpreter.SetContext(ctx)
+}
+
+// SetContext sets the context which stops the interpreter once done.
+func (in *Interpreter) SetContext(ctx context.Context) {
+	in.interrupt = ctx
+}
+
+// Context returns the context of the default interpreter.
+func Context() context.Context {
+	return defaultInterpreter.Context()
+}
+
+// Context returns the context set with SetContext.
+func (in *Interpreter) Context() context.Context {
+	return in.interrupt
+}
+
+// checkInterrupt returns a LimitError if the interpreter has been stopped
+func (in *Interpreter) checkInterrupt() error {
+	if err := in.interrupt.Err(); err != nil {
+		return LimitError{NewRuntimeError(token.Token{}, "interrupted: "+err.Error()), err}
+	}
+
+	return nil
+}
+
+// InterpretContext interprets statements with the default interpreter,
+// see Interpreter.InterpretContext.
+func InterpretContext(ctx context.Context, statements []Stmt, resolution *Resolution, report func(error)) error {
+	return defaultInterpreter.InterpretContext(ctx, statements, resolution, report)
+}
+
+// InterpretContext interprets statements like Interpret, stopping
+// once ctx is done instead of the context set with SetContext.
+func (in *Interpreter) InterpretContext(ctx context.Context, statements []Stmt, resolution *Resolution, report func(error)) error {
+	previous := in.interrupt
+	in.interrupt = ctx
+	defer func() { in.interrupt = previous }()
+
+	return in.Interpret(statements, resolution, report)
+}
@@ -0,0 +1,56 @@
+package ast_test
+
+import (
+	"context"
+	"errors"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"testing"
+	"time"
+)
+
+func parseScript(t *testing.T, source string) []ast.Stmt {
+	report := func(err error) { t.Error(err) }
+	tokens, _ := scan.Scan(source, report, scan.ScanContext{})
+	stmts, err := parse.Parse(tokens, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stmts
+}
+
+func TestInterpretContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	stopped := ast.NewInterpreter()
+	err := stopped.InterpretContext(ctx, parseScript(t, `while (true) {}`), nil, func(error) {})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the loop to be interrupted by the deadline but got %v", err)
+	}
+
+	// the context of one interpreter does not stop another
+	other := ast.NewInterpreter()
+	if err := other.Interpret(parseScript(t, `var i = 0; while (i < 10) i = i + 1;`), nil, func(err error) { t.Error(err) }); err != nil {
+		t.Errorf("expected the other interpreter to run but got %v", err)
+	}
+	if stopped.Context() != context.Background() {
+		t.Error("expected the context to be restored once the script stopped")
+	}
+}
+
+func TestFuel(t *testing.T) {
+	in := ast.NewInterpreter()
+	in.SetFuel(100)
+
+	err := in.Interpret(parseScript(t, `while (true) {}`), nil, func(error) {})
+	if !errors.Is(err, ast.ErrFuelExhausted) {
+		t.Errorf("expected the fuel to be exhausted but got %v", err)
+	}
+
+	// every script is interpreted with the full fuel
+	if err := in.Interpret(parseScript(t, `var i = 0; while (i < 10) i = i + 1;`), nil, func(err error) { t.Error(err) }); err != nil {
+		t.Errorf("expected the fuel to be refilled but got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+// LoxList is an ordered, mutable sequence of values. Lists have reference
+// semantics, copies of a LoxList share the same underlying elements.
+type LoxList struct {
+	l *list
+}
+
+type list struct {
+	frozen
+	elements []LoxValue
+}
+
+func NewLoxList(elements []LoxValue) LoxList {
+	return LoxList{l: &list{elements: elements}}
+}
+
+func (v LoxList) Type() LoxValueType {
+	return LIST
+}
+
+func (v LoxList) DebugPrint() string {
+	str, _ := valueToString(v)
+	return str
+}
+
+// Elements returns the elements of the list, the returned
+// slice must not be modified if the list is frozen.
+func (v LoxList) Elements() []LoxValue {
+	return v.l.elements
+}
+
+func (v LoxList) Len() int {
+	return len(v.l.elements)
+}
+
+func (v LoxList) SetElement(i int, value LoxValue) error {
+	if err := v.l.checkMutable("list"); err != nil {
+		return err
+	}
+
+	v.l.elements[i] = value
+	return nil
+}
+
+func (v LoxList) Append(value LoxValue) error {
+	if err := v.l.checkMutable("list"); err != nil {
+		return err
+	}
+
+	v.l.elements = append(v.l.elements, value)
+	return nil
+}
+
+// Replace replaces the elements in the range [low, high) with elements.
+func (v LoxList) Replace(low int, high int, elements []LoxValue) error {
+	if err := v.l.checkMutable("list"); err != nil {
+		return err
+	}
+
+	// build a new backing array since elements may alias the list
+	replaced := make([]LoxValue, 0, v.Len()-(high-low)+len(elements))
+	replaced = append(replaced, v.l.elements[:low]...)
+	replaced = append(replaced, elements...)
+	replaced = append(replaced, v.l.elements[high:]...)
+	v.l.elements = replaced
+	return nil
+}
+
+func isList(v LoxValue) bool {
+	return v.Type() == LIST
+}
+
+func AsList(v LoxValue) LoxList {
+	if v, ok := v.(LoxList); ok {
+		return v
+	}
+	panic("Cannot convert non-list to list")
+}
+
+func listToString(v LoxList) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("[")
+	for i, element := range v.Elements() {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		str, err := elementToString(element)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(str)
+	}
+	builder.WriteString("]")
+	return builder.String(), nil
+}
+
+// push(list, value) appends value to the end of list
+var pushFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isList(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "push expects a list")
+		}
+
+		if err := AsList(args[0]).Append(args[1]); err != nil {
+			return nil, err
+		}
+
+		return LoxNil{}, nil
+	},
+}
+
+// pop(list) removes and returns the last element of list
+var popFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isList(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "pop expects a list")
+		}
+
+		list := AsList(args[0])
+		if list.Len() == 0 {
+			return nil, NewRuntimeError(token.Token{}, "cannot pop from an empty list")
+		}
+
+		last := list.Elements()[list.Len()-1]
+		if err := list.Replace(list.Len()-1, list.Len(), nil); err != nil {
+			return nil, err
+		}
+
+		return last, nil
+	},
+}
+
+// slice(list, start, end) returns a new list with the elements of
+// list from start up to but not including end, same as list[start:end]
+var sliceFunc = NativeFunction{
+	paramLen: 3,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isList(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "slice expects a list")
+		}
+
+		elements := AsList(args[0]).Elements()
+		start, end, err := sliceBounds(args[1], args[2], len(elements))
+		if err != nil {
+			return nil, err
+		}
+
+		return NewLoxList(append([]LoxValue{}, elements[start:end]...)), nil
+	},
+}
+
+// asIndex converts v to an index in the range [0, length). Negative
+// indices count from the end, so -1 refers to the last element.
+func asIndex(v LoxValue, length int) (int, error) {
+	index, err := asInteger(v)
+	if err != nil {
+		return 0, err
+	}
+
+	if index < 0 {
+		index += length
+	}
+
+	if index < 0 || index >= length {
+		return 0, NewRuntimeError(token.Token{}, "index out of range")
+	}
+
+	return index, nil
+}
+
+// sliceBounds converts the bounds of a slice to the range [0, length].
+// A nil bound is omitted and defaults to the start or end of the sequence,
+// negative bounds count from the end like indices do.
+func sliceBounds(start LoxValue, end LoxValue, length int) (int, int, error) {
+	bound := func(v LoxValue, omitted int) (int, error) {
+		if v == nil {
+			return omitted, nil
+		}
+
+		bound, err := asInteger(v)
+		if err != nil {
+			return 0, err
+		}
+
+		if bound < 0 {
+			bound += length
+		}
+
+		if bound < 0 || bound > length {
+			return 0, NewRuntimeError(token.Token{}, "slice bound out of range")
+		}
+
+		return bound, nil
+	}
+
+	low, err := bound(start, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	high, err := bound(end, length)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if low > high {
+		return 0, 0, NewRuntimeError(token.Token{}, "slice start must not be greater than end")
+	}
+
+	return low, high, nil
+}
+
+func asInteger(v LoxValue) (int, error) {
+	if !isNumber(v) {
+		return 0, NewRuntimeError(token.Token{}, "index must be a number")
+	}
+
+	number := AsNumber(v)
+	integer := int(number)
+	if float64(integer) != number {
+		return 0, NewRuntimeError(token.Token{}, "index must be an integer")
+	}
+
+	return integer, nil
+}
@@ -0,0 +1,114 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"slices"
+)
+
+// Every iteration of a while, for and for-in loop passes through
+// loopState.iterate, which makes loops interruptible, enforces the limit
+// set with SetMaxIterations and counts the iterations of every loop when
+// enabled with CountLoops, so the loops of a script can be reported once
+// it finishes.
+
+// LoopCount records how often a loop ran. It implements error so it
+// can be reported like other diagnostics.
+type LoopCount struct {
+	Token token.Token
+	// the absolute path of the module the loop
+	// is in, empty for the main script
+	Path string
+	// the number of times the loop was started
+	Runs int
+	// the number of iterations of all runs together
+	Iterations int
+}
+
+func (c LoopCount) Error() string {
+	return fmt.Sprintf("[%d] loop - ran %d time(s), %d iteration(s) in total\n",
+		c.Token.Line, c.Runs, c.Iterations)
+}
+
+// Span returns the byte offset and length of the token of the loop.
+func (c LoopCount) Span() (int, int) {
+	return c.Token.Offset, len(c.Token.Lexme)
+}
+
+// SetMaxIterations makes the default interpreter fail loops running
+// more than n iterations at once, zero allows any number of iterations.
+func SetMaxIterations(n int) {
+	defaultInterpreter.maxIterations = n
+}
+
+// CountLoops decides whether the default interpreter counts the
+// iterations of loops, enabling it forgets the loops counted before.
+func CountLoops(enabled bool) {
+	in := defaultInterpreter
+	if enabled && !in.countLoops {
+		in.loopCounts = make(map[sourcePos]*LoopCount)
+	}
+	in.countLoops = enabled
+}
+
+// LoopCounts returns how often the loops which ran since counting
+// was enabled with CountLoops ran, in source order, those of the
+// main script first.
+func LoopCounts() []LoopCount {
+	loopCounts := defaultInterpreter.loopCounts
+	counts := make([]LoopCount, 0, len(loopCounts))
+	for _, c := range loopCounts {
+		counts = append(counts, *c)
+	}
+
+	slices.SortFunc(counts, func(a, b LoopCount) int {
+		return comparePos(sourcePos{a.Path, a.Token.Offset}, sourcePos{b.Path, b.Token.Offset})
+	})
+	return counts
+}
+
+// loopState tracks a single run of a loop
+type loopState struct {
+	in         *Interpreter
+	tok        token.Token
+	iterations int
+	// nil unless loops are counted
+	count *LoopCount
+}
+
+// beginLoop starts a run of the loop written with tok, evaluated in env
+func beginLoop(tok token.Token, env *Environment) *loopState {
+	in := env.interpreter()
+	l := &loopState{in: in, tok: tok}
+	// loops without a token cannot be reported
+	if !in.countLoops || tok.Line == 0 {
+		return l
+	}
+
+	pos := posOf(tok, env)
+	l.count = in.loopCounts[pos]
+	if l.count == nil {
+		l.count = &LoopCount{Token: tok, Path: pos.path}
+		in.loopCounts[pos] = l.count
+	}
+	l.count.Runs++
+	return l
+}
+
+// iterate must be called before every iteration of the loop
+func (l *loopState) iterate() error {
+	// the body might not be a block, which checks by itself
+	if err := l.in.checkInterrupt(); err != nil {
+		return withToken(err, l.tok)
+	}
+
+	l.iterations++
+	if l.in.maxIterations > 0 && l.iterations > l.in.maxIterations {
+		return NewRuntimeError(l.tok, fmt.Sprintf("loop exceeded the limit of %d iterations", l.in.maxIterations))
+	}
+
+	if l.count != nil {
+		l.count.Iterations++
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMaxIterations(t *testing.T) {
+	ast.SetMaxIterations(10)
+	defer ast.SetMaxIterations(0)
+
+	if err := interpret(t, `for (var i = 0; i < 10; i = i + 1) { for (x in [1, 2]) {} }`); err != nil {
+		t.Errorf("expected loops within the limit to run but got %v", err)
+	}
+
+	if err := interpret(t, `while (true) {}`); err == nil {
+		t.Error("expected the infinite loop to exceed the limit")
+	}
+}
+
+func TestCountLoops(t *testing.T) {
+	ast.CountLoops(true)
+	defer ast.CountLoops(false)
+
+	if err := interpret(t, `for (var i = 0; i < 3; i = i + 1) { var j = 0; do { j = j + 1; } while (j < 2); }`); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := ast.LoopCounts()
+	if len(counts) != 2 {
+		t.Fatalf("expected two loops but got %v", counts)
+	}
+	if counts[0].Runs != 1 || counts[0].Iterations != 3 {
+		t.Errorf("expected the outer loop to run once with 3 iterations but got %+v", counts[0])
+	}
+	if counts[1].Runs != 3 || counts[1].Iterations != 6 {
+		t.Errorf("expected the inner loop to run 3 times with 6 iterations but got %+v", counts[1])
+	}
+}
+
+func TestCountLoopsInModules(t *testing.T) {
+	ast.CountLoops(true)
+	defer ast.CountLoops(false)
+
+	// the loops of the module and the main script start at the same offset
+	ast.SetModuleLoader(func(path string) ([]ast.Stmt, *ast.Resolution, func(error), error) {
+		tokens, _ := scan.Scan(`for (var i = 0; i < 2; i = i + 1) {}`, nil, scan.ScanContext{})
+		stmts, err := parse.Parse(tokens, func(error) {})
+		return stmts, nil, func(error) {}, err
+	})
+	defer ast.SetModuleLoader(nil)
+	defer ast.ClearModules()
+
+	if err := interpret(t, `for (var i = 0; i < 3; i = i + 1) {} import counted;`); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := ast.LoopCounts()
+	if len(counts) != 2 {
+		t.Fatalf("expected two loops but got %+v", counts)
+	}
+	if counts[0].Path != "" || counts[0].Iterations != 3 {
+		t.Errorf("expected the loop of the main script with 3 iterations first but got %+v", counts[0])
+	}
+	if filepath.Base(counts[1].Path) != "counted.lox" || counts[1].Iterations != 2 {
+		t.Errorf("expected the loop of the module with 2 iterations but got %+v", counts[1])
+	}
+}
+
+func TestLoopSemantics(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "while condition once per iteration",
+			source: `var n = 0; fun next() { n = n + 1; return n; } while (next() < 3) { print n; } print n;`,
+			want:   "1\n2\n3\n",
+		},
+		{
+			name:   "if condition once",
+			source: `var n = 0; fun next() { n = n + 1; return n; } if (next() == 1) print "then"; else print "else"; print n;`,
+			want:   "then\n1\n",
+		},
+		{
+			name:   "block scopes inside loops",
+			source: `var a = "global"; var i = 0; while (i < 2) { var a = i; print a; i = i + 1; } print a;`,
+			want:   "0\n1\nglobal\n",
+		},
+		{
+			name: "closures capture the block they are declared in",
+			source: `var f; for (var i = 0; i < 2; i = i + 1) { var j = i; fun g() { return j; } if (i == 0) f = g; }
+var j = "global"; print f();`,
+			want: "0\n",
+		},
+		{
+			name: "calls restore the environment of the caller",
+			source: `fun g() { var a = "g"; return a; } { var a = "block"; var r = g(); print a; print r; }
+fun gen() { var a = "gen"; yield a; yield a; } { var a = "caller"; for (x in gen()) { print a; print x; } }`,
+			want: "block\ng\ncaller\ngen\ncaller\ngen\n",
+		},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		ast.SetOutput(&out)
+		if err := interpret(t, test.source); err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s: expected %q but got %q", test.name, test.want, out.String())
+		}
+	}
+	ast.SetOutput(os.Stdout)
+}
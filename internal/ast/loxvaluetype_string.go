@@ -15,15 +15,22 @@ func _() {
 	_ = x[OBJECT-4]
 	_ = x[FUNCTION-5]
 	_ = x[TYPE-6]
+	_ = x[BUILDER-7]
+	_ = x[LIST-8]
+	_ = x[MAP-9]
+	_ = x[SET-10]
+	_ = x[GENERATOR-11]
+	_ = x[FOREIGN-12]
 }
 
-const _LoxValueType_name = "BOOLEANNUMBERNILSTRINGOBJECTFUNCTIONTYPE"
+const _LoxValueType_name = "BOOLEANNUMBERNILSTRINGOBJECTFUNCTIONTYPEBUILDERLISTMAPSETGENERATORFOREIGN"
 
-var _LoxValueType_index = [...]uint8{0, 7, 13, 16, 22, 28, 36, 40}
+var _LoxValueType_index = [...]uint8{0, 7, 13, 16, 22, 28, 36, 40, 47, 51, 54, 57, 66, 73}
 
 func (i LoxValueType) String() string {
-	if i >= LoxValueType(len(_LoxValueType_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_LoxValueType_index)-1 {
 		return "LoxValueType(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _LoxValueType_name[_LoxValueType_index[i]:_LoxValueType_index[i+1]]
+	return _LoxValueType_name[_LoxValueType_index[idx]:_LoxValueType_index[idx+1]]
 }
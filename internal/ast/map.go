@@ -0,0 +1,264 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+// LoxMap is a mutable mapping from keys to values which remembers the order
+// keys were first inserted in. Like lists, maps have reference semantics.
+type LoxMap struct {
+	m *loxMap
+}
+
+type loxMap struct {
+	frozen
+	// position of every key in entries
+	index   map[any]int
+	entries []mapEntry
+	// the kind of value backed by the map, used in error messages
+	kind string
+}
+
+type mapEntry struct {
+	key   LoxValue
+	value LoxValue
+}
+
+func NewLoxMap() LoxMap {
+	return LoxMap{m: &loxMap{index: make(map[any]int), kind: "map"}}
+}
+
+func (v LoxMap) Type() LoxValueType {
+	return MAP
+}
+
+func (v LoxMap) DebugPrint() string {
+	str, _ := valueToString(v)
+	return str
+}
+
+func (v LoxMap) Len() int {
+	return len(v.m.entries)
+}
+
+// Get returns the value associated with key, ok is false
+// if there is no such key or the key is not hashable.
+func (v LoxMap) Get(key LoxValue) (value LoxValue, ok bool) {
+	hash, hashable := hashKey(key)
+	if !hashable {
+		return nil, false
+	}
+
+	i, ok := v.m.index[hash]
+	if !ok {
+		return nil, false
+	}
+
+	return v.m.entries[i].value, true
+}
+
+// Set associates value with key, an error is returned if
+// the key is not hashable.
+func (v LoxMap) Set(key LoxValue, value LoxValue) error {
+	if err := v.m.checkMutable(v.m.kind); err != nil {
+		return err
+	}
+
+	hash, hashable := hashKey(key)
+	if !hashable {
+		return NewRuntimeError(token.Token{}, "unhashable map key")
+	}
+
+	if i, ok := v.m.index[hash]; ok {
+		v.m.entries[i].value = value
+		return nil
+	}
+
+	v.m.index[hash] = len(v.m.entries)
+	v.m.entries = append(v.m.entries, mapEntry{key: key, value: value})
+	return nil
+}
+
+// Remove deletes key from the map and returns the value associated
+// with it, ok is false if there is no such key. An error is returned
+// if the map is frozen.
+func (v LoxMap) Remove(key LoxValue) (value LoxValue, ok bool, err error) {
+	if err := v.m.checkMutable(v.m.kind); err != nil {
+		return nil, false, err
+	}
+
+	hash, hashable := hashKey(key)
+	if !hashable {
+		return nil, false, nil
+	}
+
+	i, ok := v.m.index[hash]
+	if !ok {
+		return nil, false, nil
+	}
+
+	value = v.m.entries[i].value
+	delete(v.m.index, hash)
+	v.m.entries = append(v.m.entries[:i], v.m.entries[i+1:]...)
+	for j := i; j < len(v.m.entries); j++ {
+		hash, _ := hashKey(v.m.entries[j].key)
+		v.m.index[hash] = j
+	}
+
+	return value, true, nil
+}
+
+func (v LoxMap) Keys() []LoxValue {
+	keys := make([]LoxValue, len(v.m.entries))
+	for i, entry := range v.m.entries {
+		keys[i] = entry.key
+	}
+	return keys
+}
+
+func (v LoxMap) Values() []LoxValue {
+	values := make([]LoxValue, len(v.m.entries))
+	for i, entry := range v.m.entries {
+		values[i] = entry.value
+	}
+	return values
+}
+
+func isMap(v LoxValue) bool {
+	return v.Type() == MAP
+}
+
+func AsMap(v LoxValue) LoxMap {
+	if v, ok := v.(LoxMap); ok {
+		return v
+	}
+	panic("Cannot convert non-map to map")
+}
+
+// hashKey returns a comparable Go value for v such that two values
+// have the same hash key exactly when equals reports them as equal.
+// Values which are never equal to anything (functions) are not hashable.
+func hashKey(v LoxValue) (any, bool) {
+	type key struct {
+		typ   LoxValueType
+		value any
+	}
+
+	switch v.Type() {
+	case BOOLEAN:
+		return key{BOOLEAN, AsBoolean(v)}, true
+	case NUMBER:
+		return key{NUMBER, AsNumber(v)}, true
+	case NIL:
+		return key{NIL, nil}, true
+	case STRING:
+		return key{STRING, AsString(v)}, true
+	case OBJECT:
+		return key{OBJECT, AsObject(v).o}, true
+	case TYPE:
+		return key{TYPE, v.(LoxType).Typ}, true
+	case BUILDER:
+		return key{BUILDER, AsStringBuilder(v).builder}, true
+	case LIST:
+		return key{LIST, AsList(v).l}, true
+	case MAP:
+		return key{MAP, AsMap(v).m}, true
+	case SET:
+		return key{SET, AsSet(v).members.m}, true
+	case GENERATOR:
+		return key{GENERATOR, AsGenerator(v).g}, true
+	case FOREIGN:
+		// equal foreign values have the same type and object
+		foreign := AsForeign(v)
+		if !foreign.comparable() {
+			return nil, false
+		}
+		return key{FOREIGN, [2]any{foreign.typ, foreign.object}}, true
+	default:
+		return nil, false
+	}
+}
+
+func mapToString(v LoxMap) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("{")
+	for i, entry := range v.m.entries {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		key, err := elementToString(entry.key)
+		if err != nil {
+			return "", err
+		}
+
+		value, err := elementToString(entry.value)
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteString(key + ": " + value)
+	}
+	builder.WriteString("}")
+	return builder.String(), nil
+}
+
+// keys(map) returns a list of the keys of map in insertion order
+var keysFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isMap(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "keys expects a map")
+		}
+
+		return NewLoxList(AsMap(args[0]).Keys()), nil
+	},
+}
+
+// values(map) returns a list of the values of map in insertion order
+var valuesFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isMap(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "values expects a map")
+		}
+
+		return NewLoxList(AsMap(args[0]).Values()), nil
+	},
+}
+
+// has(map, key) reports whether map contains key
+var hasFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isMap(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "has expects a map")
+		}
+
+		_, ok := AsMap(args[0]).Get(args[1])
+		return LoxBoolean(ok), nil
+	},
+}
+
+// remove(map, key) removes key from map and returns its
+// value, or nil if map does not contain key
+var removeFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isMap(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "remove expects a map")
+		}
+
+		value, ok, err := AsMap(args[0]).Remove(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			return value, nil
+		}
+
+		return LoxNil{}, nil
+	},
+}
@@ -0,0 +1,68 @@
+package ast_test
+
+import "testing"
+
+func TestMaps(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+		// a substring of the runtime error, empty if the source runs
+		err string
+	}{
+		{
+			name:   "literal and index",
+			source: `var m = {"a": 1, "b": 2}; print m; print m["a"]; print len(m);`,
+			want:   "{\"a\": 1, \"b\": 2}\n1\n2\n",
+		},
+		{
+			name:   "a brace starts a block in statement position",
+			source: `{ print "block"; } var m = {}; print m;`,
+			want:   "block\n{}\n",
+		},
+		{
+			name:   "assignment adds and replaces keys",
+			source: `var m = {"a": 1}; m["b"] = 2; m["a"] = 3; print m;`,
+			want:   "{\"a\": 3, \"b\": 2}\n",
+		},
+		{
+			name:   "natives keep the insertion order",
+			source: `var m = {"b": 1, "a": 2}; print keys(m); print values(m); print has(m, "a"); print has(m, "c");`,
+			want:   "[\"b\", \"a\"]\n[1, 2]\ntrue\nfalse\n",
+		},
+		{
+			name:   "remove returns the value",
+			source: `var m = {"a": 1, "b": 2}; print remove(m, "a"); print remove(m, "c"); print m;`,
+			want:   "1\nnil\n{\"b\": 2}\n",
+		},
+		{
+			name:   "keys are equal like values",
+			source: `var m = {1: "number", "1": "string", nil: "nil", true: "bool"}; print m[1.0]; print m["1"]; print m[nil]; print m[true];`,
+			want:   "number\nstring\nnil\nbool\n",
+		},
+		{
+			name:   "lists are keys by identity",
+			source: `var l = [1]; var m = {}; m[l] = "l"; print has(m, l); print has(m, [1]);`,
+			want:   "true\nfalse\n",
+		},
+		{
+			name:   "nested maps",
+			source: `var m = {"a": {"b": 1}}; m["a"]["b"] = 2; print m;`,
+			want:   "{\"a\": {\"b\": 2}}\n",
+		},
+		{
+			name:   "missing key",
+			source: `var m = {"a": 1}; print m["b"];`,
+			err:    "undefined map key",
+		},
+		{
+			name:   "natives expect a map",
+			source: `keys([1]);`,
+			err:    "keys expects a map",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, test.err)
+	}
+}
@@ -0,0 +1,123 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"math"
+)
+
+// the math natives, registered in the math group
+var (
+	absFunc   = unaryMath("abs", math.Abs)
+	floorFunc = unaryMath("floor", math.Floor)
+	ceilFunc  = unaryMath("ceil", math.Ceil)
+	roundFunc = unaryMath("round", math.Round)
+	sqrtFunc  = unaryMath("sqrt", math.Sqrt)
+	powFunc   = binaryMath("pow", math.Pow)
+	minFunc   = binaryMath("min", math.Min)
+	maxFunc   = binaryMath("max", math.Max)
+)
+
+// SetMathNatives decides whether the default interpreter defines the
+// math natives, see Interpreter.SetMathNatives.
+func SetMathNatives(enabled bool) {
+	defaultInterpreter.SetMathNatives(enabled)
+}
+
+// SetMathNatives decides whether the math natives are defined for the
+// scripts interpreted afterwards, they are by default.
+func (in *Interpreter) SetMathNatives(enabled bool) {
+	in.mathEnabled = enabled
+}
+
+// numberArgs returns args as numbers, failing
+// with a runtime error naming the native if one is not
+func numberArgs(name string, args []LoxValue) ([]float64, error) {
+	numbers := make([]float64, len(args))
+	for i, arg := range args {
+		if !isNumber(arg) {
+			return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("%s expects numbers", name))
+		}
+		numbers[i] = AsNumber(arg)
+	}
+	return numbers, nil
+}
+
+func unaryMath(name string, f func(float64) float64) NativeFunction {
+	return NativeFunction{
+		paramLen: 1,
+		Function: func(args []LoxValue) (LoxValue, error) {
+			x, err := numberArgs(name, args)
+			if err != nil {
+				return nil, err
+			}
+
+			return LoxNumber(f(x[0])), nil
+		},
+	}
+}
+
+func binaryMath(name string, f func(float64, float64) float64) NativeFunction {
+	return NativeFunction{
+		paramLen: 2,
+		Function: func(args []LoxValue) (LoxValue, error) {
+			x, err := numberArgs(name, args)
+			if err != nil {
+				return nil, err
+			}
+
+			return LoxNumber(f(x[0], x[1])), nil
+		},
+	}
+}
+
+// Seed seeds the source of the random natives, random and randomInt
+// return the same numbers after every seed with the same seed. Every
+// interpreter has its own source, seeded by the current time, so a
+// seeded interpreter is reproducible whatever the others draw.
+func (in *Interpreter) Seed(seed int64) {
+	in.random.Seed(seed)
+}
+
+// random() returns a random number in [0, 1)
+var randomFunc = NativeFunction{
+	paramLen: 0,
+	external: true,
+	functionIn: func(in *Interpreter, _ token.Token, _ []LoxValue) (LoxValue, error) {
+		return LoxNumber(in.random.Float64()), nil
+	},
+}
+
+// randomInt(low, high) returns a random integer in [low, high)
+var randomIntFunc = NativeFunction{
+	paramLen: 2,
+	external: true,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		for _, arg := range args {
+			if !isNumber(arg) || AsNumber(arg) != math.Trunc(AsNumber(arg)) {
+				return nil, NewRuntimeError(token.Token{}, "randomInt expects integers")
+			}
+		}
+
+		low, high := int64(AsNumber(args[0])), int64(AsNumber(args[1]))
+		if low >= high {
+			return nil, NewRuntimeError(token.Token{}, "randomInt expects low to be less than high")
+		}
+
+		return LoxNumber(float64(low + in.random.Int63n(high-low))), nil
+	},
+}
+
+// seed(n) seeds the source of random and randomInt with the integer n,
+// they return the same numbers after every seed with the same n
+var seedFunc = NativeFunction{
+	paramLen: 1,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		if !isNumber(args[0]) || AsNumber(args[0]) != math.Trunc(AsNumber(args[0])) {
+			return nil, NewRuntimeError(token.Token{}, "seed expects an integer")
+		}
+
+		in.Seed(int64(AsNumber(args[0])))
+		return LoxNil{}, nil
+	},
+}
@@ -0,0 +1,326 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"strconv"
+	"strings"
+)
+
+// DefaultMemoSize is the number of results remembered per function
+// when memoization is enabled without a size.
+const DefaultMemoSize = 1024
+
+// SetMemoize enables memoization of pure functions for the default
+// interpreter, remembering up to size results per function. Zero
+// disables memoization.
+//
+// A function is pure if it does not print, yield, declare functions or
+// assign variables it did not declare, and only reads variables it
+// declared or which are bound to pure functions, e.g. recursive calls.
+// Calls to a pure function with arguments that are booleans, numbers,
+// nil or strings reuse the result of an earlier call with the same
+// arguments, provided the result is one of those types as well.
+func SetMemoize(size int) {
+	defaultInterpreter.memoSize = size
+}
+
+// identifies a function, function values are copied
+// but share their closure and body
+type memoKey struct {
+	closure *Environment
+	body    *Stmt
+}
+
+// memo is created with a function value and shared by its copies, the
+// remembered results are released together with the function
+type memo struct {
+	// set once the purity of the function is decided
+	analyzed bool
+	pure     bool
+	// the functions read by the function, results may only be
+	// reused while the names are still bound to the same functions
+	dependencies map[string]memoKey
+	results      map[string]LoxValue
+	// the keys of results, oldest first
+	order []string
+}
+
+func keyOf(f LoxFunction) (memoKey, bool) {
+	if len(f.Body) == 0 || f.IsGenerator || f.memo == nil {
+		return memoKey{}, false
+	}
+
+	return memoKey{f.Closure, &f.Body[0]}, true
+}
+
+// memoized returns the remembered result of calling f with arguments,
+// or a function to remember the result of the call if there is none
+func (in *Interpreter) memoized(f LoxFunction, arguments []LoxValue) (LoxValue, func(LoxValue)) {
+	key, ok := keyOf(f)
+	if !ok || in.memoSize == 0 {
+		return nil, nil
+	}
+
+	m := analyze(f, key, map[memoKey]bool{})
+	if !m.pure || !m.valid(f.Closure, map[memoKey]bool{}) {
+		return nil, nil
+	}
+
+	args, ok := argumentsKey(arguments)
+	if !ok {
+		return nil, nil
+	}
+
+	if result, ok := m.results[args]; ok {
+		return result, nil
+	}
+
+	return nil, func(result LoxValue) {
+		if _, ok := argumentsKey([]LoxValue{result}); !ok {
+			return
+		}
+
+		for len(m.order) >= in.memoSize {
+			delete(m.results, m.order[0])
+			m.order = m.order[1:]
+		}
+		m.results[args] = result
+		m.order = append(m.order, args)
+	}
+}
+
+// valid reports whether the dependencies of m are still
+// bound to the same functions in closure
+func (m *memo) valid(closure *Environment, visited map[memoKey]bool) bool {
+	for name, key := range m.dependencies {
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+
+		value, err := closure.Get(tokenNamed(name))
+		f, ok := value.(LoxFunction)
+		if err != nil || !ok {
+			return false
+		}
+
+		if k, _ := keyOf(f); k != key {
+			return false
+		}
+
+		if !f.memo.analyzed || !f.memo.valid(f.Closure, visited) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func tokenNamed(name string) token.Token {
+	return token.Token{Type: token.IDENTIFIER, Lexme: name}
+}
+
+// argumentsKey encodes arguments as a map key,
+// false if an argument is not a primitive value
+func argumentsKey(arguments []LoxValue) (string, bool) {
+	var key strings.Builder
+	for _, arg := range arguments {
+		switch arg.Type() {
+		case BOOLEAN:
+			key.WriteString(strconv.FormatBool(AsBoolean(arg)))
+		case NUMBER:
+			key.WriteString(strconv.FormatFloat(AsNumber(arg), 'g', -1, 64))
+		case NIL:
+			key.WriteString("nil")
+		case STRING:
+			key.WriteString(strconv.Quote(AsString(arg)))
+		default:
+			return "", false
+		}
+		key.WriteString(",")
+	}
+
+	return key.String(), true
+}
+
+// analyze returns the memo of f, deciding whether f is pure the first
+// time. Functions being analyzed are assumed to be pure, so recursive
+// functions can be pure.
+func analyze(f LoxFunction, key memoKey, analyzing map[memoKey]bool) *memo {
+	if f.memo.analyzed {
+		return f.memo
+	}
+
+	p := &purity{scopes: []map[string]bool{{}}, free: map[string]bool{}, pure: true}
+	for _, param := range f.Parameters {
+		p.declare(param.Lexme)
+	}
+	p.stmts(f.Body)
+
+	m := &memo{pure: p.pure, dependencies: map[string]memoKey{}, results: map[string]LoxValue{}}
+	analyzing[key] = true
+	defer delete(analyzing, key)
+
+	for name := range p.free {
+		if !m.pure {
+			break
+		}
+
+		value, err := f.Closure.Get(tokenNamed(name))
+		dependency, ok := value.(LoxFunction)
+		if err != nil || !ok {
+			m.pure = false
+			break
+		}
+
+		k, ok := keyOf(dependency)
+		if !ok {
+			m.pure = false
+			break
+		}
+
+		m.dependencies[name] = k
+		if !analyzing[k] && !analyze(dependency, k, analyzing).pure {
+			m.pure = false
+		}
+	}
+
+	// functions depending on a function still being analyzed are
+	// only remembered once the analysis of that function is done
+	if len(analyzing) == 1 || !m.pure {
+		m.analyzed = true
+		*f.memo = *m
+		return f.memo
+	}
+	return m
+}
+
+// purity walks the body of a function to decide whether it is pure
+type purity struct {
+	// the names declared by the function, innermost scope last
+	scopes []map[string]bool
+	// the names read but not declared by the function
+	free map[string]bool
+	pure bool
+}
+
+func (p *purity) declare(name string) {
+	p.scopes[len(p.scopes)-1][name] = true
+}
+
+func (p *purity) local(name string) bool {
+	for _, scope := range p.scopes {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *purity) stmts(statements []Stmt) {
+	p.scopes = append(p.scopes, map[string]bool{})
+	for _, stmt := range statements {
+		p.stmt(stmt)
+	}
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+func (p *purity) stmt(s Stmt) {
+	switch s := s.(type) {
+	case ExpressionStmt:
+		p.expr(s.Expr)
+	case VarStmt:
+		p.expr(s.Initializer)
+		p.declare(s.Name.Lexme)
+	case BlockStmt:
+		p.stmts(s.Statements)
+	case IfStmt:
+		p.expr(s.Condition)
+		p.stmt(s.ThenBranch)
+		p.stmt(s.ElseBranch)
+	case WhileStmt:
+		p.expr(s.Condition)
+		p.stmt(s.Body)
+		p.expr(s.Increment)
+	case ForInStmt:
+		p.expr(s.Iterable)
+		p.scopes = append(p.scopes, map[string]bool{s.Name.Lexme: true})
+		p.stmt(s.Body)
+		p.scopes = p.scopes[:len(p.scopes)-1]
+	case ReturnStmt:
+		p.expr(s.Expr)
+	case AssertStmt:
+		p.expr(s.Condition)
+		p.expr(s.Message)
+	case BreakStmt, ContinueStmt, nil:
+	default:
+		// prints, yields and function declarations
+		p.pure = false
+	}
+}
+
+func (p *purity) exprs(exprs []Expr) {
+	for _, e := range exprs {
+		p.expr(e)
+	}
+}
+
+func (p *purity) expr(e Expr) {
+	switch e := e.(type) {
+	case BinaryExpr:
+		p.exprs([]Expr{e.Left, e.Right})
+	case GroupingExpr:
+		p.expr(e.Expr)
+	case UnaryExpr:
+		p.expr(e.Right)
+	case PrefixExpr:
+		p.assign(e.Target)
+	case PostfixExpr:
+		p.assign(e.Target)
+	case TernaryExpr:
+		p.exprs([]Expr{e.Condition, e.Left, e.Right})
+	case VariableExpr:
+		if !p.local(e.Name.Lexme) {
+			p.free[e.Name.Lexme] = true
+		}
+	case AssignExpr:
+		p.assign(VariableExpr{Name: e.Name})
+		p.expr(e.Value)
+	case ListExpr:
+		p.exprs(e.Elements)
+	case MapExpr:
+		p.exprs(e.Keys)
+		p.exprs(e.Values)
+	case ObjectExpr:
+		p.exprs(e.Values)
+	case IndexExpr:
+		p.exprs([]Expr{e.Object, e.Index})
+	case SliceExpr:
+		p.exprs([]Expr{e.Object, e.Start, e.End})
+	case IndexAssignExpr:
+		p.exprs([]Expr{e.Object, e.Index, e.Value})
+	case SliceAssignExpr:
+		p.exprs([]Expr{e.Object, e.Start, e.End, e.Value})
+	case GetExpr:
+		p.expr(e.Object)
+	case SetExpr:
+		p.exprs([]Expr{e.Object, e.Value})
+	case CallExpr:
+		p.expr(e.Callee)
+		p.exprs(e.Arguments)
+	case LiteralExpr, NothingExpr, nil:
+	default:
+		// function expressions
+		p.pure = false
+	}
+}
+
+// assign checks the target of an assignment, only variables declared by
+// the function may be assigned. Elements may be assigned since the only
+// lists and maps a pure function can reach are the ones it created.
+func (p *purity) assign(target Expr) {
+	if variable, ok := target.(VariableExpr); ok && !p.local(variable.Name.Lexme) {
+		p.pure = false
+	}
+	p.expr(target)
+}
@@ -0,0 +1,99 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/ast"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMemoize(t *testing.T) {
+	ast.SetMemoize(ast.DefaultMemoSize)
+	defer ast.SetMemoize(0)
+
+	var out strings.Builder
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	// evaluating fib(25) without reusing results takes a few hundred
+	// thousand statements
+	ast.SetFuel(10000)
+	defer ast.SetFuel(0)
+	source := `
+		fun fib(n) { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }
+		print fib(25);`
+	if err := interpret(t, source); err != nil {
+		t.Fatalf("pure function: %v", err)
+	}
+	if out.String() != "75025\n" {
+		t.Errorf("pure function: expected %q but got %q", "75025\n", out.String())
+	}
+
+	out.Reset()
+	source = `
+		fun greet(name) { print "hello " + name; return name; }
+		greet("lox");
+		greet("lox");`
+	if err := interpret(t, source); err != nil {
+		t.Fatalf("impure function: %v", err)
+	}
+	if want := "hello lox\nhello lox\n"; out.String() != want {
+		t.Errorf("impure function: expected %q but got %q", want, out.String())
+	}
+}
+
+func TestMemoizeArguments(t *testing.T) {
+	ast.SetMemoize(ast.DefaultMemoSize)
+	defer ast.SetMemoize(0)
+
+	var out strings.Builder
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "numbers",
+			source: `fun join(a, b) { return a * 100 + b; }
+				print join(1, 23); print join(12, 3);`,
+			want: "123\n1203\n",
+		},
+		{
+			name: "separators in strings",
+			source: `fun first(a, b) { if (a == "a,") return "first"; return "second"; }
+				print first("a,", "b"); print first("a", ",b");`,
+			want: "first\nsecond\n",
+		},
+		{
+			name: "strings and numbers",
+			source: `fun same(a) { return a == 1; }
+				print same(1); print same("1");`,
+			want: "true\nfalse\n",
+		},
+		{
+			name: "list arguments",
+			source: `fun head(l) { return l[0]; }
+				var l = [1]; print head(l); l[0] = 2; print head(l);`,
+			want: "1\n2\n",
+		},
+		{
+			name: "list results",
+			source: `fun wrap(x) { return [x]; }
+				var w = wrap(1); w[0] = 5; print wrap(1);`,
+			want: "[1]\n",
+		},
+	}
+
+	for _, test := range tests {
+		out.Reset()
+		if err := interpret(t, test.source); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s: expected %q but got %q", test.name, test.want, out.String())
+		}
+	}
+}
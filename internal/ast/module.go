@@ -0,0 +1,178 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Every module is run once, in its own environment enclosed by the global
+// environment, the first time it is imported. Later imports of the same
+// file reuse the declarations of the first, until ClearModules is called.
+// The interpreter cannot scan or parse modules itself, the host loads them
+// with the loader set with SetModuleLoader.
+
+// ModuleLoader reads, scans, parses and resolves the module at path,
+// reporting its diagnostics itself. The returned resolution binds the
+// locals of the module, nil if it is not resolved, and the returned
+// report function reports the runtime errors of the module.
+type ModuleLoader func(path string) (statements []Stmt, resolution *Resolution, report func(error), err error)
+
+type module struct {
+	env *Environment
+	// false while the module is being run
+	loaded bool
+}
+
+// SetModuleLoader sets the module loader of the default interpreter,
+// see Interpreter.SetModuleLoader.
+func SetModuleLoader(loader ModuleLoader) {
+	defaultInterpreter.SetModuleLoader(loader)
+}
+
+// SetModuleLoader sets the loader of imported modules,
+// nil makes every import fail.
+func (in *Interpreter) SetModuleLoader(loader ModuleLoader) {
+	in.moduleLoader = loader
+}
+
+// SetModuleDir sets the module directory of the default interpreter,
+// see Interpreter.SetModuleDir.
+func SetModuleDir(dir string) {
+	defaultInterpreter.SetModuleDir(dir)
+}
+
+// SetModuleDir sets the directory the imports of the main script
+// are relative to, usually the directory of the script.
+func (in *Interpreter) SetModuleDir(dir string) {
+	in.moduleDir = dir
+}
+
+// ClearModules makes the default interpreter forget the imported
+// modules, the next import of a module runs it again.
+func ClearModules() {
+	defaultInterpreter.modules = map[string]*module{}
+}
+
+// Modules returns the absolute paths of the modules imported
+// by the default interpreter, sorted.
+func Modules() []string {
+	modules := defaultInterpreter.modules
+	paths := make([]string, 0, len(modules))
+	for path, m := range modules {
+		if m.loaded {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (s ImportStmt) Evaluate(env *Environment) error {
+	path := s.Path.Lexme
+	if s.Path.Type == token.IDENTIFIER {
+		path += ".lox"
+	}
+
+	m, err := env.interpreter().importModule(path)
+	if err != nil {
+		return withToken(err, s.Keyword)
+	}
+
+	if s.Path.Type == token.STRING {
+		env.importInto(m.env)
+		return nil
+	}
+
+	// sorted so the map prints the same every time
+	declarations := NewLoxMap()
+	for _, name := range m.env.Names() {
+		value, _ := m.env.Lookup(name)
+		declarations.Set(LoxString(name), value)
+	}
+	declarations.m.freeze(s.Keyword)
+	env.Define(s.Path.Lexme, declarations)
+	return nil
+}
+
+// importModule returns the module at path, relative to the directory
+// of the importing module, running it if it has not been imported before
+func (in *Interpreter) importModule(path string) (*module, error) {
+	if in.moduleLoader == nil {
+		return nil, NewRuntimeError(token.Token{}, "imports are not supported")
+	}
+
+	dir := in.moduleDir
+	if len(in.importStack) > 0 {
+		dir = filepath.Dir(in.importStack[len(in.importStack)-1])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("cannot import '%s': %s", path, err))
+	}
+
+	if m, ok := in.modules[path]; ok {
+		if !m.loaded {
+			return nil, NewRuntimeError(token.Token{}, "import cycle: "+in.importCycle(path))
+		}
+		return m, nil
+	}
+
+	statements, resolution, report, err := in.moduleLoader(path)
+	if err != nil {
+		return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("cannot import '%s': %s", in.displayPath(path), in.osReason(err)))
+	}
+
+	m := &module{env: NewEnvironment(in.globals)}
+	m.env.toplevel = true
+	m.env.path = path
+	m.env.resolution = resolution
+	in.modules[path] = m
+	in.importStack = append(in.importStack, path)
+	defer func() { in.importStack = in.importStack[:len(in.importStack)-1] }()
+
+	if err := executeBlock(statements, m.env); err != nil {
+		// the module is run again the next time it is imported
+		delete(in.modules, path)
+		report(err)
+		return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("module '%s' failed", in.displayPath(path)))
+	}
+
+	m.loaded = true
+	return m, nil
+}
+
+// importCycle describes the cycle of imports ending with importing path
+func (in *Interpreter) importCycle(path string) string {
+	var cycle []string
+	for i, imported := range in.importStack {
+		if imported == path {
+			for _, p := range in.importStack[i:] {
+				cycle = append(cycle, in.displayPath(p))
+			}
+			break
+		}
+	}
+	return strings.Join(append(cycle, in.displayPath(path)), " -> ")
+}
+
+// displayPath returns path relative to the directory of the main
+// script if it is within it, with forward slashes if output is normalized
+func (in *Interpreter) displayPath(path string) string {
+	if dir, err := filepath.Abs(in.moduleDir); err == nil {
+		if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+
+	if in.normalized {
+		return filepath.ToSlash(path)
+	}
+	return path
+}
@@ -0,0 +1,155 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A native module is an object holding natives as its fields, so the
+// natives are called through the name of the module, e.g. math.sqrt(2).
+// Like the natives, the modules are defined in the global environment
+// every time a script is interpreted, as new objects so a script assigning
+// a field of a module does not affect the scripts interpreted after it.
+// Every member belongs to a group, a module without enabled members is
+// not defined.
+
+type nativeModule struct {
+	// the names of the members in the order they are added to the object
+	names   []string
+	members map[string]registeredNative
+}
+
+// the modules every registry starts out with
+var standardModules = map[string]*nativeModule{}
+
+// registerStandardModules registers the modules of the standard
+// natives, which must be registered before
+func registerStandardModules() {
+	standard := []struct {
+		name    string
+		members []string
+	}{
+		{"math", []string{"abs", "floor", "ceil", "round", "sqrt", "pow", "min", "max", "random", "randomInt", "seed"}},
+		{"io", []string{"println", "readLine", "env", "readFile", "writeFile", "appendFile"}},
+		{"time", []string{"clock", "now", "sleep", "formatTime", "parseTime"}},
+	}
+
+	for _, module := range standard {
+		m := &nativeModule{members: map[string]registeredNative{}}
+		for _, name := range module.members {
+			native := standardNatives[name]
+			native.function.name = module.name + "." + name
+			native.doc = module.name + "." + native.doc
+			m.names = append(m.names, name)
+			m.members[name] = native
+		}
+		standardModules[module.name] = m
+	}
+}
+
+// defineModules defines the modules with enabled members, the
+// modules without any are removed like disabled natives
+func (in *Interpreter) defineModules() {
+	for name := range in.nativeModules {
+		in.defineModule(name)
+	}
+}
+
+func (in *Interpreter) defineModule(name string) {
+	module := in.nativeModules[name]
+	object := NewLoxObject()
+	for _, member := range module.names {
+		if native := module.members[member]; in.groupEnabled(native.group) {
+			object.Set(member, in.bind(native.function))
+		}
+	}
+
+	if len(object.Fields()) == 0 {
+		in.undefineModule(name)
+		return
+	}
+	in.globals.Define(name, object)
+	in.definedModules[name] = object
+}
+
+// undefineModule removes the module name from the global
+// environment unless a script has redefined it
+func (in *Interpreter) undefineModule(name string) {
+	value, _ := in.globals.Lookup(name)
+	if defined, ok := value.(LoxObject); ok && defined == in.definedModules[name] {
+		in.globals.remove(name)
+	}
+	delete(in.definedModules, name)
+}
+
+// RegisterModule registers a module with the default interpreter, see
+// Interpreter.RegisterModule.
+func RegisterModule(name string, members map[string]NativeFunction) error {
+	return defaultInterpreter.RegisterModule(name, members)
+}
+
+// RegisterModule makes members available to the scripts interpreted
+// afterwards as the fields of the global object name, e.g. name.member().
+// An error is returned if a native or module called name is already
+// registered.
+func (in *Interpreter) RegisterModule(name string, members map[string]NativeFunction) error {
+	if _, ok := in.natives[name]; ok {
+		return fmt.Errorf("native '%s' is already registered", name)
+	}
+	if _, ok := in.nativeModules[name]; ok {
+		return fmt.Errorf("module '%s' is already registered", name)
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("module '%s' has no members", name)
+	}
+
+	module := &nativeModule{members: map[string]registeredNative{}}
+	for member, f := range members {
+		f.name = name + "." + member
+		module.names = append(module.names, member)
+		module.members[member] = registeredNative{f, HostNatives, ""}
+	}
+	sort.Strings(module.names)
+
+	in.nativeModules[name] = module
+	in.defineModule(name)
+	return nil
+}
+
+// RemoveModule removes a module of the default interpreter, see
+// Interpreter.RemoveModule.
+func RemoveModule(name string) error {
+	return defaultInterpreter.RemoveModule(name)
+}
+
+// RemoveModule removes the registered module name, scripts
+// interpreted afterwards can no longer call its members.
+func (in *Interpreter) RemoveModule(name string) error {
+	if _, ok := in.nativeModules[name]; !ok {
+		return fmt.Errorf("no module '%s' is registered", name)
+	}
+
+	delete(in.nativeModules, name)
+	in.undefineModule(name)
+	return nil
+}
+
+// moduleNatives describes the members of the registered modules,
+// named like they are called, e.g. math.sqrt
+func (in *Interpreter) moduleNatives() []NativeInfo {
+	var infos []NativeInfo
+	for name, module := range in.nativeModules {
+		for _, member := range module.names {
+			native := module.members[member]
+			infos = append(infos, NativeInfo{
+				Name:     name + "." + member,
+				Group:    native.group,
+				Doc:      native.doc,
+				Arity:    native.function.paramLen,
+				Variadic: native.function.variadic,
+				Enabled:  in.groupEnabled(native.group),
+			})
+		}
+	}
+	return infos
+}
@@ -0,0 +1,283 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"sort"
+)
+
+// The natives are kept in a registry and defined in the global
+// environment every time a script is interpreted, so a script redefining
+// a native does not affect the scripts interpreted after it. Every
+// interpreter has its own registry, starting out with the standard
+// natives. Every native belongs to a group, the natives of a disabled
+// group are not defined.
+
+// the groups of natives
+const (
+	// always defined
+	CoreNatives = "core"
+	// disabled with SetMathNatives
+	MathNatives = "math"
+	// disabled with SetFileNatives
+	FileNatives = "files"
+	// only defined in the dialect where print is not a statement
+	PrintNatives = "print"
+	// registered with RegisterNative, always defined
+	HostNatives = "host"
+)
+
+type registeredNative struct {
+	function NativeFunction
+	group    string
+	// a one line description starting with a call such as len(value)
+	doc string
+}
+
+// the natives every registry starts out with
+var standardNatives = map[string]registeredNative{}
+
+func init() {
+	standard := []struct {
+		name     string
+		group    string
+		function NativeFunction
+		doc      string
+	}{
+		{"type", CoreNatives, typeFunc, "type(value) returns the type of value"},
+		{"clock", CoreNatives, clockFunc, "clock() returns the seconds since the Unix epoch"},
+		{"now", CoreNatives, nowFunc, "now() returns the milliseconds since the Unix epoch"},
+		{"sleep", CoreNatives, sleepFunc, "sleep(seconds) pauses the script for seconds"},
+		{"formatTime", CoreNatives, formatTimeFunc, "formatTime(millis, layout) formats the timestamp millis like the Go layout, RFC 3339 by default"},
+		{"parseTime", CoreNatives, parseTimeFunc, "parseTime(s, layout) returns the timestamp s formats like the Go layout, RFC 3339 by default"},
+		{"len", CoreNatives, lenFunc, "len(value) returns the length of a string, list, map or set"},
+		{"format", CoreNatives, formatFunc, "format(template, values...) replaces the %-placeholders of template by values"},
+		{"println", CoreNatives, printlnFunc, "println(values...) writes values separated by spaces and a line break"},
+		{"readLine", CoreNatives, readLineFunc, "readLine() returns the next line of input, nil once it is exhausted"},
+		{"env", CoreNatives, envFunc, "env(name) returns the environment variable name, nil if it is not set"},
+
+		{"sbNew", CoreNatives, sbNewFunc, "sbNew() creates a new empty string builder"},
+		{"sbAppend", CoreNatives, sbAppendFunc, "sbAppend(sb, value) appends value to sb and returns sb"},
+		{"sbToString", CoreNatives, sbToStringFunc, "sbToString(sb) returns the contents of sb as a string"},
+		{"buffer", CoreNatives, sbNewFunc, "buffer() creates a new empty string builder"},
+		{"append", CoreNatives, appendFunc, "append(sb, values...) appends values to sb and returns sb"},
+		{"toString", CoreNatives, toStringFunc, "toString(value) returns the string representation of value, the contents of a string builder"},
+		{"substring", CoreNatives, substringFunc, "substring(s, start, end) returns the characters of s from start up to end"},
+		{"toUpper", CoreNatives, toUpperFunc, "toUpper(s) returns s mapped to upper case"},
+		{"toLower", CoreNatives, toLowerFunc, "toLower(s) returns s mapped to lower case"},
+		{"split", CoreNatives, splitFunc, "split(s, sep) returns the substrings of s separated by sep"},
+		{"contains", CoreNatives, containsFunc, "contains(s, substr) reports whether substr is within s"},
+
+		{"push", CoreNatives, pushFunc, "push(list, value) appends value to list"},
+		{"pop", CoreNatives, popFunc, "pop(list) removes and returns the last element of list"},
+		{"slice", CoreNatives, sliceFunc, "slice(list, start, end) returns the elements of list from start up to end"},
+		{"sort", CoreNatives, sortFunc, "sort(iterable) sorts a list in place, other iterables into a new list"},
+		{"sortBy", CoreNatives, sortByFunc, "sortBy(list, compare) sorts list in place using compare(a, b)"},
+
+		{"keys", CoreNatives, keysFunc, "keys(map) returns the keys of map in insertion order"},
+		{"values", CoreNatives, valuesFunc, "values(map) returns the values of map in insertion order"},
+		{"has", CoreNatives, hasFunc, "has(map, key) reports whether map contains key"},
+		{"remove", CoreNatives, removeFunc, "remove(map, key) removes key from map and returns its value"},
+
+		{"set", CoreNatives, setFunc, "set(iterable) returns a new set of the elements of iterable"},
+		{"setAdd", CoreNatives, setAddFunc, "setAdd(set, value) adds value to set"},
+		{"setHas", CoreNatives, setHasFunc, "setHas(set, value) reports whether value is a member of set"},
+		{"setRemove", CoreNatives, setRemoveFunc, "setRemove(set, value) removes value from set"},
+		{"union", CoreNatives, unionFunc, "union(a, b) returns the members of both a and b"},
+		{"intersect", CoreNatives, intersectFunc, "intersect(a, b) returns the members of a which are members of b"},
+		{"difference", CoreNatives, differenceFunc, "difference(a, b) returns the members of a which are not members of b"},
+
+		{"freeze", CoreNatives, freezeFunc, "freeze(value) makes a list, map or set immutable and returns it"},
+		{"isFrozen", CoreNatives, isFrozenFunc, "isFrozen(value) reports whether value is frozen"},
+		{"copy", CoreNatives, copyFunc, "copy(value) returns a shallow copy of a list, map or set"},
+		{"deepCopy", CoreNatives, deepCopyFunc, "deepCopy(value) returns a deep copy of a list, map or set"},
+
+		{"forEach", CoreNatives, forEachFunc, "forEach(iterable, fn) calls fn with every element of iterable"},
+		{"map", CoreNatives, mapFunc, "map(iterable, fn) returns the results of calling fn with every element"},
+		{"filter", CoreNatives, filterFunc, "filter(iterable, fn) returns the elements for which fn returns a truthy value"},
+		{"join", CoreNatives, joinFunc, "join(iterable, sep) returns the elements of iterable separated by sep"},
+		{"next", CoreNatives, nextFunc, "next(generator) returns the next value of generator, nil once exhausted"},
+		{"parallel", CoreNatives, parallelFunc, "parallel(iterable, fn, workers) calls fn with every element on workers goroutines, each with its own copy of the globals, and returns the results in order"},
+
+		{"send", CoreNatives, sendFunc, "send(topic, value) sends value to the host over topic"},
+		{"receive", CoreNatives, receiveFunc, "receive(topic) returns the next value the host sends over topic"},
+		{"breakpoint", CoreNatives, breakpointFunc, "breakpoint() pauses the debugger at the call, it does nothing unless debugging"},
+		{"method", CoreNatives, methodFunc, "method(value, name) returns the method called name of a foreign value"},
+
+		{"abs", MathNatives, absFunc, "abs(x) returns the absolute value of x"},
+		{"floor", MathNatives, floorFunc, "floor(x) returns the greatest integer not greater than x"},
+		{"ceil", MathNatives, ceilFunc, "ceil(x) returns the least integer not less than x"},
+		{"round", MathNatives, roundFunc, "round(x) returns x rounded to the nearest integer, halves away from zero"},
+		{"sqrt", MathNatives, sqrtFunc, "sqrt(x) returns the square root of x"},
+		{"pow", MathNatives, powFunc, "pow(x, y) returns x to the power of y"},
+		{"min", MathNatives, minFunc, "min(x, y) returns the smaller of x and y"},
+		{"max", MathNatives, maxFunc, "max(x, y) returns the larger of x and y"},
+		{"random", MathNatives, randomFunc, "random() returns a random number in [0, 1)"},
+		{"randomInt", MathNatives, randomIntFunc, "randomInt(low, high) returns a random integer in [low, high)"},
+		{"seed", MathNatives, seedFunc, "seed(n) seeds random and randomInt, which return the same numbers after every seed(n)"},
+
+		{"readFile", FileNatives, readFileFunc, "readFile(path) returns the contents of the file at path"},
+		{"writeFile", FileNatives, writeFileFunc, "writeFile(path, contents) replaces the contents of the file at path"},
+		{"appendFile", FileNatives, appendFileFunc, "appendFile(path, contents) appends contents to the file at path"},
+
+		{"print", PrintNatives, printFunc, "print(values...) writes values separated by spaces"},
+	}
+
+	for _, native := range standard {
+		native.function.name = native.name
+		standardNatives[native.name] = registeredNative{native.function, native.group, native.doc}
+	}
+	registerStandardModules()
+	defaultInterpreter = NewInterpreter()
+}
+
+func (in *Interpreter) groupEnabled(group string) bool {
+	switch group {
+	case MathNatives:
+		return in.mathEnabled
+	case FileNatives:
+		return in.filesEnabled
+	case PrintNatives:
+		return in.dialect == token.NATIVE_PRINT
+	}
+	return true
+}
+
+// defineNatives defines the natives of the enabled groups. The natives of
+// a disabled group are removed again, unless a script has redefined them,
+// since the global environment outlives each script.
+func (in *Interpreter) defineNatives() {
+	for name, native := range in.natives {
+		if in.groupEnabled(native.group) {
+			in.globals.Define(name, in.bind(native.function))
+		} else {
+			in.undefineNative(name)
+		}
+	}
+	in.defineModules()
+	in.defineArguments()
+}
+
+// bind returns f defined in in, calling f uses the state of in
+func (in *Interpreter) bind(f NativeFunction) NativeFunction {
+	f.interp = in
+	return f
+}
+
+// undefineNative removes the native name from the global
+// environment unless a script has redefined it
+func (in *Interpreter) undefineNative(name string) {
+	value, _ := in.globals.Lookup(name)
+	if defined, ok := value.(NativeFunction); ok && defined.name == name {
+		in.globals.remove(name)
+	}
+}
+
+// NewNativeFunction creates a native function taking arity arguments.
+func NewNativeFunction(arity int, fn func([]LoxValue) (LoxValue, error)) NativeFunction {
+	return NativeFunction{paramLen: arity, Function: fn}
+}
+
+// RegisterNative registers a native with the default interpreter, see
+// Interpreter.RegisterNative.
+func RegisterNative(name string, doc string, f NativeFunction) error {
+	return defaultInterpreter.RegisterNative(name, doc, f)
+}
+
+// RegisterNative makes f available to the scripts interpreted afterwards
+// as the global name, documented by doc. An error is returned if a native
+// called name is already registered, see OverrideNative.
+func (in *Interpreter) RegisterNative(name string, doc string, f NativeFunction) error {
+	if _, ok := in.natives[name]; ok {
+		return fmt.Errorf("native '%s' is already registered", name)
+	}
+	if _, ok := in.nativeModules[name]; ok {
+		return fmt.Errorf("module '%s' is already registered", name)
+	}
+
+	f.name = name
+	in.natives[name] = registeredNative{f, HostNatives, doc}
+	in.globals.Define(name, in.bind(f))
+	return nil
+}
+
+// OverrideNative overrides a native of the default interpreter, see
+// Interpreter.OverrideNative.
+func OverrideNative(name string, f NativeFunction) error {
+	return defaultInterpreter.OverrideNative(name, f)
+}
+
+// OverrideNative replaces the registered native name by f, which
+// keeps the group and documentation of the native it replaces.
+func (in *Interpreter) OverrideNative(name string, f NativeFunction) error {
+	native, ok := in.natives[name]
+	if !ok {
+		return fmt.Errorf("no native '%s' is registered", name)
+	}
+
+	f.name = name
+	native.function = f
+	in.natives[name] = native
+	if in.groupEnabled(native.group) {
+		in.globals.Define(name, in.bind(f))
+	}
+	return nil
+}
+
+// RemoveNative removes a native of the default interpreter, see
+// Interpreter.RemoveNative.
+func RemoveNative(name string) error {
+	return defaultInterpreter.RemoveNative(name)
+}
+
+// RemoveNative removes the registered native name, scripts
+// interpreted afterwards can no longer call it.
+func (in *Interpreter) RemoveNative(name string) error {
+	if _, ok := in.natives[name]; !ok {
+		return fmt.Errorf("no native '%s' is registered", name)
+	}
+
+	delete(in.natives, name)
+	in.undefineNative(name)
+	return nil
+}
+
+// NativeInfo describes a registered native.
+type NativeInfo struct {
+	Name  string
+	Group string
+	Doc   string
+	Arity int
+	// variadic natives take at least Arity arguments
+	Variadic bool
+	// whether the group of the native is enabled
+	Enabled bool
+}
+
+// Natives lists the natives of the default interpreter, see
+// Interpreter.Natives.
+func Natives() []NativeInfo {
+	return defaultInterpreter.Natives()
+}
+
+// Natives lists the registered natives, including the members of the
+// modules named like math.sqrt, sorted by name.
+func (in *Interpreter) Natives() []NativeInfo {
+	infos := make([]NativeInfo, 0, len(in.natives))
+	for name, native := range in.natives {
+		infos = append(infos, NativeInfo{
+			Name:     name,
+			Group:    native.group,
+			Doc:      native.doc,
+			Arity:    native.function.paramLen,
+			Variadic: native.function.variadic,
+			Enabled:  in.groupEnabled(native.group),
+		})
+	}
+	infos = append(infos, in.moduleNatives()...)
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
@@ -0,0 +1,112 @@
+package ast
+
+import "github.com/LucazFFz/lox/internal/token"
+
+// StmtToken returns a token locating s in the source, preferably the
+// leftmost token of the statement. Not every node stores its tokens, the
+// zero token is returned if no token can be found.
+func StmtToken(s Stmt) token.Token {
+	switch s := s.(type) {
+	case ExpressionStmt:
+		return ExprToken(s.Expr)
+	case PrintStmt:
+		if s.Keyword.Line != 0 {
+			return s.Keyword
+		}
+		return ExprToken(s.Expr)
+	case VarStmt:
+		return s.Name
+	case BlockStmt:
+		for _, stmt := range s.Statements {
+			if tok := StmtToken(stmt); tok.Line != 0 {
+				return tok
+			}
+		}
+	case IfStmt:
+		if tok := ExprToken(s.Condition); tok.Line != 0 {
+			return tok
+		}
+		return StmtToken(s.ThenBranch)
+	case WhileStmt:
+		if s.Keyword.Line != 0 {
+			return s.Keyword
+		}
+		// the condition of for (;;) is a literal without a token
+		if tok := ExprToken(s.Condition); tok.Line != 0 {
+			return tok
+		}
+		return StmtToken(s.Body)
+	case ForInStmt:
+		return s.Name
+	case BreakStmt:
+		return s.Keyword
+	case ContinueStmt:
+		return s.Keyword
+	case ReturnStmt:
+		return s.Keyword
+	case AssertStmt:
+		return s.Keyword
+	case ImportStmt:
+		return s.Keyword
+	case YieldStmt:
+		return s.Keyword
+	case FunctionStmt:
+		return s.Name
+	}
+
+	return token.Token{}
+}
+
+// ExprToken returns a token locating e in the source, see StmtToken.
+func ExprToken(e Expr) token.Token {
+	// the leftmost operand if it has a token, otherwise fallback
+	leftmost := func(operand Expr, fallback token.Token) token.Token {
+		if tok := ExprToken(operand); tok.Line != 0 {
+			return tok
+		}
+		return fallback
+	}
+
+	switch e := e.(type) {
+	case BinaryExpr:
+		return leftmost(e.Left, e.Op)
+	case GroupingExpr:
+		return ExprToken(e.Expr)
+	case LiteralExpr:
+		return e.Token
+	case VariableExpr:
+		return e.Name
+	case UnaryExpr:
+		return e.Op
+	case PrefixExpr:
+		return e.Op
+	case PostfixExpr:
+		return leftmost(e.Target, e.Op)
+	case TernaryExpr:
+		return ExprToken(e.Condition)
+	case AssignExpr:
+		return e.Name
+	case ListExpr:
+		return e.Bracket
+	case MapExpr:
+		return e.Brace
+	case ObjectExpr:
+		return e.Keyword
+	case IndexExpr:
+		return leftmost(e.Object, e.Bracket)
+	case SliceExpr:
+		return leftmost(e.Object, e.Bracket)
+	case IndexAssignExpr:
+		return leftmost(e.Object, e.Bracket)
+	case SliceAssignExpr:
+		return leftmost(e.Object, e.Bracket)
+	case GetExpr:
+		return leftmost(e.Object, e.Name)
+	case SetExpr:
+		return leftmost(e.Object, e.Name)
+	case CallExpr:
+		return leftmost(e.Callee, e.Paren)
+	}
+
+	return token.Token{}
+}
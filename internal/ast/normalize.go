@@ -0,0 +1,96 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+)
+
+// Normalized output makes scripts print the same bytes on every platform,
+// so golden files recorded on one platform match on the others. Numbers
+// are formatted by strconv and maps and sets iterate in insertion order,
+// which is the same everywhere already. What differs are the line endings
+// of strings read from files and the input, and the errors of the
+// operating system, which are worded differently and use its separator
+// in paths.
+
+// SetNormalizedOutput enables or disables normalized output of the
+// default interpreter, see Interpreter.SetNormalizedOutput.
+func SetNormalizedOutput(enabled bool) {
+	defaultInterpreter.SetNormalizedOutput(enabled)
+}
+
+// SetNormalizedOutput enables or disables normalized output. Errors of
+// the operating system are reported with the same wording and forward
+// slashes in paths on every platform, see NormalizeLineEndings for the
+// line endings.
+func (in *Interpreter) SetNormalizedOutput(enabled bool) {
+	in.normalized = enabled
+}
+
+// osError returns the message of err, an error of the operating system,
+// worded the same on every platform if output is normalized
+func (in *Interpreter) osError(err error) string {
+	var pathErr *fs.PathError
+	if !in.normalized || !errors.As(err, &pathErr) {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s %s: %s", pathErr.Op, filepath.ToSlash(pathErr.Path), in.osReason(err))
+}
+
+// osReason returns why the operation failing with err, an error of the
+// operating system, failed, leaving out the operation and path
+func (in *Interpreter) osReason(err error) string {
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) {
+		return err.Error()
+	}
+	if !in.normalized {
+		return pathErr.Err.Error()
+	}
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "no such file or directory"
+	case errors.Is(err, fs.ErrPermission):
+		return "permission denied"
+	case errors.Is(err, fs.ErrExist):
+		return "file already exists"
+	}
+	return pathErr.Err.Error()
+}
+
+// lineEndingWriter replaces "\r\n" and lone "\r" line breaks by "\n"
+type lineEndingWriter struct {
+	w io.Writer
+	// whether the last byte written was a '\r', the '\n'
+	// following it in the next write is dropped
+	afterCR bool
+}
+
+// NormalizeLineEndings returns a writer writing to w with every "\r\n"
+// and lone "\r" line break replaced by "\n".
+func NormalizeLineEndings(w io.Writer) io.Writer {
+	return &lineEndingWriter{w: w}
+}
+
+func (l *lineEndingWriter) Write(p []byte) (int, error) {
+	normalized := make([]byte, 0, len(p))
+	for _, b := range p {
+		switch {
+		case b == '\n' && l.afterCR:
+		case b == '\r':
+			normalized = append(normalized, '\n')
+		default:
+			normalized = append(normalized, b)
+		}
+		l.afterCR = b == '\r'
+	}
+
+	if _, err := l.w.Write(normalized); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,23 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"io"
+	"testing"
+)
+
+func TestNormalizeLineEndings(t *testing.T) {
+	var out bytes.Buffer
+	w := ast.NormalizeLineEndings(&out)
+	// the "\r\n" is split across writes
+	for _, s := range []string{"a\r\nb\r", "\nc\rd\r", "\r\n"} {
+		if _, err := io.WriteString(w, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := "a\nb\nc\nd\n\n"; out.String() != want {
+		t.Errorf("expected %q but got %q", want, out.String())
+	}
+}
@@ -0,0 +1,72 @@
+package ast
+
+import (
+	"strings"
+)
+
+// LoxObject is a record of named fields created by an object literal,
+// e.g. object { x: 1, y: 2 }. Fields are read and written with the dot
+// operator, assigning a field the object does not have adds it. Like
+// lists, objects have reference semantics and are only equal to
+// themselves.
+type LoxObject struct {
+	o *object
+}
+
+type object struct {
+	// the field names in the order they were added
+	names  []string
+	fields map[string]LoxValue
+}
+
+func NewLoxObject() LoxObject {
+	return LoxObject{o: &object{fields: make(map[string]LoxValue)}}
+}
+
+func AsObject(v LoxValue) LoxObject {
+	if v, ok := v.(LoxObject); ok {
+		return v
+	}
+	panic("Cannot convert non-object to object")
+}
+
+// Get returns the value of the field name, ok is false if there is no such field.
+func (v LoxObject) Get(name string) (value LoxValue, ok bool) {
+	value, ok = v.o.fields[name]
+	return value, ok
+}
+
+// Set sets the field name to value, adding the field if it does not exist.
+func (v LoxObject) Set(name string, value LoxValue) {
+	if _, ok := v.o.fields[name]; !ok {
+		v.o.names = append(v.o.names, name)
+	}
+	v.o.fields[name] = value
+}
+
+// Fields returns the names of the fields in the order they were added.
+func (v LoxObject) Fields() []string {
+	return v.o.names
+}
+
+func objectToString(v LoxObject) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("object {")
+	for i, name := range v.o.names {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString(" " + name + ": ")
+
+		str, err := elementToString(v.o.fields[name])
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(str)
+	}
+	if len(v.o.names) > 0 {
+		builder.WriteString(" ")
+	}
+	builder.WriteString("}")
+	return builder.String(), nil
+}
@@ -0,0 +1,41 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"os"
+	"testing"
+)
+
+func TestObjects(t *testing.T) {
+	var out bytes.Buffer
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	err := interpret(t, `
+var p = object { x: 1, y: "a" };
+print p;
+print p.x;
+p.x = p.x + 1;
+p.z = [p.y];
+print p;
+var q = p;
+q.y = "b";
+print p.y;
+print p == q;
+print object { x: 1 } == object { x: 1 };
+print object {};
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "object { x: 1, y: \"a\" }\n1\nobject { x: 2, y: \"a\", z: [\"a\"] }\nb\ntrue\nfalse\nobject {}\n"
+	if out.String() != want {
+		t.Errorf("expected %q but got %q", want, out.String())
+	}
+
+	if err := interpret(t, `object { x: 1 }.y;`); err == nil {
+		t.Error("expected reading an undefined field to fail")
+	}
+}
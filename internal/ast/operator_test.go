@@ -0,0 +1,241 @@
+package ast_test
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/token"
+	"reflect"
+	"testing"
+)
+
+// a sample value of every value type, the operator tests
+// evaluate every operator against every pair of samples
+var samples = []struct {
+	name  string
+	value ast.LoxValue
+}{
+	{"bool", ast.LoxBoolean(true)},
+	{"number", ast.LoxNumber(2)},
+	{"nil", ast.LoxNil{}},
+	{"string", ast.LoxString("s")},
+	{"object", ast.NewLoxObject()},
+	{"function", ast.NativeFunction{}},
+	{"type", ast.LoxType{Typ: ast.NUMBER}},
+	{"builder", ast.LoxStringBuilder{}},
+	{"list", ast.NewLoxList(nil)},
+	{"map", ast.NewLoxMap()},
+	{"set", ast.NewLoxSet()},
+	{"generator", ast.LoxGenerator{}},
+	{"foreign", ast.NewForeignValue(ast.NewForeignType("entity"), 1)},
+}
+
+func TestSamplesCoverTypes(t *testing.T) {
+	covered := map[ast.LoxValueType]bool{}
+	for _, sample := range samples {
+		covered[sample.value.Type()] = true
+	}
+
+	for typ := ast.BOOLEAN; typ <= ast.FOREIGN; typ++ {
+		if !covered[typ] {
+			t.Errorf("no sample of type %s, add one to extend the operator tests", typ)
+		}
+	}
+}
+
+func operatorToken(typ token.TokenType, lexme string) token.Token {
+	return token.Token{Type: typ, Lexme: lexme, Line: 1}
+}
+
+func runtimeError(lexme string, message string) string {
+	return fmt.Sprintf("[1] runtime error at \"%s\" - %s\n", lexme, message)
+}
+
+// checkResult fails unless got and err match the expected result,
+// or the expected error message if wantErr is set
+func checkResult(t *testing.T, name string, got ast.LoxValue, err error, want ast.LoxValue, wantErr string) {
+	t.Helper()
+	switch {
+	case wantErr != "" && err == nil:
+		t.Errorf("%s: expected error %q but got %v", name, wantErr, got)
+	case wantErr != "" && err.Error() != wantErr:
+		t.Errorf("%s: expected error %q but got %q", name, wantErr, err.Error())
+	case wantErr == "" && err != nil:
+		t.Errorf("%s: expected %v but got error %q", name, want, err.Error())
+	case wantErr == "" && !reflect.DeepEqual(got, want):
+		t.Errorf("%s: expected %#v but got %#v", name, want, got)
+	}
+}
+
+func TestBinaryOperators(t *testing.T) {
+	same := func(result ast.LoxValue) map[string]ast.LoxValue {
+		results := map[string]ast.LoxValue{}
+		for _, sample := range samples {
+			results[sample.name+" "+sample.name] = result
+		}
+		return results
+	}
+
+	// equal values of the same type, functions are never equal
+	equal := same(ast.LoxBoolean(true))
+	equal["function function"] = ast.LoxBoolean(false)
+	notEqual := same(ast.LoxBoolean(false))
+	notEqual["function function"] = ast.LoxBoolean(true)
+
+	tests := []struct {
+		op    token.TokenType
+		lexme string
+		// the results for operand types, keyed by "left right"
+		results map[string]ast.LoxValue
+		// the result or error for all other operand types
+		otherwise ast.LoxValue
+		err       string
+	}{
+		{token.PLUS, "+", map[string]ast.LoxValue{
+			"number number": ast.LoxNumber(4),
+			"string string": ast.LoxString("ss"),
+		}, nil, "operands must be of same type"},
+		{token.MINUS, "-", map[string]ast.LoxValue{
+			"number number": ast.LoxNumber(0),
+		}, nil, "both operands must be numbers"},
+		{token.STAR, "*", map[string]ast.LoxValue{
+			"number number": ast.LoxNumber(4),
+		}, nil, "both operands must be numbers"},
+		{token.SLASH, "/", map[string]ast.LoxValue{
+			"number number": ast.LoxNumber(1),
+		}, nil, "both operands must be numbers"},
+		{token.GREATER, ">", map[string]ast.LoxValue{
+			"number number": ast.LoxBoolean(false),
+			"string string": ast.LoxBoolean(false),
+		}, nil, "operands must be of same type"},
+		{token.GREATER_EQUAL, ">=", map[string]ast.LoxValue{
+			"number number": ast.LoxBoolean(true),
+			"string string": ast.LoxBoolean(true),
+		}, nil, "operands must be of same type"},
+		{token.LESS, "<", map[string]ast.LoxValue{
+			"number number": ast.LoxBoolean(false),
+			"string string": ast.LoxBoolean(false),
+		}, nil, "operands must be of same type"},
+		{token.LESS_EQUAL, "<=", map[string]ast.LoxValue{
+			"number number": ast.LoxBoolean(true),
+			"string string": ast.LoxBoolean(true),
+		}, nil, "operands must be of same type"},
+		{token.EQUAL_EQUAL, "==", equal, ast.LoxBoolean(false), ""},
+		{token.BANG_EQUAL, "!=", notEqual, ast.LoxBoolean(true), ""},
+	}
+
+	for _, test := range tests {
+		for _, left := range samples {
+			for _, right := range samples {
+				name := fmt.Sprintf("%s %s %s", left.name, test.lexme, right.name)
+				expr := ast.BinaryExpr{
+					Left:  ast.LiteralExpr{Value: left.value},
+					Op:    operatorToken(test.op, test.lexme),
+					Right: ast.LiteralExpr{Value: right.value},
+				}
+				got, err := expr.Evaluate(ast.GlobalEnvironment())
+
+				want, ok := test.results[left.name+" "+right.name]
+				wantErr := ""
+				if !ok {
+					want = test.otherwise
+					if test.err != "" {
+						wantErr = runtimeError(test.lexme, test.err)
+					}
+				}
+				checkResult(t, name, got, err, want, wantErr)
+			}
+		}
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	expr := ast.BinaryExpr{
+		Left:  ast.LiteralExpr{Value: ast.LoxNumber(1)},
+		Op:    operatorToken(token.SLASH, "/"),
+		Right: ast.LiteralExpr{Value: ast.LoxNumber(0)},
+	}
+	got, err := expr.Evaluate(ast.GlobalEnvironment())
+	checkResult(t, "1 / 0", got, err, nil, runtimeError("/", "division by zero"))
+}
+
+func TestLogicalOperators(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		ast.SetStrictBool(strict)
+		for _, left := range samples {
+			for _, right := range samples {
+				// only nil is falsy among the samples, the bool is true
+				falsy := left.name == "nil"
+
+				and := ast.BinaryExpr{
+					Left:  ast.LiteralExpr{Value: left.value},
+					Op:    operatorToken(token.AND, "and"),
+					Right: ast.LiteralExpr{Value: right.value},
+				}
+				or := and
+				or.Op = operatorToken(token.OR, "or")
+
+				wantAnd, wantOr := right.value, left.value
+				if falsy {
+					wantAnd, wantOr = left.value, right.value
+				}
+
+				wantErr := ""
+				if strict && left.name != "bool" {
+					wantErr = fmt.Sprintf("condition must be a boolean but is %s (strict-bool)", left.value.Type())
+				}
+
+				got, err := and.Evaluate(ast.GlobalEnvironment())
+				name := fmt.Sprintf("%s and %s (strict %t)", left.name, right.name, strict)
+				if wantErr != "" {
+					checkResult(t, name, got, err, nil, runtimeError("and", wantErr))
+				} else {
+					checkResult(t, name, got, err, wantAnd, "")
+				}
+
+				got, err = or.Evaluate(ast.GlobalEnvironment())
+				name = fmt.Sprintf("%s or %s (strict %t)", left.name, right.name, strict)
+				if wantErr != "" {
+					checkResult(t, name, got, err, nil, runtimeError("or", wantErr))
+				} else {
+					checkResult(t, name, got, err, wantOr, "")
+				}
+			}
+		}
+	}
+	ast.SetStrictBool(false)
+}
+
+func TestUnaryOperators(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		ast.SetStrictBool(strict)
+		for _, operand := range samples {
+			negate := ast.UnaryExpr{
+				Op:    operatorToken(token.MINUS, "-"),
+				Right: ast.LiteralExpr{Value: operand.value},
+			}
+			got, err := negate.Evaluate(ast.GlobalEnvironment())
+			if operand.name == "number" {
+				checkResult(t, "-number", got, err, ast.LoxNumber(-2), "")
+			} else {
+				checkResult(t, "-"+operand.name, got, err, nil, runtimeError("-", "operand must be a number"))
+			}
+
+			not := ast.UnaryExpr{
+				Op:    operatorToken(token.BANG, "!"),
+				Right: ast.LiteralExpr{Value: operand.value},
+			}
+			got, err = not.Evaluate(ast.GlobalEnvironment())
+			name := fmt.Sprintf("!%s (strict %t)", operand.name, strict)
+			switch {
+			case strict && operand.name != "bool":
+				checkResult(t, name, got, err, nil, runtimeError("!",
+					fmt.Sprintf("condition must be a boolean but is %s (strict-bool)", operand.value.Type())))
+			case operand.name == "nil":
+				checkResult(t, name, got, err, ast.LoxBoolean(true), "")
+			default:
+				checkResult(t, name, got, err, ast.LoxBoolean(false), "")
+			}
+		}
+	}
+	ast.SetStrictBool(false)
+}
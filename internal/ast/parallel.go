@@ -0,0 +1,273 @@
+package ast
+
+import (
+	"bufio"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"maps"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Every worker of the parallel native runs in its own interpreter, forked
+// from the interpreter calling parallel. The callback and its arguments
+// are copied into the worker together with every environment and value
+// reachable from them, so workers never share mutable state: a worker
+// assigning a captured variable or modifying a list only changes its own
+// copy. The workers share the output of the interpreter and stop with
+// it, they do not read its input, record or replay calls to external
+// natives or pause in the debugger.
+
+// parallel(iterable, fn, workers) calls fn with every element of iterable
+// on a pool of workers goroutines and returns a list of the results in the
+// order of the elements. Every worker has its own copy of the globals and
+// of the variables captured by fn. If any call fails the error of the
+// first failing element is returned.
+var parallelFunc = NativeFunction{
+	paramLen: 3,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		fn, err := asCallback(args[1], 1, "parallel")
+		if err != nil {
+			return nil, err
+		}
+
+		workers, err := asInteger(args[2])
+		if err != nil || workers < 1 {
+			return nil, NewRuntimeError(token.Token{}, "parallel expects a positive number of workers")
+		}
+
+		elements := []LoxValue{}
+		err = iterate(args[0], func(v LoxValue) error {
+			elements = append(elements, v)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// the workers are forked before any of them runs,
+		// so they all start from the same state
+		output := &lockedWriter{w: in.output}
+		forks := make([]*fork, min(workers, len(elements)))
+		for i := range forks {
+			forks[i] = in.fork(output)
+		}
+
+		results := make([]LoxValue, len(elements))
+		errs := make([]error, len(elements))
+		indices := make(chan int)
+
+		var wg sync.WaitGroup
+		for _, f := range forks {
+			callback := f.value(fn).(Callable)
+			arguments := make([]LoxValue, len(elements))
+			for i, element := range elements {
+				arguments[i] = f.value(element)
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range indices {
+					results[i], errs[i] = callback.Call([]LoxValue{arguments[i]})
+				}
+			}()
+		}
+
+		for i := range elements {
+			indices <- i
+		}
+		close(indices)
+		wg.Wait()
+		return parallelResults(results, errs)
+	},
+}
+
+// parallelResults returns results as a list, or the
+// error of the first failing element if there is one
+func parallelResults(results []LoxValue, errs []error) (LoxValue, error) {
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewLoxList(results), nil
+}
+
+// lockedWriter serializes the writes of the workers to a shared writer
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// fork is a worker interpreter forked from the interpreter parent
+type fork struct {
+	parent *Interpreter
+	worker *Interpreter
+	// the copies of the environments and of the values
+	// with reference semantics, keyed like hashKey
+	envs   map[*Environment]*Environment
+	values map[any]LoxValue
+	memos  map[*memo]*memo
+}
+
+// fork returns a worker interpreter with the settings of in and a copy
+// of its globals, writing to output. The source of the random natives
+// of the worker is seeded by in, so seeded scripts stay reproducible.
+func (in *Interpreter) fork(output io.Writer) *fork {
+	worker := &Interpreter{
+		interrupt:      in.interrupt,
+		output:         output,
+		errOutput:      in.errOutput,
+		random:         rand.New(rand.NewSource(in.random.Int63())),
+		dialect:        in.dialect,
+		strictBool:     in.strictBool,
+		memoSize:       in.memoSize,
+		maxCallDepth:   in.maxCallDepth,
+		fuel:           in.fuel,
+		fuelLeft:       in.fuelLeft,
+		maxIterations:  in.maxIterations,
+		loopCounts:     make(map[sourcePos]*LoopCount),
+		slowStatements: make(map[sourcePos]*SlowStatement),
+		debug:          debugState{breakpoints: make(map[int]bool)},
+		input:          bufio.NewReader(strings.NewReader("")),
+		filesEnabled:   in.filesEnabled,
+		mathEnabled:    in.mathEnabled,
+		normalized:     in.normalized,
+		natives:        maps.Clone(in.natives),
+		nativeModules:  maps.Clone(in.nativeModules),
+		arguments:      in.arguments,
+		definedModules: map[string]LoxObject{},
+		moduleLoader:   in.moduleLoader,
+		moduleDir:      in.moduleDir,
+		modules:        map[string]*module{},
+		importStack:    slices.Clone(in.importStack),
+	}
+	// a call counts towards the depth of the call to parallel
+	worker.callStack = slices.Clone(in.callStack)
+
+	in.topicsMu.Lock()
+	worker.topics = maps.Clone(in.topics)
+	in.topicsMu.Unlock()
+
+	f := &fork{parent: in, worker: worker, envs: map[*Environment]*Environment{}, values: map[any]LoxValue{}, memos: map[*memo]*memo{}}
+	worker.globals = f.env(in.globals)
+	for path, m := range in.modules {
+		if m.loaded {
+			worker.modules[path] = &module{env: f.env(m.env), loaded: true}
+		}
+	}
+	return f
+}
+
+// env returns the copy of e in the worker
+func (f *fork) env(e *Environment) *Environment {
+	if e == nil {
+		return nil
+	}
+	if copied, ok := f.envs[e]; ok {
+		return copied
+	}
+
+	copied := &Environment{
+		names:      slices.Clone(e.names),
+		values:     make([]LoxValue, len(e.values)),
+		index:      maps.Clone(e.index),
+		allocation: trackEnvironment(),
+		toplevel:   e.toplevel,
+		path:       e.path,
+		resolution: e.resolution,
+		interp:     f.worker,
+	}
+	f.envs[e] = copied
+
+	copied.enclosing = f.env(e.enclosing)
+	for i, value := range e.values {
+		copied.values[i] = f.value(value)
+	}
+	for _, imported := range e.imports {
+		copied.imports = append(copied.imports, f.env(imported))
+	}
+	return copied
+}
+
+// value returns the copy of v in the worker. Values without reference
+// semantics are returned as they are, so are generators and foreign
+// values which cannot be copied.
+func (f *fork) value(v LoxValue) LoxValue {
+	switch v := v.(type) {
+	case LoxFunction:
+		v.Closure = f.env(v.Closure)
+		v.allocation = trackFunction()
+		if v.memo != nil {
+			// the copies of a function share their memo like the originals
+			if _, ok := f.memos[v.memo]; !ok {
+				f.memos[v.memo] = &memo{}
+			}
+			v.memo = f.memos[v.memo]
+		}
+		return v
+	case NativeFunction:
+		if v.interp == f.parent {
+			v.interp = f.worker
+		}
+		return v
+	case LoxStringBuilder, LoxObject, LoxList, LoxMap, LoxSet:
+	default:
+		return v
+	}
+
+	identity, _ := hashKey(v)
+	if copied, ok := f.values[identity]; ok {
+		return copied
+	}
+
+	switch v := v.(type) {
+	case LoxStringBuilder:
+		builder := &strings.Builder{}
+		builder.WriteString(v.builder.String())
+		f.values[identity] = LoxStringBuilder{builder: builder}
+	case LoxObject:
+		copied := LoxObject{o: &object{names: slices.Clone(v.o.names), fields: make(map[string]LoxValue, len(v.o.fields))}}
+		f.values[identity] = copied
+		for name, field := range v.o.fields {
+			copied.o.fields[name] = f.value(field)
+		}
+	case LoxList:
+		copied := LoxList{l: &list{frozen: v.l.frozen, elements: make([]LoxValue, len(v.l.elements))}}
+		f.values[identity] = copied
+		for i, element := range v.l.elements {
+			copied.l.elements[i] = f.value(element)
+		}
+	case LoxMap:
+		m := LoxMap{m: &loxMap{index: make(map[any]int, len(v.m.entries)), kind: v.m.kind}}
+		f.values[identity] = m
+		f.copyEntries(v.m, m.m)
+	case LoxSet:
+		set := LoxSet{members: LoxMap{m: &loxMap{index: make(map[any]int, v.Len()), kind: v.members.m.kind}}}
+		f.values[identity] = set
+		f.copyEntries(v.members.m, set.members.m)
+	}
+	return f.values[identity]
+}
+
+// copyEntries copies the entries of from into the empty map to
+func (f *fork) copyEntries(from *loxMap, to *loxMap) {
+	for _, entry := range from.entries {
+		key := f.value(entry.key)
+		// keys are hashable, copied keys are hashable as well
+		hash, _ := hashKey(key)
+		to.index[hash] = len(to.entries)
+		to.entries = append(to.entries, mapEntry{key, f.value(entry.value)})
+	}
+	to.frozen = from.frozen
+}
@@ -0,0 +1,62 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParallel(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`print parallel([1, 2, 3, 4, 5], fun(x) { return x * x; }, 3);`, "[1, 4, 9, 16, 25]\n"},
+		{`print parallel([], fun(x) { return x; }, 2);`, "[]\n"},
+		// workers fork interpreters of their own
+		{`print parallel([1, 2], fun(x) { return parallel([x, x + 10], fun(y) { return y; }, 2); }, 2);`, "[[1, 11], [2, 12]]\n"},
+		// workers change their own copies of the captured variables and values
+		{`var n = 0; var l = [1]; parallel([1, 2, 3], fun(x) { n = n + x; l[0] = x; return n; }, 3); print n; print l;`, "0\n[1]\n"},
+		{`var l = [1, 2]; print parallel([l, l], fun(x) { x[0] = x[0] + 10; return l[0]; }, 1); print l;`, "[11, 21]\n[1, 2]\n"},
+		{`var r = parallel([1, 2, 3, 4], fun(x) { print "x"; return random(); }, 4); print len(r);`, "x\nx\nx\nx\n4\n"},
+		{`var total = 0; fun add(x) { total = total + x; return total; } print parallel([1, 2, 3, 4], add, 1);`, "[1, 3, 6, 10]\n"},
+	}
+
+	for _, test := range tests {
+		var out strings.Builder
+		ast.SetOutput(&out)
+		done := make(chan error)
+		go func() { done <- interpret(t, test.source) }()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%s: %v", test.source, err)
+			} else if out.String() != test.want {
+				t.Errorf("%s: expected %q but got %q", test.source, test.want, out.String())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: did not return", test.source)
+		}
+	}
+	ast.SetOutput(os.Stdout)
+}
+
+func TestParallelError(t *testing.T) {
+	source := `parallel([1, 2, 3], fun(x) { if (x >= 2) assert false, "failed " + str(x); return x; }, 2);`
+	tokens, _ := scan.Scan(source, func(err error) { t.Error(err) }, scan.ScanContext{})
+	stmts, err := parse.Parse(tokens, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	ast.Interpret(stmts, nil, func(err error) { errs = append(errs, err) })
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "failed 2") {
+		t.Errorf("expected the error of the first failing element but got %v", errs)
+	}
+}
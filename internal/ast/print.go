@@ -0,0 +1,133 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"strconv"
+	"strings"
+)
+
+// print(values...) writes values separated by spaces, only defined
+// in the dialect where print is not a statement
+var printFunc = NativeFunction{
+	paramLen: 0,
+	variadic: true,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		return in.writeValues(args, "")
+	},
+}
+
+// println(values...) writes values separated by spaces and a line break
+var printlnFunc = NativeFunction{
+	paramLen: 0,
+	variadic: true,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		return in.writeValues(args, "\n")
+	},
+}
+
+func (in *Interpreter) writeValues(values []LoxValue, end string) (LoxValue, error) {
+	strs := make([]string, len(values))
+	for i, value := range values {
+		str, err := valueToString(value)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = str
+	}
+
+	fmt.Fprint(in.output, strings.Join(strs, " ")+end)
+	return LoxNil{}, nil
+}
+
+// format(template, values...) returns template with its placeholders
+// replaced by values in order:
+//
+//	%v, %s  the value as print shows it
+//	%d      an integer
+//	%f      a number, %.2f with two decimals
+//	%%      a percent sign
+var formatFunc = NativeFunction{
+	paramLen: 1,
+	variadic: true,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isString(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "format expects a string template")
+		}
+
+		str, err := format(AsString(args[0]), args[1:])
+		if err != nil {
+			return nil, err
+		}
+		return LoxString(str), nil
+	},
+}
+
+func format(template string, values []LoxValue) (string, error) {
+	var builder strings.Builder
+	next := 0
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' {
+			builder.WriteByte(template[i])
+			continue
+		}
+
+		// the optional precision of %f, -1 if omitted
+		precision := -1
+		begin, j := i, i+1
+		if j < len(template) && template[j] == '.' {
+			start := j + 1
+			for j = start; j < len(template) && template[j] >= '0' && template[j] <= '9'; j++ {
+			}
+			if j == start {
+				return "", NewRuntimeError(token.Token{}, "expected digits after '%.' in format")
+			}
+			precision, _ = strconv.Atoi(template[start:j])
+		}
+
+		if j == len(template) {
+			return "", NewRuntimeError(token.Token{}, "format ends with an incomplete placeholder")
+		}
+		verb := template[j]
+		i = j
+
+		if verb == '%' && precision == -1 {
+			builder.WriteByte('%')
+			continue
+		}
+
+		if next == len(values) {
+			return "", NewRuntimeError(token.Token{},
+				fmt.Sprintf("format has more placeholders than the %d value(s) given", len(values)))
+		}
+		value := values[next]
+		next++
+
+		switch {
+		case (verb == 'v' || verb == 's') && precision == -1:
+			str, err := valueToString(value)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(str)
+		case verb == 'd' && precision == -1:
+			if !isNumber(value) || AsNumber(value) != float64(int64(AsNumber(value))) {
+				return "", NewRuntimeError(token.Token{}, "%d expects an integer")
+			}
+			builder.WriteString(strconv.FormatInt(int64(AsNumber(value)), 10))
+		case verb == 'f':
+			if !isNumber(value) {
+				return "", NewRuntimeError(token.Token{}, "%f expects a number")
+			}
+			builder.WriteString(strconv.FormatFloat(AsNumber(value), 'f', precision, 64))
+		default:
+			return "", NewRuntimeError(token.Token{}, fmt.Sprintf("unknown placeholder '%s' in format", template[begin:j+1]))
+		}
+	}
+
+	if next < len(values) {
+		return "", NewRuntimeError(token.Token{},
+			fmt.Sprintf("format has %d value(s) but only %d placeholder(s)", len(values), next))
+	}
+	return builder.String(), nil
+}
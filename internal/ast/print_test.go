@@ -0,0 +1,116 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/scan"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	var out bytes.Buffer
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	err := interpret(t, `
+print 3;
+print 2.5;
+println("a", 1, [1, 2.5], nil);
+println(format("%s has %d items costing %.2f (%v%%)", "cart", 3, 9.5, 10));
+println();
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "3\n2.5\na 1 [1, 2.5] nil\ncart has 3 items costing 9.50 (10%)\n\n"
+	if out.String() != want {
+		t.Errorf("expected %q but got %q", want, out.String())
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []string{
+		`format("%d", 1.5);`,
+		`format("%f", "a");`,
+		`format("%d %d", 1);`,
+		`format("%d", 1, 2);`,
+		`format("%x", 1);`,
+		`format("%.f", 1);`,
+		`format("100%", 1);`,
+		`format(1);`,
+	}
+
+	for _, source := range tests {
+		if err := interpret(t, source); err == nil {
+			t.Errorf("expected %s to fail", source)
+		}
+	}
+}
+
+func TestNumberRoundTrip(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{3, "3"},
+		{-2.5, "-2.5"},
+		{0.1, "0.1"},
+		{1e9, "1000000000"},
+		{123456789012, "123456789012"},
+		{1e21, "1e+21"},
+		{1.5e-7, "1.5e-07"},
+		{0.000001, "0.000001"},
+		{1.0 / 3, "0.3333333333333333"},
+		{0, "0"},
+	}
+
+	for _, test := range tests {
+		printed := ast.LoxNumber(test.value).DebugPrint()
+		if printed != test.want {
+			t.Errorf("expected %v to print as %s but got %s", test.value, test.want, printed)
+		}
+
+		// negative numbers are negated literals
+		tokens, _ := scan.Scan(strings.TrimPrefix(printed, "-"), func(err error) { t.Error(err) }, scan.ScanContext{})
+		if len(tokens) != 2 || tokens[0].Literal != math.Abs(test.value) {
+			t.Errorf("expected %s to scan as %v but got %v", printed, test.value, tokens)
+		}
+	}
+}
+
+func TestPrintQuotedStrings(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "commas stay inside the string",
+			source: `print ["a, b", "c"]; print set(["a, b"]);`,
+			want:   "[\"a, b\", \"c\"]\nset(\"a, b\")\n",
+		},
+		{
+			name:   "backslashes and line breaks are escaped",
+			source: "print [\"a\\\\b\", \"line\nbreak\"]; print {\"tab\tkey\": \"\"};",
+			want:   "[\"a\\\\\\\\b\", \"line\\nbreak\"]\n{\"tab\\tkey\": \"\"}\n",
+		},
+		{
+			name:   "object fields",
+			source: `print object { s: "é" };`,
+			want:   "object { s: \"é\" }\n",
+		},
+		{
+			name:   "strings on their own are printed as is",
+			source: `print "a, b";`,
+			want:   "a, b\n",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, "")
+	}
+}
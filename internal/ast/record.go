@@ -0,0 +1,219 @@
+package ast
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"sort"
+)
+
+// Natives interacting with the world outside the script, such as clock
+// and the topic natives, are external. Calls to external natives can be
+// recorded and later replayed, replaying returns the recorded results
+// without touching the outside world so a script runs the same way every
+// time, e.g. in golden tests.
+//
+// Recordings are written as one JSON object per call:
+//
+//	{"native":"clock","args":[],"result":1718000000.5}
+
+type nativeCall struct {
+	Native string `json:"native"`
+	Args   []any  `json:"args"`
+	Result any    `json:"result,omitempty"`
+	// the message of the runtime error returned by the call, if any
+	Error string `json:"error,omitempty"`
+}
+
+// recordState is the recording or replay of an interpreter
+type recordState struct {
+	recorder *json.Encoder
+	// the recorded calls not yet replayed
+	replay    []nativeCall
+	replaying bool
+}
+
+// RecordNatives records the calls the default interpreter makes to
+// external natives to w, nil stops recording.
+func RecordNatives(w io.Writer) {
+	r := &defaultInterpreter.record
+	r.recorder = nil
+	if w != nil {
+		r.recorder = json.NewEncoder(w)
+	}
+}
+
+// ReplayNatives replays the calls recorded in r, calls the default
+// interpreter makes to external natives return the recorded results.
+// Calls which differ from the recording in order, native or arguments
+// fail with a runtime error.
+func ReplayNatives(r io.Reader) error {
+	var calls []nativeCall
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var call nativeCall
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			return fmt.Errorf("invalid recording at line %d: %w", line, err)
+		}
+		calls = append(calls, call)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	record := &defaultInterpreter.record
+	record.replay, record.replaying = calls, true
+	return nil
+}
+
+// StopReplay makes the external natives called by the default
+// interpreter touch the outside world again.
+func StopReplay() {
+	record := &defaultInterpreter.record
+	record.replay, record.replaying = nil, false
+}
+
+// callExternal calls the external native f, recording or replaying the call
+func callExternal(f NativeFunction, site token.Token, arguments []LoxValue) (LoxValue, error) {
+	r := &f.interpreter().record
+	if !r.replaying && r.recorder == nil {
+		return f.call(site, arguments)
+	}
+
+	args := make([]any, len(arguments))
+	for i, arg := range arguments {
+		var err error
+		if args[i], err = recordedValue(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.replaying {
+		return r.replayCall(f, args)
+	}
+
+	result, err := f.call(site, arguments)
+	recorded := nativeCall{Native: f.name, Args: args}
+	if err != nil {
+		runtimeErr, ok := err.(RuntimeError)
+		if !ok {
+			// control flow such as a stack overflow is not recorded
+			return result, err
+		}
+		recorded.Error = runtimeErr.message
+	} else if recorded.Result, err = recordedValue(result); err != nil {
+		return nil, err
+	}
+
+	if err := r.recorder.Encode(recorded); err != nil {
+		return nil, NewRuntimeError(token.Token{}, "cannot record call to "+f.name+": "+err.Error())
+	}
+	return result, err
+}
+
+func (r *recordState) replayCall(f NativeFunction, args []any) (LoxValue, error) {
+	if len(r.replay) == 0 {
+		return nil, NewRuntimeError(token.Token{},
+			fmt.Sprintf("replay diverged: %s was called after the recording ended", f.name))
+	}
+
+	call := r.replay[0]
+	r.replay = r.replay[1:]
+	if call.Native != f.name {
+		return nil, NewRuntimeError(token.Token{},
+			fmt.Sprintf("replay diverged: expected a call to %s but %s was called", call.Native, f.name))
+	}
+
+	// compare the arguments as they would be recorded
+	if marshaled(args) != marshaled(call.Args) {
+		return nil, NewRuntimeError(token.Token{},
+			fmt.Sprintf("replay diverged: %s was called with other arguments than recorded", f.name))
+	}
+
+	if call.Error != "" {
+		return nil, NewRuntimeError(token.Token{}, call.Error)
+	}
+	return replayedValue(call.Result), nil
+}
+
+func marshaled(v any) string {
+	text, _ := json.Marshal(v)
+	return string(text)
+}
+
+// recordedValue converts v to its JSON equivalent, only
+// primitives, lists and maps with string keys can be recorded
+func recordedValue(v LoxValue) (any, error) {
+	switch v.Type() {
+	case NIL:
+		return nil, nil
+	case BOOLEAN, NUMBER, STRING:
+		return literalValue(v), nil
+	case LIST:
+		elements := make([]any, AsList(v).Len())
+		for i, element := range AsList(v).Elements() {
+			var err error
+			if elements[i], err = recordedValue(element); err != nil {
+				return nil, err
+			}
+		}
+		return elements, nil
+	case MAP:
+		entries := map[string]any{}
+		m := AsMap(v)
+		for _, key := range m.Keys() {
+			if !isString(key) {
+				return nil, NewRuntimeError(token.Token{}, "cannot record maps with keys other than strings")
+			}
+
+			value, _ := m.Get(key)
+			recorded, err := recordedValue(value)
+			if err != nil {
+				return nil, err
+			}
+			entries[AsString(key)] = recorded
+		}
+		return entries, nil
+	}
+
+	return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("cannot record values of type %s", v.Type()))
+}
+
+// replayedValue is the inverse of recordedValue
+func replayedValue(v any) LoxValue {
+	switch v := v.(type) {
+	case bool:
+		return LoxBoolean(v)
+	case float64:
+		return LoxNumber(v)
+	case string:
+		return LoxString(v)
+	case []any:
+		elements := make([]LoxValue, len(v))
+		for i, element := range v {
+			elements[i] = replayedValue(element)
+		}
+		return NewLoxList(elements)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		m := NewLoxMap()
+		for _, key := range keys {
+			m.Set(LoxString(key), replayedValue(v[key]))
+		}
+		return m
+	}
+
+	return LoxNil{}
+}
@@ -0,0 +1,96 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	source := `print str(clock()) + " " + str(random()) + " " + str(randomInt(1, 1000)) + " " + readLine();`
+	var out, recording bytes.Buffer
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+	defer ast.SetInput(os.Stdin)
+
+	ast.SetInput(strings.NewReader("hello\n"))
+	ast.RecordNatives(&recording)
+	err := interpret(t, source)
+	ast.RecordNatives(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(recording.String(), "\n"); lines != 4 {
+		t.Fatalf("expected 4 recorded calls but got %q", recording.String())
+	}
+
+	// the replay neither reads the input nor the clock
+	recorded := out.String()
+	out.Reset()
+	ast.SetInput(strings.NewReader(""))
+	if err := ast.ReplayNatives(bytes.NewReader(recording.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	err = interpret(t, source)
+	ast.StopReplay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != recorded {
+		t.Errorf("expected the replay to print %q but got %q", recorded, out.String())
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		recording string
+		source    string
+		want      string
+	}{
+		{
+			name:      "truncated",
+			recording: `{"native":"clock","args":[],"result":1}`,
+			source:    `clock(); clock();`,
+			want:      "replay diverged: clock was called after the recording ended",
+		},
+		{
+			name:      "other native",
+			recording: `{"native":"random","args":[],"result":0.5}`,
+			source:    `clock();`,
+			want:      "replay diverged: expected a call to random but clock was called",
+		},
+		{
+			name:      "other arguments",
+			recording: `{"native":"randomInt","args":[1,5],"result":3}`,
+			source:    `randomInt(1, 10);`,
+			want:      "replay diverged: randomInt was called with other arguments than recorded",
+		},
+		{
+			name:      "recorded error",
+			recording: `{"native":"readFile","args":["missing.txt"],"error":"cannot read missing.txt"}`,
+			source:    `readFile("missing.txt");`,
+			want:      "cannot read missing.txt",
+		},
+	}
+
+	for _, test := range tests {
+		if err := ast.ReplayNatives(strings.NewReader(test.recording + "\n")); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var reported []error
+		ast.Interpret(parseScript(t, test.source), nil, func(err error) { reported = append(reported, err) })
+		ast.StopReplay()
+		if len(reported) != 1 || !strings.Contains(reported[0].Error(), test.want) {
+			t.Errorf("%s: expected the error %q but got %v", test.name, test.want, reported)
+		}
+	}
+
+	if err := ast.ReplayNatives(strings.NewReader("{\"native\":\"clock\"}\n{\"native\":")); err == nil ||
+		!strings.Contains(err.Error(), "invalid recording at line 2") {
+		t.Errorf("expected the truncated line to be rejected but got %v", err)
+	}
+	ast.StopReplay()
+}
@@ -0,0 +1,33 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+)
+
+// Resolution holds the bindings the resolver found for the variables of
+// a program. It is kept apart from the syntax tree, so the statements of
+// a program can be resolved again and programs resolved at the same time
+// do not share state. A variable is identified by the offset of its name,
+// so a Resolution only applies to the program it was made for.
+type Resolution struct {
+	bindings map[int]Binding
+}
+
+func NewResolution() *Resolution {
+	return &Resolution{bindings: make(map[int]Binding)}
+}
+
+// Bind binds the variable read or assigned as name to binding.
+func (r *Resolution) Bind(name token.Token, binding Binding) {
+	r.bindings[name.Offset] = binding
+}
+
+// Binding returns the binding of the variable read or assigned as name,
+// the zero Binding if it is looked up by name, such as the variables of
+// an unresolved program which has a nil Resolution.
+func (r *Resolution) Binding(name token.Token) Binding {
+	if r == nil {
+		return Binding{}
+	}
+	return r.bindings[name.Offset]
+}
@@ -0,0 +1,188 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+// LoxSet is a mutable collection of distinct values which remembers the
+// order values were first added in. Membership follows the same rules as
+// map keys, two values are the same member exactly when they are equal.
+type LoxSet struct {
+	// members are stored as the keys of a map
+	members LoxMap
+}
+
+func NewLoxSet() LoxSet {
+	members := NewLoxMap()
+	members.m.kind = "set"
+	return LoxSet{members: members}
+}
+
+func (v LoxSet) Type() LoxValueType {
+	return SET
+}
+
+func (v LoxSet) DebugPrint() string {
+	str, _ := valueToString(v)
+	return str
+}
+
+func (v LoxSet) Len() int {
+	return v.members.Len()
+}
+
+func (v LoxSet) Has(value LoxValue) bool {
+	_, ok := v.members.Get(value)
+	return ok
+}
+
+// Add adds value to the set, an error is returned if value is not hashable.
+func (v LoxSet) Add(value LoxValue) error {
+	return v.members.Set(value, LoxNil{})
+}
+
+// Remove removes value from the set and reports whether it was a
+// member, an error is returned if the set is frozen.
+func (v LoxSet) Remove(value LoxValue) (bool, error) {
+	_, ok, err := v.members.Remove(value)
+	return ok, err
+}
+
+func (v LoxSet) Members() []LoxValue {
+	return v.members.Keys()
+}
+
+func isSet(v LoxValue) bool {
+	return v.Type() == SET
+}
+
+func AsSet(v LoxValue) LoxSet {
+	if v, ok := v.(LoxSet); ok {
+		return v
+	}
+	panic("Cannot convert non-set to set")
+}
+
+func setToString(v LoxSet) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("set(")
+	for i, member := range v.Members() {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		str, err := elementToString(member)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(str)
+	}
+	builder.WriteString(")")
+	return builder.String(), nil
+}
+
+// set(iterable) returns a new set containing the elements of iterable
+var setFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		set := NewLoxSet()
+		if err := iterate(args[0], set.Add); err != nil {
+			return nil, err
+		}
+
+		return set, nil
+	},
+}
+
+// setAdd(set, value) adds value to set
+var setAddFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isSet(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "setAdd expects a set")
+		}
+
+		if err := AsSet(args[0]).Add(args[1]); err != nil {
+			return nil, err
+		}
+
+		return LoxNil{}, nil
+	},
+}
+
+// setHas(set, value) reports whether value is a member of set
+var setHasFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isSet(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "setHas expects a set")
+		}
+
+		return LoxBoolean(AsSet(args[0]).Has(args[1])), nil
+	},
+}
+
+// setRemove(set, value) removes value from set and
+// reports whether it was a member
+var setRemoveFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isSet(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "setRemove expects a set")
+		}
+
+		ok, err := AsSet(args[0]).Remove(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return LoxBoolean(ok), nil
+	},
+}
+
+// setOperation returns a native taking two sets which returns a new set
+// with the members of the first set for which keep returns true, followed
+// by the members of the second set if includeSecond is true
+func setOperation(name string, keep func(a, b LoxSet, member LoxValue) bool, includeSecond bool) NativeFunction {
+	return NativeFunction{
+		paramLen: 2,
+		Function: func(args []LoxValue) (LoxValue, error) {
+			if !isSet(args[0]) || !isSet(args[1]) {
+				return nil, NewRuntimeError(token.Token{}, name+" expects two sets")
+			}
+
+			a, b := AsSet(args[0]), AsSet(args[1])
+			result := NewLoxSet()
+			for _, member := range a.Members() {
+				if keep(a, b, member) {
+					// members of a set are always hashable
+					_ = result.Add(member)
+				}
+			}
+
+			if includeSecond {
+				for _, member := range b.Members() {
+					_ = result.Add(member)
+				}
+			}
+
+			return result, nil
+		},
+	}
+}
+
+// union(a, b) returns a new set with the members of both a and b
+var unionFunc = setOperation("union", func(_, _ LoxSet, _ LoxValue) bool {
+	return true
+}, true)
+
+// intersect(a, b) returns a new set with the members of a which are also members of b
+var intersectFunc = setOperation("intersect", func(_, b LoxSet, member LoxValue) bool {
+	return b.Has(member)
+}, false)
+
+// difference(a, b) returns a new set with the members of a which are not members of b
+var differenceFunc = setOperation("difference", func(_, b LoxSet, member LoxValue) bool {
+	return !b.Has(member)
+}, false)
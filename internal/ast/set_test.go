@@ -0,0 +1,48 @@
+package ast_test
+
+import "testing"
+
+func TestSets(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+		// a substring of the runtime error, empty if the source runs
+		err string
+	}{
+		{
+			name:   "duplicates are dropped",
+			source: `var s = set([1, 2, 2, 1.0, 3]); print s; print len(s); print set("abca");`,
+			want:   "set(1, 2, 3)\n3\nset(\"a\", \"b\", \"c\")\n",
+		},
+		{
+			name:   "membership",
+			source: `var s = set([1]); setAdd(s, 2); print setHas(s, 2); print setRemove(s, 1); print setRemove(s, 5); print s;`,
+			want:   "true\ntrue\nfalse\nset(2)\n",
+		},
+		{
+			name:   "operations return new sets",
+			source: `var a = set([1, 2]); var b = set([2, 3]); print union(a, b); print intersect(a, b); print difference(a, b); print a;`,
+			want:   "set(1, 2, 3)\nset(2)\nset(1)\nset(1, 2)\n",
+		},
+		{
+			name:   "iteration in insertion order",
+			source: `for (x in set([3, 1, 3, 2])) print x;`,
+			want:   "3\n1\n2\n",
+		},
+		{
+			name:   "natives expect a set",
+			source: `setAdd([1], 2);`,
+			err:    "setAdd expects a set",
+		},
+		{
+			name:   "operations expect two sets",
+			source: `union(set([1]), [2]);`,
+			err:    "union expects two sets",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, test.err)
+	}
+}
@@ -0,0 +1,61 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"reflect"
+)
+
+var tokenType = reflect.TypeOf(token.Token{})
+
+// Shift returns a copy of stmt whose tokens are moved by offset bytes and
+// lines lines, e.g. to reuse a statement following an edit of the source
+// instead of parsing it again. Tokens without a location stay as they are.
+// The copy must be resolved again, the Resolution of stmt does not apply.
+func Shift(stmt Stmt, offset int, lines int) Stmt {
+	return shiftValue(reflect.ValueOf(&stmt).Elem(), offset, lines).Interface().(Stmt)
+}
+
+// shiftValue copies the nodes, token slices and tokens of v, rather than
+// walking every type of node the copy is made by reflection
+func shiftValue(v reflect.Value, offset int, lines int) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		shifted := reflect.New(v.Type()).Elem()
+		shifted.Set(shiftValue(v.Elem(), offset, lines))
+		return shifted
+	case reflect.Struct:
+		if v.Type() == tokenType {
+			tok := v.Interface().(token.Token)
+			if tok.Line != 0 {
+				tok.Offset += offset
+				tok.Line += lines
+			}
+			return reflect.ValueOf(tok)
+		}
+
+		shifted := reflect.New(v.Type()).Elem()
+		shifted.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			// values in literals keep their unexported fields as they are
+			if field := shifted.Field(i); field.CanSet() {
+				field.Set(shiftValue(v.Field(i), offset, lines))
+			}
+		}
+		return shifted
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		shifted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			shifted.Index(i).Set(shiftValue(v.Index(i), offset, lines))
+		}
+		return shifted
+	}
+
+	// pointers and scalars
+	return v
+}
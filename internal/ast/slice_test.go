@@ -0,0 +1,93 @@
+package ast_test
+
+import "testing"
+
+func TestSlicing(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+		// a substring of the runtime error, empty if the source runs
+		err string
+	}{
+		{
+			name:   "slice of a list",
+			source: `var a = [1, 2, 3, 4]; print a[1:3]; print a[:2]; print a[2:]; print a[:];`,
+			want:   "[2, 3]\n[1, 2]\n[3, 4]\n[1, 2, 3, 4]\n",
+		},
+		{
+			name:   "slice of a string",
+			source: `var s = "hello"; print s[1:3]; print s[:1]; print s[3:];`,
+			want:   "el\nh\nlo\n",
+		},
+		{
+			name:   "slices copy the list",
+			source: `var a = [1, 2, 3]; var b = a[:]; push(b, 4); b[0] = 0; print a; print b;`,
+			want:   "[1, 2, 3]\n[0, 2, 3, 4]\n",
+		},
+		{
+			name:   "negative indices",
+			source: `var a = [1, 2, 3]; print a[-1]; print a[-3]; print "abc"[-2]; a[-1] = 9; print a;`,
+			want:   "3\n1\nb\n[1, 2, 9]\n",
+		},
+		{
+			name:   "negative slice bounds",
+			source: `var a = [1, 2, 3, 4]; print a[-2:]; print a[:-1]; print a[-3:-1]; print "hello"[-3:];`,
+			want:   "[3, 4]\n[1, 2, 3]\n[2, 3]\nllo\n",
+		},
+		{
+			name:   "empty slices",
+			source: `var a = [1, 2, 3]; print a[3:]; print a[1:1]; print "abc"[1:1] == "";`,
+			want:   "[]\n[]\ntrue\n",
+		},
+		{
+			name:   "slice start after the end",
+			source: `var a = [1, 2, 3]; print a[2:1];`,
+			err:    "slice start must not be greater than end",
+		},
+		{
+			name:   "index out of range",
+			source: `var a = [1, 2, 3]; print a[3];`,
+			err:    "index out of range",
+		},
+		{
+			name:   "negative index out of range",
+			source: `var a = [1, 2, 3]; print a[-4];`,
+			err:    "index out of range",
+		},
+		{
+			name:   "slice bound out of range",
+			source: `var a = [1, 2, 3]; print a[1:4];`,
+			err:    "slice bound out of range",
+		},
+		{
+			name:   "negative slice bound out of range",
+			source: `print "abc"[-4:];`,
+			err:    "slice bound out of range",
+		},
+		{
+			name:   "slice assignment replaces the elements",
+			source: `var a = [1, 2, 3, 4]; a[1:3] = [7]; print a;`,
+			want:   "[1, 7, 4]\n",
+		},
+		{
+			name:   "slice assignment grows and inserts",
+			source: `var a = [1, 2]; a[1:1] = [5, 6]; print a; a[:] = [0]; print a; a[1:] = [1, 2]; print a;`,
+			want:   "[1, 5, 6, 2]\n[0]\n[0, 1, 2]\n",
+		},
+		{
+			name:   "slice assignment with negative bounds",
+			source: `var a = [1, 2, 3, 4]; a[-2:] = []; print a;`,
+			want:   "[1, 2]\n",
+		},
+		{
+			name:   "slice assignment out of range",
+			source: `var a = [1, 2]; a[1:5] = [3];`,
+			err:    "slice bound out of range",
+		},
+	}
+
+	for _, test := range tests {
+		expectOutput(t, test.name, test.source, test.want, test.err)
+	}
+}
@@ -0,0 +1,106 @@
+package ast
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// Strings are indexed by character (rune) rather than by byte, so
+// len("åäö") is 3 and "åäö"[1] is "ä". Indices and slice bounds follow
+// the same rules as for lists, see asIndex and sliceBounds.
+
+// len(value) returns the number of characters in a string, the number
+// of elements in a list, the number of entries in a map or the number
+// of members in a set
+var lenFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		switch {
+		case isString(args[0]):
+			return LoxNumber(utf8.RuneCountInString(AsString(args[0]))), nil
+		case isList(args[0]):
+			return LoxNumber(AsList(args[0]).Len()), nil
+		case isMap(args[0]):
+			return LoxNumber(AsMap(args[0]).Len()), nil
+		case isSet(args[0]):
+			return LoxNumber(AsSet(args[0]).Len()), nil
+		}
+
+		return nil, NewRuntimeError(token.Token{}, "len expects a string, list, map or set")
+	},
+}
+
+// substring(s, start, end) returns the characters of s from
+// start up to but not including end, same as s[start:end]
+var substringFunc = NativeFunction{
+	paramLen: 3,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isString(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "substring expects a string")
+		}
+
+		runes := []rune(AsString(args[0]))
+		start, end, err := sliceBounds(args[1], args[2], len(runes))
+		if err != nil {
+			return nil, err
+		}
+
+		return LoxString(runes[start:end]), nil
+	},
+}
+
+// toUpper(s) returns s with all characters mapped to upper case
+var toUpperFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isString(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "toUpper expects a string")
+		}
+
+		return LoxString(strings.ToUpper(AsString(args[0]))), nil
+	},
+}
+
+// toLower(s) returns s with all characters mapped to lower case
+var toLowerFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isString(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "toLower expects a string")
+		}
+
+		return LoxString(strings.ToLower(AsString(args[0]))), nil
+	},
+}
+
+// split(s, sep) returns a list of the substrings of s separated by sep,
+// an empty separator splits s into its characters
+var splitFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isString(args[0]) || !isString(args[1]) {
+			return nil, NewRuntimeError(token.Token{}, "split expects two strings")
+		}
+
+		parts := strings.Split(AsString(args[0]), AsString(args[1]))
+		elements := make([]LoxValue, len(parts))
+		for i, part := range parts {
+			elements[i] = LoxString(part)
+		}
+
+		return NewLoxList(elements), nil
+	},
+}
+
+// contains(s, substr) reports whether substr is within s
+var containsFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isString(args[0]) || !isString(args[1]) {
+			return nil, NewRuntimeError(token.Token{}, "contains expects two strings")
+		}
+
+		return LoxBoolean(strings.Contains(AsString(args[0]), AsString(args[1]))), nil
+	},
+}
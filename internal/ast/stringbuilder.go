@@ -0,0 +1,105 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+// LoxStringBuilder is a mutable string buffer. Building a string by
+// repeatedly evaluating `s = s + piece` copies s every iteration, appending
+// to a builder only copies the appended piece. The sb natives are also
+// available as buffer and append, toString converts builders like any
+// other value.
+type LoxStringBuilder struct {
+	builder *strings.Builder
+}
+
+func (v LoxStringBuilder) Type() LoxValueType {
+	return BUILDER
+}
+
+func (v LoxStringBuilder) DebugPrint() string {
+	return v.builder.String()
+}
+
+func isStringBuilder(v LoxValue) bool {
+	return v.Type() == BUILDER
+}
+
+func AsStringBuilder(v LoxValue) LoxStringBuilder {
+	if v, ok := v.(LoxStringBuilder); ok {
+		return v
+	}
+	panic("Cannot convert non-builder to builder")
+}
+
+// sbNew() creates a new empty string builder
+var sbNewFunc = NativeFunction{
+	paramLen: 0,
+	Function: func(_ []LoxValue) (LoxValue, error) {
+		return LoxStringBuilder{builder: &strings.Builder{}}, nil
+	},
+}
+
+// sbAppend(sb, value) appends the string representation of value
+// to sb and returns sb so calls can be chained
+var sbAppendFunc = NativeFunction{
+	paramLen: 2,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		return appendFunc.Function(args)
+	},
+}
+
+// append(sb, values...) appends the string representation of
+// every value to sb and returns sb so calls can be chained
+var appendFunc = NativeFunction{
+	paramLen: 2,
+	variadic: true,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if err := checkStringBuilder(args[0]); err != nil {
+			return nil, err
+		}
+
+		for _, value := range args[1:] {
+			str, err := valueToString(value)
+			if err != nil {
+				return nil, err
+			}
+			AsStringBuilder(args[0]).builder.WriteString(str)
+		}
+		return args[0], nil
+	},
+}
+
+// sbToString(sb) returns the contents of sb as a string
+var sbToStringFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if err := checkStringBuilder(args[0]); err != nil {
+			return nil, err
+		}
+
+		return LoxString(AsStringBuilder(args[0]).builder.String()), nil
+	},
+}
+
+// toString(value) returns the string representation of value,
+// the contents of a string builder
+var toStringFunc = NativeFunction{
+	paramLen: 1,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		str, err := valueToString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return LoxString(str), nil
+	},
+}
+
+func checkStringBuilder(v LoxValue) error {
+	if !isStringBuilder(v) {
+		return NewRuntimeError(token.Token{}, fmt.Sprintf("expected a string builder but got %s", v.Type()))
+	}
+	return nil
+}
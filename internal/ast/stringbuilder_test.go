@@ -0,0 +1,83 @@
+package ast_test
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"os"
+	"testing"
+)
+
+const concatSource = `
+var s = "";
+for (var i = 0; i < 2000; i = i + 1) {
+	s = s + "piece";
+}
+`
+
+const builderSource = `
+var sb = sbNew();
+for (var i = 0; i < 2000; i = i + 1) {
+	sbAppend(sb, "piece");
+}
+var s = sbToString(sb);
+`
+
+func benchmarkSource(b *testing.B, source string) {
+	report := func(err error) { b.Fatal(err) }
+	tokens, _ := scan.Scan(source, report, scan.ScanContext{})
+	stmts, err := parse.Parse(tokens, report)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ast.Interpret(stmts, nil, report); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStringConcatenation(b *testing.B) {
+	benchmarkSource(b, concatSource)
+}
+
+func BenchmarkStringBuilder(b *testing.B) {
+	benchmarkSource(b, builderSource)
+}
+
+func TestStringBuilder(t *testing.T) {
+	var out bytes.Buffer
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	err := interpret(t, `
+var sb = sbNew();
+sbAppend(sbAppend(sb, "a"), 1);
+print sbToString(sb);
+var b = buffer();
+for (var i = 0; i < 3; i = i + 1) append(b, i, ",");
+print toString(append(b, nil));
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "a1\n0,1,2,nil\n"; out.String() != want {
+		t.Errorf("expected %q but got %q", want, out.String())
+	}
+
+	out.Reset()
+	if err := interpret(t, `print toString(1) + toString([true, nil]) + toString("s");`); err != nil {
+		t.Fatal(err)
+	}
+	if want := "1[true, nil]s\n"; out.String() != want {
+		t.Errorf("expected %q but got %q", want, out.String())
+	}
+
+	if err := interpret(t, `append("a", "b");`); err == nil {
+		t.Error("expected appending to a string to fail")
+	}
+}
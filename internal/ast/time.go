@@ -0,0 +1,95 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"time"
+)
+
+// Timestamps are numbers of milliseconds since the Unix epoch, as returned
+// by now. formatTime and parseTime convert them from and to strings laid
+// out like the reference time of the Go time package, e.g.
+// "2006-01-02 15:04", or RFC 3339 if no layout is given, in UTC.
+
+// now() returns the milliseconds since the Unix epoch
+var nowFunc = NativeFunction{
+	paramLen: 0,
+	external: true,
+	Function: func(_ []LoxValue) (LoxValue, error) {
+		return LoxNumber(time.Now().UnixMilli()), nil
+	},
+}
+
+// sleep(seconds) pauses the script for seconds, it is
+// abandoned once the context set with SetContext is done
+var sleepFunc = NativeFunction{
+	paramLen: 1,
+	external: true,
+	functionIn: func(in *Interpreter, _ token.Token, args []LoxValue) (LoxValue, error) {
+		if !isNumber(args[0]) || AsNumber(args[0]) < 0 {
+			return nil, NewRuntimeError(token.Token{}, "sleep expects a non-negative number of seconds")
+		}
+
+		timer := time.NewTimer(time.Duration(AsNumber(args[0]) * float64(time.Second)))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return LoxNil{}, nil
+		case <-in.interrupt.Done():
+			return nil, in.checkInterrupt()
+		}
+	},
+}
+
+// formatTime(millis, layout) formats the timestamp millis
+var formatTimeFunc = NativeFunction{
+	paramLen: 1,
+	variadic: true,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isNumber(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "formatTime expects a number of milliseconds")
+		}
+		layout, err := timeLayout("formatTime", args)
+		if err != nil {
+			return nil, err
+		}
+
+		return LoxString(time.UnixMilli(int64(AsNumber(args[0]))).UTC().Format(layout)), nil
+	},
+}
+
+// parseTime(s, layout) returns the timestamp s formats, failing
+// with a runtime error if s is not laid out like layout
+var parseTimeFunc = NativeFunction{
+	paramLen: 1,
+	variadic: true,
+	Function: func(args []LoxValue) (LoxValue, error) {
+		if !isString(args[0]) {
+			return nil, NewRuntimeError(token.Token{}, "parseTime expects a string")
+		}
+		layout, err := timeLayout("parseTime", args)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := time.Parse(layout, AsString(args[0]))
+		if err != nil {
+			return nil, NewRuntimeError(token.Token{}, fmt.Sprintf("cannot parse '%s' as a time laid out like '%s'", AsString(args[0]), layout))
+		}
+		return LoxNumber(t.UnixMilli()), nil
+	},
+}
+
+// timeLayout returns the optional layout following the first argument
+// of the native name, RFC 3339 if it is omitted
+func timeLayout(name string, args []LoxValue) (string, error) {
+	switch {
+	case len(args) == 1:
+		return time.RFC3339, nil
+	case len(args) > 2:
+		return "", NewRuntimeError(token.Token{}, fmt.Sprintf("expected at most 2 arguments but got %d", len(args)))
+	case !isString(args[1]):
+		return "", NewRuntimeError(token.Token{}, name+" expects a string layout")
+	}
+	return AsString(args[1]), nil
+}
@@ -0,0 +1,54 @@
+package ast_test
+
+import (
+	"context"
+	"errors"
+	"github.com/LucazFFz/lox/internal/ast"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSleepInterrupted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	ast.SetContext(ctx)
+	defer ast.SetContext(context.Background())
+
+	start := time.Now()
+	err := interpret(t, `sleep(60);`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected sleep to be interrupted but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected sleep to stop once interrupted but it took %v", elapsed)
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	var out strings.Builder
+	ast.SetOutput(&out)
+	defer ast.SetOutput(os.Stdout)
+
+	err := interpret(t, `
+print formatTime(86400000);
+print formatTime(90061000, "2006-01-02 15:04:05");
+print parseTime("1970-01-02T00:00:00Z");
+print parseTime(formatTime(1234000, "2006-01-02 15:04:05"), "2006-01-02 15:04:05");
+print time.formatTime(time.parseTime("2024-02-29T12:00:00+02:00"));
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "1970-01-02T00:00:00Z\n1970-01-02 01:01:01\n86400000\n1234000\n2024-02-29T10:00:00Z\n"
+	if out.String() != want {
+		t.Errorf("expected %q but got %q", want, out.String())
+	}
+
+	if err := interpret(t, `parseTime("yesterday");`); err == nil {
+		t.Error("expected parsing a malformed time to fail")
+	}
+}
@@ -2,7 +2,8 @@ package ast
 
 import (
 	"fmt"
-    "github.com/LucazFFz/lox/internal/token"
+	"github.com/LucazFFz/lox/internal/token"
+	"strconv"
 )
 
 type LoxValue interface {
@@ -19,8 +20,6 @@ type Callable interface {
 //go:generate stringer -type=LoxValueType
 type LoxValueType uint8
 
-type LoxObject struct{}
-
 type LoxBoolean bool
 
 type LoxNumber float64
@@ -34,16 +33,41 @@ type LoxType struct {
 type LoxNil struct{}
 
 type LoxFunction struct {
-	Name       token.Token
-	Parameters []token.Token
-	Body       []Stmt
-    IsAnonymous bool
-	Closure *Environment
+	Name        token.Token
+	Parameters  []token.Token
+	Body        []Stmt
+	IsAnonymous bool
+	// calling a generator function returns a LoxGenerator
+	// instead of running the body
+	IsGenerator bool
+	Closure     *Environment
+	// only set when leak detection is enabled
+	allocation *allocation
+	// the remembered results when memoization is enabled
+	memo *memo
+	// the bindings of the program the function is declared in, which
+	// may not be the one running when it is called
+	resolution *Resolution
 }
 
 type NativeFunction struct {
 	paramLen int
+	// variadic natives take at least paramLen arguments
+	variadic bool
 	Function func([]LoxValue) (LoxValue, error)
+	// used instead of Function by natives which need to
+	// know the token of the call expression invoking them
+	FunctionAt func(site token.Token, args []LoxValue) (LoxValue, error)
+	// used instead of Function by natives which use the
+	// state of the interpreter they are defined in
+	functionIn func(in *Interpreter, site token.Token, args []LoxValue) (LoxValue, error)
+	// the interpreter the native is defined in, nil until it is defined
+	interp *Interpreter
+	// the name the native is registered as
+	name string
+	// set for natives interacting with the world outside
+	// the script, their calls can be recorded and replayed
+	external bool
 }
 
 const (
@@ -54,6 +78,12 @@ const (
 	OBJECT
 	FUNCTION
 	TYPE
+	BUILDER
+	LIST
+	MAP
+	SET
+	GENERATOR
+	FOREIGN
 )
 
 func isBool(v LoxValue) bool {
@@ -76,6 +106,22 @@ func isString(v LoxValue) bool {
 	return v.Type() == STRING
 }
 
+// SetStrictBool sets whether the conditions of the scripts interpreted
+// by the default interpreter must be booleans.
+func SetStrictBool(strict bool) {
+	defaultInterpreter.strictBool = strict
+}
+
+// truthy returns whether the condition v located at tok is truthy,
+// failing if strict booleans are enabled and v is not a boolean
+func (in *Interpreter) truthy(v LoxValue, tok token.Token) (bool, error) {
+	if in.strictBool && v.Type() != BOOLEAN {
+		return false, NewRuntimeError(tok, fmt.Sprintf("condition must be a boolean but is %s (strict-bool)", v.Type()))
+	}
+
+	return isTruthy(v), nil
+}
+
 func isTruthy(v LoxValue) bool {
 	switch v.Type() {
 	case BOOLEAN:
@@ -92,22 +138,44 @@ func valueToString(v LoxValue) (string, error) {
 	case BOOLEAN:
 		return fmt.Sprintf("%t", AsBoolean(v)), nil
 	case NUMBER:
-		return fmt.Sprintf("%.1f", AsNumber(v)), nil
+		return v.(LoxNumber).DebugPrint(), nil
 	case NIL:
 		return "nil", nil
 	case STRING:
 		return fmt.Sprintf("%s", AsString(v)), nil
 	case OBJECT:
-		return "object", nil
+		return objectToString(AsObject(v))
 	case FUNCTION:
-		return "", NewRuntimeError("cannot convert function to string")
+		return v.DebugPrint(), nil
 	case TYPE:
 		return fmt.Sprintf("<class '%s'>", v.(LoxType).Typ.String()), nil
+	case BUILDER:
+		return AsStringBuilder(v).builder.String(), nil
+	case LIST:
+		return listToString(AsList(v))
+	case MAP:
+		return mapToString(AsMap(v))
+	case SET:
+		return setToString(AsSet(v))
+	case GENERATOR:
+		return "generator", nil
+	case FOREIGN:
+		return AsForeign(v).DebugPrint(), nil
 	default:
 		panic("should not reach here")
 	}
 }
 
+// elementToString is the string of v inside a list, map, set or object.
+// Strings are quoted and escaped like Go strings, so ["a, b"] is
+// distinguishable from ["a", "b"] and a string can't end the list early.
+func elementToString(v LoxValue) (string, error) {
+	if isString(v) {
+		return strconv.Quote(AsString(v)), nil
+	}
+	return valueToString(v)
+}
+
 func equals(v1 LoxValue, v2 LoxValue) bool {
 	//    // the value loxValueType nil and the loxType nil are equal
 	//    // for the other types, it makes sense to seperate the
@@ -132,9 +200,21 @@ func equals(v1 LoxValue, v2 LoxValue) bool {
 	case STRING:
 		return AsString(v1) == AsString(v2)
 	case OBJECT:
-		return true
+		return AsObject(v1).o == AsObject(v2).o
 	case TYPE:
 		return v1.(LoxType).Typ == v2.(LoxType).Typ
+	case BUILDER:
+		return AsStringBuilder(v1).builder == AsStringBuilder(v2).builder
+	case LIST:
+		return AsList(v1).l == AsList(v2).l
+	case MAP:
+		return AsMap(v1).m == AsMap(v2).m
+	case SET:
+		return AsSet(v1).members.m == AsSet(v2).members.m
+	case GENERATOR:
+		return AsGenerator(v1).g == AsGenerator(v2).g
+	case FOREIGN:
+		return foreignEquals(AsForeign(v1), AsForeign(v2))
 	default:
 		return false
 	}
@@ -194,7 +274,44 @@ func (v LoxType) Type() LoxValueType {
 }
 
 func (t LoxFunction) Call(arguments []LoxValue) (LoxValue, error) {
+	return t.CallAt(token.Token{}, arguments)
+}
+
+// CallAt calls the function from the call expression at site.
+func (t LoxFunction) CallAt(site token.Token, arguments []LoxValue) (LoxValue, error) {
+	// calls are checked as well as statements, a call evaluates
+	// its arguments before the first statement of the body
+	in := t.Closure.interpreter()
+	if err := in.checkInterrupt(); err != nil {
+		return nil, withToken(err, site)
+	}
+
+	result, remember := in.memoized(t, arguments)
+	if result != nil {
+		return result, nil
+	}
+
+	pop, err := in.pushFrame(t, site)
+	if err != nil {
+		return nil, err
+	}
+	defer pop()
+
+	if t.IsGenerator {
+		return newLoxGenerator(t, arguments), nil
+	}
+
+	result, err = t.execute(arguments)
+	if err == nil && remember != nil {
+		remember(result)
+	}
+	return result, err
+}
+
+// execute runs the body of the function with arguments bound to its parameters
+func (t LoxFunction) execute(arguments []LoxValue) (LoxValue, error) {
 	env := NewEnvironment(t.Closure)
+	env.resolution = t.resolution
 
 	for i, param := range t.Parameters {
 		env.Define(param.Lexme, arguments[i])
@@ -219,21 +336,75 @@ func (t NativeFunction) Type() LoxValueType {
 }
 
 func (t NativeFunction) DebugPrint() string {
-	return ""
+	if t.name == "" {
+		return "<native fn>"
+	}
+	return fmt.Sprintf("<native fn %s>", t.name)
 }
 
 func (t LoxFunction) DebugPrint() string {
-    return ""
+	if t.IsAnonymous {
+		return "<fn>"
+	}
+	return fmt.Sprintf("<fn %s>", t.Name.Lexme)
 }
 
 func (t NativeFunction) Call(arguments []LoxValue) (LoxValue, error) {
-	if len(arguments) != t.Arity() {
-		return nil, NewRuntimeError(fmt.Sprintf("expected %d arguments but got %d", t.Arity(), len(arguments)))
+	return t.CallAt(token.Token{}, arguments)
+}
+
+// CallAt calls the function from the call expression at site.
+func (t NativeFunction) CallAt(site token.Token, arguments []LoxValue) (LoxValue, error) {
+	if !acceptsArguments(t, len(arguments)) {
+		return nil, NewRuntimeError(token.Token{}, arityMessage(t, len(arguments)))
+	}
+
+	if t.external {
+		return callExternal(t, site, arguments)
+	}
+
+	return t.call(site, arguments)
+}
+
+// call calls the function implementing the native
+func (t NativeFunction) call(site token.Token, arguments []LoxValue) (LoxValue, error) {
+	switch {
+	case t.functionIn != nil:
+		return t.functionIn(t.interpreter(), site, arguments)
+	case t.FunctionAt != nil:
+		return t.FunctionAt(site, arguments)
 	}
 
 	return t.Function(arguments)
 }
 
+// interpreter returns the interpreter the native is defined in, the
+// default interpreter if it has not been defined in one
+func (t NativeFunction) interpreter() *Interpreter {
+	if t.interp == nil {
+		return defaultInterpreter
+	}
+	return t.interp
+}
+
+// Arity returns the number of arguments the native takes,
+// the minimum number if it is variadic.
 func (t NativeFunction) Arity() int {
 	return t.paramLen
 }
+
+// acceptsArguments reports whether function can be called with n arguments
+func acceptsArguments(function Callable, n int) bool {
+	if native, ok := function.(NativeFunction); ok && native.variadic {
+		return n >= native.paramLen
+	}
+	return n == function.Arity()
+}
+
+// arityMessage describes calling function with n arguments it does not accept
+func arityMessage(function Callable, n int) string {
+	if native, ok := function.(NativeFunction); ok && native.variadic {
+		return fmt.Sprintf("expected at least %d arguments but got %d", native.paramLen, n)
+	}
+	return fmt.Sprintf("expected %d arguments but got %d", function.Arity(), n)
+}
@@ -0,0 +1,196 @@
+// Code generated by tools/astgen. DO NOT EDIT.
+
+package ast
+
+// ExprVisitor is an operation on expressions, Accept calls the method
+// of the visitor visiting the type of the node.
+type ExprVisitor interface {
+	VisitBinaryExpr(expr BinaryExpr) any
+	VisitGroupingExpr(expr GroupingExpr) any
+	VisitLiteralExpr(expr LiteralExpr) any
+	VisitVariableExpr(expr VariableExpr) any
+	VisitUnaryExpr(expr UnaryExpr) any
+	VisitPrefixExpr(expr PrefixExpr) any
+	VisitPostfixExpr(expr PostfixExpr) any
+	VisitTernaryExpr(expr TernaryExpr) any
+	VisitAssignExpr(expr AssignExpr) any
+	VisitListExpr(expr ListExpr) any
+	VisitMapExpr(expr MapExpr) any
+	VisitObjectExpr(expr ObjectExpr) any
+	VisitIndexExpr(expr IndexExpr) any
+	VisitSliceExpr(expr SliceExpr) any
+	VisitIndexAssignExpr(expr IndexAssignExpr) any
+	VisitSliceAssignExpr(expr SliceAssignExpr) any
+	VisitGetExpr(expr GetExpr) any
+	VisitSetExpr(expr SetExpr) any
+	VisitCallExpr(expr CallExpr) any
+	VisitFunctionExpr(expr FunctionExpr) any
+	VisitNothingExpr(expr NothingExpr) any
+}
+
+type AcceptExpr interface {
+	Accept(v ExprVisitor) any
+}
+
+func (expr BinaryExpr) Accept(v ExprVisitor) any {
+	return v.VisitBinaryExpr(expr)
+}
+
+func (expr GroupingExpr) Accept(v ExprVisitor) any {
+	return v.VisitGroupingExpr(expr)
+}
+
+func (expr LiteralExpr) Accept(v ExprVisitor) any {
+	return v.VisitLiteralExpr(expr)
+}
+
+func (expr VariableExpr) Accept(v ExprVisitor) any {
+	return v.VisitVariableExpr(expr)
+}
+
+func (expr UnaryExpr) Accept(v ExprVisitor) any {
+	return v.VisitUnaryExpr(expr)
+}
+
+func (expr PrefixExpr) Accept(v ExprVisitor) any {
+	return v.VisitPrefixExpr(expr)
+}
+
+func (expr PostfixExpr) Accept(v ExprVisitor) any {
+	return v.VisitPostfixExpr(expr)
+}
+
+func (expr TernaryExpr) Accept(v ExprVisitor) any {
+	return v.VisitTernaryExpr(expr)
+}
+
+func (expr AssignExpr) Accept(v ExprVisitor) any {
+	return v.VisitAssignExpr(expr)
+}
+
+func (expr ListExpr) Accept(v ExprVisitor) any {
+	return v.VisitListExpr(expr)
+}
+
+func (expr MapExpr) Accept(v ExprVisitor) any {
+	return v.VisitMapExpr(expr)
+}
+
+func (expr ObjectExpr) Accept(v ExprVisitor) any {
+	return v.VisitObjectExpr(expr)
+}
+
+func (expr IndexExpr) Accept(v ExprVisitor) any {
+	return v.VisitIndexExpr(expr)
+}
+
+func (expr SliceExpr) Accept(v ExprVisitor) any {
+	return v.VisitSliceExpr(expr)
+}
+
+func (expr IndexAssignExpr) Accept(v ExprVisitor) any {
+	return v.VisitIndexAssignExpr(expr)
+}
+
+func (expr SliceAssignExpr) Accept(v ExprVisitor) any {
+	return v.VisitSliceAssignExpr(expr)
+}
+
+func (expr GetExpr) Accept(v ExprVisitor) any {
+	return v.VisitGetExpr(expr)
+}
+
+func (expr SetExpr) Accept(v ExprVisitor) any {
+	return v.VisitSetExpr(expr)
+}
+
+func (expr CallExpr) Accept(v ExprVisitor) any {
+	return v.VisitCallExpr(expr)
+}
+
+func (expr FunctionExpr) Accept(v ExprVisitor) any {
+	return v.VisitFunctionExpr(expr)
+}
+
+func (expr NothingExpr) Accept(v ExprVisitor) any {
+	return v.VisitNothingExpr(expr)
+}
+
+// StmtVisitor is an operation on statements, Accept calls the method
+// of the visitor visiting the type of the node.
+type StmtVisitor interface {
+	VisitExpressionStmt(stmt ExpressionStmt) any
+	VisitPrintStmt(stmt PrintStmt) any
+	VisitVarStmt(stmt VarStmt) any
+	VisitBlockStmt(stmt BlockStmt) any
+	VisitIfStmt(stmt IfStmt) any
+	VisitWhileStmt(stmt WhileStmt) any
+	VisitForInStmt(stmt ForInStmt) any
+	VisitBreakStmt(stmt BreakStmt) any
+	VisitContinueStmt(stmt ContinueStmt) any
+	VisitAssertStmt(stmt AssertStmt) any
+	VisitImportStmt(stmt ImportStmt) any
+	VisitYieldStmt(stmt YieldStmt) any
+	VisitReturnStmt(stmt ReturnStmt) any
+	VisitFunctionStmt(stmt FunctionStmt) any
+}
+
+type AcceptStmt interface {
+	Accept(v StmtVisitor) any
+}
+
+func (stmt ExpressionStmt) Accept(v StmtVisitor) any {
+	return v.VisitExpressionStmt(stmt)
+}
+
+func (stmt PrintStmt) Accept(v StmtVisitor) any {
+	return v.VisitPrintStmt(stmt)
+}
+
+func (stmt VarStmt) Accept(v StmtVisitor) any {
+	return v.VisitVarStmt(stmt)
+}
+
+func (stmt BlockStmt) Accept(v StmtVisitor) any {
+	return v.VisitBlockStmt(stmt)
+}
+
+func (stmt IfStmt) Accept(v StmtVisitor) any {
+	return v.VisitIfStmt(stmt)
+}
+
+func (stmt WhileStmt) Accept(v StmtVisitor) any {
+	return v.VisitWhileStmt(stmt)
+}
+
+func (stmt ForInStmt) Accept(v StmtVisitor) any {
+	return v.VisitForInStmt(stmt)
+}
+
+func (stmt BreakStmt) Accept(v StmtVisitor) any {
+	return v.VisitBreakStmt(stmt)
+}
+
+func (stmt ContinueStmt) Accept(v StmtVisitor) any {
+	return v.VisitContinueStmt(stmt)
+}
+
+func (stmt AssertStmt) Accept(v StmtVisitor) any {
+	return v.VisitAssertStmt(stmt)
+}
+
+func (stmt ImportStmt) Accept(v StmtVisitor) any {
+	return v.VisitImportStmt(stmt)
+}
+
+func (stmt YieldStmt) Accept(v StmtVisitor) any {
+	return v.VisitYieldStmt(stmt)
+}
+
+func (stmt ReturnStmt) Accept(v StmtVisitor) any {
+	return v.VisitReturnStmt(stmt)
+}
+
+func (stmt FunctionStmt) Accept(v StmtVisitor) any {
+	return v.VisitFunctionStmt(stmt)
+}
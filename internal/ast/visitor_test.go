@@ -0,0 +1,68 @@
+package ast_test
+
+import (
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"testing"
+)
+
+// folder sums the constant numbers printed by a program, the embedded
+// visitors are nil as the program only contains the nodes visited here
+type folder struct {
+	ast.ExprVisitor
+	ast.StmtVisitor
+	sum float64
+}
+
+func (f *folder) VisitPrintStmt(stmt ast.PrintStmt) any {
+	f.sum += stmt.Expr.Accept(f).(float64)
+	return nil
+}
+
+func (f *folder) VisitBlockStmt(stmt ast.BlockStmt) any {
+	for _, stmt := range stmt.Statements {
+		stmt.Accept(f)
+	}
+	return nil
+}
+
+func (f *folder) VisitLiteralExpr(expr ast.LiteralExpr) any {
+	return float64(expr.Value.(ast.LoxNumber))
+}
+
+func (f *folder) VisitGroupingExpr(expr ast.GroupingExpr) any {
+	return expr.Expr.Accept(f)
+}
+
+func (f *folder) VisitUnaryExpr(expr ast.UnaryExpr) any {
+	return -expr.Right.Accept(f).(float64)
+}
+
+func (f *folder) VisitBinaryExpr(expr ast.BinaryExpr) any {
+	left, right := expr.Left.Accept(f).(float64), expr.Right.Accept(f).(float64)
+	switch expr.Op.Type {
+	case token.PLUS:
+		return left + right
+	case token.STAR:
+		return left * right
+	}
+	return left - right
+}
+
+func TestVisitor(t *testing.T) {
+	tokens, _ := scan.Scan("print 1 + 2 * 3; { print -(4 - 1); print 10; }", func(error) {}, scan.ScanContext{})
+	stmts, err := parse.Parse(tokens, func(error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &folder{}
+	for _, stmt := range stmts {
+		stmt.Accept(f)
+	}
+	if f.sum != 14 {
+		t.Errorf("expected the printed numbers to sum to 14 but got %v", f.sum)
+	}
+}
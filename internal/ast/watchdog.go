@@ -0,0 +1,131 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The watchdog times the evaluation of every statement and records the
+// ones exceeding a threshold, so hot or hanging statements can be reported
+// once the script finishes. Enclosing statements (loops, blocks, calls)
+// take at least as long as the statements in them, so a slow statement is
+// usually reported together with the statements enclosing it.
+
+// sourcePos locates a token in the main script or an imported
+// module, offsets alone are ambiguous once modules are imported
+type sourcePos struct {
+	path   string
+	offset int
+}
+
+func posOf(tok token.Token, env *Environment) sourcePos {
+	return sourcePos{env.sourcePath(), tok.Offset}
+}
+
+// comparePos orders tokens by the module they are in,
+// the main script first, and then by their offset
+func comparePos(a sourcePos, b sourcePos) int {
+	if c := strings.Compare(a.path, b.path); c != 0 {
+		return c
+	}
+	return a.offset - b.offset
+}
+
+// SlowStatement is a statement which took longer than the threshold set
+// with WatchStatements. It implements error so it can be reported like
+// other diagnostics.
+type SlowStatement struct {
+	Token token.Token
+	// the absolute path of the module the statement
+	// is in, empty for the main script
+	Path string
+	// the longest single evaluation of the statement
+	Longest time.Duration
+	// the number of evaluations exceeding the threshold
+	Count int
+}
+
+func (s SlowStatement) Error() string {
+	return fmt.Sprintf("[%d] slow statement - took %v (exceeded threshold %d time(s))\n",
+		s.Token.Line, s.Longest.Round(time.Microsecond), s.Count)
+}
+
+// Span returns the byte offset and length of the token of the statement.
+func (s SlowStatement) Span() (int, int) {
+	return s.Token.Offset, len(s.Token.Lexme)
+}
+
+// WatchStatements makes the default interpreter record statements taking
+// longer than threshold to evaluate, zero disables the watchdog.
+func WatchStatements(threshold time.Duration) {
+	defaultInterpreter.slowThreshold = threshold
+}
+
+// SlowStatements returns the statements which exceeded the
+// threshold set with WatchStatements in source order, those of the
+// main script first.
+func SlowStatements() []SlowStatement {
+	slowStatements := defaultInterpreter.slowStatements
+	statements := make([]SlowStatement, 0, len(slowStatements))
+	for _, s := range slowStatements {
+		statements = append(statements, *s)
+	}
+
+	slices.SortFunc(statements, func(a, b SlowStatement) int {
+		return comparePos(sourcePos{a.Path, a.Token.Offset}, sourcePos{b.Path, b.Token.Offset})
+	})
+	return statements
+}
+
+// evaluateStmt evaluates stmt, timing it if the watchdog is enabled
+// and pausing before it if the debugger is enabled. Every statement
+// uses up a statement of fuel if SetFuel limits it.
+func evaluateStmt(stmt Stmt, env *Environment) error {
+	in := env.interpreter()
+	if in.fuel > 0 {
+		if err := in.burnFuel(); err != nil {
+			return withToken(err, StmtToken(stmt))
+		}
+	}
+
+	if d := &in.debug; d.frontEnd != nil {
+		previous := d.current
+		d.current = debugged{StmtToken(stmt), env}
+		defer func() { d.current = previous }()
+
+		if err := in.debugStmt(stmt, env); err != nil {
+			return err
+		}
+	}
+
+	if in.slowThreshold == 0 {
+		return stmt.Evaluate(env)
+	}
+
+	start := time.Now()
+	err := stmt.Evaluate(env)
+	elapsed := time.Since(start)
+	if elapsed <= in.slowThreshold {
+		return err
+	}
+
+	// statements without a token cannot be reported
+	tok := StmtToken(stmt)
+	if tok.Line == 0 {
+		return err
+	}
+
+	pos := posOf(tok, env)
+	slow, ok := in.slowStatements[pos]
+	if !ok {
+		slow = &SlowStatement{Token: tok, Path: pos.path}
+		in.slowStatements[pos] = slow
+	}
+
+	slow.Longest = max(slow.Longest, elapsed)
+	slow.Count++
+	return err
+}
@@ -0,0 +1,115 @@
+// Package diag renders errors produced by the scanner, parser and
+// interpreter together with the line of source they originate from,
+// underlining the offending lexme similar to the diagnostics of
+// compilers such as rustc and clang.
+//
+//	[3] error at "+" - missing right-hand-side operand (term)
+//	   3 | var a = 1 +;
+//	     |           ^
+package diag
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Spanned is implemented by errors which know which part of
+// the source they refer to.
+type Spanned interface {
+	error
+	// Span returns the byte offset and the length of the
+	// offending part of the source.
+	Span() (int, int)
+}
+
+type Renderer struct {
+	source string
+	index  *token.LineIndex
+	out    io.Writer
+}
+
+func NewRenderer(source string, out io.Writer) *Renderer {
+	return &Renderer{source: source, index: token.NewLineIndex(source), out: out}
+}
+
+// Report writes err to the renderers output. If err implements Spanned
+// the offending line is printed below the message with the span underlined.
+// Report has the signature expected by the report callbacks of the
+// scanner, parser and interpreter.
+func (r *Renderer) Report(err error) {
+	fmt.Fprint(r.out, err)
+
+	spanned, ok := err.(Spanned)
+	if !ok {
+		return
+	}
+
+	offset, length := spanned.Span()
+	if offset == len(r.source) && length == 0 {
+		fmt.Fprint(r.out, r.endOfFile())
+		return
+	}
+	fmt.Fprint(r.out, r.snippet(offset, length, ""))
+}
+
+// endOfFile renders errors anchored to the end of the source, such as
+// a missing ';' on the last line. The caret points past the last
+// character rather than at the blank lines following it.
+//
+//	[1] error at end of file - expected ';' after variable declaration
+//	   1 | var a = 1
+//	     |          ^ end of file
+func (r *Renderer) endOfFile() string {
+	end := len(strings.TrimRight(r.source, " \t\r\n"))
+	if end == 0 {
+		return ""
+	}
+	return r.snippet(end, 0, " end of file")
+}
+
+// snippet renders the line containing offset with the length bytes from
+// offset underlined, followed by label
+func (r *Renderer) snippet(offset int, length int, label string) string {
+	if offset < 0 || offset > len(r.source) {
+		return ""
+	}
+
+	line := r.index.Position(offset).Line
+	start := r.index.LineStart(line)
+	text := strings.TrimRight(r.source[start:r.index.LineEnd(line)], "\r")
+
+	// never underline past the end of the line, but always
+	// draw at least one caret so zero-length spans are visible
+	if offset+length > start+len(text) {
+		length = start + len(text) - offset
+	}
+	// carets are drawn per character, not per byte
+	length = utf8.RuneCountInString(r.source[offset : offset+max(length, 0)])
+	if length < 1 {
+		length = 1
+	}
+
+	gutter := fmt.Sprintf("%4d", line)
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s | %s\n", gutter, text)
+	fmt.Fprintf(&builder, "%s | %s%s%s\n",
+		strings.Repeat(" ", len(gutter)),
+		padding(r.source[start:offset]),
+		strings.Repeat("^", length),
+		label)
+	return builder.String()
+}
+
+// padding returns whitespace the width of prefix, keeping tabs as
+// tabs so the caret lines up with the printed source line
+func padding(prefix string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return '\t'
+		}
+		return ' '
+	}, prefix)
+}
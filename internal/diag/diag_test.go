@@ -0,0 +1,45 @@
+package diag_test
+
+import (
+	"errors"
+	"github.com/LucazFFz/lox/internal/diag"
+	"strings"
+	"testing"
+)
+
+// spanned is an error located in the source
+type spanned struct {
+	offset int
+	length int
+}
+
+func (s spanned) Error() string    { return "[1] error\n" }
+func (s spanned) Span() (int, int) { return s.offset, s.length }
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		err    error
+		want   string
+	}{
+		{"without span", "a", errors.New("failed\n"), "failed\n"},
+		{"lexme", "var a = 1 +;", spanned{10, 1}, "[1] error\n   1 | var a = 1 +;\n     |           ^\n"},
+		{"second line", "a;\r\nbc d;", spanned{4, 2}, "[1] error\n   2 | bc d;\n     | ^^\n"},
+		{"tabs", "\tab", spanned{1, 2}, "[1] error\n   1 | \tab\n     | \t^^\n"},
+		{"multibyte", "\"é\" + x", spanned{0, 4}, "[1] error\n   1 | \"é\" + x\n     | ^^^\n"},
+		{"past the end of the line", "ab\ncd", spanned{1, 10}, "[1] error\n   1 | ab\n     |  ^\n"},
+		{"zero length", "ab", spanned{1, 0}, "[1] error\n   1 | ab\n     |  ^\n"},
+		{"end of file", "var a = 1\n\n", spanned{11, 0}, "[1] error\n   1 | var a = 1\n     |          ^ end of file\n"},
+		{"end of empty file", "", spanned{0, 0}, "[1] error\n"},
+		{"out of range", "ab", spanned{10, 1}, "[1] error\n"},
+	}
+
+	for _, test := range tests {
+		var out strings.Builder
+		diag.NewRenderer(test.source, &out).Report(test.err)
+		if out.String() != test.want {
+			t.Errorf("%s: expected %q but got %q", test.name, test.want, out.String())
+		}
+	}
+}
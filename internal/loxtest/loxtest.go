@@ -0,0 +1,201 @@
+// Package loxtest runs Lox scripts annotated with the output and errors
+// they are expected to produce, similar to the test suite of Crafting
+// Interpreters:
+//
+//	print 1 + 2;  // expect: 3
+//	print -"a";   // expect runtime error: operand must be a number
+//	var a = 1 +;  // expect error: missing right-hand-side operand
+//
+// The printed lines are compared to the expected output in order. An
+// expected error must be reported on the line of its comment and contain
+// the expected text. Warnings are not compared.
+package loxtest
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/resolve"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+type Kind uint8
+
+const (
+	// a line printed by the script
+	OUTPUT Kind = iota
+	// an error reported while scanning, parsing or resolving the script
+	ERROR
+	// an error reported while interpreting the script
+	RUNTIME_ERROR
+)
+
+// the comment prefixes of the expectations, longest first
+var prefixes = []struct {
+	prefix string
+	kind   Kind
+}{
+	{"expect runtime error:", RUNTIME_ERROR},
+	{"expect error:", ERROR},
+	{"expect:", OUTPUT},
+}
+
+type Expectation struct {
+	Kind Kind
+	// the line of the comment
+	Line int
+	Text string
+}
+
+// Failure is an expectation the script did not meet, or output
+// or an error the script was not expected to produce.
+type Failure struct {
+	// the line of the expectation or error, 0 if unknown
+	Line    int
+	Message string
+}
+
+func (f Failure) String() string {
+	if f.Line == 0 {
+		return f.Message
+	}
+	return fmt.Sprintf("[%d] %s", f.Line, f.Message)
+}
+
+// Expectations returns the expectations annotated in the comments of
+// source, in the order they are written.
+func Expectations(source string, dialect token.Dialect) []Expectation {
+	// scan errors are reported when the script is run
+	tokens, _ := scan.Scan(source, func(error) {}, scan.ScanContext{Dialect: dialect, IncludeComments: true})
+
+	var expectations []Expectation
+	for _, tok := range tokens {
+		if tok.Type != token.COMMENT || !strings.HasPrefix(tok.Lexme, "//") {
+			continue
+		}
+
+		text := strings.TrimSpace(strings.TrimPrefix(tok.Lexme, "//"))
+		for _, p := range prefixes {
+			if rest, ok := strings.CutPrefix(text, p.prefix); ok {
+				expectations = append(expectations, Expectation{Kind: p.kind, Line: tok.Line, Text: strings.TrimSpace(rest)})
+				break
+			}
+		}
+	}
+	return expectations
+}
+
+// Run runs source and compares what it prints and the errors it reports
+// to its expectations, returning the failures in the order found. The
+// script is interpreted in the global environment like ast.Interpret, with
+// the output of the interpreter redirected while it runs.
+func Run(source string, dialect token.Dialect) []Failure {
+	index := token.NewLineIndex(source)
+	var errs []reported
+	var runtime bool
+	report := func(err error) {
+		if e, ok := err.(resolve.ResolveError); ok && e.Severity == resolve.WARNING {
+			return
+		}
+		errs = append(errs, reported{err: err, line: errorLine(index, err), runtime: runtime})
+	}
+
+	var out bytes.Buffer
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{Dialect: dialect})
+	stmts, err := parse.Parse(tokens, report)
+	if err != nil || scanErr != nil || len(errs) > 0 {
+		return compare(Expectations(source, dialect), out.String(), errs)
+	}
+
+	if resolution, err := resolve.Resolve(stmts, report); err == nil {
+		runtime = true
+		previous := ast.Output()
+		ast.SetOutput(&out)
+		ast.Interpret(stmts, resolution, report)
+		ast.SetOutput(previous)
+	}
+
+	return compare(Expectations(source, dialect), out.String(), errs)
+}
+
+// an error reported by the script
+type reported struct {
+	err     error
+	line    int
+	runtime bool
+	matched bool
+}
+
+// errorLine returns the line err is reported at, 0 if unknown
+func errorLine(index *token.LineIndex, err error) int {
+	spanned, ok := err.(diag.Spanned)
+	if !ok {
+		return 0
+	}
+
+	offset, _ := spanned.Span()
+	if offset < 0 {
+		return 0
+	}
+	return index.Position(offset).Line
+}
+
+func compare(expectations []Expectation, output string, errs []reported) []Failure {
+	var lines []string
+	if output != "" {
+		lines = strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	}
+
+	var failures []Failure
+	printed := 0
+	for _, e := range expectations {
+		if e.Kind == OUTPUT {
+			switch {
+			case printed >= len(lines):
+				failures = append(failures, Failure{e.Line, fmt.Sprintf("expected output %q but got nothing", e.Text)})
+			case strings.TrimSuffix(lines[printed], "\r") != e.Text:
+				failures = append(failures, Failure{e.Line, fmt.Sprintf("expected output %q but got %q", e.Text, lines[printed])})
+			}
+			printed++
+			continue
+		}
+
+		if !matchError(e, errs) {
+			kind := "error"
+			if e.Kind == RUNTIME_ERROR {
+				kind = "runtime error"
+			}
+			failures = append(failures, Failure{e.Line, fmt.Sprintf("expected %s %q", kind, e.Text)})
+		}
+	}
+
+	for ; printed < len(lines); printed++ {
+		failures = append(failures, Failure{0, fmt.Sprintf("unexpected output %q", lines[printed])})
+	}
+	for _, r := range errs {
+		if !r.matched {
+			failures = append(failures, Failure{r.line, "unexpected error " + strings.TrimSpace(r.err.Error())})
+		}
+	}
+	return failures
+}
+
+// matchError marks the first unmatched error meeting e as matched,
+// reporting whether there is one
+func matchError(e Expectation, errs []reported) bool {
+	for i := range errs {
+		r := &errs[i]
+		if r.matched || r.line != e.Line || r.runtime != (e.Kind == RUNTIME_ERROR) {
+			continue
+		}
+		if strings.Contains(r.err.Error(), e.Text) {
+			r.matched = true
+			return true
+		}
+	}
+	return false
+}
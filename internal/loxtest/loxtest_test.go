@@ -0,0 +1,48 @@
+package loxtest_test
+
+import (
+	"github.com/LucazFFz/lox/internal/loxtest"
+	"github.com/LucazFFz/lox/internal/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestScripts runs every script in testdata against its expectations.
+func TestScripts(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, failure := range loxtest.Run(string(source), token.BOOK) {
+				t.Error(failure)
+			}
+		})
+	}
+}
+
+func TestFailures(t *testing.T) {
+	source := `print 1; // expect: 2
+print 3;
+print -"a";
+// expect: 4
+var a = 1; // expect runtime error: undefined
+`
+	want := []loxtest.Failure{
+		{Line: 1, Message: `expected output "2" but got "1"`},
+		{Line: 4, Message: `expected output "4" but got "3"`},
+		{Line: 5, Message: `expected runtime error "undefined"`},
+		{Line: 3, Message: `unexpected error [3] runtime error at "-" - operand must be a number`},
+	}
+	if got := loxtest.Run(source, token.BOOK); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+}
@@ -0,0 +1,223 @@
+package lsp
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/resolve"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"unicode/utf16"
+)
+
+// position is a position in a document, both line and character start at
+// 0 and characters are counted in UTF-16 code units as the protocol demands
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type span struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+// the severities of diagnostics
+const (
+	severityError   = 1
+	severityWarning = 2
+)
+
+type diagnostic struct {
+	Range    span   `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// the kinds of symbols
+const (
+	symbolFunction = 12
+	symbolVariable = 13
+)
+
+type documentSymbol struct {
+	Name           string           `json:"name"`
+	Kind           int              `json:"kind"`
+	Range          span             `json:"range"`
+	SelectionRange span             `json:"selectionRange"`
+	Children       []documentSymbol `json:"children,omitempty"`
+}
+
+type hover struct {
+	Contents struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	} `json:"contents"`
+	Range span `json:"range"`
+}
+
+// analysis is the result of scanning, parsing and resolving a document
+type analysis struct {
+	text  string
+	index *token.LineIndex
+	// the statements of the document, nil if it does not parse
+	stmts       []ast.Stmt
+	diagnostics []diagnostic
+	references  []resolve.Reference
+}
+
+// document is an open document, which is parsed incrementally
+// as only the statements an edit touches need to be parsed again
+type document struct {
+	text   string
+	parser *parse.Incremental
+}
+
+func newDocument(dialect token.Dialect) *document {
+	return &document{parser: parse.NewIncremental(scan.ScanContext{Dialect: dialect}, parse.DefaultParseOptions())}
+}
+
+func (d *document) analyze() *analysis {
+	a := &analysis{text: d.text, index: token.NewLineIndex(d.text), diagnostics: []diagnostic{}}
+
+	stmts, err := d.parser.Parse(d.text, a.report)
+	if err != nil {
+		return a
+	}
+
+	a.stmts = stmts
+	a.references, _ = resolve.References(stmts, a.report)
+	return a
+}
+
+// report records err as a diagnostic of the document
+func (a *analysis) report(err error) {
+	severity := severityError
+	msg := err.Error()
+	switch err := err.(type) {
+	case scan.ScanError:
+		msg = err.Message
+	case parse.ParseError:
+		msg = err.Message
+	case resolve.ResolveError:
+		msg = err.Message
+		if err.Severity == resolve.WARNING {
+			severity = severityWarning
+		}
+	}
+
+	var r span
+	if spanned, ok := err.(diag.Spanned); ok {
+		offset, length := spanned.Span()
+		r = a.span(offset, length)
+	}
+	a.diagnostics = append(a.diagnostics, diagnostic{Range: r, Severity: severity, Source: "lox", Message: msg})
+}
+
+// position converts a byte offset of the document to a position
+func (a *analysis) position(offset int) position {
+	offset = max(0, min(offset, len(a.text)))
+	line := a.index.Position(offset).Line
+	start := a.index.LineStart(line)
+
+	character := 0
+	for _, r := range a.text[start:offset] {
+		character += utf16.RuneLen(r)
+	}
+	return position{Line: line - 1, Character: character}
+}
+
+// offset converts a position to a byte offset of the document, the
+// inverse of position
+func (a *analysis) offset(p position) int {
+	start := a.index.LineStart(p.Line + 1)
+	end := a.index.LineEnd(p.Line + 1)
+
+	character := 0
+	for i, r := range a.text[start:end] {
+		if character >= p.Character {
+			return start + i
+		}
+		character += utf16.RuneLen(r)
+	}
+	return end
+}
+
+func (a *analysis) span(offset int, length int) span {
+	return span{Start: a.position(offset), End: a.position(offset + length)}
+}
+
+func (a *analysis) tokenSpan(tok token.Token) span {
+	return a.span(tok.Offset, len(tok.Lexme))
+}
+
+// symbols returns the functions and variables declared by the document,
+// those declared in a function are children of the function
+func (a *analysis) symbols() []documentSymbol {
+	return a.stmtSymbols(a.stmts)
+}
+
+func (a *analysis) stmtSymbols(stmts []ast.Stmt) []documentSymbol {
+	symbols := []documentSymbol{}
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case ast.FunctionStmt:
+			name := a.tokenSpan(stmt.Name)
+			symbols = append(symbols, documentSymbol{
+				Name:           stmt.Name.Lexme,
+				Kind:           symbolFunction,
+				Range:          span{Start: name.Start, End: a.tokenSpan(stmt.Brace).End},
+				SelectionRange: name,
+				Children:       a.stmtSymbols(stmt.Body),
+			})
+		case ast.VarStmt:
+			name := a.tokenSpan(stmt.Name)
+			symbols = append(symbols, documentSymbol{
+				Name:           stmt.Name.Lexme,
+				Kind:           symbolVariable,
+				Range:          name,
+				SelectionRange: name,
+			})
+		case ast.BlockStmt:
+			symbols = append(symbols, a.stmtSymbols(stmt.Statements)...)
+		}
+	}
+	return symbols
+}
+
+// hover describes the variable referred to by the name at p,
+// nil if there is no name at p
+func (a *analysis) hover(p position) *hover {
+	offset := a.offset(p)
+	for _, ref := range a.references {
+		if offset < ref.Name.Offset || offset >= ref.Name.Offset+len(ref.Name.Lexme) {
+			continue
+		}
+
+		h := &hover{Range: a.tokenSpan(ref.Name)}
+		h.Contents.Kind = "markdown"
+		h.Contents.Value = describe(ref)
+		return h
+	}
+	return nil
+}
+
+// describe describes the variable ref refers to
+func describe(ref resolve.Reference) string {
+	if !ref.Binding.Resolved {
+		return fmt.Sprintf("global variable `%s`", ref.Name.Lexme)
+	}
+
+	scope := "declared in the current scope"
+	switch depth := ref.Binding.Depth; depth {
+	case 0:
+	case 1:
+		scope = "declared 1 scope up"
+	default:
+		scope = fmt.Sprintf("declared %d scopes up", depth)
+	}
+	return fmt.Sprintf("local variable `%s` (line %d)\n\n%s, scope depth %d, slot %d",
+		ref.Name.Lexme, ref.Declaration.Line, scope, ref.Binding.Depth, ref.Binding.Slot)
+}
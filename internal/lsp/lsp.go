@@ -0,0 +1,258 @@
+// Package lsp implements a minimal Language Server Protocol server for
+// Lox, communicating over a stream such as stdio. The server publishes
+// the diagnostics of the scanner, parser and resolver whenever a document
+// is opened or changed, lists the functions and variables declared in a
+// document as its symbols and shows the variable a name refers to on hover.
+//
+// [Language Server Protocol]: https://microsoft.github.io/language-server-protocol/
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// the error codes of JSON-RPC used by the server
+const (
+	parseError     = -32700
+	methodNotFound = -32601
+	invalidParams  = -32602
+)
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type Server struct {
+	in  *textproto.Reader
+	out *bufio.Writer
+	// the open documents by their URI
+	documents map[string]*document
+	shutdown  bool
+	// the dialect the documents are scanned in
+	dialect token.Dialect
+}
+
+// NewServer returns a server reading requests from in and writing
+// responses and notifications to out, the documents are scanned in dialect.
+func NewServer(in io.Reader, out io.Writer, dialect token.Dialect) *Server {
+	return &Server{
+		in:        textproto.NewReader(bufio.NewReader(in)),
+		out:       bufio.NewWriter(out),
+		documents: map[string]*document{},
+		dialect:   dialect,
+	}
+}
+
+// Serve handles messages until the client sends the exit notification or
+// the input ends. An error is returned if the client exits without asking
+// the server to shut down first or the input cannot be read.
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return errors.New("the input ended before the exit notification")
+		}
+		if err != nil {
+			var syntax *json.SyntaxError
+			if !errors.As(err, &syntax) {
+				return err
+			}
+			s.respondError(nil, parseError, err.Error())
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exited without being shut down")
+			}
+			return nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads the next message, which is preceded by
+// headers like an HTTP request
+func (s *Server) read() (message, error) {
+	header, err := s.in.ReadMIMEHeader()
+	if err != nil {
+		return message{}, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return message{}, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in.R, body); err != nil {
+		return message{}, err
+	}
+
+	var msg message
+	err = json.Unmarshal(body, &msg)
+	return msg, err
+}
+
+func (s *Server) write(msg message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(body))
+	s.out.Write(body)
+	return s.out.Flush()
+}
+
+func (s *Server) respond(id json.RawMessage, result any) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.write(message{ID: id, Result: body})
+}
+
+func (s *Server) respondError(id json.RawMessage, code int, msg string) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return s.write(message{ID: id, Error: &responseError{Code: code, Message: msg}})
+}
+
+func (s *Server) notify(method string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(message{Method: method, Params: body})
+}
+
+// handle handles a request or notification, the returned
+// error is the error writing the response
+func (s *Server) handle(msg message) error {
+	isRequest := msg.ID != nil
+
+	switch msg.Method {
+	case "initialize":
+		return s.respond(msg.ID, map[string]any{
+			"capabilities": map[string]any{
+				// the whole text is sent on every change
+				"textDocumentSync":       1,
+				"hoverProvider":          true,
+				"documentSymbolProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "lox"},
+		})
+	case "shutdown":
+		s.shutdown = true
+		return s.respond(msg.ID, nil)
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		return s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   documentID `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if json.Unmarshal(msg.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		changes := params.ContentChanges
+		return s.update(params.TextDocument.URI, changes[len(changes)-1].Text)
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument documentID `json:"textDocument"`
+		}
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", map[string]any{
+			"uri":         params.TextDocument.URI,
+			"diagnostics": []diagnostic{},
+		})
+	case "textDocument/hover":
+		var params positionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.respondError(msg.ID, invalidParams, err.Error())
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok {
+			return s.respond(msg.ID, nil)
+		}
+		return s.respond(msg.ID, doc.analyze().hover(params.Position))
+	case "textDocument/documentSymbol":
+		var params struct {
+			TextDocument documentID `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.respondError(msg.ID, invalidParams, err.Error())
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok {
+			return s.respond(msg.ID, []documentSymbol{})
+		}
+		return s.respond(msg.ID, doc.analyze().symbols())
+	}
+
+	// unknown notifications, such as initialized, are ignored
+	if isRequest {
+		return s.respondError(msg.ID, methodNotFound, "method not found: "+msg.Method)
+	}
+	return nil
+}
+
+// update stores the text of the document at uri and publishes its diagnostics
+func (s *Server) update(uri string, text string) error {
+	doc, ok := s.documents[uri]
+	if !ok {
+		doc = newDocument(s.dialect)
+		s.documents[uri] = doc
+	}
+	doc.text = text
+	return s.notify("textDocument/publishDiagnostics", map[string]any{
+		"uri":         uri,
+		"diagnostics": doc.analyze().diagnostics,
+	})
+}
+
+type documentID struct {
+	URI string `json:"uri"`
+}
+
+type positionParams struct {
+	TextDocument documentID `json:"textDocument"`
+	Position     position   `json:"position"`
+}
@@ -0,0 +1,146 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/lsp"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// frame encodes a message with the headers preceding it
+func frame(t *testing.T, msg map[string]any) string {
+	msg["jsonrpc"] = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// session sends msgs to a server and returns the messages it wrote
+func session(t *testing.T, msgs ...map[string]any) []map[string]any {
+	var in strings.Builder
+	for _, msg := range msgs {
+		in.WriteString(frame(t, msg))
+	}
+
+	var out bytes.Buffer
+	if err := lsp.NewServer(strings.NewReader(in.String()), &out, token.BOOK).Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	var written []map[string]any
+	r := textproto.NewReader(bufio.NewReader(&out))
+	for {
+		header, err := r.ReadMIMEHeader()
+		if err == io.EOF {
+			return written
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r.R, body); err != nil {
+			t.Fatal(err)
+		}
+
+		var msg map[string]any
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		written = append(written, msg)
+	}
+}
+
+func TestSession(t *testing.T) {
+	const uri = "file:///a.lox"
+	source := "var a = 1;\nfun f(x) {\n    var y = x;\n    return y + a;\n}\nprint b +;\n"
+	fixed := strings.Replace(source, "print b +;", "print f(2);", 1)
+	document := map[string]any{"uri": uri}
+	at := func(line, character int) map[string]any {
+		return map[string]any{"textDocument": document, "position": map[string]any{"line": line, "character": character}}
+	}
+
+	written := session(t,
+		map[string]any{"id": 1, "method": "initialize", "params": map[string]any{}},
+		map[string]any{"method": "initialized", "params": map[string]any{}},
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": "lox", "version": 1, "text": source}}},
+		map[string]any{"method": "textDocument/didChange", "params": map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": 2},
+			"contentChanges": []any{map[string]any{"text": fixed}}}},
+		map[string]any{"id": 2, "method": "textDocument/hover", "params": at(3, 11)},
+		map[string]any{"id": 3, "method": "textDocument/hover", "params": at(3, 15)},
+		map[string]any{"id": 4, "method": "textDocument/documentSymbol", "params": map[string]any{"textDocument": document}},
+		map[string]any{"id": 5, "method": "textDocument/definition", "params": at(0, 0)},
+		map[string]any{"id": 6, "method": "shutdown"},
+		map[string]any{"method": "exit"},
+	)
+	if len(written) != 8 {
+		t.Fatalf("expected 8 messages but got %d: %v", len(written), written)
+	}
+
+	get := func(value any, path ...any) any {
+		for _, key := range path {
+			switch key := key.(type) {
+			case string:
+				value = value.(map[string]any)[key]
+			case int:
+				value = value.([]any)[key]
+			}
+		}
+		return value
+	}
+
+	if get(written[0], "result", "capabilities", "hoverProvider") != true {
+		t.Errorf("expected the server to provide hovers but got %v", written[0])
+	}
+
+	opened := get(written[1], "params", "diagnostics").([]any)
+	found := false
+	for _, d := range opened {
+		found = found || strings.Contains(get(d, "message").(string), "missing right-hand-side operand") &&
+			get(d, "range", "start", "line") == 5.0
+	}
+	if !found {
+		t.Errorf("expected the missing operand on line 5 but got %v", opened)
+	}
+	if changed := get(written[2], "params", "diagnostics").([]any); len(changed) != 0 {
+		t.Errorf("expected the fixed document to have no diagnostics but got %v", changed)
+	}
+
+	if hover := get(written[3], "result", "contents", "value").(string); !strings.Contains(hover, "local variable `y` (line 3)") ||
+		!strings.Contains(hover, "scope depth 0") {
+		t.Errorf("expected y to be local but got %q", hover)
+	}
+	if hover := get(written[4], "result", "contents", "value").(string); hover != "global variable `a`" {
+		t.Errorf("expected a to be global but got %q", hover)
+	}
+
+	symbols := get(written[5], "result").([]any)
+	if len(symbols) != 2 || get(symbols[1], "name") != "f" || get(symbols[1], "children", 0, "name") != "y" {
+		t.Errorf("expected the symbols a and f containing y but got %v", symbols)
+	}
+
+	if get(written[6], "error", "code") != -32601.0 {
+		t.Errorf("expected an unknown method to fail but got %v", written[6])
+	}
+	if result, ok := written[7]["result"]; !ok || result != nil {
+		t.Errorf("expected shutdown to return null but got %v", written[7])
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	in := frame(t, map[string]any{"method": "exit"})
+	if err := lsp.NewServer(strings.NewReader(in), io.Discard, token.BOOK).Serve(); err == nil {
+		t.Error("expected exiting without shutting down to fail")
+	}
+}
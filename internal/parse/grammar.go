@@ -0,0 +1,291 @@
+package parse
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The grammar is read from the "Production rules" doc comments of the
+// parser, so the documented grammar is the one exported. Terminals are
+// quoted ("var") or token types in upper case (IDENTIFIER), nonterminals
+// are the names of other rules. The pseudo rule nothing stands for a
+// missing operand, which the parser reports as an error.
+
+//go:embed parse.go
+var parserSource string
+
+// Rule is a production rule of the grammar.
+type Rule struct {
+	Name string `json:"name"`
+	// the right-hand side as written in the parser
+	Definition string `json:"definition"`
+	Expr       *Node  `json:"expr"`
+	// zero for rules which are not operators, lower binds tighter
+	Precedence    int    `json:"precedence,omitempty"`
+	Associativity string `json:"associativity,omitempty"`
+}
+
+// NodeKind is the kind of a node of the right-hand side of a rule.
+type NodeKind string
+
+const (
+	// a quoted literal, such as "var"
+	TERMINAL NodeKind = "terminal"
+	// a token type, such as IDENTIFIER
+	TOKEN       NodeKind = "token"
+	NONTERMINAL NodeKind = "nonterminal"
+	SEQUENCE    NodeKind = "sequence"
+	CHOICE      NodeKind = "choice"
+	// zero or one of the single item
+	OPTIONAL NodeKind = "optional"
+	// zero or more of the single item
+	ZERO_OR_MORE NodeKind = "zeroOrMore"
+	// one or more of the single item
+	ONE_OR_MORE NodeKind = "oneOrMore"
+)
+
+// Node is a node of the right-hand side of a rule, the shape
+// railroad diagrams are drawn from.
+type Node struct {
+	Kind NodeKind `json:"kind"`
+	// the literal, token type or rule name of leaves
+	Value string  `json:"value,omitempty"`
+	Items []*Node `json:"items,omitempty"`
+}
+
+// NothingRule is the name of the pseudo rule standing for a missing operand.
+const NothingRule = "nothing"
+
+// Grammar returns the production rules of the parser in source order.
+func Grammar() ([]Rule, error) {
+	var rules []Rule
+	reading := false
+	for _, line := range strings.Split(parserSource, "\n") {
+		line = strings.TrimSpace(line)
+		// commented out productions are not part of the grammar
+		if !strings.HasPrefix(line, "//") || strings.HasPrefix(line, "// //") {
+			reading = false
+			continue
+		}
+
+		text := strings.TrimSpace(strings.TrimPrefix(line, "//"))
+		if text == "Production rules:" {
+			reading = true
+			continue
+		}
+		if !reading || text == "" {
+			continue
+		}
+
+		text = strings.TrimSpace(strings.TrimPrefix(text, "- "))
+		switch {
+		case strings.HasPrefix(text, "precedence:"):
+			if len(rules) > 0 {
+				// "none" leaves the precedence zero
+				rules[len(rules)-1].Precedence, _ = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(text, "precedence:")))
+			}
+		case strings.HasPrefix(text, "associativity:"):
+			if value := strings.TrimSpace(strings.TrimPrefix(text, "associativity:")); len(rules) > 0 && value != "none" {
+				rules[len(rules)-1].Associativity = value
+			}
+		case strings.Contains(text, "->"):
+			name, definition, _ := strings.Cut(text, "->")
+			rules = append(rules, Rule{Name: strings.TrimSpace(name), Definition: strings.TrimSpace(definition)})
+		case len(rules) > 0:
+			// the definition continues on the next line
+			rules[len(rules)-1].Definition += " " + text
+		}
+	}
+
+	for i := range rules {
+		rules[i].Definition = strings.TrimSuffix(rules[i].Definition, ";")
+		expr, err := parseDefinition(rules[i].Definition)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rules[i].Name, err)
+		}
+		rules[i].Expr = expr
+	}
+	return rules, nil
+}
+
+// WriteEBNF writes rules in the W3C EBNF notation read by
+// railroad diagram generators.
+func WriteEBNF(rules []Rule) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "/* %s stands for a missing operand, which is reported as an error */\n\n", NothingRule)
+	for _, rule := range rules {
+		fmt.Fprintf(&builder, "%s ::= %s\n", rule.Name, ebnf(rule.Expr, false))
+	}
+	return builder.String()
+}
+
+// ebnf returns node in EBNF, grouped if it is part of a larger expression
+func ebnf(node *Node, nested bool) string {
+	switch node.Kind {
+	case TERMINAL:
+		// a quote is written in single quotes
+		if strings.Contains(node.Value, "'") {
+			return `"` + node.Value + `"`
+		}
+		return "'" + node.Value + "'"
+	case TOKEN, NONTERMINAL:
+		return node.Value
+	case OPTIONAL:
+		return ebnf(node.Items[0], true) + "?"
+	case ZERO_OR_MORE:
+		return ebnf(node.Items[0], true) + "*"
+	case ONE_OR_MORE:
+		return ebnf(node.Items[0], true) + "+"
+	}
+
+	separator := " "
+	if node.Kind == CHOICE {
+		separator = " | "
+	}
+	items := make([]string, len(node.Items))
+	for i, item := range node.Items {
+		items[i] = ebnf(item, node.Kind == SEQUENCE && item.Kind == CHOICE)
+	}
+
+	str := strings.Join(items, separator)
+	if nested {
+		return "(" + str + ")"
+	}
+	return str
+}
+
+// definitionParser parses the right-hand side of a rule:
+//
+//	choice   -> sequence ("|" sequence)*;
+//	sequence -> postfix*;
+//	postfix  -> atom ("?" | "*" | "+")*;
+//	atom     -> TERMINAL | NAME | "(" choice ")";
+type definitionParser struct {
+	src string
+	pos int
+}
+
+func parseDefinition(definition string) (*Node, error) {
+	p := &definitionParser{src: definition}
+	node, err := p.choice()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected '%c' in %q", p.src[p.pos], p.src)
+	}
+	return node, nil
+}
+
+func (p *definitionParser) skipSpace() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// next returns the next character without consuming it, 0 at the end
+func (p *definitionParser) next() byte {
+	p.skipSpace()
+	if p.pos == len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *definitionParser) choice() (*Node, error) {
+	var items []*Node
+	for {
+		item, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		if p.next() != '|' {
+			break
+		}
+		p.pos++
+	}
+
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return &Node{Kind: CHOICE, Items: items}, nil
+}
+
+func (p *definitionParser) sequence() (*Node, error) {
+	var items []*Node
+	for c := p.next(); c != 0 && c != '|' && c != ')'; c = p.next() {
+		item, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	switch len(items) {
+	case 0:
+		return nil, fmt.Errorf("empty alternative in %q", p.src)
+	case 1:
+		return items[0], nil
+	}
+	return &Node{Kind: SEQUENCE, Items: items}, nil
+}
+
+func (p *definitionParser) postfix() (*Node, error) {
+	node, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		kind, ok := map[byte]NodeKind{'?': OPTIONAL, '*': ZERO_OR_MORE, '+': ONE_OR_MORE}[p.next()]
+		if !ok {
+			return node, nil
+		}
+		p.pos++
+		node = &Node{Kind: kind, Items: []*Node{node}}
+	}
+}
+
+func (p *definitionParser) atom() (*Node, error) {
+	switch c := p.next(); {
+	case c == '"':
+		end := strings.IndexByte(p.src[p.pos+1:], '"')
+		if end == -1 {
+			return nil, fmt.Errorf("unterminated terminal in %q", p.src)
+		}
+		value := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return &Node{Kind: TERMINAL, Value: value}, nil
+	case c == '(':
+		p.pos++
+		node, err := p.choice()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ')' {
+			return nil, fmt.Errorf("expected ')' in %q", p.src)
+		}
+		p.pos++
+		return node, nil
+	case isNameChar(c):
+		start := p.pos
+		for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		if strings.ToUpper(name) == name {
+			return &Node{Kind: TOKEN, Value: name}, nil
+		}
+		return &Node{Kind: NONTERMINAL, Value: name}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected '%c' in %q", p.src[p.pos], p.src)
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
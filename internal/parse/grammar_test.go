@@ -0,0 +1,35 @@
+package parse
+
+import "testing"
+
+func TestGrammar(t *testing.T) {
+	rules, err := Grammar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defined := map[string]bool{NothingRule: true}
+	for _, rule := range rules {
+		if defined[rule.Name] {
+			t.Errorf("rule %s is defined twice", rule.Name)
+		}
+		defined[rule.Name] = true
+	}
+
+	var check func(rule string, node *Node)
+	check = func(rule string, node *Node) {
+		if node.Kind == NONTERMINAL && !defined[node.Value] {
+			t.Errorf("rule %s references undefined rule %s", rule, node.Value)
+		}
+		for _, item := range node.Items {
+			check(rule, item)
+		}
+	}
+	for _, rule := range rules {
+		check(rule.Name, rule.Expr)
+	}
+
+	if rules[0].Name != "declaration" {
+		t.Errorf("first rule is %s, want declaration", rules[0].Name)
+	}
+}
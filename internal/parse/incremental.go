@@ -0,0 +1,158 @@
+package parse
+
+import (
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+// Incremental parses successive versions of a source, such as a document
+// being edited, reusing the statements an edit leaves unchanged. Only the
+// region between the last unchanged statement before the edit and the
+// first unchanged statement after it is scanned and parsed again, the
+// statements following the edit are moved to their new position.
+//
+// The source is parsed from scratch whenever the region does not parse
+// on its own, e.g. if the edit opens a string or comment running into the
+// following statements, so the result is always that of Parse. Without
+// RequireSemicolons an edit may join statements, so every version of
+// the source is parsed from scratch.
+type Incremental struct {
+	context scan.ScanContext
+	options ParseOptions
+	// the previous version of the source, its statements and their
+	// spans, the statements are nil if the source did not scan or parse
+	source string
+	stmts  []ast.Stmt
+	spans  []Span
+	// the number of statements reused by the last call to Parse
+	reused int
+}
+
+// NewIncremental returns an incremental parser scanning the
+// versions of the source in context and parsing them with options.
+func NewIncremental(context scan.ScanContext, options ParseOptions) *Incremental {
+	return &Incremental{context: context, options: options}
+}
+
+// Parse parses source, the next version of the source, like Parse.
+func (inc *Incremental) Parse(source string, report func(error)) ([]ast.Stmt, error) {
+	inc.reused = 0
+	if inc.stmts != nil && inc.options.RequireSemicolons {
+		if stmts, spans, ok := inc.reparse(source); ok {
+			inc.source, inc.stmts, inc.spans = source, stmts, spans
+			return stmts, nil
+		}
+	}
+
+	failed := false
+	tokens, _ := scan.Scan(source, func(err error) {
+		failed = true
+		report(err)
+	}, inc.context)
+	stmts, spans, err := ParseSpans(tokens, report, inc.options)
+	inc.source, inc.stmts, inc.spans = source, stmts, spans
+	if failed {
+		// the spans of a source which does not scan are not reused
+		inc.stmts = nil
+	}
+	return stmts, err
+}
+
+// Reused returns the number of statements the last call to Parse
+// reused from the previous version of the source.
+func (inc *Incremental) Reused() int {
+	return inc.reused
+}
+
+// reparse parses the region of source changed since the previous version,
+// ok is false if the region does not scan or parse without errors
+func (inc *Incremental) reparse(source string) (stmts []ast.Stmt, spans []Span, ok bool) {
+	old := inc.source
+	prefix := 0
+	for prefix < len(old) && prefix < len(source) && old[prefix] == source[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(source)-prefix &&
+		old[len(old)-1-suffix] == source[len(source)-1-suffix] {
+		suffix++
+	}
+	delta := len(source) - len(old)
+
+	// the statements before the edit, and those after it which are
+	// separated from the edit by whitespace
+	before := 0
+	for before < len(inc.spans) && inc.spans[before].End <= prefix {
+		before++
+	}
+	after := len(inc.spans)
+	for after > before && inc.spans[after-1].Start >= len(old)-suffix {
+		if start := inc.spans[after-1].Start + delta; start > 0 && !isSpace(source[start-1]) {
+			break
+		}
+		after--
+	}
+
+	start := 0
+	if before > 0 {
+		start = inc.spans[before-1].End
+	}
+	end := len(source)
+	if after < len(inc.spans) {
+		end = inc.spans[after].Start + delta
+	}
+
+	index := token.NewLineIndex(source)
+	line := index.Position(start).Line
+	failed := false
+	report := func(error) { failed = true }
+	context := inc.context
+	context.IncludeComments = true
+	tokens, _ := scan.Scan(source[start:end], report, context)
+	for i := range tokens {
+		tokens[i].Offset += start
+		tokens[i].Line += line - 1
+	}
+	if endsInLineComment(tokens, end) {
+		return nil, nil, false
+	}
+	region, regionSpans, _ := ParseSpans(tokens, report, inc.options)
+	if failed {
+		return nil, nil, false
+	}
+
+	lines := index.LineCount() - token.NewLineIndex(old).LineCount()
+	stmts = make([]ast.Stmt, 0, before+len(region)+len(inc.stmts)-after)
+	stmts = append(stmts, inc.stmts[:before]...)
+	stmts = append(stmts, region...)
+	spans = append(spans, inc.spans[:before]...)
+	spans = append(spans, regionSpans...)
+	for i := after; i < len(inc.stmts); i++ {
+		stmts = append(stmts, ast.Shift(inc.stmts[i], delta, lines))
+		spans = append(spans, Span{Start: inc.spans[i].Start + delta, End: inc.spans[i].End + delta})
+	}
+
+	inc.reused = before + len(inc.stmts) - after
+	return stmts, spans, true
+}
+
+// endsInLineComment reports whether the last of tokens is a line comment
+// running up to end, which would comment out what follows the region
+func endsInLineComment(tokens []token.Token, end int) bool {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tok := tokens[i]; tok.Type {
+		case token.EOF, token.WHITESPACE:
+			continue
+		case token.COMMENT:
+			return strings.HasPrefix(tok.Lexme, "//") && tok.Offset+len(tok.Lexme) == end
+		}
+		return false
+	}
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -3,20 +3,21 @@
 //
 // For full language spec, see [The Lox Language]. Note that some tweaks and additions
 // have been made in this specific implementation of the language such as
-// the implementation of a c-like comma operator and conditional operator
-// among others.
+// the implementation of a c-like conditional operator among others,
+// an optional c-like comma operator, see ParseOptions, and optional type
+// annotations of variables and functions, which are checked by typecheck.
 //
 // [C Operator Precedence]: https://en.cppreference.com/w/c/language/operator_precedence
 // [The Lox Language]: https://craftinginterpreters.com/the-lox-language.html
 package parse
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/LucazFFz/lox/internal/ast"
 	"github.com/LucazFFz/lox/internal/token"
+	"sort"
+	"strings"
 )
 
 type parser struct {
@@ -24,19 +25,110 @@ type parser struct {
 	current         int
 	parseErrOccured bool
 	report          func(error)
+	// number of loops enclosing the current statement within
+	// the current function, used to validate continue statements
+	loopDepth int
+	// number of functions enclosing the current statement
+	functionDepth int
+	// set once a yield statement is parsed in the current function
+	yielded bool
+	// number of blocks enclosing the current statement, synchronize
+	// stops at the '}' closing the innermost one
+	blockDepth int
+	// every error reported so far
+	errs    ErrorList
+	options ParseOptions
+	// every comment, in source order
+	comments []token.Token
+	// comments on lines of their own not yet attached to a statement,
+	// keyed by the index of the token following them
+	leadingAt map[int][]token.Token
+	// comments attached to statements, see Comments
+	leading map[int][]token.Token
 }
 
-func newParser(tokens []token.Token, report func(error)) *parser {
-	return &parser{tokens, 0, false, report}
+// ParseOptions selects the dialect of the language being parsed, such as
+// the strict Lox of Crafting Interpreters or the extended dialect of this
+// implementation. The zero value disables every optional feature, start
+// from DefaultParseOptions or StrictParseOptions instead.
+type ParseOptions struct {
+	// MaxArguments is the maximum number of arguments of a call and
+	// parameters of a function, 255 if not positive.
+	MaxArguments int
+	// EnableTernary enables the c-like conditional operator a ? b : c.
+	EnableTernary bool
+	// EnableComma enables the c-like comma operator, which evaluates
+	// its left operand, discards it and returns its right operand.
+	// Arguments, elements and fields are separated by commas regardless,
+	// wrap a comma expression in parentheses to use it as one.
+	EnableComma bool
+	// AllowAnonymousFunctions allows function expressions, fun (a) { ... }.
+	AllowAnonymousFunctions bool
+	// RequireSemicolons requires every statement to end with a ';'.
+	// Otherwise it may be left out at the end of a line, before a '}'
+	// and at the end of the file.
+	RequireSemicolons bool
+}
+
+// DefaultParseOptions returns the options of the dialect implemented by
+// this interpreter, which Parse and ParseExpression use.
+func DefaultParseOptions() ParseOptions {
+	return ParseOptions{
+		MaxArguments:            255,
+		EnableTernary:           true,
+		AllowAnonymousFunctions: true,
+		RequireSemicolons:       true,
+	}
+}
+
+// StrictParseOptions returns the options of the Lox described by
+// Crafting Interpreters, without the operators and anonymous functions
+// added by this implementation.
+func StrictParseOptions() ParseOptions {
+	return ParseOptions{MaxArguments: 255, RequireSemicolons: true}
+}
+
+func newParser(tokens []token.Token, report func(error), options ParseOptions) *parser {
+	if options.MaxArguments <= 0 {
+		options.MaxArguments = 255
+	}
+	s := &parser{
+		options:   options,
+		leadingAt: map[int][]token.Token{},
+		leading:   map[int][]token.Token{},
+	}
+	s.skipTrivia(tokens)
+	s.report = func(err error) {
+		s.parseErrOccured = true
+		s.errs = append(s.errs, err.(ParseError))
+		report(err)
+	}
+	return s
 }
 
 type ParseError struct {
 	Message string
 	Line    int
 	Lexme   string
+	Offset  int
+	// set for errors at the EOF token, their span is
+	// empty and at the end of the source
+	AtEnd bool
+}
+
+func newParseError(tok token.Token, msg string) ParseError {
+	return ParseError{
+		Line:    tok.Line,
+		Lexme:   tok.Lexme,
+		Offset:  tok.Offset,
+		Message: msg,
+		AtEnd:   tok.Type == token.EOF}
 }
 
 func (e ParseError) Error() string {
+	if e.AtEnd {
+		return fmt.Sprintf("[%d] error at end of file - %s \n", e.Line, e.Message)
+	}
 	if e.Lexme == "" {
 		return fmt.Sprintf("[%d] error - %s \n", e.Line, e.Message)
 	}
@@ -44,6 +136,100 @@ func (e ParseError) Error() string {
 	return fmt.Sprintf("[%d] error at \"%s\" - %s \n", e.Line, e.Lexme, e.Message)
 }
 
+// ErrorList is the error returned by Parse and ParseExpression, it holds
+// every error reported while parsing in the order they were reported.
+type ErrorList []ParseError
+
+// Error returns the messages of the errors, one per line.
+func (l ErrorList) Error() string {
+	var builder strings.Builder
+	for _, err := range l {
+		builder.WriteString(err.Error())
+	}
+	return builder.String()
+}
+
+// Unwrap returns the errors of the list, so errors.As finds the first.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, err := range l {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Span returns the byte offset and length of the offending lexme.
+func (e ParseError) Span() (int, int) {
+	return e.Offset, len(e.Lexme)
+}
+
+// skipTrivia keeps the tokens the productions expect. Comments are kept
+// aside, those on lines of their own are remembered as leading the token
+// following them while those written after code trail that code.
+func (s *parser) skipTrivia(tokens []token.Token) {
+	s.tokens = make([]token.Token, 0, len(tokens))
+	var pending []token.Token
+	for _, tok := range tokens {
+		switch tok.Type {
+		case token.WHITESPACE:
+			continue
+		case token.COMMENT:
+			s.comments = append(s.comments, tok)
+			n := len(s.tokens)
+			if len(pending) == 0 && n > 0 && s.tokens[n-1].Line == tok.Line {
+				continue
+			}
+			pending = append(pending, tok)
+			continue
+		}
+
+		if len(pending) > 0 {
+			s.leadingAt[len(s.tokens)] = pending
+			pending = nil
+		}
+		s.tokens = append(s.tokens, tok)
+	}
+}
+
+// attach attaches the comments leading the token at start to stmt.
+// The productions of nested statements may begin at the same token,
+// the comments are attached to the innermost one.
+func (s *parser) attach(start int, stmt ast.Stmt) {
+	comments, ok := s.leadingAt[start]
+	if !ok || stmt == nil {
+		return
+	}
+	tok := ast.StmtToken(stmt)
+	if tok.Line == 0 {
+		return
+	}
+
+	delete(s.leadingAt, start)
+	leading := append(s.leading[tok.Offset], comments...)
+	sort.Slice(leading, func(i, j int) bool { return leading[i].Offset < leading[j].Offset })
+	s.leading[tok.Offset] = leading
+}
+
+// Comments are the comments of a script parsed by ParseWithComments.
+type Comments struct {
+	// All holds every comment in source order.
+	All []token.Token
+	// the comments attached to statements, keyed by
+	// the offset of the ast.StmtToken of the statement
+	leading map[int][]token.Token
+}
+
+// Leading returns the comments on the lines directly before stmt, e.g.
+// the documentation of a function. Statements located by the same
+// ast.StmtToken, such as a block and its first statement, share them.
+func (c Comments) Leading(stmt ast.Stmt) []token.Token {
+	tok := ast.StmtToken(stmt)
+	if tok.Line == 0 {
+		return nil
+	}
+	return c.leading[tok.Offset]
+}
+
 // Parse generates an abstract syntax tree (ast.Expr) based on the given tokens.
 // The parser will use error productions and synchronize itself between
 // statements where possible to provide best effort error reporting.
@@ -58,48 +244,116 @@ func (e ParseError) Error() string {
 // Returns:
 //
 //   - ast.Expr: An abstract syntax tree.
-//   - error: An error used to signalize that a parse error occured.
+//   - error: An ErrorList of every parse error, which are also passed to report.
 //
-// NOTE: The returned error do not contain any information regarding
-// the given parse errors, that information is passed to report.
+// Comments and whitespace, see scan.ScanContext, are skipped. Use
+// ParseWithComments to keep the comments.
 func Parse(tokens []token.Token, report func(error)) ([]ast.Stmt, error) {
-	parser := newParser(tokens, report)
+	return ParseWith(tokens, report, DefaultParseOptions())
+}
+
+// ParseWith is like Parse but parses the optional features enabled by options.
+func ParseWith(tokens []token.Token, report func(error), options ParseOptions) ([]ast.Stmt, error) {
+	stmts, _, err := ParseWithComments(tokens, report, options)
+	return stmts, err
+}
+
+// ParseWithComments is like ParseWith but returns the comments among
+// tokens as well, attaching those before a statement to it. Tools such as
+// formatters use them to preserve the comments of the source.
+func ParseWithComments(tokens []token.Token, report func(error), options ParseOptions) ([]ast.Stmt, Comments, error) {
+	parser := newParser(tokens, report, options)
+	stmts, _ := program(parser)
+
+	comments := Comments{All: parser.comments, leading: parser.leading}
+	if parser.parseErrOccured {
+		return nil, comments, parser.errs
+	}
+
+	return stmts, comments, nil
+}
+
+// Span is the part of the source a statement is parsed from, from
+// the byte offset of its first token up to the end of its last token.
+type Span struct {
+	Start int
+	End   int
+}
+
+// ParseSpans is like ParseWith but returns the span of every statement
+// as well, e.g. to tell which statements an edit of the source touches.
+func ParseSpans(tokens []token.Token, report func(error), options ParseOptions) ([]ast.Stmt, []Span, error) {
+	parser := newParser(tokens, report, options)
+	stmts, spans := program(parser)
+	if parser.parseErrOccured {
+		return nil, nil, parser.errs
+	}
+
+	return stmts, spans, nil
+}
+
+// program parses the declarations up to the end of the
+// file, returning them together with their spans
+func program(s *parser) ([]ast.Stmt, []Span) {
 	var stmts []ast.Stmt = make([]ast.Stmt, 0)
+	var spans []Span
 
-	for parser.peek().Type != token.EOF {
-		stmt, err := declaration(parser)
-		// parser.advance()
+	for s.peek().Type != token.EOF {
+		start := s.peek().Offset
+		stmt, err := declaration(s)
 		if err == nil {
 			stmts = append(stmts, stmt)
+			spans = append(spans, Span{Start: start, End: tokenEnd(s.previous())})
 		}
 	}
+	return stmts, spans
+}
 
-	if parser.parseErrOccured {
-		return nil, errors.New("parse error occured")
+// tokenEnd returns the offset following the last byte of tok, the
+// lexme of a string excludes the quotes around it
+func tokenEnd(tok token.Token) int {
+	if tok.Type == token.STRING {
+		return tok.Offset + len(tok.Lexme) + 1
 	}
-
-	return stmts, nil
+	return tok.Offset + len(tok.Lexme)
 }
 
 func ParseExpression(tokens []token.Token, report func(error)) (ast.Expr, error) {
-	parser := newParser(tokens, report)
+	return ParseExpressionWith(tokens, report, DefaultParseOptions())
+}
+
+// ParseExpressionWith is like ParseExpression but parses the optional
+// features enabled by options.
+func ParseExpressionWith(tokens []token.Token, report func(error), options ParseOptions) (ast.Expr, error) {
+	parser := newParser(tokens, report, options)
 	expr, err := expression(parser)
+	if parser.parseErrOccured {
+		return nil, parser.errs
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if parser.parseErrOccured {
-		return nil, errors.New("parse error occured")
-	}
-
 	return expr, nil
 }
 
 // program -> declaration* EOF;
 
 // Production rules:
-//   - declaration -> varDeclaration | funDeclaration | statement;
-func declaration(s *parser) (ast.Stmt, error) {
+//   - declaration -> varDeclaration | funDeclaration | importDeclaration | statement;
+func declaration(s *parser) (stmt ast.Stmt, err error) {
+	start := s.current
+	defer func() { s.attach(start, stmt) }()
+
+	if s.match(token.IMPORT) {
+		s.advance()
+		stmt, err := importDeclaration(s)
+		if err != nil {
+			s.synchronize()
+			return nil, err
+		}
+		return stmt, nil
+	}
 	if s.match(token.VAR) {
 		s.advance()
 		stmt, err := varDeclaration(s)
@@ -111,6 +365,8 @@ func declaration(s *parser) (ast.Stmt, error) {
 		}
 		return stmt, nil
 	}
+	// "fun" not followed by a name starts an anonymous function
+	// expression, e.g. an immediately invoked fun () { ... }();
 	if s.check(token.FUN) && s.checkNext(token.IDENTIFIER) {
 		s.advance()
 		stmt, err := function(s, "function")
@@ -123,12 +379,34 @@ func declaration(s *parser) (ast.Stmt, error) {
 		return stmt, nil
 	}
 
-	return statement(s)
+	stmt, err = statement(s)
+	if err != nil {
+		s.synchronize()
+		return nil, err
+	}
+
+	return stmt, nil
 }
 
 // Production rules:
-//   - funDeclaration -> "fun" IDENTIFIER "(" parameters? ")" blockStmt;
-//   - parameters -> IDENTIFIER ("," IDENTIFIER)*;
+//   - importDeclaration -> "import" (STRING | IDENTIFIER) ";";
+func importDeclaration(s *parser) (ast.Stmt, error) {
+	keyword := s.previous()
+	if !s.match(token.STRING, token.IDENTIFIER) {
+		return nil, s.error(s.peek(), "expected module path or name after 'import'")
+	}
+	s.advance()
+	path := s.previous()
+
+	if err := s.terminate("expected ';' after import"); err != nil {
+		return nil, err
+	}
+
+	return ast.ImportStmt{Keyword: keyword, Path: path}, nil
+}
+
+// Production rules:
+//   - funDeclaration -> "fun" IDENTIFIER "(" functionRest;
 func function(s *parser, kind string) (ast.Stmt, error) {
 	if err := s.consume(token.IDENTIFIER, fmt.Sprintf("expected %s name", kind)); err != nil {
 		return nil, err
@@ -139,21 +417,63 @@ func function(s *parser, kind string) (ast.Stmt, error) {
 		return nil, err
 	}
 
-	var parameters []token.Token
+	sig, body, generator, err := functionRest(s, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.FunctionStmt{Name: name, FunctionExpr: newFunction(sig, body, generator)}, nil
+}
+
+// newFunction returns the function declared or written as an expression
+// with the signature sig and the body body
+func newFunction(sig signature, body ast.BlockStmt, generator bool) ast.FunctionExpr {
+	return ast.FunctionExpr{
+		Parameters:     sig.parameters,
+		ParameterTypes: sig.types,
+		ReturnType:     sig.returns,
+		Body:           body.Statements,
+		IsGenerator:    generator,
+		Brace:          body.Brace}
+}
+
+// signature is the parameters of a function and the types
+// they and the function are annotated with, see ast.FunctionExpr
+type signature struct {
+	parameters []token.Token
+	types      []token.Token
+	returns    token.Token
+}
+
+// functionRest parses the parameters and body shared by
+// function declarations and expressions, following the "(".
+// generator is true if the body contains a yield statement
+//
+// Production rules:
+//   - functionRest -> parameters? ")" ( "->" type )? blockStmt;
+//   - parameters -> IDENTIFIER ( ":" type )? ("," IDENTIFIER ( ":" type )?)*;
+func functionRest(s *parser, kind string) (sig signature, body ast.BlockStmt, generator bool, err error) {
+	annotated := false
 	if !s.check(token.RIGHT_PAREN) {
 		for {
-			if len(parameters) >= 255 {
-				err := ParseError{
-					Line:    s.peek().Line,
-					Lexme:   s.peek().Lexme,
-					Message: "cannot have more than 255 arguments"}
-				return nil, err
+			if len(sig.parameters) >= s.options.MaxArguments {
+				err := s.error(s.peek(), fmt.Sprintf("cannot have more than %d arguments", s.options.MaxArguments))
+				return signature{}, ast.BlockStmt{}, false, err
 			}
 			if err := s.consume(token.IDENTIFIER, "expected parameter name"); err != nil {
-				return nil, err
+				return signature{}, ast.BlockStmt{}, false, err
 			}
 
-			parameters = append(parameters, s.previous())
+			sig.parameters = append(sig.parameters, s.previous())
+			var typ token.Token
+			if s.match(token.COLON) {
+				s.advance()
+				if typ, err = typeName(s); err != nil {
+					return signature{}, ast.BlockStmt{}, false, err
+				}
+				annotated = true
+			}
+			sig.types = append(sig.types, typ)
 
 			if !s.match(token.COMMA) {
 				break
@@ -162,27 +482,48 @@ func function(s *parser, kind string) (ast.Stmt, error) {
 			s.advance()
 		}
 	}
+	if !annotated {
+		sig.types = nil
+	}
 
 	if err := s.consume(token.RIGHT_PAREN, "expected ')' after parameters"); err != nil {
-		return nil, err
+		return signature{}, ast.BlockStmt{}, false, err
+	}
+
+	if s.match(token.ARROW) {
+		s.advance()
+		if sig.returns, err = typeName(s); err != nil {
+			return signature{}, ast.BlockStmt{}, false, err
+		}
 	}
 
 	if err := s.consume(token.LEFT_BRACE, fmt.Sprintf("expected '{' before %s body", kind)); err != nil {
-		return nil, err
+		return signature{}, ast.BlockStmt{}, false, err
 	}
 
-	block, err := blockStmt(s)
+	block, generator, err := functionBody(s)
 	if err != nil {
-		return nil, err
+		return signature{}, ast.BlockStmt{}, false, err
 	}
 
 	// will never panic because blockStmt will always return a block
-	body := block.(ast.BlockStmt).Statements
-	return ast.FunctionStmt{Name: name, Parameters: parameters, Body: body}, nil
+	return sig, block.(ast.BlockStmt), generator, nil
 }
 
+// typeName parses the type of an annotation, which names one of the types
+// of values. Which names are types is up to the type checker.
+//
 // Production rules:
-//   - varDeclaration -> "var" IDENTIFIER ( "=" expression)? ";";
+//   - type -> IDENTIFIER | "nil" | "fun";
+func typeName(s *parser) (token.Token, error) {
+	if !s.match(token.IDENTIFIER, token.NIL, token.FUN) {
+		return token.Token{}, s.error(s.peek(), "expected type name")
+	}
+	return s.advance(), nil
+}
+
+// Production rules:
+//   - varDeclaration -> "var" IDENTIFIER ( ":" type )? ( "=" expression)? ";";
 func varDeclaration(s *parser) (ast.Stmt, error) {
 	var name token.Token
 	err := s.consume(token.IDENTIFIER, "expected variable name")
@@ -191,6 +532,14 @@ func varDeclaration(s *parser) (ast.Stmt, error) {
 	}
 
 	name = s.previous()
+	var typ token.Token
+	if s.match(token.COLON) {
+		s.advance()
+		if typ, err = typeName(s); err != nil {
+			return nil, err
+		}
+	}
+
 	var initializer ast.Expr = ast.NothingExpr{}
 	if s.match(token.EQUAL) {
 		s.advance()
@@ -200,17 +549,25 @@ func varDeclaration(s *parser) (ast.Stmt, error) {
 		}
 	}
 
-	if err := s.consume(token.SEMICOLON, "expected ';' after variable declaration"); err != nil {
+	if err := s.terminate("expected ';' after variable declaration"); err != nil {
 		return nil, err
 	}
 
-	return ast.VarStmt{Name: name, Initializer: initializer}, nil
+	return ast.VarStmt{Name: name, Type: typ, Initializer: initializer}, nil
 }
 
 // Production rules:
-//   - statement -> exprStmt | printStmt | blockStmt |
-//     ifStmt | whileStmt | forStmt | breakStmt | returnStmt;
-func statement(s *parser) (ast.Stmt, error) {
+//   - statement -> expressionStmt | printStmt | assertStmt | blockStmt |
+//     ifStmt | whileStmt | doWhileStmt | forStmt | labeledStmt | breakStmt |
+//     continueStmt | yieldStmt | returnStmt;
+func statement(s *parser) (stmt ast.Stmt, err error) {
+	start := s.current
+	defer func() { s.attach(start, stmt) }()
+
+	if s.check(token.IDENTIFIER) && s.checkNext(token.COLON) {
+		return labeledStmt(s)
+	}
+
 	if s.match(token.IF) {
 		s.advance()
 		return ifStmt(s)
@@ -221,39 +578,87 @@ func statement(s *parser) (ast.Stmt, error) {
 		return whileStmt(s)
 	}
 
+	if s.match(token.DO) {
+		s.advance()
+		return doWhileStmt(s)
+	}
+
 	if s.match(token.FOR) {
 		s.advance()
 		return forStmt(s)
 	}
 
 	// Production rules:
-	// - breakStmt -> "break" ";";
+	// - breakStmt -> "break" IDENTIFIER? ";";
 	if s.match(token.BREAK) {
-		s.advance()
-		if err := s.consume(token.SEMICOLON, "expected ';' after statement"); err != nil {
+		keyword := s.advance()
+		label := loopLabel(s)
+		if err := s.terminate("expected ';' after statement"); err != nil {
+			return nil, err
+		}
+		return ast.BreakStmt{Keyword: keyword, Label: label}, nil
+	}
+
+	// Production rules:
+	// - continueStmt -> "continue" IDENTIFIER? ";";
+	if s.match(token.CONTINUE) {
+		keyword := s.advance()
+		if s.loopDepth == 0 {
+			// the statement is still well formed, report the
+			// error without failing the production
+			s.error(keyword, "cannot use 'continue' outside of a loop")
+		}
+
+		label := loopLabel(s)
+		if err := s.terminate("expected ';' after statement"); err != nil {
+			return nil, err
+		}
+		return ast.ContinueStmt{Keyword: keyword, Label: label}, nil
+	}
+
+	// Production rules:
+	// - yieldStmt -> "yield" expression? ";";
+	if s.match(token.YIELD) {
+		keyword := s.advance()
+		if s.functionDepth == 0 {
+			s.error(keyword, "cannot use 'yield' outside of a function")
+		}
+		s.yielded = true
+
+		var expr ast.Expr
+		var err error
+		if !s.atTerminator() {
+			expr, err = expression(s)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := s.terminate("expected ';' after statement"); err != nil {
 			return nil, err
 		}
-		return ast.BreakStmt{}, nil
+
+		return ast.YieldStmt{Keyword: keyword, Expr: expr}, nil
 	}
 
 	// Production rules:
 	// - returnStmt -> "return" expression? ";";
 	if s.match(token.RETURN) {
-		s.advance()
+		keyword := s.advance()
 		var expr ast.Expr
 		var err error
-		if !s.check(token.SEMICOLON) {
+		if !s.atTerminator() {
 			expr, err = expression(s)
 			if err != nil {
 				return nil, err
 			}
 		}
 
-		if err := s.consume(token.SEMICOLON, "expected ';' after statement"); err != nil {
+		if err := s.terminate("expected ';' after statement"); err != nil {
 			return nil, err
 		}
 
-		return ast.ReturnStmt{Expr: expr}, nil
+		return ast.ReturnStmt{Keyword: keyword, Expr: expr}, nil
 	}
 
 	if s.match(token.PRINT) {
@@ -261,6 +666,11 @@ func statement(s *parser) (ast.Stmt, error) {
 		return printStmt(s)
 	}
 
+	if s.match(token.ASSERT) {
+		s.advance()
+		return assertStmt(s)
+	}
+
 	if s.match(token.LEFT_BRACE) {
 		s.advance()
 		return blockStmt(s)
@@ -272,6 +682,7 @@ func statement(s *parser) (ast.Stmt, error) {
 // Production rules:
 //   - printStmt -> "print" expression ";";
 func printStmt(s *parser) (ast.Stmt, error) {
+	keyword := s.previous()
 	expr, err := expression(s)
 	// expressions usually do not return errors but create
 	// error productions
@@ -279,11 +690,35 @@ func printStmt(s *parser) (ast.Stmt, error) {
 		return nil, err
 	}
 
-	if err := s.consume(token.SEMICOLON, "expected ';' after expression"); err != nil {
+	if err := s.terminate("expected ';' after expression"); err != nil {
 		return nil, err
 	}
 
-	return ast.PrintStmt{Expr: expr}, nil
+	return ast.PrintStmt{Keyword: keyword, Expr: expr}, nil
+}
+
+// Production rules:
+//   - assertStmt -> "assert" expression ("," expression)? ";";
+func assertStmt(s *parser) (ast.Stmt, error) {
+	keyword := s.previous()
+	condition, err := assignment(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var message ast.Expr
+	if s.match(token.COMMA) {
+		s.advance()
+		if message, err = assignment(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.terminate("expected ';' after assertion"); err != nil {
+		return nil, err
+	}
+
+	return ast.AssertStmt{Keyword: keyword, Condition: condition, Message: message}, nil
 }
 
 // Production rules:
@@ -291,31 +726,28 @@ func printStmt(s *parser) (ast.Stmt, error) {
 func blockStmt(s *parser) (ast.Stmt, error) {
 	var statements []ast.Stmt
 
+	// a bad declaration is reported and skipped like at the top level,
+	// the block still fails to parse but the errors after it are found
+	s.blockDepth++
 	for !s.check(token.RIGHT_BRACE) && !s.atEndOfFile() {
 		stmt, err := declaration(s)
-		if err != nil {
-			return nil, err
+		if err == nil {
+			statements = append(statements, stmt)
 		}
-
-		statements = append(statements, stmt)
 	}
+	s.blockDepth--
 
 	if err := s.consume(token.RIGHT_BRACE, "expected '}' after block statement"); err != nil {
 		return nil, err
 	}
 
-	return ast.BlockStmt{Statements: statements}, nil
+	return ast.BlockStmt{Statements: statements, Brace: s.previous()}, nil
 }
 
 // Production rules:
 // - ifStmt -> "if" "(" expression ")" statement ("else" statement)?;
 func ifStmt(s *parser) (ast.Stmt, error) {
-	s.consume(token.LEFT_PAREN, "expected '(' after 'if'")
-	condition, err := expression(s)
-	if err != nil {
-		return nil, err
-	}
-	s.consume(token.RIGHT_PAREN, "expected ')' after 'if'")
+	condition := condition(s, "if")
 	thenBranch, err := statement(s)
 	if err != nil {
 		return nil, err
@@ -335,96 +767,278 @@ func ifStmt(s *parser) (ast.Stmt, error) {
 		ElseBranch: elseBranch}, nil
 }
 
+// Production rules:
+// - labeledStmt -> IDENTIFIER ":" ( whileStmt | doWhileStmt | forStmt );
+func labeledStmt(s *parser) (ast.Stmt, error) {
+	label := s.advance()
+	s.advance()
+
+	var loop ast.Stmt
+	var err error
+	switch {
+	case s.match(token.WHILE):
+		s.advance()
+		loop, err = whileStmt(s)
+	case s.match(token.DO):
+		s.advance()
+		loop, err = doWhileStmt(s)
+	case s.match(token.FOR):
+		s.advance()
+		loop, err = forStmt(s)
+	default:
+		// parse the statement anyway to avoid cascading errors
+		s.error(label, "only loops can be labeled")
+		return statement(s)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return labelLoop(loop, label), nil
+}
+
+// labelLoop sets the label of loop, which may be a
+// block created by desugaring a for loop
+func labelLoop(loop ast.Stmt, label token.Token) ast.Stmt {
+	switch loop := loop.(type) {
+	case ast.WhileStmt:
+		loop.Label = label
+		return loop
+	case ast.ForInStmt:
+		loop.Label = label
+		return loop
+	case ast.BlockStmt:
+		statements := append([]ast.Stmt{}, loop.Statements...)
+		statements[1] = labelLoop(statements[1], label)
+		loop.Statements = statements
+		return loop
+	}
+
+	panic("should never reach here (unknown loop)")
+}
+
+// loopLabel parses the optional label following a break or
+// continue statement, zero if there is none
+func loopLabel(s *parser) token.Token {
+	if s.match(token.IDENTIFIER) {
+		return s.advance()
+	}
+	return token.Token{}
+}
+
 // Production rules:
 // - whileStmt -> "while" "(" expression ")" statement;
 func whileStmt(s *parser) (ast.Stmt, error) {
-	s.consume(token.LEFT_PAREN, "expected '(' after 'while'")
-	condition, err := expression(s)
+	keyword := s.previous()
+	condition := condition(s, "while")
+	body, err := loopBody(s)
 	if err != nil {
 		return nil, err
 	}
 
-	s.consume(token.RIGHT_PAREN, "expected ')' after 'while'")
-	body, err := statement(s)
+	return ast.WhileStmt{Keyword: keyword, Condition: condition, Body: body}, nil
+}
+
+// Production rules:
+// - doWhileStmt -> "do" statement "while" "(" expression ")" ";";
+func doWhileStmt(s *parser) (ast.Stmt, error) {
+	keyword := s.previous()
+	body, err := loopBody(s)
 	if err != nil {
 		return nil, err
 	}
 
-	return ast.WhileStmt{Condition: condition, Body: body}, nil
+	if err := s.consume(token.WHILE, "expected 'while' after do-while body"); err != nil {
+		return nil, err
+	}
+
+	condition := condition(s, "while")
+	if err := s.terminate("expected ';' after do-while condition"); err != nil {
+		return nil, err
+	}
+
+	return ast.WhileStmt{Keyword: keyword, Condition: condition, Body: body}, nil
 }
 
 // Production rules:
-//   - forStmt -> "for" "(" ( varDecl | exprStmt | ";")
+//   - forStmt -> "for" "(" ( varDeclaration | expressionStmt | ";")
 //     expression? ";"
-//     expression? ")" statement;
+//     expression? ")" statement | forInStmt;
 func forStmt(s *parser) (ast.Stmt, error) {
+	keyword := s.previous()
 	s.consume(token.LEFT_PAREN, "expected '(' after 'for'")
+	start := s.current
+
+	if s.check(token.VAR) && s.checkNext(token.IDENTIFIER) &&
+		s.tokens[s.current+2].Type == token.IN {
+		s.advance()
+		return forInStmt(s, start)
+	}
 
-	var initializer ast.Stmt = nil
-	var err error = nil
+	if s.check(token.IDENTIFIER) && s.checkNext(token.IN) {
+		return forInStmt(s, start)
+	}
+
+	initializer, condition, incrementer, err := forClauses(s)
+	if err != nil {
+		// the clauses are dropped, the body is parsed anyway
+		skipHeader(s, start)
+		initializer, condition, incrementer = nil, ast.NothingExpr{}, nil
+	} else {
+		s.consume(token.RIGHT_PAREN, "expected ')' after for clause")
+	}
+
+	// create ast
+	var body ast.Stmt = nil
+	body, err = loopBody(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if condition == nil {
+		var value ast.LoxBoolean = true
+		condition = ast.LiteralExpr{Value: value}
+	}
+
+	// the incrementer is not simply appended to the body
+	// since a continue statement must not skip it
+	body = ast.WhileStmt{Keyword: keyword, Condition: condition, Body: body, Increment: incrementer}
+
+	if initializer != nil {
+		body = ast.BlockStmt{
+			Statements: []ast.Stmt{initializer, body},
+		}
+	}
+
+	return body, nil
+}
+
+// forClauses parses the initializer, condition and incrementer of a
+// for loop up to the ')' closing them, the condition and incrementer
+// are nil if they are left out
+func forClauses(s *parser) (initializer ast.Stmt, condition ast.Expr, incrementer ast.Expr, err error) {
 	if s.match(token.SEMICOLON) {
 		s.advance()
-		initializer = nil
 	} else if s.match(token.VAR) {
 		s.advance()
-		initializer, err = varDeclaration(s)
-		if err != nil {
-			return nil, err
+		if initializer, err = varDeclaration(s); err != nil {
+			return nil, nil, nil, err
 		}
 	} else {
-		initializer, err = expressionStmt(s)
-		if err != nil {
-			return nil, err
+		if initializer, err = expressionStmt(s); err != nil {
+			return nil, nil, nil, err
 		}
 	}
 
-	var condition ast.Expr = nil
 	if !s.check(token.SEMICOLON) {
-		condition, err = expression(s)
-		if err != nil {
-			return nil, err
+		if condition, err = expression(s); err != nil {
+			return nil, nil, nil, err
 		}
 	}
-	s.consume(token.SEMICOLON, "expected ';' after loop condition")
+	if err := s.consume(token.SEMICOLON, "expected ';' after loop condition"); err != nil {
+		return nil, nil, nil, err
+	}
 
-	var incrementer ast.Expr = nil
 	if !s.check(token.RIGHT_PAREN) {
-		incrementer, err = expression(s)
-		if err != nil {
-			return nil, err
+		if incrementer, err = expression(s); err != nil {
+			return nil, nil, nil, err
 		}
 	}
-	s.consume(token.RIGHT_PAREN, "expected ')' after for clause")
+	return initializer, condition, incrementer, nil
+}
 
-	// create ast
-	var body ast.Stmt = nil
-	body, err = statement(s)
+// Production rules:
+//   - forInStmt -> "for" "(" "var"? IDENTIFIER "in" expression ")" statement;
+func forInStmt(s *parser, start int) (ast.Stmt, error) {
+	name := s.advance()
+	s.advance()
+
+	iterable, err := expression(s)
 	if err != nil {
-		return nil, err
+		skipHeader(s, start)
+		iterable = ast.NothingExpr{}
+	} else {
+		s.consume(token.RIGHT_PAREN, "expected ')' after for clause")
 	}
 
-	if incrementer != nil {
-		body = ast.BlockStmt{
-			Statements: []ast.Stmt{
-				body,
-				ast.ExpressionStmt{Expr: incrementer}},
-		}
+	body, err := loopBody(s)
+	if err != nil {
+		return nil, err
 	}
 
-	if condition == nil {
-		var value ast.LoxBoolean = true
-		condition = ast.LiteralExpr{Value: value}
+	return ast.ForInStmt{Name: name, Iterable: iterable, Body: body}, nil
+}
+
+// condition parses the parenthesized condition of an if, while or do-while
+// statement. A missing paren is reported and parsing goes on as if it
+// was there. A bad condition is reported and replaced by a NothingExpr,
+// skipping the rest of the header so the body is still parsed.
+func condition(s *parser, keyword string) ast.Expr {
+	s.consume(token.LEFT_PAREN, fmt.Sprintf("expected '(' after '%s'", keyword))
+	start := s.current
+	condition, err := expression(s)
+	if err != nil {
+		skipHeader(s, start)
+		return ast.NothingExpr{}
 	}
 
-	body = ast.WhileStmt{Condition: condition, Body: body}
+	s.consume(token.RIGHT_PAREN, fmt.Sprintf("expected ')' after '%s'", keyword))
+	return condition
+}
 
-	if initializer != nil {
-		body = ast.BlockStmt{
-			Statements: []ast.Stmt{initializer, body},
+// skipHeader skips the rest of the header of a control flow statement
+// whose first token after the '(' is at start, up to and including the
+// ')' closing it. It stops before a '{', which most likely starts the body.
+func skipHeader(s *parser, start int) {
+	depth := 0
+	for _, tok := range s.tokens[start:s.current] {
+		switch tok.Type {
+		case token.LEFT_PAREN:
+			depth++
+		case token.RIGHT_PAREN:
+			depth--
 		}
 	}
+	// the ')' has been consumed already
+	if depth < 0 {
+		return
+	}
 
-	return body, nil
+	for !s.atEndOfFile() && !s.check(token.LEFT_BRACE) {
+		switch s.advance().Type {
+		case token.LEFT_PAREN:
+			depth++
+		case token.RIGHT_PAREN:
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+	}
+}
+
+// loopBody parses the statement making up the body of a loop
+func loopBody(s *parser) (ast.Stmt, error) {
+	s.loopDepth++
+	defer func() { s.loopDepth-- }()
+	return statement(s)
+}
+
+// functionBody parses the block making up the body of a function,
+// loops enclosing the function do not enclose its body. generator
+// is true if the body contains a yield statement.
+func functionBody(s *parser) (body ast.Stmt, generator bool, err error) {
+	loopDepth, yielded := s.loopDepth, s.yielded
+	s.loopDepth, s.yielded = 0, false
+	s.functionDepth++
+	defer func() {
+		s.loopDepth, s.yielded = loopDepth, yielded
+		s.functionDepth--
+	}()
+
+	body, err = blockStmt(s)
+	return body, s.yielded, err
 }
 
 // Production rules:
@@ -437,7 +1051,7 @@ func expressionStmt(s *parser) (ast.Stmt, error) {
 		return nil, err
 	}
 
-	if err := s.consume(token.SEMICOLON, "expected ';' after expression"); err != nil {
+	if err := s.terminate("expected ';' after expression"); err != nil {
 		return nil, err
 	}
 
@@ -445,15 +1059,20 @@ func expressionStmt(s *parser) (ast.Stmt, error) {
 }
 
 // Production rules:
-//   - expression -> assignment;
+//   - expression -> comma | assignment;
 //   - precedence: none
 //   - associativity: none
 func expression(s *parser) (ast.Expr, error) {
+	// the comma operator is only parsed if enabled by the ParseOptions
+	if s.options.EnableComma {
+		return comma(s)
+	}
 	return assignment(s)
 }
 
 // Production rules:
-//   - assignment -> IDENTIFIER "=" (assignment | comma);
+//   - assignment -> (IDENTIFIER | call subscript | call "." IDENTIFIER)
+//     "=" assignment | conditional;
 //   - precedence: 16
 //   - associativity: right-to-left
 func assignment(s *parser) (ast.Expr, error) {
@@ -469,14 +1088,27 @@ func assignment(s *parser) (ast.Expr, error) {
 			return nil, err
 		}
 
-		if expr, ok := expr.(ast.VariableExpr); ok {
+		switch expr := expr.(type) {
+		case ast.VariableExpr:
 			return ast.AssignExpr{Name: expr.Name, Value: value}, nil
+		case ast.IndexExpr:
+			return ast.IndexAssignExpr{
+				Object:  expr.Object,
+				Bracket: expr.Bracket,
+				Index:   expr.Index,
+				Value:   value}, nil
+		case ast.SliceExpr:
+			return ast.SliceAssignExpr{
+				Object:  expr.Object,
+				Bracket: expr.Bracket,
+				Start:   expr.Start,
+				End:     expr.End,
+				Value:   value}, nil
+		case ast.GetExpr:
+			return ast.SetExpr{Object: expr.Object, Name: expr.Name, Value: value}, nil
 		}
 
-		err = ParseError{
-			Line:    s.previous().Line,
-			Lexme:   s.previous().Lexme,
-			Message: "invalid assignment target"}
+		err = newParseError(s.previous(), "invalid assignment target")
 		s.report(err)
 		return nil, errors.New("")
 	}
@@ -484,30 +1116,30 @@ func assignment(s *parser) (ast.Expr, error) {
 	return expr, nil
 }
 
-// // Production rules:
-// //   - comma -> conditional ("," conditional)*;
-// //   - precedence: 15
-// //   - associativity: left-to-right
-// func comma(s *parser) (ast.Expr, error) {
-// 	expr, err := conditional(s)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-//
-// 	for s.match(token.COMMA) {
-// 		operator := s.previous()
-// 		if right, err := conditional(s); err != nil {
-// 			return nil, err
-// 		} else {
-// 			expr = ast.BinaryExpr{Left: expr, Op: operator, Right: right}
-// 		}
-// 	}
-//
-// 	return expr, nil
-// }
+// Production rules:
+//   - comma -> assignment ("," assignment)*;
+//   - precedence: 17
+//   - associativity: left-to-right
+func comma(s *parser) (ast.Expr, error) {
+	expr, err := assignment(s)
+	if err != nil {
+		return nil, err
+	}
+
+	for s.match(token.COMMA) {
+		operator := s.advance()
+		right, err := assignment(s)
+		if err != nil {
+			return nil, err
+		}
+		expr = ast.BinaryExpr{Left: expr, Op: operator, Right: right}
+	}
+
+	return expr, nil
+}
 
 // Production rules:
-//   - conditional -> logical_or "?" logical_or ":" (conditional | logical_or);
+//   - conditional -> logical_or ("?" logical_or ":" conditional)?;
 //   - precedence: 13
 //   - associativity: right-to-left
 func conditional(s *parser) (ast.Expr, error) {
@@ -520,17 +1152,17 @@ func conditional(s *parser) (ast.Expr, error) {
 		return expr, nil
 	}
 
-	s.advance()
+	question := s.advance()
+	if !s.options.EnableTernary {
+		s.error(question, "conditional operator is not enabled")
+	}
 	left, err := logicalOr(s)
 	if err != nil {
 		return nil, err
 	}
 
 	if !s.match(token.COLON) {
-		err := ParseError{
-			Line:    s.peek().Line,
-			Lexme:   s.peek().Lexme,
-			Message: "expected ':' as part of conditional operator (conditional)"}
+		err := newParseError(s.peek(), "expected ':' as part of conditional operator (conditional)")
 		s.report(err)
 		return nil, errors.New("")
 	}
@@ -548,7 +1180,7 @@ func conditional(s *parser) (ast.Expr, error) {
 // Production rules:
 // - logical_or -> logical_and ("or" logical_and)*;
 // - precedence: 12
-// associativity: left-to-right
+// - associativity: left-to-right
 func logicalOr(s *parser) (ast.Expr, error) {
 	expr, err := logicalAnd(s)
 	if err != nil {
@@ -560,7 +1192,7 @@ func logicalOr(s *parser) (ast.Expr, error) {
 		s.advance()
 		right, err := logicalAnd(s)
 		if err != nil {
-			right = handleMissingExpression(s, s.previous().Lexme,
+			right = handleMissingExpression(s, s.previous(),
 				"missing right-hand-side operand (logical_or)")
 		}
 		expr = ast.BinaryExpr{Left: expr, Op: operator, Right: right}
@@ -572,7 +1204,7 @@ func logicalOr(s *parser) (ast.Expr, error) {
 // Production rules:
 // - logical_and -> equality ("and" equality)*;
 // - precedence: 11
-// associativity: left-to-right
+// - associativity: left-to-right
 func logicalAnd(s *parser) (ast.Expr, error) {
 	expr, err := equality(s)
 	if err != nil {
@@ -584,7 +1216,7 @@ func logicalAnd(s *parser) (ast.Expr, error) {
 		s.advance()
 		right, err := equality(s)
 		if err != nil {
-			right = handleMissingExpression(s, s.previous().Lexme,
+			right = handleMissingExpression(s, s.previous(),
 				"missing right-hand-side operand (logical_and)")
 		}
 		expr = ast.BinaryExpr{Left: expr, Op: operator, Right: right}
@@ -601,7 +1233,7 @@ func equality(s *parser) (ast.Expr, error) {
 	expr, err := comparison(s)
 	if err != nil {
 		if s.match(token.EQUAL_EQUAL, token.BANG_EQUAL) {
-			expr = handleMissingExpression(s, s.peek().Lexme,
+			expr = handleMissingExpression(s, s.peek(),
 				"missing left-hand-side operand (equality)")
 		} else {
 			return nil, err
@@ -612,7 +1244,7 @@ func equality(s *parser) (ast.Expr, error) {
 		operator := s.peek()
 		s.advance()
 		if right, err := comparison(s); err != nil {
-			expr = handleMissingExpression(s, s.peek().Lexme,
+			expr = handleMissingExpression(s, s.peek(),
 				"missing left-hand-side operand (equality)")
 		} else {
 			expr = ast.BinaryExpr{Left: expr, Op: operator, Right: right}
@@ -623,26 +1255,26 @@ func equality(s *parser) (ast.Expr, error) {
 }
 
 // Production rules:
-//   - comparison -> (nothing | term) ((">" | ">=" | "<" | "<=") (nothing | term))*;
+//   - comparison -> (nothing | term) ((">" | ">=" | "<" | "<=" | "is") (nothing | term))*;
 //   - precedence: 6
 //   - associativity: left-to-right
 func comparison(s *parser) (ast.Expr, error) {
 	expr, err := term(s)
 	if err != nil {
-		if s.match(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL) {
-			expr = handleMissingExpression(s, s.peek().Lexme,
+		if s.match(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL, token.IS) {
+			expr = handleMissingExpression(s, s.peek(),
 				"missing left-hand-side operand (comparison)")
 		} else {
 			return nil, err
 		}
 	}
 
-	for s.match(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL) {
+	for s.match(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL, token.IS) {
 		operator := s.peek()
 		s.advance()
 		right, err := term(s)
 		if err != nil {
-			right = handleMissingExpression(s, s.previous().Lexme,
+			right = handleMissingExpression(s, s.previous(),
 				"missing right-hand-side operand (comparison)")
 		}
 
@@ -652,9 +1284,9 @@ func comparison(s *parser) (ast.Expr, error) {
 	return expr, nil
 }
 
-func handleMissingExpression(s *parser, lexme string, msg string) ast.Expr {
+func handleMissingExpression(s *parser, at token.Token, msg string) ast.Expr {
 	s.parseErrOccured = true
-	s.report(ParseError{Line: s.peek().Line, Lexme: lexme, Message: msg})
+	s.report(newParseError(at, msg))
 	return ast.NothingExpr{}
 }
 
@@ -666,7 +1298,7 @@ func term(s *parser) (ast.Expr, error) {
 	expr, err := factor(s)
 	if err != nil {
 		if s.match(token.MINUS, token.PLUS) {
-			expr = handleMissingExpression(s, s.peek().Lexme,
+			expr = handleMissingExpression(s, s.peek(),
 				"missing left-hand-side operand (term)")
 		} else {
 			return nil, err
@@ -678,7 +1310,7 @@ func term(s *parser) (ast.Expr, error) {
 		s.advance()
 		right, err := factor(s)
 		if err != nil {
-			right = handleMissingExpression(s, s.previous().Lexme,
+			right = handleMissingExpression(s, s.previous(),
 				"missing right-hand-side operand (term)")
 		}
 
@@ -696,7 +1328,7 @@ func factor(s *parser) (ast.Expr, error) {
 	expr, err := unary(s)
 	if err != nil {
 		if s.match(token.SLASH, token.STAR) {
-			expr = handleMissingExpression(s, s.peek().Lexme,
+			expr = handleMissingExpression(s, s.peek(),
 				"missing left-hand-side operand (factor)")
 		} else {
 			return nil, err
@@ -708,7 +1340,7 @@ func factor(s *parser) (ast.Expr, error) {
 		s.advance()
 		right, err := unary(s)
 		if err != nil {
-			right = handleMissingExpression(s, s.previous().Lexme,
+			right = handleMissingExpression(s, s.previous(),
 				"missing right-hand-side operand (factor)")
 		}
 
@@ -719,27 +1351,73 @@ func factor(s *parser) (ast.Expr, error) {
 }
 
 // Production rules:
-//   - unary -> ("!" | "-") (unary | nothing) | call;
+//   - unary -> ("!" | "-") (unary | nothing) | ("++" | "--") unary | postfix;
 //   - precedence: 2
 //   - associativity: right-to-left
 func unary(s *parser) (ast.Expr, error) {
+	if s.match(token.PLUS_PLUS, token.MINUS_MINUS) {
+		operator := s.advance()
+		target, err := unary(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if !isIncrementTarget(target) {
+			return nil, s.error(operator, "invalid increment target")
+		}
+
+		return ast.PrefixExpr{Op: operator, Target: target}, nil
+	}
+
 	if s.match(token.BANG, token.MINUS) {
 		operator := s.peek()
 		s.advance()
 		right, err := unary(s)
 		if err != nil {
-			right = handleMissingExpression(s, s.previous().Lexme,
+			right = handleMissingExpression(s, s.previous(),
 				"missing operand (unary)")
 		}
 
 		return ast.UnaryExpr{Op: operator, Right: right}, nil
 	}
 
-	return call(s)
+	return postfix(s)
 }
 
 // Production rules:
-//   - call -> primary ("(" arguments? ")")*;
+//   - postfix -> call ("++" | "--")?;
+//   - precedence: 1
+//   - associativity: left-to-right
+func postfix(s *parser) (ast.Expr, error) {
+	expr, err := call(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.match(token.PLUS_PLUS, token.MINUS_MINUS) {
+		return expr, nil
+	}
+
+	operator := s.advance()
+	if !isIncrementTarget(expr) {
+		return nil, s.error(operator, "invalid increment target")
+	}
+
+	return ast.PostfixExpr{Op: operator, Target: expr}, nil
+}
+
+// only variables, elements and properties can be incremented or decremented
+func isIncrementTarget(expr ast.Expr) bool {
+	switch expr.(type) {
+	case ast.VariableExpr, ast.IndexExpr, ast.GetExpr:
+		return true
+	}
+	return false
+}
+
+// Production rules:
+//   - call -> functionExpr ("(" arguments? ")" | subscript | "." IDENTIFIER)*;
+//   - arguments -> expression ("," expression)*;
 //   - precedence: 1
 //   - associativity: left-to-right
 func call(s *parser) (ast.Expr, error) {
@@ -749,6 +1427,23 @@ func call(s *parser) (ast.Expr, error) {
 	}
 
 	for {
+		if s.match(token.LEFT_BRACKET) {
+			expr, err = subscript(s, expr)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if s.match(token.DOT) {
+			s.advance()
+			if err := s.consume(token.IDENTIFIER, "expected property name after '.'"); err != nil {
+				return nil, err
+			}
+			expr = ast.GetExpr{Object: expr, Name: s.previous()}
+			continue
+		}
+
 		if !s.match(token.LEFT_PAREN) {
 			return expr, nil
 		}
@@ -757,15 +1452,11 @@ func call(s *parser) (ast.Expr, error) {
 		arguments := []ast.Expr{}
 		if !s.check(token.RIGHT_PAREN) {
 			for {
-				if len(arguments) >= 255 {
-					err := ParseError{
-						Line:    s.peek().Line,
-						Lexme:   s.peek().Lexme,
-						Message: "cannot have more than 255 arguments"}
-					return nil, err
+				if len(arguments) >= s.options.MaxArguments {
+					return nil, s.error(s.peek(), fmt.Sprintf("cannot have more than %d arguments", s.options.MaxArguments))
 				}
 
-				if e, err := expression(s); err != nil {
+				if e, err := assignment(s); err != nil {
 					return nil, err
 				} else {
 					arguments = append(arguments, e)
@@ -784,95 +1475,96 @@ func call(s *parser) (ast.Expr, error) {
 			"expected ')' after arguments"); err != nil {
 			return nil, err
 		}
-		paren := s.peek()
-		expr = ast.CallStmt{Callee: expr, Paren: paren, Arguments: arguments}
+		paren := s.previous()
+		expr = ast.CallExpr{Callee: expr, Paren: paren, Arguments: arguments}
 	}
 }
 
-func functionExpr(s *parser) (ast.Expr, error) {
-	if !s.match(token.FUN) {
-		return primary(s)
-	}
+// Production rules:
+//   - subscript -> "[" expression "]" | "[" expression? ":" expression? "]";
+func subscript(s *parser, object ast.Expr) (ast.Expr, error) {
+	bracket := s.advance()
+
+	var start ast.Expr
+	var err error
+	if !s.check(token.COLON) {
+		start, err = expression(s)
+		if err != nil {
+			return nil, err
+		}
 
-	s.advance()
+		if s.match(token.RIGHT_BRACKET) {
+			s.advance()
+			return ast.IndexExpr{Object: object, Bracket: bracket, Index: start}, nil
+		}
+	}
 
-	if err := s.consume(token.LEFT_PAREN, "expected '(' after function"); err != nil {
+	if err := s.consume(token.COLON, "expected ']' or ':' after index"); err != nil {
 		return nil, err
 	}
 
-	var parameters []token.Token
-	if !s.check(token.RIGHT_PAREN) {
-		for {
-			if len(parameters) >= 255 {
-				err := ParseError{
-					Line:    s.peek().Line,
-					Lexme:   s.peek().Lexme,
-					Message: "cannot have more than 255 arguments"}
-				return nil, err
-			}
-			if err := s.consume(token.IDENTIFIER, "expected parameter name"); err != nil {
-				return nil, err
-			}
+	var end ast.Expr
+	if !s.check(token.RIGHT_BRACKET) {
+		end, err = expression(s)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-			parameters = append(parameters, s.previous())
+	if err := s.consume(token.RIGHT_BRACKET, "expected ']' after slice"); err != nil {
+		return nil, err
+	}
 
-			if !s.match(token.COMMA) {
-				break
-			}
+	return ast.SliceExpr{Object: object, Bracket: bracket, Start: start, End: end}, nil
+}
 
-			s.advance()
-		}
+// Production rules:
+//   - functionExpr -> "fun" "(" functionRest | primary;
+func functionExpr(s *parser) (ast.Expr, error) {
+	if !s.match(token.FUN) {
+		return primary(s)
 	}
 
-	if err := s.consume(token.RIGHT_PAREN, "expected ')' after parameters"); err != nil {
-		return nil, err
+	keyword := s.advance()
+	if !s.options.AllowAnonymousFunctions {
+		s.error(keyword, "anonymous functions are not allowed")
 	}
 
-	if err := s.consume(token.LEFT_BRACE, "expected '{' before %s body"); err != nil {
+	if err := s.consume(token.LEFT_PAREN, "expected '(' after function"); err != nil {
 		return nil, err
 	}
 
-	block, err := blockStmt(s)
+	sig, body, generator, err := functionRest(s, "function")
 	if err != nil {
 		return nil, err
 	}
 
-	// will never panic because blockStmt will always return a block
-	body := block.(ast.BlockStmt).Statements
-	return ast.FunctionExpr{Parameters: parameters, Body: body}, nil
+	return newFunction(sig, body, generator), nil
 }
 
 // Production rules:
-//   - primary -> NUMBER | STRING | IDENTIFIER | nothing | "true" | "false" | "nil" | "(" expression ")";
+//   - primary -> NUMBER | STRING | IDENTIFIER | nothing | "true" | "false" | "nil" | "(" expression ")" | list | map | object;
 //   - precedence: 1
 //   - associativity: none
 func primary(s *parser) (ast.Expr, error) {
 	switch s.peek().Type {
 	case token.FALSE:
 		s.advance()
-		return ast.LiteralExpr{Value: ast.LoxBoolean(false)}, nil
+		return ast.LiteralExpr{Value: ast.LoxBoolean(false), Token: s.previous()}, nil
 	case token.TRUE:
 		s.advance()
-		return ast.LiteralExpr{Value: ast.LoxBoolean(true)}, nil
+		return ast.LiteralExpr{Value: ast.LoxBoolean(true), Token: s.previous()}, nil
 	case token.NIL:
 		s.advance()
-		return ast.LiteralExpr{Value: ast.LoxNil{}}, nil
+		return ast.LiteralExpr{Value: ast.LoxNil{}, Token: s.previous()}, nil
 	case token.NUMBER:
 		s.advance()
-
-		var num float64
-		b := s.previous().Literal
-		buf := bytes.NewReader(b)
-		err := binary.Read(buf, binary.LittleEndian, &num)
-		if err != nil {
-			panic(err)
-		}
-
-		return ast.LiteralExpr{Value: ast.LoxNumber(num)}, nil
+		num := s.previous().Literal.(float64)
+		return ast.LiteralExpr{Value: ast.LoxNumber(num), Token: s.previous()}, nil
 	case token.STRING:
 		s.advance()
-		value := s.previous().Literal
-		return ast.LiteralExpr{Value: ast.LoxString(value)}, nil
+		value := s.previous().Literal.(string)
+		return ast.LiteralExpr{Value: ast.LoxString(value), Token: s.previous()}, nil
 	case token.LEFT_PAREN:
 		s.advance()
 		if expr, err := expression(s); err != nil {
@@ -884,20 +1576,150 @@ func primary(s *parser) (ast.Expr, error) {
 	case token.IDENTIFIER:
 		s.advance()
 		return ast.VariableExpr{Name: s.previous()}, nil
+	case token.LEFT_BRACKET:
+		return list(s)
+	case token.LEFT_BRACE:
+		// blocks are statements, in expression position
+		// a brace always starts a map literal
+		return mapLiteral(s)
+	case token.OBJECT:
+		return objectLiteral(s)
 	case token.ERROR:
+		// the scanner reported the error of the token already
+		s.advance()
 		s.parseErrOccured = true
 		return ast.NothingExpr{}, nil
 	default:
-		err := ParseError{
-			Line:    s.peek().Line,
-			Lexme:   s.peek().Lexme,
-			Message: "unexpected token"}
-		s.report(err)
-		return nil, errors.New("")
+		return nil, s.error(s.peek(), "unexpected token")
+	}
+}
+
+// Production rules:
+//   - list -> "[" (expression ("," expression)*)? "]";
+func list(s *parser) (ast.Expr, error) {
+	bracket := s.advance()
+	elements := []ast.Expr{}
+	if !s.check(token.RIGHT_BRACKET) {
+		for {
+			element, err := assignment(s)
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, element)
+
+			if !s.match(token.COMMA) {
+				break
+			}
+
+			s.advance()
+		}
+	}
+
+	if err := s.consume(token.RIGHT_BRACKET, "expected ']' after list elements"); err != nil {
+		return nil, err
+	}
+
+	return ast.ListExpr{Bracket: bracket, Elements: elements}, nil
+}
+
+// Production rules:
+//   - map -> "{" (entry ("," entry)*)? "}";
+//   - entry -> expression ":" expression;
+func mapLiteral(s *parser) (ast.Expr, error) {
+	brace := s.advance()
+	keys := []ast.Expr{}
+	values := []ast.Expr{}
+	if !s.check(token.RIGHT_BRACE) {
+		for {
+			key, err := assignment(s)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := s.consume(token.COLON, "expected ':' after map key"); err != nil {
+				return nil, err
+			}
+
+			value, err := assignment(s)
+			if err != nil {
+				return nil, err
+			}
+
+			keys = append(keys, key)
+			values = append(values, value)
+
+			if !s.match(token.COMMA) {
+				break
+			}
+
+			s.advance()
+		}
+	}
+
+	if err := s.consume(token.RIGHT_BRACE, "expected '}' after map entries"); err != nil {
+		return nil, err
 	}
+
+	return ast.MapExpr{Brace: brace, Keys: keys, Values: values}, nil
+}
+
+// Production rules:
+//   - object -> "object" "{" (field ("," field)*)? "}";
+//   - field -> IDENTIFIER ":" expression;
+func objectLiteral(s *parser) (ast.Expr, error) {
+	keyword := s.advance()
+	if err := s.consume(token.LEFT_BRACE, "expected '{' after 'object'"); err != nil {
+		return nil, err
+	}
+
+	names := []token.Token{}
+	values := []ast.Expr{}
+	if !s.check(token.RIGHT_BRACE) {
+		for {
+			if err := s.consume(token.IDENTIFIER, "expected field name"); err != nil {
+				return nil, err
+			}
+			name := s.previous()
+
+			if err := s.consume(token.COLON, "expected ':' after field name"); err != nil {
+				return nil, err
+			}
+
+			value, err := assignment(s)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, other := range names {
+				if other.Lexme == name.Lexme {
+					s.error(name, fmt.Sprintf("duplicate field '%s'", name.Lexme))
+				}
+			}
+
+			names = append(names, name)
+			values = append(values, value)
+
+			if !s.match(token.COMMA) {
+				break
+			}
+
+			s.advance()
+		}
+	}
+
+	if err := s.consume(token.RIGHT_BRACE, "expected '}' after object fields"); err != nil {
+		return nil, err
+	}
+
+	return ast.ObjectExpr{Keyword: keyword, Names: names, Values: values}, nil
 }
 
 func (s *parser) synchronize() {
+	// the '}' is left to close the block
+	if s.blockDepth > 0 && s.check(token.RIGHT_BRACE) {
+		return
+	}
 	s.advance()
 
 	for !s.atEndOfFile() {
@@ -906,6 +1728,10 @@ func (s *parser) synchronize() {
 		}
 
 		switch s.peek().Type {
+		case token.RIGHT_BRACE:
+			if s.blockDepth > 0 {
+				return
+			}
 		case token.CLASS:
 			return
 		case token.FUN:
@@ -918,8 +1744,14 @@ func (s *parser) synchronize() {
 			return
 		case token.WHILE:
 			return
+		case token.DO:
+			return
 		case token.PRINT:
 			return
+		case token.ASSERT:
+			return
+		case token.IMPORT:
+			return
 		case token.RETURN:
 			return
 		}
@@ -928,19 +1760,47 @@ func (s *parser) synchronize() {
 	}
 }
 
+// error reports a parse error at tok and returns an error
+// signalling that the current production failed
+func (s *parser) error(tok token.Token, msg string) error {
+	s.parseErrOccured = true
+	// the error following an ERROR token is caused by it, the
+	// scanner reported the token already
+	if s.current == 0 || s.previous().Type != token.ERROR {
+		s.report(newParseError(tok, msg))
+	}
+	return errors.New("")
+}
+
+// atTerminator reports whether the next token ends a statement, a ';'
+// or, unless semicolons are required, the start of the next line, a '}'
+// or the end of the file.
+func (s *parser) atTerminator() bool {
+	if s.check(token.SEMICOLON) {
+		return true
+	}
+	if s.options.RequireSemicolons {
+		return false
+	}
+	return s.atEndOfFile() || s.check(token.RIGHT_BRACE) || s.peek().Line > s.previous().Line
+}
+
+// terminate consumes the ';' ending a statement, see atTerminator
+// for when it may be left out.
+func (s *parser) terminate(msg string) error {
+	if !s.check(token.SEMICOLON) && s.atTerminator() {
+		return nil
+	}
+	return s.consume(token.SEMICOLON, msg)
+}
+
 func (s *parser) consume(typ token.TokenType, msg string) error {
 	if s.check(typ) {
 		s.advance()
 		return nil
 	}
 
-	err := ParseError{
-		Line:    s.peek().Line,
-		Lexme:   s.peek().Lexme,
-		Message: msg}
-	s.parseErrOccured = true
-	s.report(err)
-	return errors.New("")
+	return s.error(s.peek(), msg)
 }
 
 func (s *parser) match(types ...token.TokenType) bool {
@@ -961,10 +1821,10 @@ func (s *parser) check(typ token.TokenType) bool {
 }
 
 func (s *parser) checkNext(typ token.TokenType) bool {
-    if s.atEndOfFile() {
-        return false
-    }
-    return s.peekNext().Type == typ
+	if s.atEndOfFile() {
+		return false
+	}
+	return s.peekNext().Type == typ
 }
 
 func (s *parser) advance() token.Token {
@@ -983,7 +1843,7 @@ func (s *parser) peek() token.Token {
 }
 
 func (s *parser) peekNext() token.Token {
-    return s.tokens[s.current+1]
+	return s.tokens[s.current+1]
 }
 
 func (s *parser) atEndOfFile() bool {
@@ -0,0 +1,221 @@
+package parse_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the error corpus")
+
+// TestErrorCorpus parses every malformed program in testdata/errors and
+// compares the rendered diagnostics with the .golden file next to it.
+// Run `go test ./internal/parse -update` to accept changed diagnostics.
+func TestErrorCorpus(t *testing.T) {
+	sources, err := filepath.Glob("testdata/errors/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range sources {
+		name := strings.TrimSuffix(filepath.Base(path), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			report := diag.NewRenderer(string(source), &out).Report
+			tokens, _ := scan.Scan(string(source), report, scan.ScanContext{})
+			parse.Parse(tokens, report)
+
+			golden := strings.TrimSuffix(path, ".lox") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := out.String(); got != string(want) {
+				t.Errorf("diagnostics differ from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	tokens, _ := scan.Scan("var a = ;\nprint 1", func(error) {}, scan.ScanContext{})
+	var reported []error
+	_, err := parse.Parse(tokens, func(err error) { reported = append(reported, err) })
+
+	var list parse.ErrorList
+	if !errors.As(err, &list) || len(list) != len(reported) {
+		t.Fatalf("expected the %d reported errors but got %v", len(reported), err)
+	}
+	var first parse.ParseError
+	if !errors.As(err, &first) || first != list[0] {
+		t.Errorf("expected errors.As to find the first error %v but got %v", list[0], first)
+	}
+	if last := list[len(list)-1]; last.Line != 2 || !last.AtEnd {
+		t.Errorf("expected the last error at the end of line 2 but got %v", last)
+	}
+}
+
+func TestCommaOption(t *testing.T) {
+	parseExpr := func(source string, options parse.ParseOptions) ast.Expr {
+		tokens, _ := scan.Scan(source, func(error) {}, scan.ScanContext{})
+		expr, err := parse.ParseExpressionWith(tokens, func(error) {}, options)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", source, err)
+		}
+		return expr
+	}
+
+	enabled := parse.DefaultParseOptions()
+	enabled.EnableComma = true
+	expr, ok := parseExpr("a = 1, b = 2, c", enabled).(ast.BinaryExpr)
+	if !ok || expr.Op.Type != token.COMMA {
+		t.Fatalf("expected a comma expression but got %s", expr.DebugPrint())
+	}
+	if left, ok := expr.Left.(ast.BinaryExpr); !ok || left.Op.Type != token.COMMA {
+		t.Errorf("expected the comma operator to be left associative but got %s", expr.DebugPrint())
+	}
+	if _, ok := expr.Right.(ast.VariableExpr); !ok {
+		t.Errorf("expected the comma operator to bind looser than assignment but got %s", expr.DebugPrint())
+	}
+
+	// commas still separate arguments, wrapped in parentheses
+	// a comma expression is a single argument
+	call, ok := parseExpr("f(1, (2, 3))", enabled).(ast.CallExpr)
+	if !ok || len(call.Arguments) != 2 {
+		t.Fatalf("expected a call with 2 arguments but got %v", call)
+	}
+
+	if _, ok := parseExpr("1, 2", parse.DefaultParseOptions()).(ast.BinaryExpr); ok {
+		t.Errorf("expected the comma operator to be disabled by default")
+	}
+}
+
+func TestComments(t *testing.T) {
+	source := `// adds two numbers
+/* returns their sum */
+fun add(a, b) {
+    // the sum
+    return a + b; // trailing
+}
+
+print add(1, 2); /* trailing */
+// end of file
+`
+	tokens, _ := scan.Scan(source, func(error) {}, scan.ScanContext{IncludeComments: true, IncludeWhitespace: true})
+	stmts, comments, err := parse.ParseWithComments(tokens, func(error) {}, parse.DefaultParseOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 || len(comments.All) != 6 {
+		t.Fatalf("expected 2 statements and 6 comments but got %d and %d", len(stmts), len(comments.All))
+	}
+
+	lexmes := func(toks []token.Token) []string {
+		var lexmes []string
+		for _, tok := range toks {
+			lexmes = append(lexmes, tok.Lexme)
+		}
+		return lexmes
+	}
+
+	fn := stmts[0].(ast.FunctionStmt)
+	if got := lexmes(comments.Leading(fn)); strings.Join(got, "|") != "// adds two numbers|/* returns their sum */" {
+		t.Errorf("expected the documentation of add but got %q", got)
+	}
+	if got := lexmes(comments.Leading(fn.Body[0])); strings.Join(got, "|") != "// the sum" {
+		t.Errorf("expected the comment of the return statement but got %q", got)
+	}
+	if got := comments.Leading(stmts[1]); len(got) != 0 {
+		t.Errorf("expected trailing comments to lead nothing but got %q", lexmes(got))
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	context := scan.ScanContext{}
+	inc := parse.NewIncremental(context, parse.DefaultParseOptions())
+	edits := []struct {
+		source string
+		reused int
+	}{
+		{"var a = 1;\nfun f(x) {\n    return x + a;\n}\nprint f(2);\n", 0},
+		{"var a = 10;\nfun f(x) {\n    return x + a;\n}\nprint f(2);\n", 2},
+		{"var a = 10;\nfun f(x) {\n    return x * a;\n}\n\nprint f(2);\n", 2},
+		{"var a = 10;\nvar b = \"s\";\nfun f(x) {\n    return x * a;\n}\n\nprint f(2);\n", 3},
+		// the region does not parse on its own
+		{"var a = 10;\nvar b = \"s;\nfun f(x) {\n    return x * a;\n}\n\nprint f(2);\n", 0},
+		{"var a = 10;\nvar b = \"s\";\nfun f(x) {\n    return x * a;\n}\n\nprint f(2);\n", 0},
+		// a line comment running into the following statement
+		{"var a = 10;\nvar b = \"s\"; // print f(2);\n", 0},
+		{"var a = 10;\nvar b = \"s\"; // print f(2);\nprint b;\n", 2},
+		{"var a = 10; // var b = \"s\"; // print f(2);\nprint b;\n", 0},
+	}
+
+	for i, edit := range edits {
+		stmts, err := inc.Parse(edit.source, func(error) {})
+		tokens, _ := scan.Scan(edit.source, func(error) {}, context)
+		expected, expectedErr := parse.Parse(tokens, func(error) {})
+		if (err == nil) != (expectedErr == nil) || !reflect.DeepEqual(stmts, expected) {
+			t.Errorf("edit %d: expected %v but got %v", i, expected, stmts)
+		}
+		if inc.Reused() != edit.reused {
+			t.Errorf("edit %d: expected %d reused statements but got %d", i, edit.reused, inc.Reused())
+		}
+	}
+}
+
+// FuzzParse parses arbitrary sources, which must never panic and must
+// report every error they return, run with go test -fuzz FuzzParse ./internal/parse
+func FuzzParse(f *testing.F) {
+	paths, _ := filepath.Glob(filepath.Join("testdata", "errors", "*.lox"))
+	for _, path := range paths {
+		if source, err := os.ReadFile(path); err == nil {
+			f.Add(string(source))
+		}
+	}
+	f.Add("fun f(a, b) { return a ? b : fun () { yield a; }; } print f(1, 2)[0:1];")
+
+	f.Fuzz(func(t *testing.T, source string) {
+		// tokens which failed to scan are reported by the scanner
+		var scanned []error
+		report := func(err error) { scanned = append(scanned, err) }
+		tokens, _ := scan.Scan(source, report, scan.ScanContext{IncludeComments: true})
+
+		var reported []error
+		_, err := parse.Parse(tokens, func(err error) { reported = append(reported, err) })
+		if (err == nil && len(reported) > 0) || (err != nil && len(reported)+len(scanned) == 0) {
+			t.Fatalf("returned %v but reported %v", err, reported)
+		}
+
+		reported = nil
+		options := parse.DefaultParseOptions()
+		options.EnableComma = true
+		options.RequireSemicolons = false
+		_, err = parse.ParseExpressionWith(tokens, func(err error) { reported = append(reported, err) }, options)
+		if err != nil && len(reported)+len(scanned) == 0 {
+			t.Fatalf("returned %v but reported nothing", err)
+		}
+	})
+}
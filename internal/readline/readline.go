@@ -0,0 +1,284 @@
+// Package readline reads lines from a terminal with emacs-style line
+// editing and a history browsed with the arrow keys, persisted to a file.
+// When the input is not a terminal lines are read as they are.
+//
+// Keys:
+//
+//	Left, Right, Ctrl-B, Ctrl-F  move the cursor by a character
+//	Home, End, Ctrl-A, Ctrl-E    move the cursor to the start or end
+//	Up, Down, Ctrl-P, Ctrl-N     browse the history
+//	Backspace, Delete            delete the character before or at the cursor
+//	Ctrl-K, Ctrl-U               delete to the end or start of the line
+//	Ctrl-C                       cancel the line, ReadLine returns ErrInterrupt
+//	Ctrl-D                       delete at the cursor, end the input on an empty line
+package readline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrInterrupt is returned by ReadLine if the line is cancelled with Ctrl-C.
+var ErrInterrupt = errors.New("interrupted")
+
+// the number of lines kept in the history
+const maxHistory = 1000
+
+type Editor struct {
+	in  *bufio.Reader
+	out io.Writer
+	// the file descriptor of the terminal, -1 if the input is not one
+	fd      int
+	history []string
+	// the file added lines are appended to, nil if the history is not kept
+	historyFile *os.File
+}
+
+// New returns an editor reading from in and echoing to out.
+func New(in *os.File, out io.Writer) *Editor {
+	fd := int(in.Fd())
+	if !isTerminal(fd) {
+		fd = -1
+	}
+	return &Editor{in: bufio.NewReader(in), out: out, fd: fd}
+}
+
+// Terminal reports whether the input is a terminal, lines are
+// only edited if it is.
+func (e *Editor) Terminal() bool {
+	return e.fd >= 0
+}
+
+// SetHistoryFile loads the history from the file at path, which need not
+// exist, and appends the lines added afterwards to it.
+func (e *Editor) SetHistoryFile(path string) error {
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			e.addToHistory(line)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	e.historyFile = file
+	return nil
+}
+
+// Close closes the history file.
+func (e *Editor) Close() error {
+	if e.historyFile == nil {
+		return nil
+	}
+	return e.historyFile.Close()
+}
+
+// AddHistory adds line to the history, blank lines and
+// repetitions of the previous line are left out.
+func (e *Editor) AddHistory(line string) {
+	if !e.addToHistory(line) || e.historyFile == nil {
+		return
+	}
+	fmt.Fprintln(e.historyFile, line)
+}
+
+func (e *Editor) addToHistory(line string) bool {
+	if strings.TrimSpace(line) == "" || strings.Contains(line, "\n") ||
+		len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return false
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+	return true
+}
+
+// ReadLine writes prompt and returns the line read, without the line
+// break. io.EOF is returned once the input ends, ErrInterrupt if the
+// line is cancelled.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	if e.fd < 0 {
+		line, err := e.in.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	line, err := e.edit(prompt)
+	// raw mode does not translate the line break
+	fmt.Fprint(e.out, "\r\n")
+	return line, err
+}
+
+// line is the line being edited
+type line struct {
+	text   []rune
+	cursor int
+}
+
+// edit reads keys until the line is entered, redrawing it after every key
+func (e *Editor) edit(prompt string) (string, error) {
+	var l line
+	// the history entry shown, len(e.history) for the line being edited
+	shown := len(e.history)
+	var edited []rune
+
+	browse := func(to int) {
+		if to < 0 || to > len(e.history) {
+			return
+		}
+		if shown == len(e.history) {
+			edited = l.text
+		}
+		shown = to
+		if shown == len(e.history) {
+			l.text = edited
+		} else {
+			l.text = []rune(e.history[shown])
+		}
+		l.cursor = len(l.text)
+	}
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			return string(l.text), nil
+		case ctrl('C'):
+			fmt.Fprint(e.out, "^C")
+			return "", ErrInterrupt
+		case ctrl('D'):
+			if len(l.text) == 0 {
+				return "", io.EOF
+			}
+			l.delete(l.cursor)
+		case ctrl('A'):
+			l.cursor = 0
+		case ctrl('E'):
+			l.cursor = len(l.text)
+		case ctrl('B'):
+			l.move(-1)
+		case ctrl('F'):
+			l.move(1)
+		case ctrl('P'):
+			browse(shown - 1)
+		case ctrl('N'):
+			browse(shown + 1)
+		case ctrl('K'):
+			l.text = l.text[:l.cursor]
+		case ctrl('U'):
+			l.text = l.text[l.cursor:]
+			l.cursor = 0
+		case ctrl('H'), 127:
+			if l.cursor > 0 {
+				l.delete(l.cursor - 1)
+				l.cursor--
+			}
+		case 27:
+			switch e.escape() {
+			case 'A':
+				browse(shown - 1)
+			case 'B':
+				browse(shown + 1)
+			case 'C':
+				l.move(1)
+			case 'D':
+				l.move(-1)
+			case 'H':
+				l.cursor = 0
+			case 'F':
+				l.cursor = len(l.text)
+			case '~':
+				l.delete(l.cursor)
+			}
+		default:
+			if r >= ' ' {
+				l.text = append(l.text[:l.cursor], append([]rune{r}, l.text[l.cursor:]...)...)
+				l.cursor++
+			}
+		}
+
+		e.redraw(prompt, l)
+	}
+}
+
+// escape reads the rest of an escape sequence, returning its final
+// character for the keys edit handles and 0 for others. Delete is "\x1b[3~",
+// Home and End are sent as "\x1b[H", "\x1bOH" or "\x1b[1~" and "\x1b[4~".
+func (e *Editor) escape() rune {
+	kind, _, err := e.in.ReadRune()
+	if err != nil || kind != '[' && kind != 'O' {
+		return 0
+	}
+
+	var params []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+		if r >= '0' && r <= '9' || r == ';' {
+			params = append(params, r)
+			continue
+		}
+
+		if r != '~' {
+			return r
+		}
+		switch string(params) {
+		case "1", "7":
+			return 'H'
+		case "4", "8":
+			return 'F'
+		case "3":
+			return '~'
+		}
+		return 0
+	}
+}
+
+// redraw writes the prompt and line over the current line
+// of the terminal and moves the cursor into place
+func (e *Editor) redraw(prompt string, l line) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(l.text))
+	if back := len(l.text) - l.cursor; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func (l *line) move(by int) {
+	l.cursor = max(0, min(len(l.text), l.cursor+by))
+}
+
+// delete deletes the character at i, if any
+func (l *line) delete(i int) {
+	if i < len(l.text) {
+		l.text = append(l.text[:i], l.text[i+1:]...)
+	}
+}
+
+// ctrl returns the character sent by pressing Ctrl and key
+func ctrl(key rune) rune {
+	return key & 0x1f
+}
@@ -0,0 +1,64 @@
+package readline
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		want string
+		err  error
+	}{
+		{"plain", "print 1;\r", "print 1;", nil},
+		{"backspace", "ab\x7fc\r", "ac", nil},
+		{"home and end", "bc\x01a\x05d\r", "abcd", nil},
+		{"arrows", "ac\x1b[Db\x1b[C\x1b[Cd\r", "abcd", nil},
+		{"delete", "abc\x1b[H\x1b[3~\r", "bc", nil},
+		{"kill", "abcd\x02\x02\x0b\x01\x06\x15\r", "b", nil},
+		{"unicode", "ä\x01ö\r", "öä", nil},
+		{"history", "\x1b[A\x1b[A!\r", "first!", nil},
+		{"history back to the edited line", "x\x10\x0e\r", "x", nil},
+		{"interrupt", "abc\x03", "", ErrInterrupt},
+		{"end of input", "\x04", "", io.EOF},
+		{"ctrl-d deletes", "ab\x01\x04\r", "b", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := &Editor{in: bufio.NewReader(strings.NewReader(test.keys)), out: io.Discard, history: []string{"first", "second"}}
+			got, err := e.edit("> ")
+			if got != test.want || !errors.Is(err, test.err) {
+				t.Errorf("expected %q, %v but got %q, %v", test.want, test.err, got, err)
+			}
+		})
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	e := &Editor{}
+	if err := e.SetHistoryFile(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"a", "a", " ", "b"} {
+		e.AddHistory(line)
+	}
+	e.Close()
+
+	e = &Editor{}
+	if err := e.SetHistoryFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if want := []string{"a", "b"}; !slices.Equal(e.history, want) {
+		t.Errorf("expected the history %v but got %v", want, e.history)
+	}
+}
@@ -0,0 +1,483 @@
+// Package resolve statically checks parsed programs before they are
+// interpreted. It reports errors for programs the parser accepts but
+// which can never be valid, such as a return outside of a function, and
+// warnings for code which is valid but likely a mistake, such as unused
+// variables, unreachable statements, assignments of a variable to itself
+// and conditions which are always true or false.
+package resolve
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/token"
+	"sort"
+	"strings"
+)
+
+// Severity decides whether a diagnostic fails the program.
+type Severity uint8
+
+const (
+	ERROR Severity = iota
+	// warnings are reported but do not stop the program from running
+	WARNING
+)
+
+type ResolveError struct {
+	Severity Severity
+	Message  string
+	Line     int
+	Lexme    string
+	Offset   int
+	// line of the earlier declaration a redeclaration conflicts with,
+	// 0 for other diagnostics
+	PreviousLine int
+}
+
+func (e ResolveError) Error() string {
+	kind := "error"
+	if e.Severity == WARNING {
+		kind = "warning"
+	}
+
+	return fmt.Sprintf("[%d] %s at \"%s\" - %s \n", e.Line, kind, e.Lexme, e.Message)
+}
+
+// Span returns the byte offset and length of the offending lexme.
+func (e ResolveError) Span() (int, int) {
+	return e.Offset, len(e.Lexme)
+}
+
+type variable struct {
+	name token.Token
+	// parameter, function or variable, used in warnings
+	kind string
+	read bool
+	// set while the initializer of the variable is resolved
+	initializing bool
+	// the index of the variable in its environment at runtime
+	slot int
+}
+
+// scope holds the variables declared in a block or function,
+// in the order they were declared
+type scope struct {
+	variables []*variable
+	names     map[string]*variable
+	// set once a module is imported by path into the scope, which
+	// defines variables not known until the module is run
+	dynamic bool
+}
+
+type resolver struct {
+	// local scopes, innermost last. Globals are not tracked since they
+	// can be declared after the functions using them and read by later
+	// REPL inputs.
+	scopes []*scope
+	// number of functions enclosing the current statement
+	functionDepth int
+	// whether the innermost function enclosing the current statement is a generator
+	generator bool
+	// the labels of the loops enclosing the current
+	// statement within the current function
+	labels []token.Token
+	// reported in source order once the program is resolved
+	diagnostics []ResolveError
+	errOccurred bool
+	// every read and assignment of a variable, in the order resolved
+	references []Reference
+	// the bindings of the variables
+	resolution *ast.Resolution
+}
+
+// Reference is a read or assignment of a variable, see References.
+type Reference struct {
+	Name token.Token
+	// the binding of a local variable, unresolved for other variables
+	Binding ast.Binding
+	// the name in the declaration of a local variable,
+	// the zero token for other variables
+	Declaration token.Token
+}
+
+// Resolve checks statements, reporting every diagnostic to report. The
+// returned error signals that an error was reported, warnings alone do
+// not fail the program. Every read and assignment of a local variable is
+// bound to the slot of the variable, see ast.Binding, the interpreter
+// looks up the other variables by name. The bindings are returned in a
+// Resolution to interpret the statements with, the statements themselves
+// are not changed, so they can be resolved again.
+func Resolve(statements []ast.Stmt, report func(error)) (*ast.Resolution, error) {
+	r := resolve(statements, report)
+	if r.errOccurred {
+		return r.resolution, errors.New("resolve error occured")
+	}
+	return r.resolution, nil
+}
+
+// References resolves statements like Resolve and returns every read and
+// assignment of a variable, e.g. for tools showing the variable a name
+// refers to.
+func References(statements []ast.Stmt, report func(error)) ([]Reference, error) {
+	r := resolve(statements, report)
+	if r.errOccurred {
+		return r.references, errors.New("resolve error occured")
+	}
+	return r.references, nil
+}
+
+// resolve resolves statements, reporting the diagnostics in source order
+func resolve(statements []ast.Stmt, report func(error)) *resolver {
+	r := &resolver{resolution: ast.NewResolution()}
+	r.stmts(statements)
+
+	sort.SliceStable(r.diagnostics, func(i, j int) bool {
+		return r.diagnostics[i].Offset < r.diagnostics[j].Offset
+	})
+	for _, diagnostic := range r.diagnostics {
+		report(diagnostic)
+	}
+	return r
+}
+
+func (r *resolver) diagnostic(severity Severity, tok token.Token, msg string) {
+	if severity == ERROR {
+		r.errOccurred = true
+	}
+
+	// statements without tokens cannot be located
+	if tok.Line == 0 && severity == WARNING {
+		return
+	}
+
+	r.diagnostics = append(r.diagnostics,
+		ResolveError{Severity: severity, Message: msg, Line: tok.Line, Lexme: tok.Lexme, Offset: tok.Offset})
+}
+
+func (r *resolver) beginScope() {
+	r.scopes = append(r.scopes, &scope{names: map[string]*variable{}})
+}
+
+// endScope discards the innermost scope, warning about
+// the variables declared in it which were never read
+func (r *resolver) endScope() {
+	s := r.scopes[len(r.scopes)-1]
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	for _, v := range s.variables {
+		// names starting with an underscore are unused on purpose
+		if v.read || strings.HasPrefix(v.name.Lexme, "_") {
+			continue
+		}
+		r.diagnostic(WARNING, v.name, fmt.Sprintf("%s '%s' is never used", v.kind, v.name.Lexme))
+	}
+}
+
+func (r *resolver) declare(name token.Token, kind string) *variable {
+	v := &variable{name: name, kind: kind}
+	if len(r.scopes) == 0 {
+		return v
+	}
+
+	s := r.scopes[len(r.scopes)-1]
+	if previous, ok := s.names[name.Lexme]; ok {
+		r.diagnostic(ERROR, name, fmt.Sprintf("variable '%s' already declared in this scope (previously declared at line %d)",
+			name.Lexme, previous.name.Line))
+		r.diagnostics[len(r.diagnostics)-1].PreviousLine = previous.name.Line
+		return v
+	}
+
+	v.slot = len(s.variables)
+	s.names[name.Lexme] = v
+	s.variables = append(s.variables, v)
+	return v
+}
+
+// read marks the innermost variable called name as read and binds it
+func (r *resolver) read(name token.Token) {
+	v := r.bind(name)
+	if v == nil {
+		return
+	}
+
+	if v.initializing {
+		r.diagnostic(ERROR, name,
+			fmt.Sprintf("cannot read local variable '%s' in its own initializer", name.Lexme))
+	}
+	v.read = true
+}
+
+// bind binds name to the innermost local variable called name and
+// returns it, nil if name is not a local variable or may be one defined
+// by an imported module. Otherwise name is bound as a global.
+func (r *resolver) bind(name token.Token) *variable {
+	global := true
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		v, ok := r.scopes[i].names[name.Lexme]
+		if !ok {
+			if r.scopes[i].dynamic {
+				global = false
+				break
+			}
+			continue
+		}
+
+		resolved := ast.Binding{Resolved: true, Depth: len(r.scopes) - 1 - i, Slot: v.slot}
+		r.resolution.Bind(name, resolved)
+		r.references = append(r.references, Reference{Name: name, Binding: resolved, Declaration: v.name})
+		return v
+	}
+
+	if global {
+		r.resolution.Bind(name, ast.Binding{Global: true})
+	}
+	r.references = append(r.references, Reference{Name: name})
+	return nil
+}
+
+// stmts resolves a list of statements, warning about the first statement
+// following one which always jumps, such as a return statement
+func (r *resolver) stmts(statements []ast.Stmt) {
+	for i, stmt := range statements {
+		r.stmt(stmt)
+
+		if jumps(stmt) && i+1 < len(statements) {
+			r.diagnostic(WARNING, ast.StmtToken(statements[i+1]), "unreachable code")
+			// the unreachable code is still resolved
+			// to report errors and unused variables
+			r.stmts(statements[i+1:])
+			return
+		}
+	}
+}
+
+// jumps reports whether stmt always ends with a return, break or
+// continue statement, so the statements following it never run
+func jumps(stmt ast.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case ast.ReturnStmt, ast.BreakStmt, ast.ContinueStmt:
+		return true
+	case ast.BlockStmt:
+		return len(stmt.Statements) > 0 && jumps(stmt.Statements[len(stmt.Statements)-1])
+	case ast.IfStmt:
+		return stmt.ElseBranch != nil && jumps(stmt.ThenBranch) && jumps(stmt.ElseBranch)
+	}
+	return false
+}
+
+// constant warns about a condition which is a literal, such as
+// if (true), since it is always true or always false
+func (r *resolver) constant(condition ast.Expr) {
+	for {
+		grouping, ok := condition.(ast.GroupingExpr)
+		if !ok {
+			break
+		}
+		condition = grouping.Expr
+	}
+
+	literal, ok := condition.(ast.LiteralExpr)
+	if !ok {
+		return
+	}
+
+	always := true
+	switch literal.Value.Type() {
+	case ast.BOOLEAN:
+		always = ast.AsBoolean(literal.Value)
+	case ast.NIL:
+		always = false
+	}
+	r.diagnostic(WARNING, literal.Token, fmt.Sprintf("condition is always %t", always))
+}
+
+// isTrue reports whether e is the literal true
+func isTrue(e ast.Expr) bool {
+	literal, ok := e.(ast.LiteralExpr)
+	return ok && literal.Value.Type() == ast.BOOLEAN && ast.AsBoolean(literal.Value)
+}
+
+func (r *resolver) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case ast.ExpressionStmt:
+		r.expr(s.Expr)
+	case ast.PrintStmt:
+		r.expr(s.Expr)
+	case ast.VarStmt:
+		v := r.declare(s.Name, "variable")
+		v.initializing = true
+		r.expr(s.Initializer)
+		v.initializing = false
+	case ast.BlockStmt:
+		r.beginScope()
+		r.stmts(s.Statements)
+		r.endScope()
+	case ast.IfStmt:
+		r.constant(s.Condition)
+		r.expr(s.Condition)
+		r.stmt(s.ThenBranch)
+		r.stmt(s.ElseBranch)
+	case ast.WhileStmt:
+		// while (true) is how infinite loops are written
+		if !isTrue(s.Condition) {
+			r.constant(s.Condition)
+		}
+		r.expr(s.Condition)
+		r.beginLoop(s.Label)
+		r.stmt(s.Body)
+		r.endLoop(s.Label)
+		r.expr(s.Increment)
+	case ast.ForInStmt:
+		r.expr(s.Iterable)
+		r.beginScope()
+		// loop variables are often only needed to loop
+		r.declare(s.Name, "loop variable").read = true
+		r.beginLoop(s.Label)
+		r.stmt(s.Body)
+		r.endLoop(s.Label)
+		r.endScope()
+	case ast.BreakStmt:
+		r.jump(s.Label)
+	case ast.ContinueStmt:
+		r.jump(s.Label)
+	case ast.ReturnStmt:
+		if r.functionDepth == 0 {
+			r.diagnostic(ERROR, ast.StmtToken(s), "cannot return from top-level code")
+		}
+		if r.generator && s.Expr != nil {
+			r.diagnostic(WARNING, ast.StmtToken(s), "the value returned by a generator is discarded")
+		}
+		r.expr(s.Expr)
+	case ast.AssertStmt:
+		r.expr(s.Condition)
+		r.expr(s.Message)
+	case ast.ImportStmt:
+		// the declarations of a module imported by path are not known
+		// until it is run, like globals they are not tracked
+		if s.Path.Type == token.IDENTIFIER {
+			r.declare(s.Path, "module")
+		} else if len(r.scopes) > 0 {
+			r.scopes[len(r.scopes)-1].dynamic = true
+		}
+	case ast.YieldStmt:
+		r.expr(s.Expr)
+	case ast.FunctionStmt:
+		// declared before the body is resolved so it can call itself
+		r.declare(s.Name, "function")
+		r.function(s.Parameters, s.Body, s.IsGenerator)
+	case nil:
+	default:
+		panic("should never reach here (unknown statement)")
+	}
+}
+
+// beginLoop enters a loop labeled label, zero if it has none
+func (r *resolver) beginLoop(label token.Token) {
+	if label.Lexme == "" {
+		return
+	}
+
+	for _, enclosing := range r.labels {
+		if enclosing.Lexme == label.Lexme {
+			r.diagnostic(ERROR, label, fmt.Sprintf("label '%s' already labels an enclosing loop", label.Lexme))
+		}
+	}
+	r.labels = append(r.labels, label)
+}
+
+func (r *resolver) endLoop(label token.Token) {
+	if label.Lexme != "" {
+		r.labels = r.labels[:len(r.labels)-1]
+	}
+}
+
+// jump checks that a break or continue statement with
+// a label is enclosed by a loop with that label
+func (r *resolver) jump(label token.Token) {
+	if label.Lexme == "" {
+		return
+	}
+
+	for _, enclosing := range r.labels {
+		if enclosing.Lexme == label.Lexme {
+			return
+		}
+	}
+	r.diagnostic(ERROR, label, fmt.Sprintf("no enclosing loop labeled '%s'", label.Lexme))
+}
+
+func (r *resolver) function(parameters []token.Token, body []ast.Stmt, generator bool) {
+	// loops enclosing the function do not enclose its body
+	labels, enclosing := r.labels, r.generator
+	r.labels, r.generator = nil, generator
+	r.functionDepth++
+	r.beginScope()
+	for _, param := range parameters {
+		r.declare(param, "parameter")
+	}
+	r.stmts(body)
+	r.endScope()
+	r.functionDepth--
+	r.labels, r.generator = labels, enclosing
+}
+
+func (r *resolver) exprs(exprs ...ast.Expr) {
+	for _, e := range exprs {
+		r.expr(e)
+	}
+}
+
+func (r *resolver) expr(e ast.Expr) {
+	switch e := e.(type) {
+	case ast.BinaryExpr:
+		r.exprs(e.Left, e.Right)
+	case ast.GroupingExpr:
+		r.expr(e.Expr)
+	case ast.VariableExpr:
+		r.read(e.Name)
+	case ast.UnaryExpr:
+		r.expr(e.Right)
+	case ast.PrefixExpr:
+		r.expr(e.Target)
+	case ast.PostfixExpr:
+		r.expr(e.Target)
+	case ast.TernaryExpr:
+		r.constant(e.Condition)
+		r.exprs(e.Condition, e.Left, e.Right)
+	case ast.AssignExpr:
+		if value, ok := e.Value.(ast.VariableExpr); ok && value.Name.Lexme == e.Name.Lexme {
+			r.diagnostic(WARNING, e.Name, fmt.Sprintf("'%s' is assigned to itself", e.Name.Lexme))
+		}
+		r.expr(e.Value)
+		// assigning a variable does not read it
+		r.bind(e.Name)
+	case ast.ListExpr:
+		r.exprs(e.Elements...)
+	case ast.MapExpr:
+		r.exprs(e.Keys...)
+		r.exprs(e.Values...)
+	case ast.ObjectExpr:
+		r.exprs(e.Values...)
+	case ast.IndexExpr:
+		r.exprs(e.Object, e.Index)
+	case ast.SliceExpr:
+		r.exprs(e.Object, e.Start, e.End)
+	case ast.IndexAssignExpr:
+		r.exprs(e.Object, e.Index, e.Value)
+	case ast.SliceAssignExpr:
+		r.exprs(e.Object, e.Start, e.End, e.Value)
+	case ast.GetExpr:
+		r.expr(e.Object)
+	case ast.SetExpr:
+		r.exprs(e.Object, e.Value)
+	case ast.FunctionExpr:
+		r.function(e.Parameters, e.Body, e.IsGenerator)
+	case ast.CallExpr:
+		r.expr(e.Callee)
+		r.exprs(e.Arguments...)
+	case ast.LiteralExpr, ast.NothingExpr, nil:
+	default:
+		panic("should never reach here (unknown expression)")
+	}
+}
@@ -0,0 +1,62 @@
+package resolve_test
+
+import (
+	"bytes"
+	"flag"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/resolve"
+	"github.com/LucazFFz/lox/internal/scan"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the diagnostics corpus")
+
+// TestCorpus resolves every program in testdata and compares the
+// rendered errors and warnings with the .golden file next to it.
+// Run `go test ./internal/resolve -update` to accept changed diagnostics.
+func TestCorpus(t *testing.T) {
+	sources, err := filepath.Glob("testdata/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range sources {
+		name := strings.TrimSuffix(filepath.Base(path), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			report := diag.NewRenderer(string(source), &out).Report
+			tokens, _ := scan.Scan(string(source), report, scan.ScanContext{})
+			stmts, err := parse.Parse(tokens, report)
+			if err != nil {
+				t.Fatal(out.String())
+			}
+			resolve.Resolve(stmts, report)
+
+			golden := strings.TrimSuffix(path, ".lox") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := out.String(); got != string(want) {
+				t.Errorf("diagnostics differ from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package scan
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"strings"
+)
+
+// Pragma is a directive configuring the interpreter for a script. Pragmas
+// are written in line comments at the top of a script, before any code:
+//
+//	// lox: strict-bool, max-depth=512
+//
+// Value is empty for pragmas written without one.
+type Pragma struct {
+	Name   string
+	Value  string
+	Line   int
+	Offset int
+}
+
+// Pragmas returns the pragmas of source. Only line comments preceding
+// the first token are read, pragmas without a name are reported as
+// ScanErrors and skipped. Which pragmas exist is up to the caller.
+func Pragmas(source string, report func(error)) []Pragma {
+	// errors in the code are reported when the script is scanned
+	s := newScanner(source, func(error) {}, ScanContext{IncludeComments: true})
+
+	var pragmas []Pragma
+	for !atEndOfFile(s) {
+		s.tokenEnd = s.tokenStart
+		count := len(s.tokens)
+		scanToken(s)
+		if len(s.tokens) == count {
+			continue
+		}
+
+		comment := s.tokens[len(s.tokens)-1]
+		if comment.Type != token.COMMENT {
+			break
+		}
+
+		// block comments are not read
+		if !strings.HasPrefix(comment.Lexme, "//") {
+			continue
+		}
+
+		pragmas = append(pragmas, parsePragmas(comment, report)...)
+	}
+
+	return pragmas
+}
+
+// parsePragmas parses the comma separated pragmas following
+// "lox:" in comment, nil if it is an ordinary comment
+func parsePragmas(comment token.Token, report func(error)) []Pragma {
+	text := strings.TrimLeft(strings.TrimPrefix(comment.Lexme, "//"), " \t")
+	offset := comment.Offset + len(comment.Lexme) - len(text)
+	if !strings.HasPrefix(text, "lox:") {
+		return nil
+	}
+
+	text = text[len("lox:"):]
+	offset += len("lox:")
+
+	var pragmas []Pragma
+	for _, entry := range strings.Split(text, ",") {
+		// the offset of the entry without leading whitespace
+		start := offset + len(entry) - len(strings.TrimLeft(entry, " \t"))
+		offset += len(entry) + 1
+
+		name, value, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" {
+			report(ScanError{
+				Line:    comment.Line,
+				Lexme:   strings.TrimSpace(entry),
+				Message: "expected a pragma name",
+				Offset:  start})
+			continue
+		}
+
+		pragmas = append(pragmas, Pragma{Name: name, Value: value, Line: comment.Line, Offset: start})
+	}
+
+	return pragmas
+}
@@ -1,13 +1,14 @@
 package scan
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/LucazFFz/lox/internal/token"
+	"io"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type scanner struct {
@@ -20,64 +21,135 @@ type scanner struct {
 	context        ScanContext
 	report         func(error)
 	scanErrOccured bool
+	// the input of a streaming scanner, nil once it has been read
+	// completely and for a scanner of a string
+	reader io.Reader
+	// the error reading the input failed with
+	readErr error
+	// the offset of src in the input, a streaming scanner
+	// drops the source of the tokens it has returned
+	base int
 }
 
 func newScanner(source string, report func(error), context ScanContext) *scanner {
 	keywords := map[string]token.TokenType{
-		"class":  token.CLASS,
-		"and":    token.AND,
-		"else":   token.ELSE,
-		"false":  token.FALSE,
-		"for":    token.FOR,
-		"fun":    token.FUN,
-		"if":     token.IF,
-		"nil":    token.NIL,
-		"or":     token.OR,
-		"print":  token.PRINT,
-		"return": token.RETURN,
-		"super":  token.SUPER,
-		"this":   token.THIS,
-		"true":   token.TRUE,
-		"var":    token.VAR,
-		"while":  token.WHILE,
-        "break":  token.BREAK,
+		"class":    token.CLASS,
+		"and":      token.AND,
+		"else":     token.ELSE,
+		"false":    token.FALSE,
+		"for":      token.FOR,
+		"fun":      token.FUN,
+		"if":       token.IF,
+		"nil":      token.NIL,
+		"or":       token.OR,
+		"print":    token.PRINT,
+		"return":   token.RETURN,
+		"super":    token.SUPER,
+		"this":     token.THIS,
+		"true":     token.TRUE,
+		"var":      token.VAR,
+		"while":    token.WHILE,
+		"break":    token.BREAK,
+		"in":       token.IN,
+		"continue": token.CONTINUE,
+		"yield":    token.YIELD,
+		"do":       token.DO,
+		"assert":   token.ASSERT,
+		"import":   token.IMPORT,
+		"object":   token.OBJECT,
+		"is":       token.IS,
 	}
 
-	return &scanner{source, 0, 0, 1, keywords, []token.Token{}, context, report, false}
+	if context.Dialect == token.NATIVE_PRINT {
+		delete(keywords, "print")
+	}
+
+	return &scanner{
+		src:      source,
+		line:     1,
+		keywords: keywords,
+		tokens:   []token.Token{},
+		context:  context,
+		report:   report,
+	}
 }
 
 type ScanContext struct {
 	IncludeComments   bool
 	IncludeWhitespace bool
+	// decides which words are keywords
+	Dialect token.Dialect
 }
 
 type ScanError struct {
 	Message string
 	Line    int
 	Lexme   string
+	Offset  int
 }
 
+// Error quotes the lexme with escapes, the lexme of a scan
+// error may be a quote or a character which cannot be printed.
 func (e ScanError) Error() string {
-	return fmt.Sprintf("[%d] error at \"%s\" - %s \n", e.Line, e.Lexme, e.Message)
+	return fmt.Sprintf("[%d] error at %q - %s \n", e.Line, e.Lexme, e.Message)
+}
+
+// Span returns the byte offset and length of the offending lexme.
+func (e ScanError) Span() (int, int) {
+	return e.Offset, len(e.Lexme)
+}
+
+// ErrorList is the error returned by Scan, it holds every error
+// reported while scanning in the order they were reported.
+type ErrorList []ScanError
+
+// Error returns the messages of the errors, one per line.
+func (l ErrorList) Error() string {
+	var builder strings.Builder
+	for _, err := range l {
+		builder.WriteString(err.Error())
+	}
+	return builder.String()
 }
 
+// Unwrap returns the errors of the list, so errors.As finds the first.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, err := range l {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Scan returns the tokens of source ending with an EOF token. Errors are
+// passed to report and returned as an ErrorList, the tokens which fail
+// to scan are ERROR tokens.
 func Scan(source string, report func(error), context ScanContext) ([]token.Token, error) {
-	s := newScanner(source, report, context)
+	var errs ErrorList
+	s := newScanner(source, func(err error) {
+		errs = append(errs, err.(ScanError))
+		report(err)
+	}, context)
 	for !atEndOfFile(s) {
 		s.tokenEnd = s.tokenStart
 		scanToken(s)
 	}
 
-	s.tokens = append(s.tokens, token.NewToken(token.EOF, "", nil, s.line))
+	// errors at the EOF token are shown on the last line which is not
+	// blank, rather than on the blank lines following it
+	line := 1 + strings.Count(strings.TrimRight(s.src, " \t\r\n"), "\n")
+	s.tokens = append(s.tokens, token.NewToken(token.EOF, "", nil, line, len(s.src)))
 
+	if len(errs) > 0 {
+		return s.tokens, errs
+	}
 	return s.tokens, nil
 }
 
 func scanToken(s *scanner) {
-
 	appendToken := func(s *scanner, typ token.TokenType) {
 		lexme := getLexme(s, 0, 0)
-		token := token.NewToken(typ, lexme, nil, s.line)
+		token := token.NewToken(typ, lexme, nil, s.line, offset(s))
 		s.tokens = append(s.tokens, token)
 	}
 
@@ -91,15 +163,39 @@ func scanToken(s *scanner) {
 		appendToken(s, token.LEFT_BRACE)
 	case '}':
 		appendToken(s, token.RIGHT_BRACE)
+	case '[':
+		appendToken(s, token.LEFT_BRACKET)
+	case ']':
+		appendToken(s, token.RIGHT_BRACKET)
 	case ',':
 		appendToken(s, token.COMMA)
 	case '.':
+		// a number without an integer part, e.g. .5
+		if unicode.IsDigit(peek(s)) {
+			number := handleNumber(s, true)
+			lexme := getLexme(s, 0, 0)
+			token := token.NewToken(token.NUMBER, lexme, number, s.line, offset(s))
+			s.tokens = append(s.tokens, token)
+			break
+		}
 		appendToken(s, token.DOT)
 	case '-':
+		if match(s, '-') {
+			appendToken(s, token.MINUS_MINUS)
+			break
+		}
+		if match(s, '>') {
+			appendToken(s, token.ARROW)
+			break
+		}
 		appendToken(s, token.MINUS)
 	case ';':
 		appendToken(s, token.SEMICOLON)
 	case '+':
+		if match(s, '+') {
+			appendToken(s, token.PLUS_PLUS)
+			break
+		}
 		appendToken(s, token.PLUS)
 	case '*':
 		appendToken(s, token.STAR)
@@ -133,93 +229,125 @@ func scanToken(s *scanner) {
 		appendToken(s, token.GREATER)
 	case '/':
 		if peek(s) == '/' || peek(s) == '*' {
-			lexme := handleComment(s)
+			// block comments may span several lines, the
+			// token is located at the line it starts on
+			line := s.line
+			if !handleComment(s) {
+				s.tokens = append(s.tokens, token.NewToken(token.ERROR, getLexme(s, 0, 0), nil, line, offset(s)))
+				break
+			}
 			if s.context.IncludeComments {
-				token := token.NewToken(token.COMMENT, lexme, nil, s.line)
+				token := token.NewToken(token.COMMENT, getLexme(s, 0, 0), nil, line, offset(s))
 				s.tokens = append(s.tokens, token)
 			}
 			break
 		}
 
-		token := token.NewToken(token.SLASH, getLexme(s, 0, 0), nil, s.line)
+		token := token.NewToken(token.SLASH, getLexme(s, 0, 0), nil, s.line, offset(s))
 		s.tokens = append(s.tokens, token)
-	case '\n':
-		s.line++
+	case '\n', '\r':
+		// the '\r' of a "\r\n" line break does not end the line itself
+		if c == '\n' || peek(s) != '\n' {
+			s.line++
+		}
 		fallthrough
-	case ' ', '\r', '\t':
+	case ' ', '\t':
 		if s.context.IncludeWhitespace {
-			token := token.NewToken(token.WHITESPACE, string(c), nil, s.line)
+			token := token.NewToken(token.WHITESPACE, string(c), nil, s.line, offset(s))
 			s.tokens = append(s.tokens, token)
 		}
 	case '"':
+		// strings may span several lines, the token
+		// is located at the line it starts on
+		line := s.line
 		lexme, err := handleString(s)
 		if err != nil {
-			err := ScanError{Line: s.line, Lexme: lexme, Message: err.Error()}
-			s.report(err)
+			// reported at the opening quote, the string runs to the end of the file
+			s.report(ScanError{Line: line, Lexme: "\"", Message: err.Error(), Offset: offset(s)})
 			s.scanErrOccured = true
-            s.tokens = append(s.tokens, token.NewToken(token.ERROR, lexme, nil, s.line))
+			s.tokens = append(s.tokens, token.NewToken(token.ERROR, getLexme(s, 0, 0), nil, line, offset(s)))
 			break
 		}
 
-		token := token.NewToken(token.STRING, lexme, []byte(lexme), s.line)
+		token := token.NewToken(token.STRING, lexme, lexme, line, offset(s)+1)
 		s.tokens = append(s.tokens, token)
 	default:
 		if unicode.IsDigit(c) {
-			number := handleNumber(s)
-			buf := bytes.NewBuffer(make([]byte, 0, 8))
-			if err := binary.Write(buf, binary.LittleEndian, number); err != nil {
-				panic(err)
-			}
-
+			number := handleNumber(s, false)
 			lexme := getLexme(s, 0, 0)
-			token := token.NewToken(token.NUMBER, lexme, buf.Bytes(), s.line)
+			token := token.NewToken(token.NUMBER, lexme, number, s.line, offset(s))
 			s.tokens = append(s.tokens, token)
 			break
 		}
 
 		if unicode.IsLetter(c) || c == '_' {
 			typ, lexme := handleIdentifier(s)
-			token := token.NewToken(typ, lexme, []byte(lexme), s.line)
+			token := token.NewToken(typ, lexme, nil, s.line, offset(s))
 			s.tokens = append(s.tokens, token)
 			break
 		}
 
-		err := ScanError{Line: s.line, Lexme: getLexme(s, 0, 0), Message: "unexpected character '" + string(c) + "'"}
-		s.tokens = append(s.tokens, token.NewToken(token.ERROR, getLexme(s, 0, 0), nil, s.line))
+		err := ScanError{
+			Line:    s.line,
+			Lexme:   getLexme(s, 0, 0),
+			Message: "unexpected character '" + string(c) + "'",
+			Offset:  offset(s)}
+		s.tokens = append(s.tokens, token.NewToken(token.ERROR, getLexme(s, 0, 0), nil, s.line, offset(s)))
 		s.scanErrOccured = true
 		s.report(err)
 	}
 }
 
-func handleComment(s *scanner) string {
+// handleComment scans the comment following the first '/'. Line comments
+// end before the next newline, block comments end at the "*/" matching
+// their "/*" and may contain other block comments. An unterminated block
+// comment is reported, runs to the end of the file and is not terminated.
+func handleComment(s *scanner) bool {
 	if match(s, '/') {
-		for peek(s) != 0 && !atEndOfFile(s) {
+		// the line break is left to be scanned as whitespace
+		for peek(s) != '\n' && peek(s) != '\r' && !atEndOfFile(s) {
 			advance(s)
 		}
-		return getLexme(s, 2, -1)
+		return true
 	}
 
-	if match(s, '*') {
-		for peek(s) != '*' && peekNext(s) != '/' {
-			if c := peek(s); c == '\n' {
-				s.line++
-			} else if c == rune(0) {
-				return getLexme(s, 2, -1)
-
-			}
+	advance(s)
+	// the number of block comments not yet closed
+	depth := 1
+	for depth > 0 {
+		switch {
+		case atEndOfFile(s):
+			s.report(ScanError{
+				Line:    s.line,
+				Lexme:   "/*",
+				Message: "unterminated block comment",
+				Offset:  offset(s)})
+			s.scanErrOccured = true
+			return false
+		case peek(s) == '/' && peekNext(s) == '*':
 			advance(s)
+			depth++
+		case peek(s) == '*' && peekNext(s) == '/':
+			advance(s)
+			depth--
+		case atLineBreak(s):
+			s.line++
 		}
 		advance(s)
-		advance(s)
-		return getLexme(s, 2, -2)
 	}
+	return true
+}
 
-	return ""
+// atLineBreak reports whether the next character ends a line. Lines end
+// with "\n", "\r\n" or a lone "\r", the line of a "\r\n" break ends at
+// its '\n'.
+func atLineBreak(s *scanner) bool {
+	return peek(s) == '\n' || peek(s) == '\r' && peekNext(s) != '\n'
 }
 
 func handleString(s *scanner) (string, error) {
 	for peek(s) != '"' && !atEndOfFile(s) {
-		if peek(s) == '\n' {
+		if atLineBreak(s) {
 			s.line++
 		}
 		advance(s)
@@ -233,21 +361,56 @@ func handleString(s *scanner) (string, error) {
 	return getLexme(s, 1, -1), nil
 }
 
-func handleNumber(s *scanner) float64 {
+// handleNumber scans the rest of a number, fraction is set if the
+// number starts with the '.' of its fractional part
+func handleNumber(s *scanner, fraction bool) float64 {
 	for unicode.IsDigit(peek(s)) {
 		advance(s)
 	}
 
-	if peek(s) == '.' && unicode.IsDigit(peekNext(s)) {
+	if !fraction && peek(s) == '.' && unicode.IsDigit(peekNext(s)) {
 		advance(s)
 		for unicode.IsDigit(peek(s)) {
 			advance(s)
 		}
 	}
+
+	// an exponent, e.g. 1e9 or 2.5E-3
+	if n := exponentLength(s); n > 0 {
+		for range n {
+			advance(s)
+		}
+		for unicode.IsDigit(peek(s)) {
+			advance(s)
+		}
+	}
+
 	num, _ := strconv.ParseFloat(getLexme(s, 0, 0), 64)
 	return num
 }
 
+// exponentLength returns the length of the exponent marker, sign and
+// first digit following the digits of a number, zero if there is none
+func exponentLength(s *scanner) int {
+	if atEndOfFile(s) {
+		return 0
+	}
+
+	rest := s.src[s.tokenStart:]
+	if rest[0] != 'e' && rest[0] != 'E' {
+		return 0
+	}
+
+	n := 1
+	if n < len(rest) && (rest[n] == '+' || rest[n] == '-') {
+		n++
+	}
+	if n >= len(rest) || rest[n] < '0' || rest[n] > '9' {
+		return 0
+	}
+	return n + 1
+}
+
 func handleIdentifier(s *scanner) (token.TokenType, string) {
 	for unicode.IsDigit(peek(s)) || unicode.IsLetter(peek(s)) || peek(s) == '_' {
 		advance(s)
@@ -273,7 +436,29 @@ func getLexme(s *scanner, startOffset int, endOffset int) string {
 	return s.src[s.tokenEnd+startOffset : s.tokenStart+endOffset]
 }
 
+// offset returns the offset of the token being scanned in the input
+func offset(s *scanner) int {
+	return s.base + s.tokenEnd
+}
+
+// fill reads the input of a streaming scanner until the two characters
+// following the current one are buffered or the input ends
+func fill(s *scanner) {
+	for s.reader != nil && s.tokenStart+2*utf8.UTFMax > len(s.src) {
+		buf := make([]byte, 4096)
+		n, err := s.reader.Read(buf)
+		s.src += string(buf[:n])
+		if err != nil {
+			if err != io.EOF {
+				s.readErr = err
+			}
+			s.reader = nil
+		}
+	}
+}
+
 func atEndOfFile(s *scanner) bool {
+	fill(s)
 	return s.tokenStart >= len(s.src)
 }
 
@@ -288,21 +473,33 @@ func match(s *scanner, expected rune) bool {
 	return true
 }
 
+// advance consumes the next character, a byte of invalid
+// UTF-8 is consumed on its own as utf8.RuneError
 func advance(s *scanner) rune {
-	s.tokenStart++
-	return rune(s.src[s.tokenStart-1])
+	if atEndOfFile(s) {
+		return rune(0)
+	}
+	r, width := utf8.DecodeRuneInString(s.src[s.tokenStart:])
+	s.tokenStart += width
+	return r
 }
 
 func peek(s *scanner) rune {
 	if atEndOfFile(s) {
 		return rune(0)
 	}
-	return rune(s.src[s.tokenStart])
+	r, _ := utf8.DecodeRuneInString(s.src[s.tokenStart:])
+	return r
 }
 
 func peekNext(s *scanner) rune {
-	if s.tokenStart+1 >= len(s.src) {
+	if atEndOfFile(s) {
+		return rune(0)
+	}
+	_, width := utf8.DecodeRuneInString(s.src[s.tokenStart:])
+	if s.tokenStart+width >= len(s.src) {
 		return rune(0)
 	}
-	return rune(s.src[s.tokenStart+1])
+	r, _ := utf8.DecodeRuneInString(s.src[s.tokenStart+width:])
+	return r
 }
@@ -0,0 +1,392 @@
+package scan_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// the comment and code tokens, EOF excluded
+		want []token.Token
+		// the message of the expected scan error, if any
+		err string
+	}{
+		{
+			name:   "single line",
+			source: "a // b * / c\nd",
+			want: []token.Token{
+				{Type: token.IDENTIFIER, Lexme: "a", Line: 1, Offset: 0},
+				{Type: token.COMMENT, Lexme: "// b * / c", Line: 1, Offset: 2},
+				{Type: token.IDENTIFIER, Lexme: "d", Line: 2, Offset: 13},
+			},
+		},
+		{
+			name:   "line comment at end of file",
+			source: "a //",
+			want: []token.Token{
+				{Type: token.IDENTIFIER, Lexme: "a", Line: 1, Offset: 0},
+				{Type: token.COMMENT, Lexme: "//", Line: 1, Offset: 2},
+			},
+		},
+		{
+			name:   "lone star and slash",
+			source: "/* a * b / c **/ d",
+			want: []token.Token{
+				{Type: token.COMMENT, Lexme: "/* a * b / c **/", Line: 1, Offset: 0},
+				{Type: token.IDENTIFIER, Lexme: "d", Line: 1, Offset: 17},
+			},
+		},
+		{
+			name:   "multi line",
+			source: "/* a\nb\n*/ c",
+			want: []token.Token{
+				{Type: token.COMMENT, Lexme: "/* a\nb\n*/", Line: 1, Offset: 0},
+				{Type: token.IDENTIFIER, Lexme: "c", Line: 3, Offset: 10},
+			},
+		},
+		{
+			name:   "nested",
+			source: "/* a /* b\n*/ c */ d",
+			want: []token.Token{
+				{Type: token.COMMENT, Lexme: "/* a /* b\n*/ c */", Line: 1, Offset: 0},
+				{Type: token.IDENTIFIER, Lexme: "d", Line: 2, Offset: 18},
+			},
+		},
+		{
+			name:   "empty",
+			source: "/**/a",
+			want: []token.Token{
+				{Type: token.COMMENT, Lexme: "/**/", Line: 1, Offset: 0},
+				{Type: token.IDENTIFIER, Lexme: "a", Line: 1, Offset: 4},
+			},
+		},
+		{
+			name:   "unterminated",
+			source: "a /* b\nc",
+			want: []token.Token{
+				{Type: token.IDENTIFIER, Lexme: "a", Line: 1, Offset: 0},
+				{Type: token.ERROR, Lexme: "/* b\nc", Line: 1, Offset: 2},
+			},
+			err: "unterminated block comment",
+		},
+		{
+			name:   "unterminated nested",
+			source: "/* a /* b */",
+			want: []token.Token{
+				{Type: token.ERROR, Lexme: "/* a /* b */", Line: 1, Offset: 0},
+			},
+			err: "unterminated block comment",
+		},
+		{
+			name:   "division",
+			source: "a / b",
+			want: []token.Token{
+				{Type: token.IDENTIFIER, Lexme: "a", Line: 1, Offset: 0},
+				{Type: token.SLASH, Lexme: "/", Line: 1, Offset: 2},
+				{Type: token.IDENTIFIER, Lexme: "b", Line: 1, Offset: 4},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var errs []error
+		report := func(err error) { errs = append(errs, err) }
+		tokens, _ := scan.Scan(test.source, report, scan.ScanContext{IncludeComments: true})
+
+		tokens = tokens[:len(tokens)-1]
+		if len(tokens) != len(test.want) {
+			t.Errorf("%s: expected tokens %v but got %v", test.name, test.want, tokens)
+			continue
+		}
+		for i, got := range tokens {
+			want := test.want[i]
+			if got.Type != want.Type || got.Lexme != want.Lexme || got.Line != want.Line || got.Offset != want.Offset {
+				t.Errorf("%s: expected token %v at %d but got %v at %d", test.name, want, want.Offset, got, got.Offset)
+			}
+		}
+
+		switch {
+		case test.err == "" && len(errs) != 0:
+			t.Errorf("%s: unexpected errors %v", test.name, errs)
+		case test.err != "" && (len(errs) != 1 || errs[0].(scan.ScanError).Message != test.err):
+			t.Errorf("%s: expected error %q but got %v", test.name, test.err, errs)
+		}
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	source := "a\r\nb\rc\n/* x\r\ny\r*/ d // e\r\nf \"g\r\nh\" i\r\r\nj"
+	want := []token.Token{
+		{Type: token.IDENTIFIER, Lexme: "a", Line: 1, Offset: 0},
+		{Type: token.IDENTIFIER, Lexme: "b", Line: 2, Offset: 3},
+		{Type: token.IDENTIFIER, Lexme: "c", Line: 3, Offset: 5},
+		{Type: token.COMMENT, Lexme: "/* x\r\ny\r*/", Line: 4, Offset: 7},
+		{Type: token.IDENTIFIER, Lexme: "d", Line: 6, Offset: 18},
+		{Type: token.COMMENT, Lexme: "// e", Line: 6, Offset: 20},
+		{Type: token.IDENTIFIER, Lexme: "f", Line: 7, Offset: 26},
+		{Type: token.STRING, Lexme: "g\r\nh", Line: 7, Offset: 29},
+		{Type: token.IDENTIFIER, Lexme: "i", Line: 8, Offset: 35},
+		{Type: token.IDENTIFIER, Lexme: "j", Line: 10, Offset: 39},
+	}
+
+	var errs []error
+	tokens, _ := scan.Scan(source, func(err error) { errs = append(errs, err) }, scan.ScanContext{IncludeComments: true})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	tokens = tokens[:len(tokens)-1]
+	if len(tokens) != len(want) {
+		t.Fatalf("expected tokens %v but got %v", want, tokens)
+	}
+	for i, got := range tokens {
+		if got.Type != want[i].Type || got.Lexme != want[i].Lexme || got.Line != want[i].Line || got.Offset != want[i].Offset {
+			t.Errorf("expected token %v at %d but got %v at %d", want[i], want[i].Offset, got, got.Offset)
+		}
+	}
+
+	index := token.NewLineIndex(source)
+	if index.LineCount() != 10 {
+		t.Errorf("expected 10 lines but got %d", index.LineCount())
+	}
+	for _, tok := range want {
+		if pos := index.Position(tok.Offset); pos.Line != tok.Line {
+			t.Errorf("expected %v to be on line %d but the index places it at %v", tok, tok.Line, pos)
+		}
+	}
+	if start, end := index.LineStart(1), index.LineEnd(1); source[start:end] != "a" {
+		t.Errorf("expected the first line to be \"a\" but got %q", source[start:end])
+	}
+	if start, end := index.LineStart(2), index.LineEnd(2); source[start:end] != "b" {
+		t.Errorf("expected the second line to be \"b\" but got %q", source[start:end])
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	tokens, _ := scan.Scan(`12.5 "a b" c`, func(err error) { t.Error(err) }, scan.ScanContext{})
+	want := []any{12.5, "a b", nil, nil}
+	for i, tok := range tokens {
+		if tok.Literal != want[i] {
+			t.Errorf("expected the literal of %v to be %#v but got %#v", tok, want[i], tok.Literal)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		source string
+		// the lexmes of the tokens, EOF excluded
+		want []string
+		// the value of the first token
+		value float64
+	}{
+		{"1e9", []string{"1e9"}, 1e9},
+		{"2.5E-3", []string{"2.5E-3"}, 2.5e-3},
+		{"1e+2", []string{"1e+2"}, 100},
+		{".5", []string{".5"}, 0.5},
+		{".5e1", []string{".5e1"}, 5},
+		// not exponents
+		{"1e", []string{"1", "e"}, 1},
+		{"1e+", []string{"1", "e", "+"}, 1},
+		{"2.e", []string{"2", ".", "e"}, 2},
+		{"a.b", []string{"a", ".", "b"}, 0},
+		{".5.5", []string{".5", ".5"}, 0.5},
+	}
+
+	for _, test := range tests {
+		tokens, _ := scan.Scan(test.source, func(err error) { t.Error(err) }, scan.ScanContext{})
+		var lexmes []string
+		for _, tok := range tokens[:len(tokens)-1] {
+			lexmes = append(lexmes, tok.Lexme)
+		}
+		if fmt.Sprint(lexmes) != fmt.Sprint(test.want) {
+			t.Errorf("%s: expected tokens %v but got %v", test.source, test.want, lexmes)
+			continue
+		}
+		if value, ok := tokens[0].Literal.(float64); ok && value != test.value {
+			t.Errorf("%s: expected %v but got %v", test.source, test.value, value)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	source := "var größe = \"ü→ö\";\nπ2 = größe;"
+	want := []struct {
+		tok    token.Token
+		column int
+	}{
+		{token.Token{Type: token.VAR, Lexme: "var", Line: 1, Offset: 0}, 1},
+		{token.Token{Type: token.IDENTIFIER, Lexme: "größe", Line: 1, Offset: 4}, 5},
+		{token.Token{Type: token.EQUAL, Lexme: "=", Line: 1, Offset: 12}, 11},
+		{token.Token{Type: token.STRING, Lexme: "ü→ö", Line: 1, Offset: 15}, 14},
+		{token.Token{Type: token.SEMICOLON, Lexme: ";", Line: 1, Offset: 23}, 18},
+		{token.Token{Type: token.IDENTIFIER, Lexme: "π2", Line: 2, Offset: 25}, 1},
+		{token.Token{Type: token.EQUAL, Lexme: "=", Line: 2, Offset: 29}, 4},
+		{token.Token{Type: token.IDENTIFIER, Lexme: "größe", Line: 2, Offset: 31}, 6},
+		{token.Token{Type: token.SEMICOLON, Lexme: ";", Line: 2, Offset: 38}, 11},
+	}
+
+	tokens, _ := scan.Scan(source, func(err error) { t.Error(err) }, scan.ScanContext{})
+	tokens = tokens[:len(tokens)-1]
+	if len(tokens) != len(want) {
+		t.Fatalf("expected %d tokens but got %v", len(want), tokens)
+	}
+
+	index := token.NewLineIndex(source)
+	for i, got := range tokens {
+		w := want[i]
+		if got.Type != w.tok.Type || got.Lexme != w.tok.Lexme || got.Line != w.tok.Line || got.Offset != w.tok.Offset {
+			t.Errorf("expected token %v at %d but got %v at %d", w.tok, w.tok.Offset, got, got.Offset)
+		}
+		pos := index.Position(got.Offset)
+		if pos.Column != w.column {
+			t.Errorf("expected %v at column %d but got %d", got, w.column, pos.Column)
+		}
+		if offset := index.Offset(pos); offset != got.Offset {
+			t.Errorf("expected %v to be at offset %d but got %d", pos, got.Offset, offset)
+		}
+	}
+	if got := tokens[3].Literal; got != "ü→ö" {
+		t.Errorf("expected the string \"ü→ö\" but got %q", got)
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	var errs []error
+	tokens, _ := scan.Scan("a \xff b", func(err error) { errs = append(errs, err) }, scan.ScanContext{})
+	if len(errs) != 1 || len(tokens) != 4 || tokens[1].Type != token.ERROR || tokens[2].Lexme != "b" {
+		t.Errorf("expected an error for the invalid byte but got %v and %v", tokens, errs)
+	}
+}
+
+// TestTruncatedInput scans every prefix of a source, ending the input in
+// the middle of every kind of token, with Scan and the streaming Scanner.
+func TestTruncatedInput(t *testing.T) {
+	source := "var größe = \"a\nb\"; /* c /* d */ */ print 1.5e-3 + .25 >= 0x; // e\r\n!= \xff 12E+"
+	for end := range len(source) + 1 {
+		prefix := source[:end]
+		var reported []error
+		tokens, err := scan.Scan(prefix, func(err error) { reported = append(reported, err) }, scan.ScanContext{IncludeComments: true})
+		if (err != nil) != (len(reported) > 0) {
+			t.Fatalf("%q: returned %v but reported %v", prefix, err, reported)
+		}
+		if last := tokens[len(tokens)-1]; last.Type != token.EOF || last.Offset != len(prefix) {
+			t.Fatalf("%q: expected EOF at %d but got %v", prefix, len(prefix), last)
+		}
+		for _, tok := range tokens {
+			if tok.Offset < 0 || tok.Offset+len(tok.Lexme) > len(prefix) {
+				t.Fatalf("%q: token %v is out of bounds", prefix, tok)
+			}
+		}
+
+		s := scan.NewScanner(iotest.OneByteReader(strings.NewReader(prefix)))
+		for i := 0; ; i++ {
+			tok, _ := s.Next()
+			if tok.Type == token.EOF {
+				break
+			}
+			if i > len(prefix) {
+				t.Fatalf("%q: expected the scanner to reach EOF", prefix)
+			}
+		}
+	}
+}
+
+func TestScanner(t *testing.T) {
+	source := "var größe = \"a\nb\"; // c\r\n/* d */ print größe @ 1.5;\n/* e"
+	var want []error
+	tokens, _ := scan.Scan(source, func(err error) { want = append(want, err) }, scan.ScanContext{IncludeComments: true})
+
+	// one byte at a time to split characters and tokens between reads
+	s := scan.NewScanner(iotest.OneByteReader(strings.NewReader(source)))
+	s.SetContext(scan.ScanContext{IncludeComments: true})
+	var errs []error
+	for i, want := range tokens {
+		got, err := s.Next()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if got != want {
+			t.Errorf("expected token %d to be %v at %d but got %v at %d", i, want, want.Offset, got, got.Offset)
+		}
+	}
+	if fmt.Sprint(errs) != fmt.Sprint(want) {
+		t.Errorf("expected errors %v but got %v", want, errs)
+	}
+
+	if tok, err := s.Next(); tok.Type != token.EOF || err != nil {
+		t.Errorf("expected EOF again but got %v, %v", tok, err)
+	}
+
+	failing := scan.NewScanner(io.MultiReader(strings.NewReader("a b"), iotest.ErrReader(io.ErrUnexpectedEOF)))
+	for {
+		tok, err := failing.Next()
+		if err != nil {
+			if err != io.ErrUnexpectedEOF {
+				t.Errorf("expected the read error but got %v", err)
+			}
+			break
+		}
+		if tok.Type == token.EOF {
+			t.Error("expected the read error but got EOF")
+			break
+		}
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	_, err := scan.Scan("a @ b # \"c", func(error) {}, scan.ScanContext{})
+	var list scan.ErrorList
+	if !errors.As(err, &list) || len(list) != 3 {
+		t.Fatalf("expected 3 errors but got %v", err)
+	}
+	for i, message := range []string{"unexpected character '@'", "unexpected character '#'", "unterminated string"} {
+		if list[i].Message != message {
+			t.Errorf("expected error %d to be %q but got %v", i, message, list[i])
+		}
+	}
+	// an unterminated string is reported at its opening quote
+	if offset, length := list[2].Span(); offset != 8 || length != 1 {
+		t.Errorf("expected the unterminated string at its quote but got %d, %d", offset, length)
+	}
+
+	if _, err := scan.Scan("a b", func(error) {}, scan.ScanContext{}); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+}
+
+// FuzzScan scans arbitrary sources, which must never panic and must
+// report every error they return, run with go test -fuzz FuzzScan ./internal/scan
+func FuzzScan(f *testing.F) {
+	for _, seed := range []string{"var a = 1.5e3;", `"unterminated`, "/* open", "a // b\n.5e", "1e+", "é \x00 \r\n"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, source string) {
+		var reported []error
+		tokens, err := scan.Scan(source, func(err error) { reported = append(reported, err) },
+			scan.ScanContext{IncludeComments: true, IncludeWhitespace: true})
+
+		var list scan.ErrorList
+		if (err != nil) != (len(reported) > 0) || (err != nil && (!errors.As(err, &list) || len(list) != len(reported))) {
+			t.Fatalf("returned %v but reported %v", err, reported)
+		}
+		if len(tokens) == 0 || tokens[len(tokens)-1].Type != token.EOF {
+			t.Fatalf("expected the tokens to end with EOF but got %v", tokens)
+		}
+		for _, tok := range tokens {
+			if tok.Offset < 0 || tok.Offset+len(tok.Lexme) > len(source) {
+				t.Fatalf("token %v is out of the bounds of the source", tok)
+			}
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package scan
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+)
+
+// Scanner scans a script read from an io.Reader one token at a time.
+// Unlike Scan it never holds the whole source or every token in memory,
+// only the source of the token being scanned, so it suits very large
+// scripts and tools scanning as they read.
+type Scanner struct {
+	s *scanner
+	// the first error reported while scanning the next token
+	err error
+}
+
+// NewScanner returns a scanner reading the script from r, scanning
+// in the default context. See SetContext to change it.
+func NewScanner(r io.Reader) *Scanner {
+	sc := &Scanner{}
+	sc.SetContext(ScanContext{})
+	sc.s.reader = r
+	return sc
+}
+
+// SetContext sets the context the tokens are scanned in, it must
+// be called before the first call to Next.
+func (sc *Scanner) SetContext(context ScanContext) {
+	report := func(err error) {
+		if sc.err == nil {
+			sc.err = err
+		}
+	}
+
+	s := newScanner("", report, context)
+	if sc.s != nil {
+		s.reader = sc.s.reader
+	}
+	sc.s = s
+}
+
+// Next returns the next token. A token the scanner fails to scan is
+// returned as an ERROR token together with its ScanError, scanning
+// continues after it. Once the input ends Next returns the EOF token
+// on every call, or the error reading the input failed with. Errors
+// outside of tokens, such as an unterminated comment, are returned
+// with the token following them.
+func (sc *Scanner) Next() (token.Token, error) {
+	s := sc.s
+	for len(s.tokens) == 0 {
+		if atEndOfFile(s) {
+			if s.readErr != nil {
+				return token.Token{}, s.readErr
+			}
+			// e.g. an unterminated comment, which is not a token
+			return token.NewToken(token.EOF, "", nil, s.line, s.base+len(s.src)), sc.takeErr()
+		}
+
+		// the source of the returned tokens is no longer needed
+		s.base += s.tokenStart
+		s.src = s.src[s.tokenStart:]
+		s.tokenStart = 0
+		s.tokenEnd = 0
+		scanToken(s)
+	}
+
+	tok := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	return tok, sc.takeErr()
+}
+
+func (sc *Scanner) takeErr() error {
+	err := sc.err
+	sc.err = nil
+	return err
+}
@@ -0,0 +1,45 @@
+package token
+
+// Class groups token types by the role they play in the source,
+// e.g. to highlight them.
+type Class string
+
+const (
+	ClassKeyword    Class = "keyword"
+	ClassIdentifier Class = "identifier"
+	ClassString     Class = "string"
+	ClassNumber     Class = "number"
+	// true, false and nil
+	ClassConstant    Class = "constant"
+	ClassOperator    Class = "operator"
+	ClassPunctuation Class = "punctuation"
+	ClassComment     Class = "comment"
+	// whitespace, EOF and errors
+	ClassOther Class = "other"
+)
+
+func (t TokenType) Class() Class {
+	switch t {
+	case COMMENT:
+		return ClassComment
+	case IDENTIFIER:
+		return ClassIdentifier
+	case STRING:
+		return ClassString
+	case NUMBER:
+		return ClassNumber
+	case TRUE, FALSE, NIL:
+		return ClassConstant
+	case LEFT_PAREN, RIGHT_PAREN, LEFT_BRACE, RIGHT_BRACE, LEFT_BRACKET,
+		RIGHT_BRACKET, COMMA, DOT, SEMICOLON, ARROW:
+		return ClassPunctuation
+	}
+
+	switch {
+	case t >= PLUS && t <= MINUS_MINUS:
+		return ClassOperator
+	case t >= AND && t <= IS:
+		return ClassKeyword
+	}
+	return ClassOther
+}
@@ -0,0 +1,13 @@
+package token
+
+// Dialect selects between variations of the language which
+// differ in their keywords and standard library.
+type Dialect uint8
+
+const (
+	// the language as described in Crafting Interpreters, print is a statement
+	BOOK Dialect = iota
+	// print is a native function called like print(value) rather than
+	// a statement, "print" is an ordinary identifier
+	NATIVE_PRINT
+)
@@ -0,0 +1,102 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Position is a human readable location in a source, both line
+// and column start at 1. Columns are counted in characters, a multi-byte
+// UTF-8 character is a single column.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// LineIndex converts between byte offsets and positions in a source. It
+// is built once per source so that components which need positions (the
+// diagnostics renderer, tooling etc.) do not have to recount newlines.
+type LineIndex struct {
+	// byte offset of the first character of every line
+	lines []int
+	// byte offset of the line break ending every line but the last
+	ends   []int
+	source string
+}
+
+// NewLineIndex indexes the lines of source, which end
+// with "\n", "\r\n" or a lone "\r".
+func NewLineIndex(source string) *LineIndex {
+	lines := []int{0}
+	var ends []int
+	for i := 0; i < len(source); i++ {
+		switch {
+		case source[i] == '\n':
+			end := i
+			if i > 0 && source[i-1] == '\r' {
+				end--
+			}
+			ends = append(ends, end)
+			lines = append(lines, i+1)
+		case source[i] == '\r' && (i+1 == len(source) || source[i+1] != '\n'):
+			ends = append(ends, i)
+			lines = append(lines, i+1)
+		}
+	}
+
+	return &LineIndex{lines: lines, ends: ends, source: source}
+}
+
+// LineCount returns the number of lines in the source, a source
+// always has at least one (possibly empty) line.
+func (l *LineIndex) LineCount() int {
+	return len(l.lines)
+}
+
+// LineStart returns the byte offset of the first character of line.
+// Lines out of range are clamped to the first and last line.
+func (l *LineIndex) LineStart(line int) int {
+	return l.lines[l.clampLine(line)-1]
+}
+
+// LineEnd returns the byte offset of the line break ending line, or
+// the size of the source for the last line.
+func (l *LineIndex) LineEnd(line int) int {
+	line = l.clampLine(line)
+	if line == len(l.lines) {
+		return len(l.source)
+	}
+
+	return l.ends[line-1]
+}
+
+// Position returns the position of offset. Offsets out of range
+// are clamped to the start and end of the source.
+func (l *LineIndex) Position(offset int) Position {
+	offset = max(0, min(offset, len(l.source)))
+	// index of the first line starting after offset
+	line := sort.SearchInts(l.lines, offset+1)
+	column := utf8.RuneCountInString(l.source[l.lines[line-1]:offset]) + 1
+	return Position{Line: line, Column: column}
+}
+
+// Offset returns the byte offset of pos, the inverse of Position.
+// Columns past the end of the line are clamped to the end of the line.
+func (l *LineIndex) Offset(pos Position) int {
+	offset := l.LineStart(pos.Line)
+	end := l.LineEnd(pos.Line)
+	for column := 1; column < pos.Column && offset < end; column++ {
+		_, width := utf8.DecodeRuneInString(l.source[offset:end])
+		offset += width
+	}
+	return offset
+}
+
+func (l *LineIndex) clampLine(line int) int {
+	return max(1, min(line, len(l.lines)))
+}
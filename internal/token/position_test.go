@@ -0,0 +1,72 @@
+package token_test
+
+import (
+	"github.com/LucazFFz/lox/internal/token"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLineIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		lines  int
+		// positions of offsets in the source
+		positions map[int]token.Position
+	}{
+		{"empty", "", 1, map[int]token.Position{0: {1, 1}}},
+		{"single line", "ab", 1, map[int]token.Position{0: {1, 1}, 1: {1, 2}, 2: {1, 3}}},
+		{"line feeds", "a\nb\n", 3, map[int]token.Position{1: {1, 2}, 2: {2, 1}, 4: {3, 1}}},
+		{"crlf", "a\r\nb\rc", 3, map[int]token.Position{1: {1, 2}, 2: {1, 3}, 3: {2, 1}, 5: {3, 1}, 6: {3, 2}}},
+		{"multibyte", "é€\nx😀y", 2, map[int]token.Position{2: {1, 2}, 5: {1, 3}, 6: {2, 1}, 7: {2, 2}, 11: {2, 3}, 12: {2, 4}}},
+	}
+
+	for _, test := range tests {
+		index := token.NewLineIndex(test.source)
+		if index.LineCount() != test.lines {
+			t.Errorf("%s: expected %d lines but got %d", test.name, test.lines, index.LineCount())
+		}
+
+		for offset, want := range test.positions {
+			if got := index.Position(offset); got != want {
+				t.Errorf("%s: expected offset %d at %v but got %v", test.name, offset, want, got)
+			}
+		}
+
+		// every offset starting a character or a line break round trips,
+		// the offsets within a "\r\n" break resolve to the end of its line
+		for offset := 0; offset <= len(test.source); {
+			pos := index.Position(offset)
+			if got := index.Offset(pos); got != offset {
+				t.Errorf("%s: expected %v to be at offset %d but got %d", test.name, pos, offset, got)
+			}
+			if offset == len(test.source) {
+				break
+			}
+			_, width := utf8.DecodeRuneInString(test.source[offset:])
+			if test.source[offset:min(offset+2, len(test.source))] == "\r\n" {
+				width = 2
+			}
+			offset += width
+		}
+	}
+}
+
+func TestLineIndexClamps(t *testing.T) {
+	index := token.NewLineIndex("ab\ncd")
+	if got := index.Position(-1); got != (token.Position{Line: 1, Column: 1}) {
+		t.Errorf("expected a negative offset at the start but got %v", got)
+	}
+	if got := index.Position(100); got != (token.Position{Line: 2, Column: 3}) {
+		t.Errorf("expected an offset past the end at the end but got %v", got)
+	}
+	if got := index.Offset(token.Position{Line: 1, Column: 10}); got != 2 {
+		t.Errorf("expected a column past the end of a line at its end but got %d", got)
+	}
+	if got := index.LineStart(5); got != 3 {
+		t.Errorf("expected a line past the end to be the last line but got %d", got)
+	}
+	if got := index.LineEnd(2); got != 5 {
+		t.Errorf("expected the last line to end at the end of the source but got %d", got)
+	}
+}
@@ -8,14 +8,18 @@ import (
 type TokenType uint8
 
 type Token struct {
-	Type    TokenType
-	Lexme   string
-	Literal []byte
+	Type  TokenType
+	Lexme string
+	// the value of a literal, a float64 for a NUMBER
+	// and a string for a STRING, nil for other tokens
+	Literal any
 	Line    int
+	// byte offset of the start of the lexme in the source
+	Offset int
 }
 
-func NewToken(token TokenType, lexme string, literal []byte, line int) Token {
-	return Token{token, lexme, literal, line}
+func NewToken(token TokenType, lexme string, literal any, line int, offset int) Token {
+	return Token{token, lexme, literal, line, offset}
 }
 
 func (t Token) String() string {
@@ -26,13 +30,15 @@ const (
 	WHITESPACE TokenType = iota
 	COMMENT
 	EOF
-    ERROR
+	ERROR
 
 	// Single-character tokens.
 	LEFT_PAREN
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
 	DOT
 	PLUS
@@ -52,6 +58,10 @@ const (
 	LESS_EQUAL
 	COLON
 	QUESTION
+	PLUS_PLUS
+	MINUS_MINUS
+	// -> preceding the return type of a function
+	ARROW
 
 	// Literals
 	IDENTIFIER
@@ -75,5 +85,13 @@ const (
 	TRUE
 	VAR
 	WHILE
-    BREAK
+	BREAK
+	IN
+	CONTINUE
+	YIELD
+	DO
+	ASSERT
+	IMPORT
+	OBJECT
+	IS
 )
@@ -16,51 +16,66 @@ func _() {
 	_ = x[RIGHT_PAREN-5]
 	_ = x[LEFT_BRACE-6]
 	_ = x[RIGHT_BRACE-7]
-	_ = x[COMMA-8]
-	_ = x[DOT-9]
-	_ = x[PLUS-10]
-	_ = x[MINUS-11]
-	_ = x[SEMICOLON-12]
-	_ = x[SLASH-13]
-	_ = x[STAR-14]
-	_ = x[BANG-15]
-	_ = x[BANG_EQUAL-16]
-	_ = x[EQUAL-17]
-	_ = x[EQUAL_EQUAL-18]
-	_ = x[GREATER-19]
-	_ = x[GREATER_EQUAL-20]
-	_ = x[LESS-21]
-	_ = x[LESS_EQUAL-22]
-	_ = x[COLON-23]
-	_ = x[QUESTION-24]
-	_ = x[IDENTIFIER-25]
-	_ = x[STRING-26]
-	_ = x[NUMBER-27]
-	_ = x[AND-28]
-	_ = x[CLASS-29]
-	_ = x[ELSE-30]
-	_ = x[FALSE-31]
-	_ = x[FUN-32]
-	_ = x[FOR-33]
-	_ = x[IF-34]
-	_ = x[NIL-35]
-	_ = x[OR-36]
-	_ = x[PRINT-37]
-	_ = x[RETURN-38]
-	_ = x[SUPER-39]
-	_ = x[THIS-40]
-	_ = x[TRUE-41]
-	_ = x[VAR-42]
-	_ = x[WHILE-43]
+	_ = x[LEFT_BRACKET-8]
+	_ = x[RIGHT_BRACKET-9]
+	_ = x[COMMA-10]
+	_ = x[DOT-11]
+	_ = x[PLUS-12]
+	_ = x[MINUS-13]
+	_ = x[SEMICOLON-14]
+	_ = x[SLASH-15]
+	_ = x[STAR-16]
+	_ = x[BANG-17]
+	_ = x[BANG_EQUAL-18]
+	_ = x[EQUAL-19]
+	_ = x[EQUAL_EQUAL-20]
+	_ = x[GREATER-21]
+	_ = x[GREATER_EQUAL-22]
+	_ = x[LESS-23]
+	_ = x[LESS_EQUAL-24]
+	_ = x[COLON-25]
+	_ = x[QUESTION-26]
+	_ = x[PLUS_PLUS-27]
+	_ = x[MINUS_MINUS-28]
+	_ = x[ARROW-29]
+	_ = x[IDENTIFIER-30]
+	_ = x[STRING-31]
+	_ = x[NUMBER-32]
+	_ = x[AND-33]
+	_ = x[CLASS-34]
+	_ = x[ELSE-35]
+	_ = x[FALSE-36]
+	_ = x[FUN-37]
+	_ = x[FOR-38]
+	_ = x[IF-39]
+	_ = x[NIL-40]
+	_ = x[OR-41]
+	_ = x[PRINT-42]
+	_ = x[RETURN-43]
+	_ = x[SUPER-44]
+	_ = x[THIS-45]
+	_ = x[TRUE-46]
+	_ = x[VAR-47]
+	_ = x[WHILE-48]
+	_ = x[BREAK-49]
+	_ = x[IN-50]
+	_ = x[CONTINUE-51]
+	_ = x[YIELD-52]
+	_ = x[DO-53]
+	_ = x[ASSERT-54]
+	_ = x[IMPORT-55]
+	_ = x[OBJECT-56]
+	_ = x[IS-57]
 }
 
-const _TokenType_name = "WHITESPACECOMMENTEOFERRORLEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTPLUSMINUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALCOLONQUESTIONIDENTIFIERSTRINGNUMBERANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSUPERTHISTRUEVARWHILE"
+const _TokenType_name = "WHITESPACECOMMENTEOFERRORLEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACELEFT_BRACKETRIGHT_BRACKETCOMMADOTPLUSMINUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALCOLONQUESTIONPLUS_PLUSMINUS_MINUSARROWIDENTIFIERSTRINGNUMBERANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSUPERTHISTRUEVARWHILEBREAKINCONTINUEYIELDDOASSERTIMPORTOBJECTIS"
 
-var _TokenType_index = [...]uint16{0, 10, 17, 20, 25, 35, 46, 56, 67, 72, 75, 79, 84, 93, 98, 102, 106, 116, 121, 132, 139, 152, 156, 166, 171, 179, 189, 195, 201, 204, 209, 213, 218, 221, 224, 226, 229, 231, 236, 242, 247, 251, 255, 258, 263}
+var _TokenType_index = [...]uint16{0, 10, 17, 20, 25, 35, 46, 56, 67, 79, 92, 97, 100, 104, 109, 118, 123, 127, 131, 141, 146, 157, 164, 177, 181, 191, 196, 204, 213, 224, 229, 239, 245, 251, 254, 259, 263, 268, 271, 274, 276, 279, 281, 286, 292, 297, 301, 305, 308, 313, 318, 320, 328, 333, 335, 341, 347, 353, 355}
 
 func (i TokenType) String() string {
-	if i >= TokenType(len(_TokenType_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_TokenType_index)-1 {
 		return "TokenType(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _TokenType_name[_TokenType_index[i]:_TokenType_index[i+1]]
+	return _TokenType_name[_TokenType_index[idx]:_TokenType_index[idx+1]]
 }
@@ -0,0 +1,539 @@
+// Package typecheck checks the types of resolved programs before they are
+// interpreted. Variables, parameters and functions may be annotated with
+// the type of their values:
+//
+//	var x: num = 3;
+//	fun f(a: str) -> num { return len(a); }
+//
+// The checker infers the types of literals and operators and reports
+// values which cannot have the type they are used as, such as a string
+// assigned to a variable annotated num or a number added to a string.
+// Values of unannotated variables, parameters and calls have the type any,
+// which matches every type, so unannotated programs only fail if an
+// operator is applied to values known to have the wrong type, such as
+// 1 + "a". The interpreter ignores annotations, checking them is opt-in.
+package typecheck
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/token"
+	"sort"
+)
+
+// Type is the type of a value known before the program runs.
+type Type uint8
+
+const (
+	// the type of values whose type is not known, matches every type
+	ANY Type = iota
+	BOOLEAN
+	NUMBER
+	STRING
+	NIL
+	LIST
+	MAP
+	FUNCTION
+)
+
+// the names types are annotated with, by type
+var typeNames = [...]string{
+	ANY:      "any",
+	BOOLEAN:  "bool",
+	NUMBER:   "num",
+	STRING:   "str",
+	NIL:      "nil",
+	LIST:     "list",
+	MAP:      "map",
+	FUNCTION: "fun",
+}
+
+func (t Type) String() string {
+	return typeNames[t]
+}
+
+// matches reports whether a value of type t can be used as a value of type to
+func (t Type) matches(to Type) bool {
+	return t == ANY || to == ANY || t == to
+}
+
+type TypeError struct {
+	Message string
+	Line    int
+	Lexme   string
+	Offset  int
+}
+
+func (e TypeError) Error() string {
+	return fmt.Sprintf("[%d] error at \"%s\" - %s \n", e.Line, e.Lexme, e.Message)
+}
+
+// Span returns the byte offset and length of the offending lexme.
+func (e TypeError) Span() (int, int) {
+	return e.Offset, len(e.Lexme)
+}
+
+// function is the signature of a declared function
+type function struct {
+	name       string
+	parameters []token.Token
+	types      []Type
+	returns    Type
+	generator  bool
+}
+
+type variable struct {
+	typ Type
+	// the signature of a declared function, nil for other variables
+	// and functions which may have been reassigned
+	function *function
+}
+
+// checker visits the nodes of a program, the visit
+// methods of expressions return the Type of the expression
+type checker struct {
+	// the scopes of the program, the global scope first
+	scopes []map[string]*variable
+	// the function whose body is checked, nil at the top level
+	function *function
+	errs     []TypeError
+}
+
+// Check checks the types of statements, which must have been resolved,
+// reporting every error to report in source order. The returned error
+// signals that an error was reported.
+func Check(statements []ast.Stmt, report func(error)) error {
+	c := &checker{scopes: []map[string]*variable{{}}}
+	c.stmts(statements)
+
+	sort.SliceStable(c.errs, func(i, j int) bool {
+		return c.errs[i].Offset < c.errs[j].Offset
+	})
+	for _, err := range c.errs {
+		report(err)
+	}
+
+	if len(c.errs) > 0 {
+		return errors.New("type error occured")
+	}
+	return nil
+}
+
+func (c *checker) error(tok token.Token, msg string) {
+	c.errs = append(c.errs, TypeError{Message: msg, Line: tok.Line, Lexme: tok.Lexme, Offset: tok.Offset})
+}
+
+// annotation returns the type typ names, ANY if it is zero
+func (c *checker) annotation(typ token.Token) Type {
+	if typ.Lexme == "" {
+		return ANY
+	}
+
+	for t, name := range typeNames {
+		if name == typ.Lexme {
+			return Type(t)
+		}
+	}
+	c.error(typ, fmt.Sprintf("unknown type '%s'", typ.Lexme))
+	return ANY
+}
+
+func (c *checker) beginScope() {
+	c.scopes = append(c.scopes, map[string]*variable{})
+}
+
+func (c *checker) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *checker) declare(name token.Token, v *variable) {
+	c.scopes[len(c.scopes)-1][name.Lexme] = v
+}
+
+// lookup returns the innermost variable called name, nil for
+// variables not declared before, such as natives
+func (c *checker) lookup(name token.Token) *variable {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if v, ok := c.scopes[i][name.Lexme]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func (c *checker) stmts(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		c.stmt(stmt)
+	}
+}
+
+func (c *checker) stmt(stmt ast.Stmt) {
+	if stmt != nil {
+		stmt.Accept(c)
+	}
+}
+
+// typeOf returns the type of e, NIL for an omitted expression
+func (c *checker) typeOf(e ast.Expr) Type {
+	if e == nil {
+		return NIL
+	}
+	return e.Accept(c).(Type)
+}
+
+func (c *checker) VisitExpressionStmt(stmt ast.ExpressionStmt) any {
+	c.typeOf(stmt.Expr)
+	return nil
+}
+
+func (c *checker) VisitPrintStmt(stmt ast.PrintStmt) any {
+	c.typeOf(stmt.Expr)
+	return nil
+}
+
+func (c *checker) VisitVarStmt(stmt ast.VarStmt) any {
+	typ := c.annotation(stmt.Type)
+	value := c.typeOf(stmt.Initializer)
+	// a variable declared without an initializer is assigned later
+	if _, ok := stmt.Initializer.(ast.NothingExpr); !ok && !value.matches(typ) {
+		c.error(ast.ExprToken(stmt.Initializer),
+			fmt.Sprintf("cannot use %s as %s in the declaration of '%s'", value, typ, stmt.Name.Lexme))
+	}
+
+	c.declare(stmt.Name, &variable{typ: typ})
+	return nil
+}
+
+func (c *checker) VisitBlockStmt(stmt ast.BlockStmt) any {
+	c.beginScope()
+	c.stmts(stmt.Statements)
+	c.endScope()
+	return nil
+}
+
+func (c *checker) VisitIfStmt(stmt ast.IfStmt) any {
+	c.typeOf(stmt.Condition)
+	c.stmt(stmt.ThenBranch)
+	c.stmt(stmt.ElseBranch)
+	return nil
+}
+
+func (c *checker) VisitWhileStmt(stmt ast.WhileStmt) any {
+	c.typeOf(stmt.Condition)
+	c.stmt(stmt.Body)
+	c.typeOf(stmt.Increment)
+	return nil
+}
+
+func (c *checker) VisitForInStmt(stmt ast.ForInStmt) any {
+	c.typeOf(stmt.Iterable)
+	c.beginScope()
+	c.declare(stmt.Name, &variable{typ: ANY})
+	c.stmt(stmt.Body)
+	c.endScope()
+	return nil
+}
+
+func (c *checker) VisitBreakStmt(stmt ast.BreakStmt) any {
+	return nil
+}
+
+func (c *checker) VisitContinueStmt(stmt ast.ContinueStmt) any {
+	return nil
+}
+
+func (c *checker) VisitAssertStmt(stmt ast.AssertStmt) any {
+	c.typeOf(stmt.Condition)
+	c.typeOf(stmt.Message)
+	return nil
+}
+
+func (c *checker) VisitImportStmt(stmt ast.ImportStmt) any {
+	if stmt.Path.Type == token.IDENTIFIER {
+		c.declare(stmt.Path, &variable{typ: MAP})
+	}
+	return nil
+}
+
+func (c *checker) VisitYieldStmt(stmt ast.YieldStmt) any {
+	c.typeOf(stmt.Expr)
+	return nil
+}
+
+func (c *checker) VisitReturnStmt(stmt ast.ReturnStmt) any {
+	value := c.typeOf(stmt.Expr)
+	// the value returned by a generator is discarded
+	if c.function == nil || c.function.generator || value.matches(c.function.returns) {
+		return nil
+	}
+
+	tok := ast.StmtToken(stmt)
+	if stmt.Expr != nil {
+		tok = ast.ExprToken(stmt.Expr)
+	}
+	c.error(tok, fmt.Sprintf("cannot return %s from %s returning %s", value, c.function.name, c.function.returns))
+	return nil
+}
+
+func (c *checker) VisitFunctionStmt(stmt ast.FunctionStmt) any {
+	fn := c.signature("'"+stmt.Name.Lexme+"'", stmt.Parameters, stmt.ParameterTypes, stmt.ReturnType, stmt.IsGenerator)
+	// declared before the body is checked so it can call itself
+	c.declare(stmt.Name, &variable{typ: FUNCTION, function: fn})
+	c.body(fn, stmt.Body)
+	return nil
+}
+
+// signature returns the signature of a function called name
+func (c *checker) signature(name string, parameters []token.Token, types []token.Token,
+	returns token.Token, generator bool) *function {
+	fn := &function{name: name, parameters: parameters, returns: c.annotation(returns), generator: generator}
+	for i := range parameters {
+		typ := ANY
+		if i < len(types) {
+			typ = c.annotation(types[i])
+		}
+		fn.types = append(fn.types, typ)
+	}
+	return fn
+}
+
+// body checks the body of fn with its parameters in scope
+func (c *checker) body(fn *function, body []ast.Stmt) {
+	enclosing := c.function
+	c.function = fn
+	c.beginScope()
+	for i, param := range fn.parameters {
+		c.declare(param, &variable{typ: fn.types[i]})
+	}
+	c.stmts(body)
+	c.endScope()
+	c.function = enclosing
+}
+
+func (c *checker) VisitBinaryExpr(expr ast.BinaryExpr) any {
+	left, right := c.typeOf(expr.Left), c.typeOf(expr.Right)
+
+	switch expr.Op.Type {
+	case token.PLUS, token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL:
+		if !operand(left) || !operand(right) || !left.matches(right) {
+			c.error(expr.Op, fmt.Sprintf("operands of '%s' must be two numbers or two strings but got %s and %s",
+				expr.Op.Lexme, left, right))
+			return ANY
+		}
+
+		if expr.Op.Type != token.PLUS {
+			return BOOLEAN
+		}
+		// both operands have the same type
+		if left == ANY {
+			return right
+		}
+		return left
+	case token.MINUS, token.STAR, token.SLASH:
+		if !left.matches(NUMBER) || !right.matches(NUMBER) {
+			c.error(expr.Op, fmt.Sprintf("operands of '%s' must be numbers but got %s and %s",
+				expr.Op.Lexme, left, right))
+		}
+		return NUMBER
+	case token.EQUAL_EQUAL, token.BANG_EQUAL, token.IS:
+		return BOOLEAN
+	case token.AND, token.OR:
+		if left == right {
+			return left
+		}
+		return ANY
+	case token.COMMA:
+		return right
+	}
+	return ANY
+}
+
+// operand reports whether typ may be an operand of '+' and the
+// comparison operators, which add and compare numbers and strings
+func operand(typ Type) bool {
+	return typ == ANY || typ == NUMBER || typ == STRING
+}
+
+func (c *checker) VisitGroupingExpr(expr ast.GroupingExpr) any {
+	return c.typeOf(expr.Expr)
+}
+
+func (c *checker) VisitLiteralExpr(expr ast.LiteralExpr) any {
+	switch expr.Value.Type() {
+	case ast.BOOLEAN:
+		return BOOLEAN
+	case ast.NUMBER:
+		return NUMBER
+	case ast.STRING:
+		return STRING
+	case ast.NIL:
+		return NIL
+	}
+	return ANY
+}
+
+func (c *checker) VisitVariableExpr(expr ast.VariableExpr) any {
+	if v := c.lookup(expr.Name); v != nil {
+		return v.typ
+	}
+	return ANY
+}
+
+func (c *checker) VisitUnaryExpr(expr ast.UnaryExpr) any {
+	right := c.typeOf(expr.Right)
+	if expr.Op.Type == token.BANG {
+		return BOOLEAN
+	}
+
+	if !right.matches(NUMBER) {
+		c.error(expr.Op, fmt.Sprintf("operand of '%s' must be a number but got %s", expr.Op.Lexme, right))
+	}
+	return NUMBER
+}
+
+func (c *checker) VisitPrefixExpr(expr ast.PrefixExpr) any {
+	return c.increment(expr.Op, expr.Target)
+}
+
+func (c *checker) VisitPostfixExpr(expr ast.PostfixExpr) any {
+	return c.increment(expr.Op, expr.Target)
+}
+
+// increment checks the target of a ++ or -- operator
+func (c *checker) increment(op token.Token, target ast.Expr) Type {
+	if typ := c.typeOf(target); !typ.matches(NUMBER) {
+		c.error(op, fmt.Sprintf("operand of '%s' must be a number but got %s", op.Lexme, typ))
+	}
+	return NUMBER
+}
+
+func (c *checker) VisitTernaryExpr(expr ast.TernaryExpr) any {
+	c.typeOf(expr.Condition)
+	left, right := c.typeOf(expr.Left), c.typeOf(expr.Right)
+	if left == right {
+		return left
+	}
+	return ANY
+}
+
+func (c *checker) VisitAssignExpr(expr ast.AssignExpr) any {
+	value := c.typeOf(expr.Value)
+	v := c.lookup(expr.Name)
+	if v == nil {
+		return value
+	}
+
+	if !value.matches(v.typ) {
+		c.error(ast.ExprToken(expr.Value), fmt.Sprintf("cannot assign %s to '%s' of type %s", value, expr.Name.Lexme, v.typ))
+	}
+	// the function the variable refers to is no longer known
+	v.function = nil
+	return value
+}
+
+func (c *checker) VisitListExpr(expr ast.ListExpr) any {
+	for _, element := range expr.Elements {
+		c.typeOf(element)
+	}
+	return LIST
+}
+
+func (c *checker) VisitMapExpr(expr ast.MapExpr) any {
+	for i := range expr.Keys {
+		c.typeOf(expr.Keys[i])
+		c.typeOf(expr.Values[i])
+	}
+	return MAP
+}
+
+func (c *checker) VisitObjectExpr(expr ast.ObjectExpr) any {
+	for _, value := range expr.Values {
+		c.typeOf(value)
+	}
+	return ANY
+}
+
+func (c *checker) VisitIndexExpr(expr ast.IndexExpr) any {
+	object := c.typeOf(expr.Object)
+	c.typeOf(expr.Index)
+	// the characters of a string are strings
+	if object == STRING {
+		return STRING
+	}
+	return ANY
+}
+
+func (c *checker) VisitSliceExpr(expr ast.SliceExpr) any {
+	object := c.typeOf(expr.Object)
+	c.typeOf(expr.Start)
+	c.typeOf(expr.End)
+	if object == STRING || object == LIST {
+		return object
+	}
+	return ANY
+}
+
+func (c *checker) VisitIndexAssignExpr(expr ast.IndexAssignExpr) any {
+	c.typeOf(expr.Object)
+	c.typeOf(expr.Index)
+	return c.typeOf(expr.Value)
+}
+
+func (c *checker) VisitSliceAssignExpr(expr ast.SliceAssignExpr) any {
+	c.typeOf(expr.Object)
+	c.typeOf(expr.Start)
+	c.typeOf(expr.End)
+	return c.typeOf(expr.Value)
+}
+
+func (c *checker) VisitGetExpr(expr ast.GetExpr) any {
+	c.typeOf(expr.Object)
+	return ANY
+}
+
+func (c *checker) VisitSetExpr(expr ast.SetExpr) any {
+	c.typeOf(expr.Object)
+	return c.typeOf(expr.Value)
+}
+
+func (c *checker) VisitCallExpr(expr ast.CallExpr) any {
+	c.typeOf(expr.Callee)
+	arguments := make([]Type, len(expr.Arguments))
+	for i, argument := range expr.Arguments {
+		arguments[i] = c.typeOf(argument)
+	}
+
+	variable, ok := expr.Callee.(ast.VariableExpr)
+	if !ok {
+		return ANY
+	}
+	v := c.lookup(variable.Name)
+	if v == nil || v.function == nil {
+		return ANY
+	}
+
+	// the number of arguments is checked when the function is called
+	fn := v.function
+	for i, typ := range arguments {
+		if i < len(fn.types) && !typ.matches(fn.types[i]) {
+			c.error(ast.ExprToken(expr.Arguments[i]), fmt.Sprintf("cannot use %s as %s for parameter '%s' of %s",
+				typ, fn.types[i], fn.parameters[i].Lexme, fn.name))
+		}
+	}
+
+	if fn.generator {
+		return ANY
+	}
+	return fn.returns
+}
+
+func (c *checker) VisitFunctionExpr(expr ast.FunctionExpr) any {
+	fn := c.signature("a function", expr.Parameters, expr.ParameterTypes, expr.ReturnType, expr.IsGenerator)
+	c.body(fn, expr.Body)
+	return FUNCTION
+}
+
+func (c *checker) VisitNothingExpr(expr ast.NothingExpr) any {
+	return NIL
+}
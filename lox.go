@@ -1,64 +1,112 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
 	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/readline"
+	"github.com/LucazFFz/lox/internal/resolve"
 	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"github.com/LucazFFz/lox/internal/typecheck"
 	"github.com/urfave/cli/v2"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
+// the dialect scripts are scanned and interpreted in
+var dialect = token.BOOK
+
+// whether the types of scripts are checked before they run, see --typecheck
+var checkTypes = false
+
+// the writers the output of scripts and the REPL and diagnostics are
+// written to, replaced in tests
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
 func main() {
+	ast.SetOutput(stdout)
+	ast.SetErrorOutput(stderr)
+	ast.SetModuleLoader(loadModule)
 	app := &cli.App{
 		Name:        "Lox interpreter",
 		Usage:       "",
 		Description: "A interpreter for the lox programming language.",
-		UsageText:   "lox [script] - Script might be omitted to enter interactive mode.",
+		UsageText: "lox [script [-- arguments...]] - Script might be omitted to enter interactive mode.\n" +
+			"lox command [command options] [arguments...]",
+		Commands: []*cli.Command{
+			runCommand,
+			replCommand,
+			checkCommand,
+			lintCommand,
+			astCommand,
+			tokensCommand,
+			highlightCommand,
+			grammarCommand,
+			fmtCommand,
+			benchCommand,
+			testCommand,
+			lspCommand,
+		},
+		Flags: append(interpreterFlags(), emitFlag),
+		// lox [script] is kept as a shorthand for lox run and lox repl
 		Action: func(cCtx *cli.Context) error {
 			if cCtx.Args().Len() == 0 {
-				runRepl()
-				print("Leaving Lox REPL")
-				return cli.Exit("", 0)
-			} else if cCtx.Args().Len() == 1 {
-				err := runFile(cCtx.Args().First())
-				if err != nil {
-					return cli.Exit(err.Error(), 64)
-				}
+				return replCommand.Action(cCtx)
 			}
 
-			return nil
+			return runCommand.Action(cCtx)
 		},
+		OnUsageError: onUsageError,
 	}
 
 	if err := app.Run(os.Args); err != nil {
+		// errors returned by flag actions are not handled by the app
+		if exit, ok := err.(cli.ExitCoder); ok {
+			cli.HandleExitCoder(exit)
+		}
 		log.Fatal(err)
 	}
 }
 
 func runRepl() {
+	session := newReplSession()
+	editor := readline.New(os.Stdin, stdout)
+	defer editor.Close()
+	// lines piped into the REPL are not kept
+	if home, err := os.UserHomeDir(); err == nil && editor.Terminal() {
+		if err := editor.SetHistoryFile(filepath.Join(home, ".lox_history")); err != nil {
+			fmt.Fprintf(stderr, "cannot keep the history: %s\n", err)
+		}
+	}
+
 	block_mode := false
-	reader := bufio.NewReader(os.Stdin)
-	var text string
 	for {
+		var text string
+		var err error
 		if block_mode {
-			var block strings.Builder
-			for {
-				fmt.Print("lox|")
-				text, _ = reader.ReadString('\n')
-				block.WriteString(text)
-				if len(text) < 2 {
-					block_mode = false
-					text = block.String()
-					break
-				}
-			}
+			block_mode = false
+			text, err = readBlock(editor)
 		} else {
-			fmt.Print("lox>")
-			text, _ = reader.ReadString('\n')
+			text, err = editor.ReadLine("lox>")
+			editor.AddHistory(text)
+		}
+		// Ctrl-C cancels the line or block
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		}
+		// Ctrl-D or the end of the piped input
+		if err != nil {
+			return
 		}
 
 		text = strings.Trim(text, "\n ")
@@ -81,82 +129,233 @@ func runRepl() {
 				}
 			}
 
-			println("unrecognized command")
+			switch text {
+			case ":help":
+				fmt.Fprintln(stdout, ":q            quit the REPL")
+				fmt.Fprintln(stdout, ":blk          enter statements until an empty line")
+				fmt.Fprintln(stdout, ":help natives list the native functions")
+				fmt.Fprintln(stdout, ":env          list the variables defined by the inputs")
+				fmt.Fprintln(stdout, ":env *        list every variable, including natives and types")
+				fmt.Fprintln(stdout, ":env <name>   show the variable name")
+				fmt.Fprintln(stdout, ":load <file>  run a script in the session")
+				fmt.Fprintln(stdout, ":save <file>  write the statements run without an error to a file")
+				continue
+			case ":help natives":
+				printNatives()
+				continue
+			}
+
+			if text == ":env" || strings.HasPrefix(text, ":env ") {
+				printEnv(strings.TrimSpace(text[len(":env"):]))
+				continue
+			}
+
+			if command, path, ok := strings.Cut(text, " "); ok && (command == ":load" || command == ":save") {
+				path = strings.TrimSpace(path)
+				if command == ":load" {
+					evaluate(func() {
+						if err := session.load(path); err != nil {
+							fmt.Fprintf(stderr, "cannot load: %s\n", err)
+						}
+					})
+				} else if err := session.save(path); err != nil {
+					fmt.Fprintf(stderr, "cannot save: %s\n", err)
+				} else {
+					fmt.Fprintf(stdout, "saved %d statement(s) to %s\n", len(session.executed), path)
+				}
+				continue
+			}
+
+			fmt.Fprintln(stderr, "unrecognized command")
 			continue
 		}
 
 		if text[len(text)-1] != ';' && text[len(text)-1] != '}' {
 			// execute expression
-			execExpr(string(text))
+			evaluate(func() { session.execExpr(text) })
 			continue
 		}
 
 		// execute statement
-		exec(string(text))
+		evaluate(func() { session.exec(text) })
 	}
 }
 
-func runFile(path string) error {
-	if text, err := os.ReadFile(path); err != nil {
-		return err
-	} else {
-		exec(string(text))
-		return nil
+// readBlock reads the lines of a block until an empty line
+func readBlock(editor *readline.Editor) (string, error) {
+	var block strings.Builder
+	for {
+		line, err := editor.ReadLine("lox|")
+		if err != nil {
+			return "", err
+		}
+		if line == "" {
+			return block.String(), nil
+		}
+
+		editor.AddHistory(line)
+		block.WriteString(line + "\n")
 	}
 }
 
-func execExpr(source string) {
-	// allow REPL to parse only expressions and print the evaluated value,
-	// done for user convenience
-	tokens, _ := scan.Scan(source, report, scan.ScanContext{})
-	expr, err := parse.ParseExpression(tokens, report)
-	if err != nil {
-		return
+// printNatives lists the natives scripts can call to stdout
+func printNatives() {
+	for _, native := range ast.Natives() {
+		if !native.Enabled {
+			continue
+		}
+
+		doc := native.Doc
+		if doc == "" {
+			doc = fmt.Sprintf("%s takes %d argument(s)", native.Name, native.Arity)
+		}
+		fmt.Fprintln(stdout, doc)
 	}
+}
 
-	val, err := expr.Evaluate()
-	if err != nil {
+// printEnv lists the variables defined by the REPL inputs to stdout, every
+// variable if arg is "*" or only the variable called arg otherwise
+func printEnv(arg string) {
+	env := ast.GlobalEnvironment()
+	if arg != "" && arg != "*" {
+		value, ok := env.Lookup(arg)
+		if !ok {
+			fmt.Fprintf(stderr, "undefined variable '%s'\n", arg)
+			return
+		}
+		fmt.Fprintln(stdout, binding(arg, value))
 		return
 	}
 
-	println(val.DebugPrint())
+	for _, name := range env.Names() {
+		if arg == "*" || !ast.IsBuiltin(name) {
+			value, _ := env.Lookup(name)
+			fmt.Fprintln(stdout, binding(name, value))
+		}
+	}
 }
 
-func exec(source string) {
-	tokens, _ := scan.Scan(source, report, scan.ScanContext{})
-	// for _, token := range tokens {
-	// 	fmt.Println(token)
-	// }
+// binding formats the variable name bound to value, quoting strings
+func binding(name string, value ast.LoxValue) string {
+	str := value.DebugPrint()
+	if value.Type() == ast.STRING {
+		str = strconv.Quote(str)
+	}
 
+	kind := value.Type().String()
+	if ast.IsBuiltin(name) {
+		kind += ", builtin"
+	}
+	return fmt.Sprintf("%s = %s (%s)", name, str, kind)
+}
+
+// parseSource scans and parses source, reporting errors to stderr
+func parseSource(source string) ([]ast.Stmt, error) {
+	report := diag.NewRenderer(source, stderr).Report
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{Dialect: dialect})
 	stmts, err := parse.Parse(tokens, report)
-    for _, stmt := range(stmts) {
-        println(stmt.DebugPrint())
+	if err != nil {
+		return nil, err
+	}
+	if scanErr != nil {
+		return nil, scanErr
+	}
 
-    }
+	if _, err := analyze(stmts, report); err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
+
+// analyze resolves stmts and checks their types if enabled
+// by --typecheck, reporting the diagnostics to report
+func analyze(stmts []ast.Stmt, report func(error)) (*ast.Resolution, error) {
+	resolution, err := resolve.Resolve(stmts, report)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	ast.Interpret(stmts, report)
-	// for _, token := range tokens {
-	// 	fmt.Println(token)
-	// }
-	//
+	if checkTypes {
+		return resolution, typecheck.Check(stmts, report)
+	}
+	return resolution, nil
+}
+
+// the report functions of the loaded modules keyed by their path, the
+// slow statements and loops of a module are reported with its source
+var moduleReports = map[string]func(error){}
+
+// loadModule reads, parses and resolves the module at path, its
+// diagnostics are reported to stderr prefixed by the path
+func loadModule(path string) ([]ast.Stmt, *ast.Resolution, func(error), error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	render := diag.NewRenderer(string(source), stderr).Report
+	report := func(err error) {
+		fmt.Fprintf(stderr, "%s: ", path)
+		render(err)
+	}
+	moduleReports[path] = report
+
+	tokens, scanErr := scan.Scan(string(source), report, scan.ScanContext{Dialect: dialect})
+	stmts, err := parse.Parse(tokens, report)
+	if err != nil || scanErr != nil {
+		return nil, nil, nil, errors.New("the module has errors")
+	}
 
-	// fmt.Println(expr.Print())
+	resolution, err := analyze(stmts, report)
+	if err != nil {
+		return nil, nil, nil, errors.New("the module has errors")
+	}
+	return stmts, resolution, report, nil
+}
 
-	// value, err := expr.Evaluate()
-	// if err != nil {
-	// 	fmt.Println(err)
-	// } else {
-	// 	fmt.Println(value.Print())
-	// }
+// exec runs source, the returned error is a parseError or
+// runtimeError if the script failed
+func exec(source string) error {
+	return execWith(source, ast.Interpret)
 }
 
-func report(err error) {
-	switch e := err.(type) {
-	default:
-		fmt.Print(e)
+// reportIn reports err with the report function of the module at
+// path, or with report if path is empty or the module is unknown
+func reportIn(path string, report func(error), err error) {
+	if r, ok := moduleReports[path]; ok && path != "" {
+		r(err)
+		return
 	}
+	report(err)
 }
 
+// execWith runs source like exec, interpreting it with interpret
+func execWith(source string, interpret func([]ast.Stmt, *ast.Resolution, func(error)) error) error {
+	report := diag.NewRenderer(source, stderr).Report
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{Dialect: dialect})
+	stmts, err := parse.Parse(tokens, report)
+	if err != nil {
+		return parseError{err}
+	}
+	if scanErr != nil {
+		return parseError{scanErr}
+	}
+
+	resolution, err := analyze(stmts, report)
+	if err != nil {
+		return parseError{err}
+	}
+
+	err = interpret(stmts, resolution, report)
+	for _, slow := range ast.SlowStatements() {
+		reportIn(slow.Path, report, slow)
+	}
+	for _, count := range ast.LoopCounts() {
+		reportIn(count.Path, report, count)
+	}
+
+	if err != nil {
+		return runtimeError{err}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/LucazFFz/lox/internal/lsp"
+	"github.com/urfave/cli/v2"
+	"os"
+)
+
+var lspCommand = &cli.Command{
+	Name:         "lsp",
+	Usage:        "run a language server over stdin and stdout, for editors supporting the Language Server Protocol",
+	Flags:        []cli.Flag{dialectFlag},
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.Args().Len() > 0 {
+			return usageError(cCtx, "expected no arguments but got %d", cCtx.Args().Len())
+		}
+
+		return lsp.NewServer(os.Stdin, os.Stdout, dialect).Serve()
+	},
+}
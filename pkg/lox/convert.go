@@ -0,0 +1,142 @@
+package lox
+
+import (
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"reflect"
+	"sort"
+)
+
+// ToValue converts a Go value to a Lox value. nil, booleans, numbers and
+// strings become their Lox counterparts, slices and arrays become lists
+// and maps with string keys become maps, with their elements converted
+// recursively. Values are returned as they are.
+func ToValue(v any) (Value, error) {
+	if v == nil {
+		return ast.LoxNil{}, nil
+	}
+	if value, ok := v.(Value); ok {
+		return value, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return ast.LoxBoolean(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return ast.LoxNumber(float64(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return ast.LoxNumber(float64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return ast.LoxNumber(rv.Float()), nil
+	case reflect.String:
+		return ast.LoxString(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return ast.LoxNil{}, nil
+		}
+
+		elements := make([]ast.LoxValue, rv.Len())
+		for i := range elements {
+			element, err := ToValue(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return ast.NewLoxList(elements), nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot convert maps with %s keys to Lox values", rv.Type().Key())
+		}
+		if rv.IsNil() {
+			return ast.LoxNil{}, nil
+		}
+
+		// Go maps are unordered, the keys are inserted in sorted order
+		keys := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+
+		m := ast.NewLoxMap()
+		for _, key := range keys {
+			value, err := ToValue(rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).Interface())
+			if err != nil {
+				return nil, err
+			}
+			if err := m.Set(ast.LoxString(key), value); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert values of type %T to Lox values", v)
+}
+
+// ForeignType is the method table of foreign values, which hand objects
+// of the host to scripts. Scripts cannot look into a foreign value, they
+// only pass it around and call its methods, which are added with
+// AddMethod.
+type ForeignType = ast.ForeignType
+
+// NewForeignType creates a foreign type without methods called name.
+func NewForeignType(name string) *ForeignType {
+	return ast.NewForeignType(name)
+}
+
+// NewForeignValue wraps object in a foreign value of type typ, FromValue
+// returns object again.
+func NewForeignValue(typ *ForeignType, object any) Value {
+	return ast.NewForeignValue(typ, object)
+}
+
+// FromValue converts a Lox value to a Go value, the inverse of ToValue.
+// nil, booleans, numbers and strings become nil, bool, float64 and string,
+// lists become []any and maps with string keys become map[string]any.
+// Foreign values become the objects they hold.
+func FromValue(v Value) (any, error) {
+	switch v.Type() {
+	case ast.NIL:
+		return nil, nil
+	case ast.BOOLEAN:
+		return ast.AsBoolean(v), nil
+	case ast.NUMBER:
+		return ast.AsNumber(v), nil
+	case ast.STRING:
+		return ast.AsString(v), nil
+	case ast.FOREIGN:
+		return ast.AsForeign(v).Object(), nil
+	case ast.LIST:
+		list := ast.AsList(v)
+		elements := make([]any, list.Len())
+		for i, element := range list.Elements() {
+			converted, err := FromValue(element)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = converted
+		}
+		return elements, nil
+	case ast.MAP:
+		m := ast.AsMap(v)
+		entries := make(map[string]any, m.Len())
+		for _, key := range m.Keys() {
+			if key.Type() != ast.STRING {
+				return nil, fmt.Errorf("cannot convert maps with %s keys to Go values", key.Type())
+			}
+
+			value, _ := m.Get(key)
+			converted, err := FromValue(value)
+			if err != nil {
+				return nil, err
+			}
+			entries[ast.AsString(key)] = converted
+		}
+		return entries, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert values of type %s to Go values", v.Type())
+}
@@ -0,0 +1,428 @@
+// Package lox embeds the Lox interpreter in Go programs, e.g. as a
+// scripting layer:
+//
+//	interp := lox.New(lox.WithStdout(&out))
+//	if err := interp.Run(`var greeting = "hello";`); err != nil {
+//		return err
+//	}
+//	value, err := interp.Eval(`greeting + " world"`)
+//
+// Globals defined by a script are visible to the scripts and expressions
+// run after it. Every Interpreter has its own globals, writers, natives,
+// imported modules and topics, so Interpreters do not affect each other.
+package lox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/resolve"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"io"
+	"os"
+	"time"
+)
+
+// Value is a Lox value, such as the result of Eval.
+type Value = ast.LoxValue
+
+// LimitError stops a script exceeding the limits set with WithFuel and
+// WithTimeout or the context passed to RunContext. It wraps
+// ErrFuelExhausted or the error of the context, e.g.
+// context.DeadlineExceeded, so errors.Is tells the limits apart.
+type LimitError = ast.LimitError
+
+// ErrFuelExhausted is wrapped by the error of a script which
+// evaluated more statements than allowed by WithFuel.
+var ErrFuelExhausted = ast.ErrFuelExhausted
+
+// ParseOptions selects the dialect of the scripts run by an interpreter,
+// see WithParseOptions.
+type ParseOptions = parse.ParseOptions
+
+// DefaultParseOptions returns the options of the dialect scripts are
+// written in by default, Lox extended with e.g. the conditional operator
+// and anonymous functions.
+func DefaultParseOptions() ParseOptions {
+	return parse.DefaultParseOptions()
+}
+
+// StrictParseOptions returns the options of the Lox
+// described by Crafting Interpreters.
+func StrictParseOptions() ParseOptions {
+	return parse.StrictParseOptions()
+}
+
+type Interpreter struct {
+	interp *ast.Interpreter
+	// whether the math natives, such as sqrt and random, are defined
+	math bool
+	// whether the natives touching the file system are defined
+	files bool
+	// the directory imports are relative to, empty if scripts cannot import
+	modules string
+	// whether the output is the same on every platform
+	normalized bool
+	// the number of statements a script may evaluate, zero if unlimited
+	fuel int
+	// the time a script may run for, zero if unlimited
+	timeout time.Duration
+	// the dialect scripts are parsed in
+	syntax ParseOptions
+	// the arguments scripts find in ARGS
+	args []string
+}
+
+type Option func(*Interpreter)
+
+// WithStdout sets the writer print statements write to, os.Stdout by default.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.interp.SetOutput(w)
+	}
+}
+
+// WithStderr sets the writer errors and warnings are written to,
+// os.Stderr by default.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.interp.SetErrorOutput(w)
+	}
+}
+
+// WithoutMath leaves the math natives, such as sqrt and random,
+// undefined for scripts run by the interpreter.
+func WithoutMath() Option {
+	return func(i *Interpreter) {
+		i.math = false
+	}
+}
+
+// WithoutFiles leaves the natives touching the file system, readFile,
+// writeFile and appendFile, undefined for scripts run by the interpreter,
+// e.g. to sandbox untrusted scripts.
+func WithoutFiles() Option {
+	return func(i *Interpreter) {
+		i.files = false
+	}
+}
+
+// WithStdin sets the reader readLine reads from, os.Stdin by default.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) {
+		i.interp.SetInput(r)
+	}
+}
+
+// WithModules lets scripts import the modules in dir, imports are
+// relative to dir or the directory of the importing module. Scripts
+// cannot import modules by default.
+func WithModules(dir string) Option {
+	return func(i *Interpreter) {
+		i.modules = dir
+	}
+}
+
+// WithNormalizedOutput makes scripts print the same bytes on every
+// platform: line breaks are written as "\n" and errors of the operating
+// system are worded the same, e.g. to compare the output to golden files.
+func WithNormalizedOutput() Option {
+	return func(i *Interpreter) {
+		i.normalized = true
+	}
+}
+
+// WithFuel stops every script and expression once it has evaluated
+// statements statements, e.g. to bound the work of untrusted scripts.
+func WithFuel(statements int) Option {
+	return func(i *Interpreter) {
+		i.fuel = statements
+	}
+}
+
+// WithTimeout stops every script and expression running for longer than d.
+func WithTimeout(d time.Duration) Option {
+	return func(i *Interpreter) {
+		i.timeout = d
+	}
+}
+
+// WithArgs sets the arguments scripts find in the global list ARGS,
+// which is empty by default.
+func WithArgs(args ...string) Option {
+	return func(i *Interpreter) {
+		i.args = args
+	}
+}
+
+// WithSeed seeds the source of the random natives, random and randomInt,
+// so the scripts run by the interpreter are reproducible. Every
+// interpreter has its own source, seeded by the current time by default.
+func WithSeed(seed int64) Option {
+	return func(i *Interpreter) {
+		i.interp.Seed(seed)
+	}
+}
+
+// WithParseOptions sets the dialect scripts, expressions and the modules
+// they import are parsed in, DefaultParseOptions by default.
+func WithParseOptions(options ParseOptions) Option {
+	return func(i *Interpreter) {
+		i.syntax = options
+	}
+}
+
+func New(options ...Option) *Interpreter {
+	i := &Interpreter{
+		interp: ast.NewInterpreter(),
+		math:   true,
+		files:  true,
+		syntax: parse.DefaultParseOptions(),
+	}
+	for _, option := range options {
+		option(i)
+	}
+	if i.normalized {
+		i.interp.SetOutput(ast.NormalizeLineEndings(i.interp.Output()))
+		i.interp.SetErrorOutput(ast.NormalizeLineEndings(i.interp.ErrorOutput()))
+	}
+	return i
+}
+
+// Run runs the script source. The returned error joins the errors which
+// stopped the script, they are written to stderr as well. Warnings are
+// only written to stderr.
+func (i *Interpreter) Run(source string) error {
+	return i.RunContext(context.Background(), source)
+}
+
+// RunContext runs the script source like Run, stopping it
+// with a LimitError once ctx is done.
+func (i *Interpreter) RunContext(ctx context.Context, source string) error {
+	report, errs := i.reporter(source)
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{})
+	stmts, err := parse.ParseWith(tokens, report, i.syntax)
+	if err != nil || scanErr != nil || len(*errs) > 0 {
+		return errors.Join(*errs...)
+	}
+
+	resolution, err := resolve.Resolve(stmts, report)
+	if err != nil {
+		return errors.Join(*errs...)
+	}
+
+	i.configure()
+	ctx, cancel := i.limit(ctx)
+	defer cancel()
+	if err := i.interp.InterpretContext(ctx, stmts, resolution, report); err != nil {
+		return errors.Join(*errs...)
+	}
+	return nil
+}
+
+// Eval evaluates the expression expr and returns its value.
+// Errors are returned like they are by Run.
+func (i *Interpreter) Eval(expr string) (Value, error) {
+	return i.EvalContext(context.Background(), expr)
+}
+
+// EvalContext evaluates the expression expr like Eval,
+// stopping it with a LimitError once ctx is done.
+func (i *Interpreter) EvalContext(ctx context.Context, expr string) (Value, error) {
+	report, errs := i.reporter(expr)
+	tokens, scanErr := scan.Scan(expr, report, scan.ScanContext{})
+	parsed, err := parse.ParseExpressionWith(tokens, report, i.syntax)
+	if err != nil || scanErr != nil || len(*errs) > 0 {
+		return nil, errors.Join(*errs...)
+	}
+
+	i.configure()
+	ctx, cancel := i.limit(ctx)
+	defer cancel()
+	previous := i.interp.Context()
+	i.interp.SetContext(ctx)
+	defer i.interp.SetContext(previous)
+
+	value, err := i.interp.InterpretExpr(parsed, nil)
+	if err != nil {
+		report(err)
+		return nil, err
+	}
+	return value, nil
+}
+
+// NativeInfo describes a native function scripts can call.
+type NativeInfo = ast.NativeInfo
+
+// RegisterFunc makes fn available to scripts as the function name taking
+// arity arguments. An error is returned if a native called name is
+// already registered, see OverrideFunc. A nil result is returned to the
+// script as nil, errors returned by fn stop the script like runtime errors
+// do. Use ToValue and FromValue to convert between Go and Lox values:
+//
+//	err := interp.RegisterFunc("upper", 1, func(args []lox.Value) (lox.Value, error) {
+//		s, err := lox.FromValue(args[0])
+//		if err != nil {
+//			return nil, err
+//		}
+//		return lox.ToValue(strings.ToUpper(fmt.Sprint(s)))
+//	})
+func (i *Interpreter) RegisterFunc(name string, arity int, fn func(args []Value) (Value, error)) error {
+	return i.interp.RegisterNative(name, "", native(arity, fn))
+}
+
+// OverrideFunc replaces the native name, including the standard
+// natives such as clock, by fn.
+func (i *Interpreter) OverrideFunc(name string, arity int, fn func(args []Value) (Value, error)) error {
+	return i.interp.OverrideNative(name, native(arity, fn))
+}
+
+// RemoveFunc removes the native name, including the standard natives.
+func (i *Interpreter) RemoveFunc(name string) error {
+	return i.interp.RemoveNative(name)
+}
+
+// Func is a native function taking Arity arguments, see RegisterModule.
+type Func struct {
+	Arity int
+	Fn    func(args []Value) (Value, error)
+}
+
+// RegisterModule makes funcs available to scripts as the members of the
+// global object name, keyed by their names, which scripts call like
+// name.member(). An error is returned if a native or module called name
+// is already registered. The functions behave like those registered
+// with RegisterFunc:
+//
+//	err := interp.RegisterModule("strings", map[string]lox.Func{
+//		"repeat": {Arity: 2, Fn: repeat},
+//		"trim":   {Arity: 1, Fn: trim},
+//	})
+func (i *Interpreter) RegisterModule(name string, funcs map[string]Func) error {
+	members := make(map[string]ast.NativeFunction, len(funcs))
+	for member, f := range funcs {
+		members[member] = native(f.Arity, f.Fn)
+	}
+	return i.interp.RegisterModule(name, members)
+}
+
+// RemoveModule removes the module name, including the standard
+// modules such as math.
+func (i *Interpreter) RemoveModule(name string) error {
+	return i.interp.RemoveModule(name)
+}
+
+// Define defines the global name as value, replacing any global of the
+// same name.
+func (i *Interpreter) Define(name string, value Value) {
+	i.interp.DefineGlobal(name, value)
+}
+
+// BindTopic binds ch to topic, so scripts pass values to the host with
+// send(topic, value) and receive the values the host sends over ch with
+// receive(topic). Blocking sends and receives are abandoned once the
+// script is stopped. A channel bound before replaces the one bound to
+// topic. The host must not close a channel scripts send values to.
+func (i *Interpreter) BindTopic(topic string, ch chan Value) {
+	i.interp.BindTopic(topic, ch)
+}
+
+// Natives lists the natives scripts can call, sorted by name.
+func (i *Interpreter) Natives() []NativeInfo {
+	i.configure()
+	infos := i.interp.Natives()
+	enabled := infos[:0]
+	for _, info := range infos {
+		if info.Enabled {
+			enabled = append(enabled, info)
+		}
+	}
+	return enabled
+}
+
+func native(arity int, fn func(args []Value) (Value, error)) ast.NativeFunction {
+	return ast.NewNativeFunction(arity, func(args []ast.LoxValue) (ast.LoxValue, error) {
+		result, err := fn(args)
+		if err != nil {
+			// errors of the interpreter, e.g. from calling back into
+			// the script, are already located
+			if _, ok := err.(interface{ Span() (int, int) }); !ok {
+				err = ast.NewRuntimeError(token.Token{}, err.Error())
+			}
+			return nil, err
+		}
+
+		if result == nil {
+			return ast.LoxNil{}, nil
+		}
+		return result, nil
+	})
+}
+
+// configure applies the options of the interpreter to its state
+func (i *Interpreter) configure() {
+	i.interp.SetNormalizedOutput(i.normalized)
+	i.interp.SetMathNatives(i.math)
+	i.interp.SetFileNatives(i.files)
+	i.interp.SetArguments(i.args)
+	i.interp.SetFuel(i.fuel)
+	if i.modules == "" {
+		i.interp.SetModuleLoader(nil)
+	} else {
+		i.interp.SetModuleDir(i.modules)
+		i.interp.SetModuleLoader(i.loadModule)
+	}
+}
+
+// limit returns ctx limited to the timeout of the interpreter
+func (i *Interpreter) limit(ctx context.Context) (context.Context, context.CancelFunc) {
+	if i.timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, i.timeout)
+}
+
+// loadModule reads, parses and resolves the module at path, its
+// diagnostics are written to stderr prefixed by the path
+func (i *Interpreter) loadModule(path string) ([]ast.Stmt, *ast.Resolution, func(error), error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	render, errs := i.reporter(string(source))
+	report := func(err error) {
+		fmt.Fprintf(i.interp.ErrorOutput(), "%s: ", path)
+		render(err)
+	}
+
+	tokens, scanErr := scan.Scan(string(source), report, scan.ScanContext{})
+	stmts, err := parse.ParseWith(tokens, report, i.syntax)
+	if err != nil || scanErr != nil || len(*errs) > 0 {
+		return nil, nil, nil, errors.New("the module has errors")
+	}
+
+	resolution, err := resolve.Resolve(stmts, report)
+	if err != nil {
+		return nil, nil, nil, errors.New("the module has errors")
+	}
+	return stmts, resolution, report, nil
+}
+
+// reporter returns a function writing diagnostics of source to stderr
+// and collecting the errors among them
+func (i *Interpreter) reporter(source string) (func(error), *[]error) {
+	render := diag.NewRenderer(source, i.interp.ErrorOutput()).Report
+	errs := &[]error{}
+	return func(err error) {
+		render(err)
+		if diagnostic, ok := err.(resolve.ResolveError); ok && diagnostic.Severity == resolve.WARNING {
+			return
+		}
+		*errs = append(*errs, err)
+	}, errs
+}
@@ -0,0 +1,650 @@
+package lox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/LucazFFz/lox/pkg/lox"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunAndEval(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+
+	if err := interp.Run(`var greeting = "hello"; print greeting;`); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "hello\n" {
+		t.Errorf("expected the script to print hello but got %q", stdout.String())
+	}
+
+	value, err := interp.Eval(`greeting + " world"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.DebugPrint() != "hello world" {
+		t.Errorf("expected hello world but got %s", value.DebugPrint())
+	}
+
+	if stderr.Len() != 0 {
+		t.Errorf("expected no diagnostics but got %q", stderr.String())
+	}
+}
+
+func TestInterpretersAreIndependent(t *testing.T) {
+	var stdout bytes.Buffer
+	first := lox.New(lox.WithStdout(&stdout))
+	second := lox.New(lox.WithStdout(&stdout), lox.WithoutMath())
+
+	if err := first.Run(`var shared = 1; fun counter() { shared = shared + 1; return shared; }`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Eval(`shared`); err == nil {
+		t.Error("expected the globals of one interpreter to be undefined in another")
+	}
+
+	if err := second.Run(`var shared = "second";`); err != nil {
+		t.Fatal(err)
+	}
+	value, err := first.Eval(`counter()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.DebugPrint() != "2" {
+		t.Errorf("expected the first interpreter to keep its global but got %s", value.DebugPrint())
+	}
+
+	if _, err := first.Eval(`sqrt(4)`); err != nil {
+		t.Errorf("expected the math natives of one interpreter to be unaffected by another: %v", err)
+	}
+}
+
+func TestConcurrentInterpreters(t *testing.T) {
+	source := `
+var total = 0;
+fun add(x) { total = total + x; return total; }
+var s = "";
+for (var i = 0; i < 200; i = i + 1) { add(i); s = s + str(random()); }
+print total;`
+
+	outputs := make([]bytes.Buffer, 4)
+	errs := make(chan error, len(outputs))
+	for i := range outputs {
+		interp := lox.New(lox.WithStdout(&outputs[i]), lox.WithSeed(int64(i)), lox.WithArgs("a"))
+		go func() { errs <- interp.Run(source) }()
+	}
+
+	for range outputs {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	for _, out := range outputs {
+		if out.String() != "19900\n" {
+			t.Errorf("expected every interpreter to print 19900 but got %q", out.String())
+		}
+	}
+}
+
+func TestWriters(t *testing.T) {
+	var firstOut, firstErr, secondOut, secondErr bytes.Buffer
+	first := lox.New(lox.WithStdout(&firstOut), lox.WithStderr(&firstErr))
+	second := lox.New(lox.WithStdout(&secondOut), lox.WithStderr(&secondErr))
+
+	if err := first.Run(`print "first"; println("native");`); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Run(`print "second"; nil + 1;`); err == nil {
+		t.Fatal("expected the second script to fail")
+	}
+
+	if firstOut.String() != "first\nnative\n" || firstErr.Len() != 0 {
+		t.Errorf("expected the first interpreter to write to its writers only but got %q and %q",
+			firstOut.String(), firstErr.String())
+	}
+	if secondOut.String() != "second\n" || !strings.Contains(secondErr.String(), "operands must be") {
+		t.Errorf("expected the second interpreter to write to its writers only but got %q and %q",
+			secondOut.String(), secondErr.String())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var stderr bytes.Buffer
+	interp := lox.New(lox.WithStderr(&stderr))
+
+	err := interp.Run(`print 1 +;`)
+	if err == nil || !strings.Contains(err.Error(), "missing right-hand-side operand") {
+		t.Errorf("expected a parse error but got %v", err)
+	}
+
+	err = interp.Run(`print -"a";`)
+	if err == nil || !strings.Contains(err.Error(), "operand must be a number") {
+		t.Errorf("expected a runtime error but got %v", err)
+	}
+
+	if _, err := interp.Eval(`undefined`); err == nil {
+		t.Error("expected evaluating an undefined variable to fail")
+	}
+
+	if !strings.Contains(stderr.String(), "operand must be a number") {
+		t.Errorf("expected the errors to be written to stderr but got %q", stderr.String())
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+
+	defer interp.RemoveFunc("lookup")
+	err := interp.RegisterFunc("lookup", 1, func(args []lox.Value) (lox.Value, error) {
+		key, err := lox.FromValue(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if key != "users" {
+			return nil, errors.New("unknown key")
+		}
+		return lox.ToValue(map[string]any{"names": []string{"ada", "alan"}, "count": 2})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := interp.Run(`var users = lookup("users"); print users["names"][1];`); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "alan\n" {
+		t.Errorf("expected the script to print alan but got %q", stdout.String())
+	}
+
+	value, err := interp.Eval(`users`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := lox.FromValue(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"names": []any{"ada", "alan"}, "count": 2.0}
+	if !reflect.DeepEqual(users, want) {
+		t.Errorf("expected %v but got %v", want, users)
+	}
+
+	err = interp.Run(`lookup("groups");`)
+	if err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("expected the error of the function but got %v", err)
+	}
+}
+
+func TestMath(t *testing.T) {
+	var stderr bytes.Buffer
+	interp := lox.New(lox.WithStderr(&stderr))
+
+	tests := map[string]string{
+		`abs(-2.5)`:           "2.5",
+		`floor(-1.5)`:         "-2",
+		`ceil(1.2)`:           "2",
+		`round(2.5)`:          "3",
+		`sqrt(16)`:            "4",
+		`pow(2, 10)`:          "1024",
+		`min(3, -1)`:          "-1",
+		`max(3, -1)`:          "3",
+		`randomInt(4, 5)`:     "4",
+		`floor(random() * 0)`: "0",
+	}
+	for expr, want := range tests {
+		value, err := interp.Eval(expr)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		if value.DebugPrint() != want {
+			t.Errorf("%s: expected %s but got %s", expr, want, value.DebugPrint())
+		}
+	}
+
+	if _, err := interp.Eval(`sqrt("a")`); err == nil || !strings.Contains(err.Error(), "sqrt expects numbers") {
+		t.Errorf("expected sqrt of a string to fail but got %v", err)
+	}
+	if _, err := interp.Eval(`randomInt(1, 1)`); err == nil {
+		t.Error("expected randomInt of an empty range to fail")
+	}
+}
+
+func TestIO(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	path := filepath.Join(t.TempDir(), "out.txt")
+	interp := lox.New(lox.WithStdin(strings.NewReader("first\r\nsecond")),
+		lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+	defer interp.RemoveFunc("path")
+	err := interp.RegisterFunc("path", 0, func(_ []lox.Value) (lox.Value, error) {
+		return lox.ToValue(path)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = interp.Run(`
+writeFile(path(), readLine() + ",");
+appendFile(path(), readLine());
+print readFile(path());
+print readLine();
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "first,second\nnil\n" {
+		t.Errorf("expected the lines to be copied but got %q", stdout.String())
+	}
+
+	err = interp.Run(`readFile(path() + ".missing");`)
+	if err == nil || !strings.Contains(err.Error(), "no such file or directory") {
+		t.Errorf("expected the error of the os but got %v", err)
+	}
+
+	sandboxed := lox.New(lox.WithoutFiles(), lox.WithStderr(&stderr))
+	if err := sandboxed.Run(`readFile("/etc/passwd");`); err == nil {
+		t.Error("expected readFile to be undefined in the sandbox")
+	}
+}
+
+func TestNativeRegistry(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr), lox.WithoutMath())
+	constant := func(n int) func([]lox.Value) (lox.Value, error) {
+		return func(_ []lox.Value) (lox.Value, error) { return lox.ToValue(n) }
+	}
+
+	if err := interp.RegisterFunc("clock", 0, constant(0)); err == nil {
+		t.Error("expected registering a standard native again to fail")
+	}
+	if err := interp.OverrideFunc("answer", 0, constant(0)); err == nil {
+		t.Error("expected overriding an unknown native to fail")
+	}
+
+	if err := interp.RegisterFunc("answer", 0, constant(42)); err != nil {
+		t.Fatal(err)
+	}
+	if err := interp.RegisterFunc("answer", 0, constant(42)); err == nil {
+		t.Error("expected registering a native twice to fail")
+	}
+	if err := interp.Run(`print answer();`); err != nil {
+		t.Fatal(err)
+	}
+	if err := interp.OverrideFunc("answer", 0, constant(43)); err != nil {
+		t.Fatal(err)
+	}
+	if err := interp.Run(`print answer();`); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "42\n43\n" {
+		t.Errorf("expected the overridden native to be called but got %q", stdout.String())
+	}
+
+	listed := map[string]lox.NativeInfo{}
+	for _, native := range interp.Natives() {
+		listed[native.Name] = native
+	}
+	if listed["answer"].Group != "host" || listed["clock"].Doc == "" {
+		t.Errorf("expected answer to be a host native and clock to be documented but got %v", listed)
+	}
+	if _, ok := listed["sqrt"]; ok {
+		t.Error("expected the disabled math natives not to be listed")
+	}
+
+	if err := interp.RemoveFunc("answer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := interp.Run(`answer();`); err == nil {
+		t.Error("expected calling a removed native to fail")
+	}
+	if err := interp.RemoveFunc("answer"); err == nil {
+		t.Error("expected removing a native twice to fail")
+	}
+}
+
+func TestRegistriesAreIndependent(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	first := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+	second := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+
+	answer := func([]lox.Value) (lox.Value, error) { return lox.ToValue(42) }
+	if err := first.RegisterFunc("answer", 0, answer); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.RegisterFunc("answer", 0, answer); err != nil {
+		t.Errorf("expected every interpreter to have its own registry: %v", err)
+	}
+	if err := first.RemoveFunc("clock"); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.RemoveModule("math"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.Run(`clock();`); err == nil {
+		t.Error("expected clock to be removed from the first interpreter")
+	}
+	if err := second.Run(`clock(); math.sqrt(4); answer();`); err != nil {
+		t.Errorf("expected the natives of the second interpreter to be unaffected: %v", err)
+	}
+}
+
+func TestHostValues(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+
+	type counter struct{ n int }
+	counterType := lox.NewForeignType("counter")
+	counterType.AddMethod("add", 1, func(receiver any, args []lox.Value) (lox.Value, error) {
+		c := receiver.(*counter)
+		n, err := lox.FromValue(args[0])
+		if err != nil {
+			return nil, err
+		}
+		c.n += int(n.(float64))
+		return lox.ToValue(c.n)
+	})
+	c := &counter{}
+	interp.Define("hits", lox.NewForeignValue(counterType, c))
+	limit, _ := lox.ToValue(3)
+	interp.Define("limit", limit)
+
+	in, out := make(chan lox.Value), make(chan lox.Value, 1)
+	interp.BindTopic("in", in)
+	interp.BindTopic("out", out)
+	go func() {
+		for n := range 2 {
+			v, _ := lox.ToValue(n + 1)
+			in <- v
+		}
+	}()
+
+	if err := interp.Run(`hits.add(receive("in")); hits.add(receive("in") * limit); send("out", hits);`); err != nil {
+		t.Fatal(err)
+	}
+	if c.n != 7 {
+		t.Errorf("expected the counter to be at 7 but it is at %d", c.n)
+	}
+	object, err := lox.FromValue(<-out)
+	if err != nil || object != c {
+		t.Errorf("expected the foreign value to hold the counter but got %v, %v", object, err)
+	}
+}
+
+func TestNativeModules(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+	greet := func(args []lox.Value) (lox.Value, error) {
+		return lox.ToValue("hello " + args[0].DebugPrint())
+	}
+
+	if err := interp.RegisterModule("math", map[string]lox.Func{"greet": {Arity: 1, Fn: greet}}); err == nil {
+		t.Error("expected registering a standard module again to fail")
+	}
+	if err := interp.RegisterModule("greetings", map[string]lox.Func{"greet": {Arity: 1, Fn: greet}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := interp.Run(`print math.sqrt(16); print greetings.greet("lox"); greetings.greet = nil;`); err != nil {
+		t.Fatal(err)
+	}
+	// assigning a member does not affect the scripts run afterwards
+	if err := interp.Run(`print greetings.greet("again");`); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "4\nhello lox\nhello again\n" {
+		t.Errorf("expected the module members to be called but got %q", stdout.String())
+	}
+
+	listed := map[string]lox.NativeInfo{}
+	for _, native := range interp.Natives() {
+		listed[native.Name] = native
+	}
+	if listed["greetings.greet"].Arity != 1 || listed["math.sqrt"].Doc == "" {
+		t.Errorf("expected the module members to be listed but got %v", listed)
+	}
+
+	if err := interp.RemoveModule("greetings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := interp.Run(`greetings.greet("lox");`); err == nil {
+		t.Error("expected calling a member of a removed module to fail")
+	}
+
+	sandboxed := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr), lox.WithoutMath())
+	if err := sandboxed.Run(`math.sqrt(2);`); err == nil {
+		t.Error("expected the math module to be undefined without the math natives")
+	}
+}
+
+func TestArguments(t *testing.T) {
+	t.Setenv("LOX_TEST_GREETING", "hello")
+	var stdout, stderr bytes.Buffer
+	interp := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr), lox.WithArgs("a", "b c"))
+
+	if err := interp.Run(`print ARGS; push(ARGS, "d"); print env("LOX_TEST_GREETING"); print env("LOX_TEST_UNSET");`); err != nil {
+		t.Fatal(err)
+	}
+	// every script gets its own ARGS
+	if err := interp.Run(`print len(ARGS);`); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[\"a\", \"b c\"]\nhello\nnil\n2\n"; stdout.String() != want {
+		t.Errorf("expected %q but got %q", want, stdout.String())
+	}
+}
+
+func TestSeed(t *testing.T) {
+	numbers := func(interp *lox.Interpreter, source string) string {
+		t.Helper()
+		value, err := interp.Eval(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value.DebugPrint()
+	}
+
+	const draw = `[random(), randomInt(0, 1000), random()]`
+	first, second := lox.New(lox.WithSeed(7)), lox.New(lox.WithSeed(7))
+	if a, b := numbers(first, draw), numbers(second, draw); a != b {
+		t.Errorf("expected interpreters with the same seed to draw the same numbers but got %s and %s", a, b)
+	}
+
+	// every interpreter draws from its own source
+	other := lox.New(lox.WithSeed(7))
+	numbers(first, draw)
+	if a, b := numbers(second, draw), numbers(other, draw); a == b {
+		t.Errorf("expected the sources of the interpreters to advance independently but both drew %s", a)
+	}
+
+	for _, interp := range []*lox.Interpreter{first, other} {
+		if err := interp.Run(`seed(42);`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a, b := numbers(first, draw), numbers(other, draw); a != b {
+		t.Errorf("expected seed to make random reproducible but got %s and %s", a, b)
+	}
+
+	// seeding or drawing from one interpreter leaves the
+	// sequence of another interpreter unchanged
+	alone, interleaved := lox.New(lox.WithSeed(3)), lox.New(lox.WithSeed(3))
+	noisy := lox.New(lox.WithSeed(5))
+	var want, got []string
+	for range 3 {
+		want = append(want, numbers(alone, draw))
+	}
+	for range 3 {
+		if err := noisy.Run(`seed(3); random();`); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, numbers(interleaved, draw))
+		numbers(noisy, draw)
+	}
+	if !slices.Equal(want, got) {
+		t.Errorf("expected the sequence of a seeded interpreter to be %v whatever other interpreters draw but got %v", want, got)
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib/util.lox": `fun double(x) { return x * 2; } print "util loaded";`,
+		"counter.lox":  `import "lib/util.lox"; var n = 0; fun next() { n = n + 1; return double(n); }`,
+		"a.lox":        `import b;`,
+		"b.lox":        `import a;`,
+	}
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	interp := lox.New(lox.WithModules(dir), lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+	err := interp.Run(`
+import "lib/util.lox";
+import counter;
+print double(1);
+print counter.next();
+print counter.next();
+`)
+	if err != nil {
+		t.Fatal(err, stderr.String())
+	}
+	// util is run once, though it is imported twice
+	if stdout.String() != "util loaded\n2\n2\n4\n" {
+		t.Errorf("expected the modules to be run once but got %q", stdout.String())
+	}
+
+	if err := interp.Run(`import a;`); err == nil {
+		t.Error("expected the import cycle to fail")
+	}
+	if !strings.Contains(stderr.String(), "import cycle: a.lox -> b.lox -> a.lox") {
+		t.Errorf("expected the import cycle to be reported but got %q", stderr.String())
+	}
+
+	if err := lox.New(lox.WithStderr(&stderr)).Run(`import counter;`); err == nil {
+		t.Error("expected imports to fail without WithModules")
+	}
+}
+
+func TestNormalizedOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	path := filepath.Join(t.TempDir(), "crlf.txt")
+	if err := os.WriteFile(path, []byte("a\r\nb"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	interp := lox.New(lox.WithNormalizedOutput(), lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+	defer interp.RemoveFunc("dir")
+	err := interp.RegisterFunc("dir", 0, func(_ []lox.Value) (lox.Value, error) {
+		return lox.ToValue(filepath.Dir(path))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := interp.Run(`print readFile(dir() + "/crlf.txt");`); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "a\nb\n" {
+		t.Errorf("expected the line endings to be normalized but got %q", stdout.String())
+	}
+
+	interp.Run(`readFile(dir() + "/missing.txt");`)
+	want := "open " + filepath.ToSlash(filepath.Dir(path)) + "/missing.txt: no such file or directory"
+	if !strings.Contains(stderr.String(), want) {
+		t.Errorf("expected the error %q but got %q", want, stderr.String())
+	}
+}
+
+func TestLimits(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr), lox.WithFuel(10))
+
+	if err := interp.Run(`for (var i = 0; i < 3; i = i + 1) print i;`); err != nil {
+		t.Fatalf("expected the script to have enough fuel but got %v", err)
+	}
+
+	err := interp.Run(`var i = 0; while (true) { i = i + 1; } print "unreachable";`)
+	var limit lox.LimitError
+	if !errors.Is(err, lox.ErrFuelExhausted) || !errors.As(err, &limit) || limit.Token.Line != 1 {
+		t.Errorf("expected the fuel to run out but got %v", err)
+	}
+	if strings.Contains(stdout.String(), "unreachable") {
+		t.Error("expected the script to stop once the fuel ran out")
+	}
+
+	// every script gets the full fuel
+	if err := interp.Run(`print 1;`); err != nil {
+		t.Errorf("expected the next script to be refueled but got %v", err)
+	}
+
+	interp = lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr), lox.WithTimeout(10*time.Millisecond))
+	err = interp.Run(`while (true) {}`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the script to time out but got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	interp = lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr))
+	if err := interp.RunContext(ctx, `print 1;`); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled context to stop the script but got %v", err)
+	}
+	if _, err := interp.EvalContext(ctx, `len("a")`); err != nil {
+		t.Errorf("expected calling a native not to be interrupted but got %v", err)
+	}
+	if err := interp.Run(`fun f() { return 1; }`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := interp.EvalContext(ctx, `f()`); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled context to stop the call but got %v", err)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	strict := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr),
+		lox.WithParseOptions(lox.StrictParseOptions()))
+
+	rejected := map[string]string{
+		`print true ? 1 : 2;`:            "conditional operator is not enabled",
+		`var f = fun (a) { return a; };`: "anonymous functions are not allowed",
+		"print 1\nprint 2;":              "expected ';'",
+	}
+	for source, want := range rejected {
+		if err := strict.Run(source); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q to fail with %q but got %v", source, want, err)
+		}
+	}
+
+	options := lox.DefaultParseOptions()
+	options.RequireSemicolons = false
+	options.MaxArguments = 2
+	loose := lox.New(lox.WithStdout(&stdout), lox.WithStderr(&stderr), lox.WithParseOptions(options))
+
+	stdout.Reset()
+	if err := loose.Run("fun add(a, b) { return a + b }\nprint add(1, 2)\nprint true ? 3 : 4"); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "3\n3\n" {
+		t.Errorf("expected 3 twice but got %q", stdout.String())
+	}
+
+	if err := loose.Run(`print add(1, 2, 3);`); err == nil || !strings.Contains(err.Error(), "more than 2 arguments") {
+		t.Errorf("expected too many arguments to fail but got %v", err)
+	}
+}
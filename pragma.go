@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/urfave/cli/v2"
+	"os"
+	"strconv"
+	"time"
+)
+
+// pragmaValidators validates the value of every known pragma. A pragma
+// sets the same option as the interpreter flag it is named after.
+var pragmaValidators = map[string]func(value string) error{
+	"strict-bool": validateBool,
+	"count-loops": validateBool,
+	"normalize":   validateBool,
+	"max-depth": func(value string) error {
+		if depth, err := strconv.Atoi(value); err != nil || depth < 1 {
+			return errors.New("expected a depth of at least 1")
+		}
+		return nil
+	},
+	"max-iterations": func(value string) error {
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return errors.New("expected a number of iterations of at least 0")
+		}
+		return nil
+	},
+	"memoize": func(value string) error {
+		if size, err := strconv.Atoi(value); value != "" && (err != nil || size < 0) {
+			return errors.New("expected no value or the number of results to remember")
+		}
+		return nil
+	},
+	"fuel": func(value string) error {
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return errors.New("expected a number of statements of at least 0")
+		}
+		return nil
+	},
+	"timeout":        validateDuration,
+	"slow-statement": validateDuration,
+	"dialect": func(value string) error {
+		if _, ok := dialects[value]; !ok {
+			return errors.New("expected book or native-print")
+		}
+		return nil
+	},
+}
+
+func validateBool(value string) error {
+	if value != "" && value != "true" && value != "false" {
+		return errors.New("expected no value, true or false")
+	}
+	return nil
+}
+
+func validateDuration(value string) error {
+	if _, err := time.ParseDuration(value); err != nil {
+		return errors.New("expected a duration such as 500ms or 2s")
+	}
+	return nil
+}
+
+// scriptPragmas returns the values of the pragmas of source by name and
+// applies the dialect, which must be known before the script is scanned.
+// Options set by flags take precedence over pragmas, which are left out.
+func scriptPragmas(cCtx *cli.Context, source string) (map[string]string, error) {
+	report := diag.NewRenderer(source, os.Stderr).Report
+	failed := false
+	pragmas := map[string]string{}
+	for _, pragma := range scan.Pragmas(source, func(err error) { failed = true; report(err) }) {
+		validate, ok := pragmaValidators[pragma.Name]
+		if !ok {
+			failed = true
+			report(pragmaError(pragma, pragma.Name, fmt.Sprintf("unknown pragma '%s'", pragma.Name)))
+			continue
+		}
+
+		if err := validate(pragma.Value); err != nil {
+			failed = true
+			report(pragmaError(pragma, pragma.Name+"="+pragma.Value, err.Error()))
+			continue
+		}
+
+		if !cCtx.IsSet(pragma.Name) {
+			pragmas[pragma.Name] = pragma.Value
+		}
+	}
+
+	if failed {
+		return nil, cli.Exit("", exitData)
+	}
+
+	// scripts run one after another must not inherit the dialect
+	if !cCtx.IsSet("dialect") {
+		dialect = dialects["book"]
+		if name, ok := pragmas["dialect"]; ok {
+			dialect = dialects[name]
+		}
+		ast.SetDialect(dialect)
+	}
+
+	return pragmas, nil
+}
+
+func pragmaError(pragma scan.Pragma, text string, message string) error {
+	return scan.ScanError{Line: pragma.Line, Lexme: text, Message: message, Offset: pragma.Offset}
+}
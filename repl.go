@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/ast"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/parse"
+	"github.com/LucazFFz/lox/internal/scan"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// evaluations running longer than this show a spinner
+	spinnerDelay = 500 * time.Millisecond
+	spinnerTick  = 100 * time.Millisecond
+)
+
+var spinnerFrames = []rune(`|/-\`)
+
+// replSession runs the inputs of a REPL, every input sees the globals
+// defined by the inputs before it. An input failing to parse defines
+// nothing, one failing at runtime keeps the globals defined before the
+// error. Inputs are resolved on their own, the resolver does not track
+// globals so nothing it knows carries over between inputs.
+type replSession struct {
+	interpreter *ast.Session
+	// the formatted source of the statements run without an error, in order
+	executed []string
+}
+
+func newReplSession() *replSession {
+	return &replSession{interpreter: ast.NewSession()}
+}
+
+// exec runs an input of statements, one at a time so the
+// statements running without an error can be saved
+func (s *replSession) exec(source string) error {
+	return execWith(source, func(stmts []ast.Stmt, resolution *ast.Resolution, report func(error)) error {
+		var err error
+		for _, stmt := range stmts {
+			if stmtErr := s.interpreter.Interpret([]ast.Stmt{stmt}, resolution, report); stmtErr != nil {
+				err = stmtErr
+				// the evaluation was interrupted
+				if ast.Context().Err() != nil {
+					break
+				}
+				continue
+			}
+			s.executed = append(s.executed, ast.Format(source, []ast.Stmt{stmt}, nil))
+		}
+		return err
+	})
+}
+
+// load runs the script at path as an input
+func (s *replSession) load(path string) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	s.exec(string(source))
+	return nil
+}
+
+// save writes the statements run without an error to the file at path,
+// running the file recreates the globals of the session. Expression
+// inputs are left out, comments are lost.
+func (s *replSession) save(path string) error {
+	return os.WriteFile(path, []byte(strings.Join(s.executed, "")), 0o644)
+}
+
+// execExpr evaluates an input holding a single expression
+// and prints its value, so values can be inspected without print
+func (s *replSession) execExpr(source string) {
+	report := diag.NewRenderer(source, stderr).Report
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{Dialect: dialect})
+	expr, err := parse.ParseExpression(tokens, report)
+	if err != nil || scanErr != nil {
+		return
+	}
+
+	value, err := s.interpreter.InterpretExpr(expr, nil)
+	if err != nil {
+		report(err)
+		return
+	}
+
+	fmt.Fprintln(stdout, value.DebugPrint())
+}
+
+// evaluate runs eval on its own goroutine, stopping it without ending the
+// session on Ctrl-C. The interpreter stops at the next statement once the
+// context of the evaluation is cancelled, which leaves the globals defined
+// so far in place. A second Ctrl-C quits the REPL, e.g. if eval is stuck
+// in a native which does not check the context.
+//
+// Evaluations running for longer than spinnerDelay show a spinner with
+// the elapsed time on stderr if it is a terminal.
+func evaluate(eval func()) {
+	session := ast.Context()
+	ctx, cancel := context.WithCancel(session)
+	ast.SetContext(ctx)
+	defer func() {
+		cancel()
+		ast.SetContext(session)
+	}()
+
+	// the evaluation reports its errors from its own goroutine
+	previous := stderr
+	stderr = &lockedWriter{w: previous}
+	defer func() { stderr = previous }()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		eval()
+	}()
+
+	start := time.Now()
+	ticker := time.NewTicker(spinnerTick)
+	defer ticker.Stop()
+
+	spinning := false
+	for frame := 0; ; frame++ {
+		select {
+		case <-done:
+			if spinning {
+				clearSpinner()
+			}
+			return
+		case <-interrupts:
+			if ctx.Err() != nil {
+				clearSpinner()
+				fmt.Fprintln(stderr, "Leaving Lox REPL")
+				os.Exit(130)
+			}
+
+			// the notice is printed before the error of the interrupted evaluation
+			if spinning {
+				clearSpinner()
+			}
+			spinning = false
+			fmt.Fprintln(stderr, "interrupting evaluation, press Ctrl-C again to quit")
+			cancel()
+		case <-ticker.C:
+			if ctx.Err() != nil || time.Since(start) < spinnerDelay || !isTerminal(os.Stderr) {
+				continue
+			}
+
+			spinning = true
+			fmt.Fprintf(os.Stderr, "\r%c %s\033[K",
+				spinnerFrames[frame%len(spinnerFrames)], time.Since(start).Round(spinnerTick))
+		}
+	}
+}
+
+// lockedWriter serializes the writes of goroutines to a shared writer
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func clearSpinner() {
+	if isTerminal(os.Stderr) {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"github.com/LucazFFz/lox/internal/ast"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplSession(t *testing.T) {
+	var out, errs bytes.Buffer
+	stdout, stderr = &out, &errs
+	defer func() { stdout, stderr = os.Stdout, os.Stderr }()
+
+	session := newReplSession()
+	inputs := []struct {
+		source string
+		// the printed value of an expression input
+		want string
+	}{
+		// the natives are defined for an expression as the first input
+		{source: `len("ab")`, want: "2"},
+		{source: `var a = 1;`},
+		{source: `a + 1`, want: "2"},
+		// nothing is defined by an input failing to parse
+		{source: `var b = 2; var c = ;`},
+		{source: `a`, want: "1"},
+		// the globals defined before a runtime error stay defined
+		{source: `var d = 3; nil + 1; var e = 4;`},
+		{source: `d + e`, want: "7"},
+		// a redefined native stays redefined
+		{source: `fun len(x) { return 0; }`},
+		{source: `len("ab")`, want: "0"},
+	}
+
+	for _, input := range inputs {
+		out.Reset()
+		if strings.HasSuffix(input.source, ";") || strings.HasSuffix(input.source, "}") {
+			session.exec(input.source)
+		} else {
+			session.execExpr(input.source)
+		}
+
+		if got := strings.TrimSpace(out.String()); got != input.want {
+			t.Errorf("%s: expected %q but got %q, errors:\n%s", input.source, input.want, got, errs.String())
+		}
+	}
+
+	out.Reset()
+	session.execExpr("b")
+	if !strings.Contains(errs.String(), "undefined variable 'b'") {
+		t.Errorf("expected b to be undefined but got %q", out.String())
+	}
+}
+
+func TestReplLoadAndSave(t *testing.T) {
+	var out, errs bytes.Buffer
+	stdout, stderr = &out, &errs
+	defer func() { stdout, stderr = os.Stdout, os.Stderr }()
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.lox")
+	if err := os.WriteFile(script, []byte("var a = 1;\nfun inc(x) { return x + 1; }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	session := newReplSession()
+	if err := session.load(script); err != nil {
+		t.Fatal(err)
+	}
+	// the failing statement is not saved, nor is an input failing to parse
+	session.exec(`var b = inc(a); nil + 1;`)
+	session.exec(`var c = ;`)
+
+	saved := filepath.Join(dir, "saved.lox")
+	if err := session.save(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "var a = 1;\nfun inc(x) {\n    return x + 1;\n}\nvar b = inc(a);\n"
+	if string(data) != want {
+		t.Errorf("expected the session to be saved as\n%s\nbut got\n%s", want, data)
+	}
+
+	if err := session.load(filepath.Join(dir, "missing.lox")); err == nil {
+		t.Error("expected loading a missing file to fail")
+	}
+}
+
+func TestReplInterrupt(t *testing.T) {
+	var out, errs bytes.Buffer
+	stdout, stderr = &out, &errs
+	ast.SetOutput(&out)
+	defer func() {
+		stdout, stderr = os.Stdout, os.Stderr
+		ast.SetOutput(os.Stdout)
+	}()
+
+	session := newReplSession()
+	evaluate(func() {
+		session.exec(`var i = 0;`)
+		// the evaluation is interrupted like by Ctrl-C
+		process, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := process.Signal(os.Interrupt); err != nil {
+			t.Error(err)
+			return
+		}
+		session.exec(`while (true) i = i + 1;`)
+	})
+	// the session outlives the evaluation, keeping the globals
+	// defined before it was interrupted
+	evaluate(func() { session.exec(`print i >= 0;`) })
+
+	compareGolden(t, "interrupt.golden", "stdout:\n"+out.String()+"stderr:\n"+errs.String())
+}
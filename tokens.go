@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/LucazFFz/lox/internal/diag"
+	"github.com/LucazFFz/lox/internal/scan"
+	"github.com/LucazFFz/lox/internal/token"
+	"github.com/urfave/cli/v2"
+)
+
+var tokensCommand = &cli.Command{
+	Name:      "tokens",
+	Usage:     "print the tokens of a script",
+	ArgsUsage: "<script>",
+	Flags: []cli.Flag{
+		dialectFlag,
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the tokens as a JSON array",
+		},
+	},
+	OnUsageError: onUsageError,
+	Action: func(cCtx *cli.Context) error {
+		if err := expectScripts(cCtx, 1, 1); err != nil {
+			return err
+		}
+
+		source, err := readScript(cCtx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		if _, err := scriptPragmas(cCtx, source); err != nil {
+			return err
+		}
+
+		return dumpTokens(source, cCtx.Bool("json"))
+	},
+}
+
+// tokenDump is the JSON representation of a token
+type tokenDump struct {
+	Type    string `json:"type"`
+	Lexme   string `json:"lexme"`
+	Literal any    `json:"literal,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Offset  int    `json:"offset"`
+}
+
+func dumpTokens(source string, asJSON bool) error {
+	report := diag.NewRenderer(source, stderr).Report
+	tokens, scanErr := scan.Scan(source, report, scan.ScanContext{Dialect: dialect})
+	index := token.NewLineIndex(source)
+
+	dumps := make([]tokenDump, len(tokens))
+	for i, tok := range tokens {
+		dumps[i] = tokenDump{
+			Type:    tok.Type.String(),
+			Lexme:   tok.Lexme,
+			Literal: tok.Literal,
+			Line:    tok.Line,
+			Column:  index.Position(tok.Offset).Column,
+			Offset:  tok.Offset,
+		}
+	}
+
+	if asJSON {
+		out, err := json.MarshalIndent(dumps, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+	} else {
+		for _, dump := range dumps {
+			fmt.Fprintf(stdout, "%d:%d\t%-14s %q", dump.Line, dump.Column, dump.Type, dump.Lexme)
+			if dump.Literal != nil {
+				fmt.Fprintf(stdout, "\t%v", dump.Literal)
+			}
+			fmt.Fprintln(stdout)
+		}
+	}
+
+	// the tokens are printed even if the script fails to scan
+	if scanErr != nil {
+		return cli.Exit("", exitData)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Command astgen generates the nodes of the syntax tree in internal/ast,
+// which are declared below. It writes expr.go and stmt.go declaring the
+// expressions and statements, and visitor.go declaring their visitors and
+// Accept methods, to the directory given as its argument or the current
+// directory. It is run by go generate in internal/ast:
+//
+//	go generate ./internal/ast
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// node is a type of node, the body of the struct is written as is
+type node struct {
+	name   string
+	doc    string
+	fields string
+}
+
+// the DebugPrint and Evaluate methods of the nodes are written by hand in
+// debug_print.go and evaluate.go, a new node needs both
+var exprs = []node{
+	{name: "Binary", fields: `
+		Left  Expr
+		Op    token.Token
+		Right Expr`},
+	{name: "Grouping", fields: `
+		Expr Expr`},
+	{name: "Literal", fields: `
+		Value LoxValue
+		// zero for literals not written in the source
+		Token token.Token`},
+	{name: "Variable", fields: `
+		Name token.Token`},
+	{name: "Unary", fields: `
+		Op    token.Token
+		Right Expr`},
+	{name: "Prefix", doc: "++target or --target, evaluates to the updated value", fields: `
+		Op     token.Token
+		Target Expr`},
+	{name: "Postfix", doc: "target++ or target--, evaluates to the value before the update", fields: `
+		Op     token.Token
+		Target Expr`},
+	{name: "Ternary", fields: `
+		Condition Expr
+		Left      Expr
+		Right     Expr`},
+	{name: "Assign", fields: `
+		Name  token.Token
+		Value Expr`},
+	{name: "List", fields: `
+		Bracket  token.Token
+		Elements []Expr`},
+	{name: "Map", fields: `
+		Brace  token.Token
+		Keys   []Expr
+		Values []Expr`},
+	{name: "Object", doc: "object { name: value, ... }", fields: `
+		Keyword token.Token
+		Names   []token.Token
+		Values  []Expr`},
+	{name: "Index", fields: `
+		Object  Expr
+		Bracket token.Token
+		Index   Expr`},
+	{name: "Slice", doc: "Start and End are nil when omitted, e.g. list[:2]", fields: `
+		Object  Expr
+		Bracket token.Token
+		Start   Expr
+		End     Expr`},
+	{name: "IndexAssign", fields: `
+		Object  Expr
+		Bracket token.Token
+		Index   Expr
+		Value   Expr`},
+	{name: "SliceAssign", fields: `
+		Object  Expr
+		Bracket token.Token
+		Start   Expr
+		End     Expr
+		Value   Expr`},
+	{name: "Get", doc: "object.name", fields: `
+		Object Expr
+		Name   token.Token`},
+	{name: "Set", doc: "object.name = value", fields: `
+		Object Expr
+		Name   token.Token
+		Value  Expr`},
+	{name: "Call", doc: "callee(arguments...), formerly CallStmt", fields: `
+		Callee Expr
+		// the parenthesis closing the arguments, locates the call
+		Paren     token.Token
+		Arguments []Expr`},
+	{name: "Function", doc: "an anonymous function, fun (parameters...) { body }", fields: `
+		Parameters []token.Token
+		// the types the parameters are annotated with, zero for a parameter
+		// without one and nil if none of the parameters has one
+		ParameterTypes []token.Token
+		// the annotated return type, zero if the function has none
+		ReturnType  token.Token
+		Body        []Stmt
+		IsGenerator bool
+		// the brace closing the body
+		Brace token.Token`},
+	{name: "Nothing"},
+}
+
+var stmts = []node{
+	{name: "Expression", fields: `
+		Expr Expr`},
+	{name: "Print", fields: `
+		Keyword token.Token
+		Expr    Expr`},
+	{name: "Var", fields: `
+		Name token.Token
+		// the annotated type, zero if the variable has none
+		Type        token.Token
+		Initializer Expr`},
+	{name: "Block", fields: `
+		Statements []Stmt
+		// the closing brace, zero for blocks created when desugaring
+		Brace token.Token`},
+	{name: "If", fields: `
+		Condition  Expr
+		ThenBranch Stmt
+		ElseBranch Stmt`},
+	{name: "While", fields: `
+		// the "while", "for" or "do" keyword the loop was written with,
+		// the body of do-while loops runs before the condition is checked
+		Keyword token.Token
+		// the label of the loop, zero if it has none
+		Label     token.Token
+		Condition Expr
+		Body      Stmt
+		// evaluated after every iteration, even one ended by a continue
+		// statement, set when desugaring for loops
+		Increment Expr`},
+	{name: "ForIn", doc: "for (var name in iterable) body", fields: `
+		Name     token.Token
+		Iterable Expr
+		Body     Stmt
+		Label    token.Token`},
+	{name: "Break", doc: "Label is the label of the loop to break out of,\nzero for the innermost loop", fields: `
+		Keyword token.Token
+		Label   token.Token`},
+	{name: "Continue", fields: `
+		Keyword token.Token
+		Label   token.Token`},
+	{name: "Assert", doc: "fails with a runtime error showing Condition\nif Condition is false, Message is optional", fields: `
+		Keyword   token.Token
+		Condition Expr
+		Message   Expr`},
+	{name: "Import", doc: "runs the module at Path, a STRING with the path of the module or an\n" +
+		"IDENTIFIER naming a module in the directory of the importer. Importing a\n" +
+		"path defines the top-level declarations of the module in the current\n" +
+		"environment, importing a name defines a map of them called name.", fields: `
+		Keyword token.Token
+		Path    token.Token`},
+	{name: "Yield", doc: "only valid inside functions, which become generator functions", fields: `
+		Keyword token.Token
+		Expr    Expr`},
+	{name: "Return", fields: `
+		Keyword token.Token
+		Expr    Expr`},
+	{name: "Function", doc: "a function declaration, the function named Name", fields: `
+		Name token.Token
+		FunctionExpr`},
+}
+
+const header = "// Code generated by tools/astgen. DO NOT EDIT.\n\npackage ast\n\n"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("astgen: ")
+
+	dir := "."
+	if len(os.Args) > 1 {
+		dir = os.Args[1]
+	}
+
+	for name, source := range generate() {
+		formatted, err := format.Source(source)
+		if err != nil {
+			log.Fatalf("formatting %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), formatted, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// generate returns the unformatted source of the generated files by their names
+func generate() map[string][]byte {
+	var visitors bytes.Buffer
+	visitors.WriteString(header)
+	defineVisitor(&visitors, "Expr", "expressions", "expr", exprs)
+	defineVisitor(&visitors, "Stmt", "statements", "stmt", stmts)
+
+	return map[string][]byte{
+		"expr.go":    defineNodes("Expr", "EvaluateExpr", exprs),
+		"stmt.go":    defineNodes("Stmt", "EvaluateStmt", stmts),
+		"visitor.go": visitors.Bytes(),
+	}
+}
+
+func defineNodes(base string, evaluate string, nodes []node) []byte {
+	var b bytes.Buffer
+	b.WriteString(header)
+	b.WriteString("import \"github.com/LucazFFz/lox/internal/token\"\n\n")
+	fmt.Fprintf(&b, "type %s interface {\nDebugPrint\n%s\nAccept%s\n}\n\n", base, evaluate, base)
+
+	for _, n := range nodes {
+		if n.doc != "" {
+			b.WriteString("// " + strings.ReplaceAll(n.doc, "\n", "\n// ") + "\n")
+		}
+		if n.fields == "" {
+			fmt.Fprintf(&b, "type %s%s struct{}\n\n", n.name, base)
+			continue
+		}
+		fmt.Fprintf(&b, "type %s%s struct {%s\n}\n\n", n.name, base, n.fields)
+	}
+	return b.Bytes()
+}
+
+func defineVisitor(b *bytes.Buffer, base string, description string, param string, nodes []node) {
+	fmt.Fprintf(b, "// %sVisitor is an operation on %s, Accept calls the method\n", base, description)
+	b.WriteString("// of the visitor visiting the type of the node.\n")
+	fmt.Fprintf(b, "type %sVisitor interface {\n", base)
+	for _, n := range nodes {
+		fmt.Fprintf(b, "Visit%[1]s%[2]s(%[3]s %[1]s%[2]s) any\n", n.name, base, param)
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(b, "type Accept%[1]s interface {\nAccept(v %[1]sVisitor) any\n}\n\n", base)
+	for _, n := range nodes {
+		fmt.Fprintf(b, "func (%[3]s %[1]s%[2]s) Accept(v %[2]sVisitor) any {\nreturn v.Visit%[1]s%[2]s(%[3]s)\n}\n\n", n.name, base, param)
+	}
+}
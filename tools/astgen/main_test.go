@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenerated fails if the files in internal/ast were edited
+// by hand or the declarations were changed without running go generate
+func TestGenerated(t *testing.T) {
+	for name, source := range generate() {
+		formatted, err := format.Source(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		written, err := os.ReadFile(filepath.Join("..", "..", "internal", "ast", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(written, formatted) {
+			t.Errorf("internal/ast/%s is out of date, run go generate ./internal/ast", name)
+		}
+	}
+}